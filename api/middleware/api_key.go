@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"github.com/gorilla/mux"
+	"net/http"
+	"noterfy/api"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/principal"
+)
+
+// DefaultAPIKeyHeader is the default header of the API key
+// that identifies a client.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// NewAPIKeyMiddleware takes conf API key config and returns an
+// instance of named API key middleware.
+func NewAPIKeyMiddleware(conf APIKeyConfig) api.NamedMiddleware {
+	return api.NewNamedMiddleware("APIKey", APIKeyAuth(conf))
+}
+
+// APIKey is the key of a client of the API.
+type APIKey struct {
+	// Name identifies the client. It is the name of its principal.
+	Name string
+	// Key is the secret sent by the client in the API key header.
+	Key string
+}
+
+// APIKeyConfig contains all the necessary configuration
+// for the API key middleware.
+type APIKeyConfig struct {
+	// Header is the header of the API key. Default is
+	// DefaultAPIKeyHeader.
+	Header string
+	// Keys are the keys of the known clients.
+	Keys []APIKey
+}
+
+// APIKeyAuth is a middleware that sets the client of the API key of
+// the request as its principal. See principal.FromContext. Only the
+// keys of conf are accepted, the requests with an unknown key are
+// anonymous so a client can't choose its identity. The principal of
+// a verified client certificate takes precedence.
+func APIKeyAuth(conf APIKeyConfig) mux.MiddlewareFunc {
+	if conf.Header == "" {
+		conf.Header = DefaultAPIKeyHeader
+	}
+
+	// The keys are looked up by their digest so the lookup
+	// doesn't leak how much of a key matches.
+	names := make(map[[sha256.Size]byte]string, len(conf.Keys))
+	for _, k := range conf.Keys {
+		names[sha256.Sum256([]byte(k.Key))] = k.Name
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(conf.Header)
+			if _, ok := principal.FromContext(r.Context()); ok || key == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			name, ok := names[sha256.Sum256([]byte(key))]
+			if !ok {
+				logger.FromContext(r.Context()).Debug("middleware: unknown API key")
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			p := principal.Principal{Name: name, Method: principal.MethodAPIKey}
+			ctx := principal.WithContext(r.Context(), p)
+			ctx = logger.WithContext(ctx, logger.FromContext(ctx).WithField("principal", p.Name))
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/pkg/principal"
+	"testing"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	var (
+		got   principal.Principal
+		found bool
+	)
+	h := APIKeyAuth(APIKeyConfig{
+		Header: "X-Key",
+		Keys:   []APIKey{{Name: "mobile", Key: "secret"}},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, found = principal.FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name  string
+		key   string
+		setup func(r *http.Request) *http.Request
+		want  principal.Principal
+		found bool
+	}{
+		{name: "Known key", key: "secret", want: principal.Principal{Name: "mobile", Method: principal.MethodAPIKey}, found: true},
+		{name: "Unknown key", key: "guess"},
+		{name: "Missing key"},
+		{
+			name: "The client certificate takes precedence",
+			key:  "secret",
+			setup: func(r *http.Request) *http.Request {
+				p := principal.Principal{Name: "CN=alice", Method: principal.MethodClientCert}
+				return r.WithContext(principal.WithContext(r.Context(), p))
+			},
+			want:  principal.Principal{Name: "CN=alice", Method: principal.MethodClientCert},
+			found: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found = principal.Principal{}, false
+			req := httptest.NewRequest(http.MethodGet, "/v1/note", nil)
+			req.Header.Set("X-Key", tt.key)
+			if tt.setup != nil {
+				req = tt.setup(req)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
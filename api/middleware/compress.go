@@ -0,0 +1,305 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net/http"
+	"noterfy/api"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCompressMinSize is the default minimum size in bytes of
+// the compressed responses.
+const DefaultCompressMinSize = 1024
+
+// The encodings of the compressed responses in the
+// order of preference.
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// CompressConfig contains all the necessary configuration
+// for the compression middleware.
+type CompressConfig struct {
+	// MinSize is the minimum size in bytes of the compressed responses.
+	// The smaller responses are not worth compressing. If the value is
+	// zero the DefaultCompressMinSize will be use.
+	MinSize int
+}
+
+// NewCompressMiddleware takes the optional conf and returns an
+// instance of named compression middleware. If nil is provided
+// it will use the default configuration.
+func NewCompressMiddleware(conf *CompressConfig) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Compress", Compress(conf))
+}
+
+// Compress is a middleware that compresses the responses with brotli
+// or gzip, depending on the Accept-Encoding header of the request.
+// Only the responses of a compressible media type and at least the
+// minimum size are compressed.
+func Compress(conf *CompressConfig) mux.MiddlewareFunc {
+	minSize := DefaultCompressMinSize
+	if conf != nil && conf.MinSize > 0 {
+		minSize = conf.MinSize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := acceptEncoding(r.Header.Values("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minSize:        minSize,
+				status:         http.StatusOK,
+			}
+			defer func() { _ = cw.Close() }()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptEncoding returns the preferred encoding supported by the
+// middleware in the values of the Accept-Encoding header. It
+// returns an empty string when none is accepted.
+func acceptEncoding(values []string) string {
+	qualities := make(map[string]float64)
+	for _, value := range values {
+		for _, coding := range strings.Split(value, ",") {
+			params := strings.Split(coding, ";")
+			name := strings.ToLower(strings.TrimSpace(params[0]))
+
+			q := 1.0
+			for _, p := range params[1:] {
+				p = strings.TrimSpace(p)
+				if strings.HasPrefix(p, "q=") {
+					if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+						q = f
+					}
+				}
+			}
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		q, ok := qualities[encoding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressible tells whether the responses of the media type
+// benefit from compression.
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case mt == "text/event-stream":
+		// The events are streamed as they happen.
+		return false
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "+json"),
+		strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+
+	switch mt {
+	case "application/json", "application/x-protobuf", "application/javascript", "application/xml":
+		return true
+	}
+	return false
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}}
+	brotliWriters = sync.Pool{New: func() interface{} {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}}
+)
+
+// compressWriter buffers the beginning of the response until it
+// knows whether the response is compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status      int
+	wroteHeader bool
+	buf         []byte
+	// started is true once the headers are written to the
+	// http.ResponseWriter.
+	started bool
+	// encoder is the compressor when the response is compressed.
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+
+	// The responses without a body are written right away.
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.start(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.started {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.startAndFlush(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements the http.Flusher for the streaming responses.
+func (w *compressWriter) Flush() {
+	if !w.started {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := w.startAndFlush(len(w.buf) >= w.minSize); err != nil {
+			return
+		}
+	}
+
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes the buffered response and finishes the compression.
+func (w *compressWriter) Close() error {
+	if !w.started {
+		if !w.wroteHeader {
+			// The handler didn't write anything.
+			return nil
+		}
+		if err := w.startAndFlush(false); err != nil {
+			return err
+		}
+	}
+
+	if w.encoder == nil {
+		return nil
+	}
+
+	err := w.encoder.Close()
+	switch e := w.encoder.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(e)
+	case *brotli.Writer:
+		brotliWriters.Put(e)
+	}
+	w.encoder = nil
+	return err
+}
+
+// startAndFlush writes the headers and the buffered beginning of the
+// response. The response is compressed when compress is true and it
+// is compressible.
+func (w *compressWriter) startAndFlush(compress bool) error {
+	w.start(compress)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// start writes the headers of the response.
+func (w *compressWriter) start(compress bool) {
+	w.started = true
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if !compressible(h.Get("Content-Type")) {
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+
+	// The caches keep a response per encoding.
+	h.Add("Vary", "Accept-Encoding")
+
+	// The ranges and the already encoded responses are
+	// not compressed.
+	if !compress || w.status == http.StatusPartialContent ||
+		h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	// The compressed response is not byte-for-byte the same
+	// representation as the uncompressed one.
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	switch w.encoding {
+	case encodingBrotli:
+		bw := brotliWriters.Get().(*brotli.Writer)
+		bw.Reset(w.ResponseWriter)
+		w.encoder = bw
+	case encodingGzip:
+		gw := gzipWriters.Get().(*gzip.Writer)
+		gw.Reset(w.ResponseWriter)
+		w.encoder = gw
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"title":"Unit Test","content":"This is a test"}`, 100)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		method         string
+		contentType    string
+		body           string
+		etag           string
+		wantEncoding   string
+		wantETag       string
+	}{
+		{
+			name:           "Brotli is preferred",
+			acceptEncoding: "gzip, deflate, br",
+			contentType:    "application/json",
+			body:           large,
+			etag:           `"v1"`,
+			wantEncoding:   "br",
+			wantETag:       `W/"v1"`,
+		},
+		{
+			name:           "Gzip",
+			acceptEncoding: "gzip",
+			contentType:    "application/x-protobuf",
+			body:           large,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "Quality of the encodings",
+			acceptEncoding: "br;q=0.5, gzip;q=0.8",
+			contentType:    "text/plain; charset=utf-8",
+			body:           large,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "Encoding refused",
+			acceptEncoding: "br;q=0, *",
+			contentType:    "application/json",
+			body:           large,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:        "Encoding not accepted",
+			contentType: "application/json",
+			body:        large,
+		},
+		{
+			name:           "Response smaller than the minimum size",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           `{"title":"Unit Test"}`,
+			etag:           `"v1"`,
+			wantETag:       `"v1"`,
+		},
+		{
+			name:           "Media type not compressible",
+			acceptEncoding: "gzip",
+			contentType:    "image/png",
+			body:           large,
+		},
+		{
+			name:           "Content type detected",
+			acceptEncoding: "gzip",
+			body:           "<html><body>" + large + "</body></html>",
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "Head request",
+			acceptEncoding: "gzip",
+			method:         http.MethodHead,
+			contentType:    "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.etag != "" {
+					w.Header().Set("ETag", tt.etag)
+				}
+				// The body is written in chunks smaller than the minimum size.
+				for body := tt.body; body != ""; {
+					n := len(body)
+					if n > 100 {
+						n = 100
+					}
+					_, _ = io.WriteString(w, body[:n])
+					body = body[n:]
+				}
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/v1/notes", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.wantEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.wantETag, rec.Header().Get("ETag"))
+
+			var body io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "br":
+				body = brotli.NewReader(rec.Body)
+			case "gzip":
+				gr, err := gzip.NewReader(rec.Body)
+				require.NoError(t, err)
+				body = gr
+			}
+			got, err := ioutil.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(got))
+
+			if tt.wantEncoding != "" {
+				assert.Less(t, rec.Body.Len(), len(tt.body))
+				assert.Equal(t, []string{"Accept-Encoding"}, rec.Header().Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestCompressStatus(t *testing.T) {
+	handler := Compress(&CompressConfig{MinSize: 10})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"message":"Note not found"}`)
+	}))
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	rec = do("/empty")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Zero(t, rec.Body.Len())
+}
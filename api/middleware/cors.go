@@ -5,6 +5,7 @@ import (
 	"github.com/rs/cors"
 	"net/http"
 	"noterfy/api"
+	"sync/atomic"
 )
 
 // NewCORSMiddleware initializes the CORS middleware. It takes
@@ -30,6 +31,27 @@ type CORSConfig struct {
 
 // CORS is a middleware for the CORS handler.
 func CORS(conf *CORSConfig) mux.MiddlewareFunc {
+	return NewCORSHandler(conf).Middleware
+}
+
+// CORSHandler is a CORS middleware whose configuration can
+// be changed while the server is running.
+type CORSHandler struct {
+	cors atomic.Value // *cors.Cors
+}
+
+// NewCORSHandler takes an optional conf for extra configuration and
+// returns a CORS handler. If nil is provided it will use the default
+// configuration.
+func NewCORSHandler(conf *CORSConfig) *CORSHandler {
+	h := new(CORSHandler)
+	h.Update(conf)
+	return h
+}
+
+// Update replaces the configuration of the handler with conf. If
+// nil is provided it will use the default configuration.
+func (h *CORSHandler) Update(conf *CORSConfig) {
 	var options cors.Options
 
 	if conf != nil {
@@ -47,7 +69,12 @@ func CORS(conf *CORSConfig) mux.MiddlewareFunc {
 		}
 	}
 
-	return func(next http.Handler) http.Handler {
-		return cors.New(options).Handler(next)
-	}
+	h.cors.Store(cors.New(options))
+}
+
+// Middleware handles the CORS requests before calling next.
+func (h *CORSHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.cors.Load().(*cors.Cors).ServeHTTP(w, r, next.ServeHTTP)
+	})
 }
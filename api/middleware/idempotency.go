@@ -0,0 +1,350 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"noterfy/api"
+	"noterfy/idempotency"
+	idempotencystore "noterfy/idempotency/store/memory"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/problem"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The headers of the idempotent requests. See the IETF draft
+// "The Idempotency-Key HTTP Header Field".
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed
+	// from the record of a previous request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// The defaults of the idempotency middleware.
+const (
+	// DefaultIdempotencyTTL is how long the responses are kept.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyLease is how long a key is held by a request
+	// in progress, so the key of a request that never completes,
+	// e.g. because the server died, is released.
+	DefaultIdempotencyLease = time.Minute
+	// DefaultIdempotencyMaxBodySize is the maximum size of the body
+	// of the requests and the recorded responses. It leaves room for
+	// the attachments.
+	DefaultIdempotencyMaxBodySize = 16 << 20
+	// MaxIdempotencyKeyLength is the maximum length of a key.
+	MaxIdempotencyKeyLength = 255
+)
+
+// The codes of the problems of the idempotent requests.
+const (
+	CodeIdempotencyKeyInvalid = "request.idempotency_key_invalid"
+	CodeIdempotencyKeyReused  = "request.idempotency_key_reused"
+	CodeIdempotencyInProgress = "request.idempotency_in_progress"
+	CodeRequestTooLarge       = "request.too_large"
+)
+
+var (
+	idempotencyKeyInvalid = problem.Kind{Code: CodeIdempotencyKeyInvalid, Status: http.StatusBadRequest, Title: "Invalid idempotency key"}
+	idempotencyKeyReused  = problem.Kind{Code: CodeIdempotencyKeyReused, Status: http.StatusUnprocessableEntity, Title: "Idempotency key reused with a different request"}
+	idempotencyInProgress = problem.Kind{Code: CodeIdempotencyInProgress, Status: http.StatusConflict, Title: "Request with the idempotency key in progress"}
+	requestTooLarge       = problem.Kind{Code: CodeRequestTooLarge, Status: http.StatusRequestEntityTooLarge, Title: "Request too large"}
+)
+
+// NewIdempotencyMiddleware takes conf idempotency config and returns
+// an instance of named idempotency middleware.
+func NewIdempotencyMiddleware(conf IdempotencyConfig) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Idempotency", Idempotency(conf))
+}
+
+// IdempotencyConfig contains all the necessary configuration
+// for the idempotency middleware.
+type IdempotencyConfig struct {
+	// Store stores the records of the requests. Default is
+	// an in-memory store.
+	Store idempotency.Store
+	// TTL is how long the responses are replayed. Default is
+	// DefaultIdempotencyTTL.
+	TTL time.Duration
+	// Lease is how long the key of a request in progress is held.
+	// Default is DefaultIdempotencyLease.
+	Lease time.Duration
+	// Methods are the methods of the requests that can have an
+	// idempotency key. Default is POST, PUT, PATCH and DELETE.
+	Methods []string
+	// MaxBodySize is the maximum size of the body of the requests
+	// with an idempotency key and of their recorded responses. Default
+	// is DefaultIdempotencyMaxBodySize.
+	MaxBodySize int64
+	// Clock tells the current time. Default is the real clock.
+	Clock clock.Clock
+}
+
+func withIdempotencyDefaults(conf IdempotencyConfig) IdempotencyConfig {
+	if conf.TTL <= 0 {
+		conf.TTL = DefaultIdempotencyTTL
+	}
+	if conf.Lease <= 0 {
+		conf.Lease = DefaultIdempotencyLease
+	}
+	if len(conf.Methods) == 0 {
+		conf.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = DefaultIdempotencyMaxBodySize
+	}
+	if conf.Clock == nil {
+		conf.Clock = clock.New()
+	}
+	if conf.Store == nil {
+		conf.Store = idempotencystore.New(conf.Clock)
+	}
+	return conf
+}
+
+// Idempotency is a middleware that processes the requests with the
+// same Idempotency-Key header only once. The response of the first
+// request is recorded for the conf TTL and replayed to its retries
+// with the Idempotent-Replayed header.
+//
+// The keys are scoped to the client, identified by its principal,
+// e.g. its verified API key. A key reused with a different method, URI or
+// body is rejected, as well as a retry while the first request is
+// in progress. The key is held for the conf Lease until the response
+// is recorded. The server errors and the panics are not recorded so
+// the requests can be retried.
+func Idempotency(conf IdempotencyConfig) mux.MiddlewareFunc {
+	conf = withIdempotencyDefaults(conf)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !containsFold(conf.Methods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > MaxIdempotencyKeyLength {
+				writeProblem(w, r, idempotencyKeyInvalid,
+					"The idempotency key must be at most "+strconv.Itoa(MaxIdempotencyKeyLength)+" characters.")
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, conf.MaxBodySize+1))
+			_ = r.Body.Close()
+			if err != nil {
+				writeProblem(w, r, problem.Internal, "")
+				return
+			}
+			if int64(len(body)) > conf.MaxBodySize {
+				writeProblem(w, r, requestTooLarge,
+					"The body of a request with an idempotency key must be at most "+strconv.FormatInt(conf.MaxBodySize, 10)+" bytes.")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := conf.Clock.Now()
+			record := &idempotency.Record{
+				Key:         idempotencyScope(r) + "|" + key,
+				Fingerprint: fingerprint(r, body),
+				CreatedTime: now,
+				ExpireTime:  now.Add(conf.Lease),
+			}
+
+			log := logger.FromContext(r.Context()).WithField("idempotency_key", key)
+			err = conf.Store.Create(r.Context(), record)
+			if errors.Is(err, idempotency.ErrExists) {
+				replay(w, r, conf.Store, record)
+				return
+			}
+			if err != nil {
+				// The requests are processed rather than failing
+				// when the store is unavailable.
+				log.Error("middleware: unable to record the idempotent request: ", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The record is kept even if the client is gone because
+			// it is likely to retry the request.
+			ctx := context.Background()
+			release := func() {
+				if err := conf.Store.Delete(ctx, record.Key); err != nil {
+					log.Error("middleware: unable to release the idempotency key: ", err)
+				}
+			}
+			defer func() {
+				if v := recover(); v != nil {
+					release()
+					panic(v)
+				}
+			}()
+
+			before := w.Header().Clone()
+			rec := &recordingWriter{ResponseWriter: w, maxSize: conf.MaxBodySize}
+			next.ServeHTTP(rec, r)
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError || status == statusClientClosed || rec.overflow {
+				release()
+				return
+			}
+
+			record.Status = status
+			record.Header = changedHeader(before, rec.header)
+			record.Body = rec.body.Bytes()
+			record.ExpireTime = conf.Clock.Now().Add(conf.TTL)
+			if err := conf.Store.Put(ctx, record); err != nil {
+				log.Error("middleware: unable to record the idempotent response: ", err)
+			}
+		})
+	}
+}
+
+// statusClientClosed is the status code of the responses
+// of the cancelled requests.
+const statusClientClosed = 499
+
+// replay writes the recorded response of the request with the
+// key of record. The request must match the recorded request.
+func replay(w http.ResponseWriter, r *http.Request, store idempotency.Store, record *idempotency.Record) {
+	recorded, err := store.Get(r.Context(), record.Key)
+	if errors.Is(err, idempotency.ErrNotFound) {
+		// The record expired or was released in the meantime.
+		w.Header().Set(RetryAfterHeader, "1")
+		writeProblem(w, r, idempotencyInProgress, "Retry the request.")
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("middleware: unable to get the idempotent request: ", err)
+		writeProblem(w, r, problem.Internal, "")
+		return
+	}
+
+	if recorded.Fingerprint != record.Fingerprint {
+		writeProblem(w, r, idempotencyKeyReused,
+			"The idempotency key was used by a request with a different method, URI or body.")
+		return
+	}
+
+	if !recorded.Completed() {
+		w.Header().Set(RetryAfterHeader, "1")
+		writeProblem(w, r, idempotencyInProgress, "The request with the idempotency key is still processed.")
+		return
+	}
+
+	h := w.Header()
+	for k, v := range recorded.Header {
+		h[k] = v
+	}
+	h.Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(recorded.Status)
+	_, _ = w.Write(recorded.Body)
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, kind problem.Kind, detail string) {
+	p := problem.FromError(nil, kind)
+	p.Detail = detail
+	p.Instance = r.URL.RequestURI()
+	problem.Write(w, p)
+}
+
+// idempotencyScope identifies the client of r by its principal.
+// The unverified API keys are ignored, see APIKeyAuth. The anonymous
+// clients share the same scope.
+func idempotencyScope(r *http.Request) string {
+	if p, ok := principal.FromContext(r.Context()); ok {
+		return "principal:" + p.Name
+	}
+	return "anonymous"
+}
+
+// fingerprint returns the digest of the method, the URI, the
+// content type and the body of r.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type")} {
+		_, _ = io.WriteString(hash, part)
+		_, _ = hash.Write([]byte{0})
+	}
+	_, _ = hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// changedHeader returns the values of after that are not in before,
+// i.e. the header of the response set by the handler rather than
+// by the outer middlewares.
+func changedHeader(before, after http.Header) http.Header {
+	changed := make(http.Header)
+	for k, v := range after {
+		if !reflect.DeepEqual(before[k], v) {
+			changed[k] = v
+		}
+	}
+	return changed
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingWriter records the status code, the header and the
+// body written to the http.ResponseWriter. The body is recorded
+// up to maxSize bytes.
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	maxSize  int64
+	overflow bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if int64(w.body.Len()+len(b)) > w.maxSize {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements the http.Flusher for the streaming responses.
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/idempotency"
+	idempotencystore "noterfy/idempotency/store/memory"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/problem"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	clock *clock.Fake
+	store idempotency.Store
+	// calls is the number of requests handled by the handler.
+	calls int32
+	// status is the status code of the handler.
+	status int
+	// handler is the handler behind the middleware.
+	handler http.Handler
+}
+
+func (s *IdempotencyTestSuite) SetupTest() {
+	s.clock = clock.NewFake(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	s.store = idempotencystore.New(s.clock)
+	s.calls = 0
+	s.status = http.StatusCreated
+	s.handler = Idempotency(IdempotencyConfig{
+		Store:       s.store,
+		TTL:         time.Hour,
+		MaxBodySize: 64,
+		Clock:       s.clock,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&s.calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/v1/note/"+strconv.Itoa(int(n)))
+		w.WriteHeader(s.status)
+		_, _ = io.WriteString(w, `{"call":`+strconv.Itoa(int(n))+`,"body":`+strconv.Quote(string(body))+`}`)
+	}))
+}
+
+// do does a request with the body and the optional header.
+func (s *IdempotencyTestSuite) do(method, target, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	// The outer middlewares set their headers first.
+	rec.Header().Set(RequestIDHeader, "outer")
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *IdempotencyTestSuite) assertProblem(rec *httptest.ResponseRecorder, status int, code string) {
+	s.Equal(status, rec.Code)
+	s.Equal(problem.MediaType, rec.Header().Get("Content-Type"))
+	var p problem.Problem
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(&p))
+	s.Equal(code, p.Code)
+}
+
+func (s *IdempotencyTestSuite) TestReplay() {
+	first := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Equal(http.StatusCreated, first.Code)
+	s.Empty(first.Header().Get(IdempotentReplayedHeader))
+
+	retry := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Equal(http.StatusCreated, retry.Code)
+	s.Equal("true", retry.Header().Get(IdempotentReplayedHeader))
+	s.Equal(first.Body.String(), retry.Body.String())
+	s.Equal("/v1/note/1", retry.Header().Get("Location"))
+	s.Equal("application/json", retry.Header().Get("Content-Type"))
+	s.Equal(int32(1), s.calls)
+
+	record, err := s.store.Get(context.Background(), "anonymous|key-1")
+	s.Require().NoError(err)
+	s.Empty(record.Header.Get(RequestIDHeader), "the headers of the outer middlewares are not recorded")
+}
+
+func (s *IdempotencyTestSuite) TestWithoutKey() {
+	s.do(http.MethodPost, "/v1/note", `{"title":"a"}`)
+	s.do(http.MethodPost, "/v1/note", `{"title":"a"}`)
+	s.Equal(int32(2), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestReadOnlyMethod() {
+	s.do(http.MethodGet, "/v1/note", "", IdempotencyKeyHeader, "key-1")
+	rec := s.do(http.MethodGet, "/v1/note", "", IdempotencyKeyHeader, "key-1")
+	s.Empty(rec.Header().Get(IdempotentReplayedHeader))
+	s.Equal(int32(2), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestKeyReusedWithDifferentRequest() {
+	s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+
+	for _, tt := range []struct {
+		method, target, body string
+	}{
+		{http.MethodPost, "/v1/note", `{"title":"b"}`},
+		{http.MethodPost, "/v1/note?template=daily", `{"title":"a"}`},
+		{http.MethodPut, "/v1/note", `{"title":"a"}`},
+	} {
+		rec := s.do(tt.method, tt.target, tt.body, IdempotencyKeyHeader, "key-1")
+		s.assertProblem(rec, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused)
+	}
+	s.Equal(int32(1), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestKeysAreScopedToTheClient() {
+	s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+
+	for _, name := range []string{"CN=client-a", "CN=client-b"} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/note", strings.NewReader(`{"title":"b"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		req = req.WithContext(principal.WithContext(req.Context(), principal.Principal{Name: name}))
+		rec := httptest.NewRecorder()
+		s.handler.ServeHTTP(rec, req)
+		s.Equal(http.StatusCreated, rec.Code)
+	}
+
+	s.Run("The unverified API keys should not scope the keys", func() {
+		rec := s.do(http.MethodPost, "/v1/note", `{"title":"c"}`, IdempotencyKeyHeader, "key-1", DefaultAPIKeyHeader, "random")
+		s.assertProblem(rec, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused)
+	})
+
+	s.Equal(int32(3), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestInProgress() {
+	req := httptest.NewRequest(http.MethodPost, "/v1/note", nil)
+	req.Header.Set("Content-Type", "application/json")
+	now := s.clock.Now()
+	s.Require().NoError(s.store.Create(context.Background(), &idempotency.Record{
+		Key:         "anonymous|key-1",
+		Fingerprint: fingerprint(req, []byte(`{"title":"a"}`)),
+		CreatedTime: now,
+		ExpireTime:  now.Add(time.Hour),
+	}))
+
+	rec := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Equal("1", rec.Header().Get(RetryAfterHeader))
+	s.assertProblem(rec, http.StatusConflict, CodeIdempotencyInProgress)
+	s.Equal(int32(0), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestExpired() {
+	s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.clock.Advance(time.Hour)
+
+	rec := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Empty(rec.Header().Get(IdempotentReplayedHeader))
+	s.Equal(int32(2), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestLease() {
+	req := httptest.NewRequest(http.MethodPost, "/v1/note", nil)
+	req.Header.Set("Content-Type", "application/json")
+	s.Require().NoError(s.store.Create(context.Background(), &idempotency.Record{
+		Key:         "anonymous|key-1",
+		Fingerprint: fingerprint(req, []byte(`{"title":"a"}`)),
+		CreatedTime: s.clock.Now(),
+		ExpireTime:  s.clock.Now().Add(DefaultIdempotencyLease),
+	}))
+	s.assertProblem(s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1"),
+		http.StatusConflict, CodeIdempotencyInProgress)
+
+	// The request that held the key never completed.
+	s.clock.Advance(DefaultIdempotencyLease)
+	rec := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Equal(http.StatusCreated, rec.Code)
+
+	s.Run("The recorded response should be kept for the TTL", func() {
+		s.clock.Advance(time.Hour - time.Second)
+		rec := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+		s.Equal("true", rec.Header().Get(IdempotentReplayedHeader))
+		s.Equal(int32(1), s.calls)
+	})
+}
+
+func (s *IdempotencyTestSuite) TestPanicReleasesTheKey() {
+	handler := s.handler
+	s.handler = Idempotency(IdempotencyConfig{Store: s.store, Clock: s.clock})(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			atomic.AddInt32(&s.calls, 1)
+			panic(http.ErrAbortHandler)
+		}))
+	s.PanicsWithValue(http.ErrAbortHandler, func() {
+		s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	})
+
+	s.handler = handler
+	rec := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Equal(http.StatusCreated, rec.Code)
+	s.Empty(rec.Header().Get(IdempotentReplayedHeader))
+	s.Equal(int32(2), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestServerErrorsAreNotRecorded() {
+	s.status = http.StatusServiceUnavailable
+	s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+
+	s.status = http.StatusCreated
+	rec := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Equal(http.StatusCreated, rec.Code)
+	s.Empty(rec.Header().Get(IdempotentReplayedHeader))
+	s.Equal(int32(2), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestClientErrorsAreRecorded() {
+	s.status = http.StatusUnprocessableEntity
+	s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+
+	s.status = http.StatusCreated
+	rec := s.do(http.MethodPost, "/v1/note", `{"title":"a"}`, IdempotencyKeyHeader, "key-1")
+	s.Equal(http.StatusUnprocessableEntity, rec.Code)
+	s.Equal("true", rec.Header().Get(IdempotentReplayedHeader))
+	s.Equal(int32(1), s.calls)
+}
+
+func (s *IdempotencyTestSuite) TestInvalidRequests() {
+	rec := s.do(http.MethodPost, "/v1/note", `{}`, IdempotencyKeyHeader, strings.Repeat("k", MaxIdempotencyKeyLength+1))
+	s.assertProblem(rec, http.StatusBadRequest, CodeIdempotencyKeyInvalid)
+
+	rec = s.do(http.MethodPost, "/v1/note", strings.Repeat("a", 65), IdempotencyKeyHeader, "key-1")
+	s.assertProblem(rec, http.StatusRequestEntityTooLarge, CodeRequestTooLarge)
+
+	s.Equal(int32(0), s.calls)
+}
@@ -1,10 +1,11 @@
 package middleware
 
 import (
-	"github.com/gorilla/handlers"
+	"github.com/sirupsen/logrus"
 	"net/http"
 	"noterfy/api"
-	"os"
+	"noterfy/pkg/logger"
+	"time"
 )
 
 // NewLoggingMiddleware returns a logging middleware with its name.
@@ -13,7 +14,21 @@ func NewLoggingMiddleware() api.NamedMiddleware {
 }
 
 // Logging is an http handler middleware which responsible
-// for logging the request details.
+// for logging the request details. The request is logged with
+// the log entry of its context so it's correlated with the other
+// logs of the request. See RequestID.
 func Logging(h http.Handler) http.Handler {
-	return handlers.LoggingHandler(os.Stdout, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+
+		logger.FromContext(r.Context()).WithFields(logrus.Fields{
+			"status":      rec.status,
+			"bytes":       rec.written,
+			"duration_ms": time.Since(begin).Milliseconds(),
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		}).Info("request completed")
+	})
 }
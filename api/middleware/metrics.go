@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"noterfy/api"
+	"strconv"
+	"time"
+)
+
+// NewMetricsMiddleware initializes the metrics middleware. It takes
+// the registerer of the metrics. If nil is provided it will use the
+// prometheus.DefaultRegisterer.
+func NewMetricsMiddleware(reg prometheus.Registerer) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Metrics", Metrics(reg))
+}
+
+// Metrics is a middleware that records the number and the latency
+// of the requests per route, method and status code.
+func Metrics(reg prometheus.Registerer) mux.MiddlewareFunc {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	labels := []string{"route", "method", "status"}
+	requests := register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "noterfy",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of the HTTP requests.",
+	}, labels)).(*prometheus.CounterVec)
+	latency := register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "noterfy",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, labels)).(*prometheus.HistogramVec)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			lvs := []string{routeTemplate(r), r.Method, strconv.Itoa(rec.status)}
+			requests.WithLabelValues(lvs...).Inc()
+			latency.WithLabelValues(lvs...).Observe(time.Since(begin).Seconds())
+		})
+	}
+}
+
+// register registers c to reg. It returns the already registered
+// collector when there's one so the middleware can be created
+// more than once.
+func register(reg prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		return alreadyRegistered.ExistingCollector
+	}
+	panic(err)
+}
+
+// routeTemplate returns the path template of the matched route
+// instead of the path to keep the number of label values low.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"noterfy/api"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/principal"
+)
+
+// NewClientCertMiddleware returns a client certificate middleware
+// with its name.
+func NewClientCertMiddleware() api.NamedMiddleware {
+	return api.NewNamedMiddleware("ClientCert", ClientCert)
+}
+
+// ClientCert is a middleware that sets the subject of the verified
+// TLS client certificate of the request as its principal. See
+// principal.FromContext. The principal is also added to the log
+// entry of the request. The requests without a verified certificate
+// are anonymous.
+func ClientCert(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		p := principal.Principal{
+			Name:   r.TLS.VerifiedChains[0][0].Subject.String(),
+			Method: principal.MethodClientCert,
+		}
+
+		ctx := principal.WithContext(r.Context(), p)
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx).WithField("principal", p.Name))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
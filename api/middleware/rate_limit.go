@@ -1,78 +1,249 @@
 package middleware
 
 import (
-	"github.com/didip/tollbooth"
-	"github.com/didip/tollbooth/limiter"
 	"github.com/gorilla/mux"
+	"math"
+	"net"
 	"net/http"
 	"noterfy/api"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/problem"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// The headers of the rate limit responses. See the IETF draft
+// "RateLimit header fields for HTTP".
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
 // NewRateLimitMiddleware takes conf rate-limit config an return an instance
 // of named rate-limit middleware
 func NewRateLimitMiddleware(conf RateLimitConfig) api.NamedMiddleware {
 	return api.NewNamedMiddleware("RateLimit", RateLimit(conf))
 }
 
+// RateLimitBudget is the number of requests a client can do in a window.
+type RateLimitBudget struct {
+	// Limit is the maximum number of requests in the window. Zero
+	// or less means the requests are not limited.
+	Limit int
+	// Window is the duration of the window.
+	Window time.Duration
+}
+
+// RateLimitPolicy is the budget of the requests matching a route and
+// methods. Every policy has its own budget for each client.
+type RateLimitPolicy struct {
+	// Name identifies the budget of the policy.
+	Name string
+	// Route is the path template of the route, e.g. "/v1/note/{id}".
+	// An empty route matches all the routes.
+	Route string
+	// Methods are the methods of the requests. Empty matches
+	// all the methods.
+	Methods []string
+	RateLimitBudget
+}
+
+func (p *RateLimitPolicy) matches(route, method string) bool {
+	if p.Route != "" && p.Route != route {
+		return false
+	}
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
 // RateLimitConfig contains all the necessary
 // configuration for the rate-limit middleware.
 type RateLimitConfig struct {
-	// DefaultExpirationTTL is the duration ttl of the token
-	// in the token-bucket algorithm.
-	DefaultExpirationTTL time.Duration
-	// How frequently expire job triggers. How long to
-	// retain the token from the memory cache after expiration.
-	ExpireJobInterval time.Duration
-	// MaxBurst is the maximum threshold of the rate-limit. Meaning
-	// when request peak at MaxBurst in a time period succeeding
-	// request will return a http.StatusTooManyRequests.
-	MaxBurst float64
-	// IPLookUps list of Headers to lookup the IP.
-	// Default is: "RemoteAddr", "X-Forwarded-For", "X-Real-IP"
+	// Policies are the budgets of specific routes and methods. The
+	// first matching policy is used, otherwise the read or the write
+	// budget is used.
+	Policies []RateLimitPolicy
+	// Read is the budget of the GET, HEAD and OPTIONS requests
+	// that don't match a policy.
+	Read RateLimitBudget
+	// Write is the budget of the other requests that don't
+	// match a policy.
+	Write RateLimitBudget
+	// IPLookUps list of Headers to lookup the IP of the clients without
+	// a principal. The first one found is used.
+	// Default is: "RemoteAddr". Only add "X-Forwarded-For" or
+	// "X-Real-IP" behind a trusted proxy.
 	IPLookUps []string
-	// LimitMethods are the methods that will only apply the rate-limit.
-	LimitMethods []string
-	// LimitBasicAuthUsers are the list of users from the Basic Authentication
-	// to apply the rate-limit.
-	LimitBasicAuthUsers []string
+	// Store stores the request counts. It is only used by
+	// NewRateLimiter. Default is an in-memory store.
+	Store RateLimitStore
+	// Clock tells the current time. Default is the real clock.
+	Clock clock.Clock
+	// Disabled turns off the rate-limit.
+	Disabled bool
 }
 
 // RateLimit do a rate-limiting request middleware based on the
 // conf rate-limit configuration.
 func RateLimit(conf RateLimitConfig) mux.MiddlewareFunc {
-	lmt := tollbooth.NewLimiter(
-		conf.MaxBurst,
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: conf.DefaultExpirationTTL,
-			ExpireJobInterval:    conf.ExpireJobInterval,
-		},
-	)
-
-	if len(conf.IPLookUps) > 1 {
-		lmt.SetIPLookups(conf.IPLookUps)
-	}
+	return NewRateLimiter(conf).Middleware
+}
+
+// RateLimiter is a rate-limit middleware whose configuration can
+// be changed while the server is running.
+//
+// The requests are limited per client, identified by its principal,
+// e.g. its verified API key, then its IP, and per policy.
+type RateLimiter struct {
+	store RateLimitStore
+	conf  atomic.Value // RateLimitConfig
+}
 
-	if len(conf.LimitMethods) > 1 {
-		lmt.SetMethods(conf.LimitMethods)
+// NewRateLimiter takes conf rate-limit config and returns
+// a rate limiter.
+func NewRateLimiter(conf RateLimitConfig) *RateLimiter {
+	conf = withRateLimitDefaults(conf)
+
+	rl := &RateLimiter{store: conf.Store}
+	if rl.store == nil {
+		rl.store = NewMemoryRateLimitStore(conf.Clock)
 	}
+	rl.conf.Store(conf)
+	return rl
+}
 
-	lmt.SetMessage("You have reached maximum request limit.")
-	lmt.SetMessageContentType("text/plain;charset=utf-8")
+// Update replaces the configuration of the rate limiter with conf.
+// The store is kept so the clients keep their counts.
+func (rl *RateLimiter) Update(conf RateLimitConfig) {
+	rl.conf.Store(withRateLimitDefaults(conf))
+}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func withRateLimitDefaults(conf RateLimitConfig) RateLimitConfig {
+	if len(conf.IPLookUps) == 0 {
+		conf.IPLookUps = []string{"RemoteAddr"}
+	}
+	if conf.Clock == nil {
+		conf.Clock = clock.New()
+	}
+	return conf
+}
 
-			httpError := tollbooth.LimitByRequest(lmt, w, r)
-			if httpError != nil {
-				lmt.ExecOnLimitReached(w, r)
-				w.Header().Add("Content-Type", lmt.GetMessageContentType())
-				w.WriteHeader(httpError.StatusCode)
-				_, _ = w.Write([]byte(httpError.Message))
-				return
-			}
+// Middleware rate-limits the requests to next.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conf := rl.conf.Load().(RateLimitConfig)
+		if conf.Disabled {
+			next.ServeHTTP(w, r)
+			return
+		}
 
+		name, budget := policyOf(&conf, routeTemplate(r), r.Method)
+		if budget.Limit <= 0 || budget.Window <= 0 {
 			next.ServeHTTP(w, r)
-		})
+			return
+		}
+
+		count, err := rl.store.Increment(r.Context(), name+"|"+clientKey(&conf, r), budget.Window)
+		if err != nil {
+			// The requests are allowed rather than failing
+			// when the store is unavailable.
+			logger.FromContext(r.Context()).Error("middleware: unable to rate-limit the request: ", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reset := int(math.Ceil(count.Reset.Sub(conf.Clock.Now()).Seconds()))
+		if reset < 0 {
+			reset = 0
+		}
+		remaining := budget.Limit - count.Count
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		h := w.Header()
+		h.Set(RateLimitLimitHeader, strconv.Itoa(budget.Limit))
+		h.Set(RateLimitRemainingHeader, strconv.Itoa(remaining))
+		h.Set(RateLimitResetHeader, strconv.Itoa(reset))
+
+		if count.Count > budget.Limit {
+			h.Set(RetryAfterHeader, strconv.Itoa(reset))
+			p := problem.FromError(nil, rateLimited)
+			p.Detail = "You have reached maximum request limit."
+			p.Instance = r.URL.RequestURI()
+			problem.Write(w, p)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CodeRateLimited is the code of the problem of the requests
+// over the budget of their policy.
+const CodeRateLimited = "request.rate_limited"
+
+var rateLimited = problem.Kind{Code: CodeRateLimited, Status: http.StatusTooManyRequests, Title: "Too many requests"}
+
+// policyOf returns the name and the budget of the policy of
+// the requests to the route with the method.
+func policyOf(conf *RateLimitConfig, route, method string) (string, RateLimitBudget) {
+	for i := range conf.Policies {
+		if p := &conf.Policies[i]; p.matches(route, method) {
+			return "policy:" + p.Name, p.RateLimitBudget
+		}
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "read", conf.Read
+	default:
+		return "write", conf.Write
+	}
+}
+
+// clientKey identifies the client of r by its principal, then its
+// IP. The unverified API keys are ignored, otherwise a client could
+// get a new budget with a new key. See APIKeyAuth.
+func clientKey(conf *RateLimitConfig, r *http.Request) string {
+	if p, ok := principal.FromContext(r.Context()); ok {
+		return "principal:" + p.Name
+	}
+
+	return "ip:" + clientIP(conf.IPLookUps, r)
+}
+
+func clientIP(lookups []string, r *http.Request) string {
+	for _, lookup := range lookups {
+		switch lookup {
+		case "RemoteAddr":
+			if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				return host
+			}
+			return r.RemoteAddr
+		case "X-Forwarded-For":
+			// The first address is the client, the others are the proxies.
+			if v := r.Header.Get(lookup); v != "" {
+				return strings.TrimSpace(strings.Split(v, ",")[0])
+			}
+		default:
+			if v := r.Header.Get(lookup); v != "" {
+				return strings.TrimSpace(v)
+			}
+		}
 	}
+	return r.RemoteAddr
 }
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"noterfy/pkg/clock"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how frequently the memory store
+// removes the expired windows.
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitCount is the number of requests of a key in its
+// current window.
+type RateLimitCount struct {
+	// Count is the number of requests in the window, including
+	// the current one.
+	Count int
+	// Reset is when the window ends and the count resets.
+	Reset time.Time
+}
+
+// RateLimitStore stores the request counts of the rate limiter, e.g.
+// in memory or in a database shared by the instances of the server.
+type RateLimitStore interface {
+	// Increment counts a request of key in its current window and
+	// returns the count. A new window of the duration window starts
+	// when there is no current window.
+	Increment(ctx context.Context, key string, window time.Duration) (RateLimitCount, error)
+}
+
+// NewMemoryRateLimitStore takes the clock c and returns a rate
+// limit store that keeps the counts in memory. If nil is provided
+// it will use the real clock.
+func NewMemoryRateLimitStore(c clock.Clock) RateLimitStore {
+	if c == nil {
+		c = clock.New()
+	}
+	return &memoryRateLimitStore{
+		clock:   c,
+		windows: make(map[string]*RateLimitCount),
+	}
+}
+
+type memoryRateLimitStore struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	windows   map[string]*RateLimitCount
+	lastSweep time.Time
+}
+
+func (s *memoryRateLimitStore) Increment(_ context.Context, key string, window time.Duration) (RateLimitCount, error) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= rateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.Reset) {
+		w = &RateLimitCount{Reset: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.Count++
+	return *w, nil
+}
+
+// sweep removes the windows that ended before now.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	for key, w := range s.windows {
+		if !now.Before(w.Reset) {
+			delete(s.windows, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/problem"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	clock *clock.Fake
+}
+
+func (s *RateLimitTestSuite) SetupTest() {
+	s.clock = clock.NewFake(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+}
+
+// router returns a router of the routes "/v1/note" and "/v1/note/{id}"
+// rate-limited by rl.
+func (s *RateLimitTestSuite) router(rl *RateLimiter) http.Handler {
+	r := mux.NewRouter()
+	r.Use(rl.Middleware)
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	r.HandleFunc("/v1/note", noop)
+	r.HandleFunc("/v1/note/{id}", noop)
+	return r
+}
+
+// do does a request from the client with the IP ip. The optional
+// header is set on the request.
+func (s *RateLimitTestSuite) do(h http.Handler, method, path, ip string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":41234"
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *RateLimitTestSuite) TestHeaders() {
+	h := s.router(NewRateLimiter(RateLimitConfig{
+		Read:  RateLimitBudget{Limit: 2, Window: time.Minute},
+		Clock: s.clock,
+	}))
+
+	rec := s.do(h, http.MethodGet, "/v1/note", "10.0.0.1")
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("2", rec.Header().Get(RateLimitLimitHeader))
+	s.Equal("1", rec.Header().Get(RateLimitRemainingHeader))
+	s.Equal("60", rec.Header().Get(RateLimitResetHeader))
+	s.Empty(rec.Header().Get(RetryAfterHeader))
+
+	s.clock.Advance(15 * time.Second)
+	rec = s.do(h, http.MethodGet, "/v1/note", "10.0.0.1")
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("0", rec.Header().Get(RateLimitRemainingHeader))
+	s.Equal("45", rec.Header().Get(RateLimitResetHeader))
+
+	rec = s.do(h, http.MethodGet, "/v1/note", "10.0.0.1")
+	s.Equal(http.StatusTooManyRequests, rec.Code)
+	s.Equal("0", rec.Header().Get(RateLimitRemainingHeader))
+	s.Equal("45", rec.Header().Get(RetryAfterHeader))
+	s.Equal(problem.MediaType, rec.Header().Get("Content-Type"))
+
+	var body problem.Problem
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(&body))
+	s.Equal(CodeRateLimited, body.Code)
+	s.Equal(http.StatusTooManyRequests, body.Status)
+	s.Equal("/v1/note", body.Instance)
+
+	// The window is over.
+	s.clock.Advance(45 * time.Second)
+	rec = s.do(h, http.MethodGet, "/v1/note", "10.0.0.1")
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("1", rec.Header().Get(RateLimitRemainingHeader))
+}
+
+func (s *RateLimitTestSuite) TestReadWriteBudgets() {
+	h := s.router(NewRateLimiter(RateLimitConfig{
+		Read:  RateLimitBudget{Limit: 2, Window: time.Minute},
+		Write: RateLimitBudget{Limit: 1, Window: time.Minute},
+		Clock: s.clock,
+	}))
+
+	s.Equal(http.StatusOK, s.do(h, http.MethodPost, "/v1/note", "10.0.0.1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(h, http.MethodPut, "/v1/note/1", "10.0.0.1").Code)
+
+	// The reads have their own budget.
+	s.Equal(http.StatusOK, s.do(h, http.MethodGet, "/v1/note/1", "10.0.0.1").Code)
+	s.Equal(http.StatusOK, s.do(h, http.MethodHead, "/v1/note", "10.0.0.1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(h, http.MethodGet, "/v1/note", "10.0.0.1").Code)
+}
+
+func (s *RateLimitTestSuite) TestPolicies() {
+	h := s.router(NewRateLimiter(RateLimitConfig{
+		Policies: []RateLimitPolicy{
+			{Name: "delete", Route: "/v1/note/{id}", Methods: []string{"delete"},
+				RateLimitBudget: RateLimitBudget{Limit: 1, Window: time.Minute}},
+			{Name: "unlimited", Route: "/v1/note/{id}"},
+		},
+		Write: RateLimitBudget{Limit: 1, Window: time.Minute},
+		Clock: s.clock,
+	}))
+
+	s.Equal(http.StatusOK, s.do(h, http.MethodDelete, "/v1/note/1", "10.0.0.1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(h, http.MethodDelete, "/v1/note/2", "10.0.0.1").Code)
+
+	// The write budget is not used by the policy.
+	s.Equal(http.StatusOK, s.do(h, http.MethodPost, "/v1/note", "10.0.0.1").Code)
+
+	for i := 0; i < 3; i++ {
+		rec := s.do(h, http.MethodPut, "/v1/note/1", "10.0.0.1")
+		s.Equal(http.StatusOK, rec.Code)
+		s.Empty(rec.Header().Get(RateLimitLimitHeader))
+	}
+}
+
+func (s *RateLimitTestSuite) TestClientKeys() {
+	rl := NewRateLimiter(RateLimitConfig{
+		Read:      RateLimitBudget{Limit: 1, Window: time.Minute},
+		IPLookUps: []string{"X-Forwarded-For", "RemoteAddr"},
+		Clock:     s.clock,
+	})
+	h := s.router(rl)
+
+	// The clients are identified by their IP.
+	s.Equal(http.StatusOK, s.do(h, http.MethodGet, "/v1/note", "10.0.0.1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(h, http.MethodGet, "/v1/note", "10.0.0.1").Code)
+	s.Equal(http.StatusOK, s.do(h, http.MethodGet, "/v1/note", "10.0.0.2").Code)
+	s.Equal(http.StatusOK, s.do(h, http.MethodGet, "/v1/note", "10.0.0.1",
+		"X-Forwarded-For", "192.168.1.1, 10.0.0.1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(h, http.MethodGet, "/v1/note", "10.0.0.3",
+		"X-Forwarded-For", "192.168.1.1").Code)
+
+	// The clients with a known API key are identified by it from any
+	// IP while the unknown keys are ignored.
+	withKeys := APIKeyAuth(APIKeyConfig{Keys: []APIKey{{Name: "client-1", Key: "key-1"}}})(h)
+	s.Equal(http.StatusOK, s.do(withKeys, http.MethodGet, "/v1/note", "10.0.0.4", DefaultAPIKeyHeader, "key-1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(withKeys, http.MethodGet, "/v1/note", "10.0.0.5", DefaultAPIKeyHeader, "key-1").Code)
+	s.Equal(http.StatusOK, s.do(withKeys, http.MethodGet, "/v1/note", "10.0.0.6", DefaultAPIKeyHeader, "random-1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(withKeys, http.MethodGet, "/v1/note", "10.0.0.6", DefaultAPIKeyHeader, "random-2").Code)
+
+	// The authenticated clients are identified by their principal.
+	withPrincipal := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := principal.Principal{Name: name, Method: principal.MethodClientCert}
+			h.ServeHTTP(w, r.WithContext(principal.WithContext(r.Context(), p)))
+		})
+	}
+	s.Equal(http.StatusOK, s.do(withPrincipal("CN=alice"), http.MethodGet, "/v1/note", "10.0.0.1").Code)
+	s.Equal(http.StatusTooManyRequests, s.do(withPrincipal("CN=alice"), http.MethodGet, "/v1/note", "10.0.0.7").Code)
+	s.Equal(http.StatusOK, s.do(withPrincipal("CN=bob"), http.MethodGet, "/v1/note", "10.0.0.1").Code)
+}
+
+// stubRateLimitStore is a rate limit store that records the keys
+// and returns err.
+type stubRateLimitStore struct {
+	keys []string
+	err  error
+}
+
+func (s *stubRateLimitStore) Increment(_ context.Context, key string, window time.Duration) (RateLimitCount, error) {
+	s.keys = append(s.keys, key)
+	return RateLimitCount{Count: len(s.keys)}, s.err
+}
+
+func (s *RateLimitTestSuite) TestStore() {
+	store := &stubRateLimitStore{}
+	h := s.router(NewRateLimiter(RateLimitConfig{
+		Write: RateLimitBudget{Limit: 10, Window: time.Minute},
+		Store: store,
+		Clock: s.clock,
+	}))
+
+	s.Equal(http.StatusOK, s.do(h, http.MethodPost, "/v1/note", "10.0.0.1").Code)
+	s.Equal([]string{"write|ip:10.0.0.1"}, store.keys)
+
+	// The requests are allowed when the store fails.
+	store.err = errors.New("connection refused")
+	rec := s.do(h, http.MethodPost, "/v1/note", "10.0.0.1")
+	s.Equal(http.StatusOK, rec.Code)
+	s.Empty(rec.Header().Get(RateLimitLimitHeader))
+}
+
+func (s *RateLimitTestSuite) TestUpdate() {
+	rl := NewRateLimiter(RateLimitConfig{Disabled: true, Clock: s.clock})
+	h := s.router(rl)
+
+	for i := 0; i < 3; i++ {
+		s.Equal(http.StatusOK, s.do(h, http.MethodGet, "/v1/note", "10.0.0.1").Code)
+	}
+
+	rl.Update(RateLimitConfig{
+		Read:  RateLimitBudget{Limit: 2, Window: time.Minute},
+		Clock: s.clock,
+	})
+	s.Equal(http.StatusOK, s.do(h, http.MethodGet, "/v1/note", "10.0.0.1").Code)
+
+	// The counts are kept by the update.
+	rl.Update(RateLimitConfig{
+		Read:  RateLimitBudget{Limit: 1, Window: time.Minute},
+		Clock: s.clock,
+	})
+	s.Equal(http.StatusTooManyRequests, s.do(h, http.MethodGet, "/v1/note", "10.0.0.1").Code)
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	c := clock.NewFake(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryRateLimitStore(c)
+	ctx := context.Background()
+
+	got, err := store.Increment(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, RateLimitCount{Count: 1, Reset: c.Now().Add(time.Minute)}, got)
+
+	c.Advance(30 * time.Second)
+	got, _ = store.Increment(ctx, "a", time.Minute)
+	assert.Equal(t, 2, got.Count)
+	got, _ = store.Increment(ctx, "b", time.Minute)
+	assert.Equal(t, 1, got.Count)
+
+	c.Advance(30 * time.Second)
+	got, _ = store.Increment(ctx, "a", time.Minute)
+	assert.Equal(t, RateLimitCount{Count: 1, Reset: c.Now().Add(time.Minute)}, got)
+
+	// The expired windows are removed.
+	c.Advance(2 * time.Minute)
+	_, _ = store.Increment(ctx, "c", time.Minute)
+	assert.Len(t, store.(*memoryRateLimitStore).windows, 1)
+}
+
+func TestCORSHandlerUpdate(t *testing.T) {
+	h := NewCORSHandler(nil)
+	handler := h.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	origin := func() string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://example.com")
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Equal(t, "*", origin())
+
+	h.Update(&CORSConfig{AllowedOrigins: []string{"https://other.com"}})
+	assert.Empty(t, origin())
+
+	h.Update(&CORSConfig{AllowedOrigins: []string{"https://example.com"}})
+	assert.Equal(t, "https://example.com", origin())
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"noterfy/api"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/requestid"
+)
+
+// RequestIDHeader is the header of the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request ID
+// given by the client.
+const maxRequestIDLength = 128
+
+// NewRequestIDMiddleware returns a request ID middleware with its name.
+func NewRequestIDMiddleware() api.NamedMiddleware {
+	return api.NewNamedMiddleware("RequestID", RequestID)
+}
+
+// RequestID is a middleware that accepts the X-Request-ID of the
+// request or generates a new one. The ID is written back to the
+// response and the ID and a log entry with the ID are added to the
+// context of the request. See requestid.FromContext and
+// logger.FromContext.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		entry := logger.FromContext(r.Context()).WithFields(logrus.Fields{
+			"request_id": id,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+		})
+		ctx := requestid.WithContext(r.Context(), id)
+		h.ServeHTTP(w, r.WithContext(logger.WithContext(ctx, entry)))
+	})
+}
+
+// isValidRequestID reports whether the id given by the client
+// is safe to log and to echo back.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
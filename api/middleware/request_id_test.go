@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/requestid"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var buff bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buff)
+	l.SetFormatter(&logrus.JSONFormatter{})
+
+	var ctxID string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = requestid.FromContext(r.Context())
+		logger.FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	})
+
+	// Use the test logger as the base of the entries.
+	base := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r.WithContext(logger.WithContext(r.Context(), logrus.NewEntry(l))))
+		})
+	}
+	h := base(RequestID(Logging(handler)))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "Accept the client ID", header: "abc-123", keep: true},
+		{name: "Generate a missing ID", header: ""},
+		{name: "Replace an unsafe ID", header: "abc\x00123"},
+		{name: "Replace a long ID", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buff.Reset()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/notes", nil)
+			req.Header.Set(RequestIDHeader, tt.header)
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, id)
+			if tt.keep {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.NotEqual(t, tt.header, id)
+			}
+			assert.Equal(t, id, ctxID)
+
+			// Both the handler and the access logs have the request ID.
+			lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+			require.Len(t, lines, 2)
+			for _, line := range lines {
+				var entry map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(line), &entry))
+				assert.Equal(t, id, entry["request_id"])
+				assert.Equal(t, "/v1/notes", entry["path"])
+			}
+
+			var access map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &access))
+			assert.Equal(t, float64(http.StatusCreated), access["status"])
+			assert.Equal(t, float64(len("created")), access["bytes"])
+		})
+	}
+}
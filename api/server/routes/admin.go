@@ -0,0 +1,166 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"noterfy/api"
+	nhttp "noterfy/pkg/http"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/problem"
+)
+
+var errInvalidLogLevel = errors.New("routes: invalid log level")
+
+// The codes of the problems of the admin routes.
+const (
+	CodeAdminUnauthenticated = "admin.unauthenticated"
+	CodeAdminForbidden       = "admin.forbidden"
+	CodeAdminInvalidLogLevel = "admin.invalid_log_level"
+	CodeAuditInvalidQuery    = "audit.invalid_query"
+)
+
+var (
+	adminUnauthenticated = problem.Kind{Code: CodeAdminUnauthenticated, Status: http.StatusUnauthorized, Title: "Authentication required"}
+	adminForbidden       = problem.Kind{Code: CodeAdminForbidden, Status: http.StatusForbidden, Title: "Admin access required"}
+	invalidLogLevel      = problem.Kind{Code: CodeAdminInvalidLogLevel, Status: http.StatusBadRequest, Title: "Invalid log level"}
+	auditInvalidQuery    = problem.Kind{Code: CodeAuditInvalidQuery, Status: http.StatusBadRequest, Title: "Invalid audit query"}
+)
+
+// Admins are the names of the principals allowed to use the admin
+// routes, i.e. the subjects of the client certificates and the names
+// of the API keys. No principal is allowed when it is empty.
+type Admins []string
+
+// Require returns the handler that serves the requests of the admins
+// with next. The other requests get a 401 when they have no principal
+// and a 403 otherwise.
+func (a Admins) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := principal.FromContext(r.Context())
+		switch {
+		case !ok:
+			writeAdminProblem(w, r, problem.New(adminUnauthenticated,
+				"Use a client certificate or an API key of an admin.", nil))
+		case !a.contains(p.Name):
+			logger.FromContext(r.Context()).WithField("principal", p.Name).Warn("routes: denied an admin request")
+			writeAdminProblem(w, r, problem.New(adminForbidden,
+				fmt.Sprintf("The principal %s is not an admin.", p.Name), nil))
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (a Admins) contains(name string) bool {
+	for _, admin := range a {
+		if admin == name {
+			return true
+		}
+	}
+	return false
+}
+
+func writeAdminProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := problem.FromError(err, adminErrorKinds.Kind(err))
+	p.Instance = r.URL.RequestURI()
+	problem.Write(w, p)
+}
+
+// adminErrorKinds maps the errors of the admin routes to the
+// kinds of problems.
+var adminErrorKinds = problem.Mapping{
+	{Err: errInvalidLogLevel, Kind: invalidLogLevel},
+}
+
+// AdminRoutes takes the logger of the server and the admins and
+// returns the routes for administering the server at runtime. If nil
+// logger is provided it will use the logrus standard logger.
+func AdminRoutes(l *logrus.Logger, admins Admins) []api.Route {
+	return []api.Route{
+		LogLevelRoute(l, admins),
+	}
+}
+
+// LogLevelRoute returns the route that changes the level of the
+// logger l at runtime. Only the admins can use it. If nil logger is
+// provided it will use the logrus standard logger.
+func LogLevelRoute(l *logrus.Logger, admins Admins) api.Route {
+	if l == nil {
+		l = logrus.StandardLogger()
+	}
+
+	handler := httptransport.NewServer(
+		makeLogLevelEndpoint(l),
+		decodeLogLevelRequest,
+		encodeResponse,
+		httptransport.ServerBefore(httptransport.PopulateRequestContext),
+		httptransport.ServerErrorEncoder(encodeAdminError),
+	)
+
+	return &nhttp.Route{
+		HandlerValue: admins.Require(handler),
+		MethodValue:  http.MethodPut,
+		PathValue:    "/admin/loglevel",
+	}
+}
+
+// LogLevelRequest is a container for the log level request.
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LogLevelResponse is a container for the log level response.
+type LogLevelResponse struct {
+	Level         string `json:"level"`
+	PreviousLevel string `json:"previous_level"`
+}
+
+func decodeLogLevelRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, problem.New(invalidLogLevel, "The body must be a JSON object with a level.", err)
+	}
+	return req, nil
+}
+
+func makeLogLevelEndpoint(l *logrus.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(LogLevelRequest)
+		level, err := logrus.ParseLevel(request.Level)
+		if err != nil {
+			return nil, problem.New(invalidLogLevel,
+				"Use one of [panic/fatal/error/warn/info/debug/trace].", errInvalidLogLevel)
+		}
+
+		previous := l.GetLevel()
+		l.SetLevel(level)
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"level":          level.String(),
+			"previous_level": previous.String(),
+		}).Warn("routes: changed the log level")
+
+		return &LogLevelResponse{Level: level.String(), PreviousLevel: previous.String()}, nil
+	}
+}
+
+// encodeAdminError writes err as the problem details of the
+// admin routes.
+func encodeAdminError(ctx context.Context, err error, w http.ResponseWriter) {
+	kind := adminErrorKinds.Kind(err)
+	if kind.Status >= http.StatusInternalServerError {
+		logger.FromContext(ctx).Error("routes: admin request failed: ", err)
+	}
+
+	p := problem.FromError(err, kind)
+	if uri, ok := ctx.Value(httptransport.ContextKeyRequestURI).(string); ok {
+		p.Instance = uri
+	}
+	problem.Write(w, p)
+}
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"noterfy/api"
+	"noterfy/audit"
+	nhttp "noterfy/pkg/http"
+	"noterfy/pkg/problem"
+	"strconv"
+	"time"
+)
+
+// The limits of the entries of an audit response.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// AuditRoutes takes the audit log l and the admins and returns the
+// routes for querying it.
+func AuditRoutes(l *audit.Log, admins Admins) []api.Route {
+	return []api.Route{
+		AuditRoute(l, admins),
+	}
+}
+
+// AuditRoute returns the route that lists the entries of the audit
+// log l selected by the query parameters note_id, principal,
+// operation, request_id, since, until, after and limit. Only the
+// admins can use it.
+func AuditRoute(l *audit.Log, admins Admins) api.Route {
+	handler := httptransport.NewServer(
+		makeAuditEndpoint(l),
+		decodeAuditRequest,
+		encodeResponse,
+		httptransport.ServerBefore(httptransport.PopulateRequestContext),
+		httptransport.ServerErrorEncoder(encodeAdminError),
+	)
+
+	return &nhttp.Route{
+		HandlerValue: admins.Require(handler),
+		MethodValue:  http.MethodGet,
+		PathValue:    "/admin/audit",
+	}
+}
+
+// AuditResponse is a container for the audit response.
+type AuditResponse struct {
+	Entries []*audit.Entry `json:"entries"`
+	// Next is the value of the after query parameter of the next
+	// entries. It is omitted when there are no more entries.
+	Next uint64 `json:"next,omitempty"`
+}
+
+func decodeAuditRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	f := audit.Filter{
+		Principal: query.Get("principal"),
+		Operation: audit.Operation(query.Get("operation")),
+		RequestID: query.Get("request_id"),
+		Limit:     DefaultAuditLimit,
+	}
+
+	if v := query.Get("note_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, invalidAuditQuery("The note_id must be a UUID.")
+		}
+		f.NoteID = id
+	}
+
+	if f.Operation != "" && !isOperation(f.Operation) {
+		return nil, invalidAuditQuery(fmt.Sprintf("The operation must be one of %v.", audit.Operations))
+	}
+
+	var err error
+	if f.Since, err = parseAuditTime(query, "since"); err != nil {
+		return nil, err
+	}
+	if f.Until, err = parseAuditTime(query, "until"); err != nil {
+		return nil, err
+	}
+
+	if v := query.Get("after"); v != "" {
+		if f.After, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, invalidAuditQuery("The after must be a sequence number.")
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		f.Limit, err = strconv.Atoi(v)
+		if err != nil || f.Limit < 1 || f.Limit > MaxAuditLimit {
+			return nil, invalidAuditQuery(fmt.Sprintf("The limit must be between 1 and %d.", MaxAuditLimit))
+		}
+	}
+
+	return f, nil
+}
+
+func parseAuditTime(query url.Values, name string) (time.Time, error) {
+	v := query.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, invalidAuditQuery("The " + name + " must be an RFC 3339 timestamp.")
+	}
+	return t, nil
+}
+
+func isOperation(op audit.Operation) bool {
+	for _, o := range audit.Operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func makeAuditEndpoint(l *audit.Log) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		f := req.(audit.Filter)
+		limit := f.Limit
+
+		// One more entry tells whether there are more entries.
+		f.Limit++
+		entries, err := l.Query(f)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := &AuditResponse{Entries: entries}
+		if len(entries) > limit {
+			resp.Entries = entries[:limit]
+			resp.Next = resp.Entries[limit-1].Seq
+		}
+		if resp.Entries == nil {
+			resp.Entries = []*audit.Entry{}
+		}
+		return resp, nil
+	}
+}
+
+// invalidAuditQuery returns the error of an invalid query
+// parameter with the detail.
+func invalidAuditQuery(detail string) error {
+	return problem.New(auditInvalidQuery, detail, nil)
+}
@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"noterfy/api"
+	"noterfy/pkg/health"
+	nhttp "noterfy/pkg/http"
+)
+
+// HealthRoutes takes the registry of the health checks and returns
+// the routes of the liveness and the readiness probes.
+func HealthRoutes(reg *health.Registry) []api.Route {
+	return []api.Route{
+		LivenessRoute(reg),
+		ReadinessRoute(reg),
+	}
+}
+
+// LivenessRoute returns the route that runs the liveness checks of reg.
+// It responds with 503 when one of the checks fails.
+func LivenessRoute(reg *health.Registry) api.Route {
+	return &nhttp.Route{
+		HandlerValue: newProbeHandler(reg.Live),
+		MethodValue:  http.MethodGet,
+		PathValue:    "/health/live",
+	}
+}
+
+// ReadinessRoute returns the route that runs the readiness checks of reg.
+// It responds with 503 when one of the checks fails or the server
+// is draining.
+func ReadinessRoute(reg *health.Registry) api.Route {
+	return &nhttp.Route{
+		HandlerValue: newProbeHandler(reg.Ready),
+		MethodValue:  http.MethodGet,
+		PathValue:    "/health/ready",
+	}
+}
+
+type probeRequest struct{}
+
+func newProbeHandler(probe func(context.Context) *health.Report) http.Handler {
+	return httptransport.NewServer(
+		makeProbeEndpoint(probe),
+		decodeProbeRequest,
+		encodeProbeResponse,
+	)
+}
+
+func decodeProbeRequest(context.Context, *http.Request) (interface{}, error) {
+	return probeRequest{}, nil
+}
+
+func makeProbeEndpoint(probe func(context.Context) *health.Report) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return probe(ctx), nil
+	}
+}
+
+func encodeProbeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	report := response.(*health.Report)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Healthy() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 	"noterfy/api"
 	nhttp "noterfy/pkg/http"
@@ -17,10 +19,28 @@ func Routes(meta *Metadata) []api.Route {
 	return []api.Route{
 		HealthCheckRoute(),
 		MetadataRoute(meta),
+		MetricsRoute(nil),
+	}
+}
+
+// MetricsRoute returns the route that exposes the metrics in the
+// Prometheus format. It takes the gatherer of the metrics. If nil is
+// provided it will use the prometheus.DefaultGatherer.
+func MetricsRoute(gatherer prometheus.Gatherer) api.Route {
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+
+	return &nhttp.Route{
+		HandlerValue: promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}),
+		MethodValue:  http.MethodGet,
+		PathValue:    "/metrics",
 	}
 }
 
 // HealthCheckRoute return the route for the health check endpoint.
+// It only tells that the server is up, see HealthRoutes for the
+// liveness and the readiness probes.
 func HealthCheckRoute() api.Route {
 
 	handler := httptransport.NewServer(
@@ -57,7 +77,6 @@ func encodeResponse(_ context.Context, w http.ResponseWriter, resp interface{})
 
 func makeHealthCheckEndpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-		// Naive-way implementation.
 		response = &HealthCheckResponse{
 			Message: "OK",
 		}
@@ -1,11 +1,24 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"net/http"
 	"net/http/httptest"
+	"noterfy/api/middleware"
+	"noterfy/audit"
+	"noterfy/pkg/health"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/problem"
+	"strings"
 	"testing"
 	"time"
 )
@@ -67,3 +80,226 @@ func (t *MetadataTestSuite) TestMetaHandler() {
 	t.Require().NoError(err)
 	t.Equal(meta, got.Meta)
 }
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test counter."}))
+
+	route := MetricsRoute(reg)
+	router := mux.NewRouter()
+	router.Use(middleware.Metrics(reg))
+	router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		body := rec.Body.String()
+		assert.Contains(t, body, "test_total 0")
+		if i == 1 {
+			// The first scrape is recorded by the metrics middleware.
+			assert.Contains(t, body, `noterfy_http_requests_total{method="GET",route="/metrics",status="200"} 1`)
+		}
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	l := logrus.New()
+	l.SetLevel(logrus.InfoLevel)
+
+	route := LogLevelRoute(l, Admins{"CN=admin"})
+	router := mux.NewRouter()
+	router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	router.Use(asPrincipal("CN=admin"))
+
+	for name, status := range map[string]int{"none": http.StatusUnauthorized, "CN=alice": http.StatusForbidden} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(`{"level":"trace"}`))
+		req.Header.Set("X-Principal", name)
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, name)
+	}
+	require.Equal(t, logrus.InfoLevel, l.GetLevel())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(`{"level":"debug"}`))
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got LogLevelResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, LogLevelResponse{Level: "debug", PreviousLevel: "info"}, got)
+	assert.Equal(t, logrus.DebugLevel, l.GetLevel())
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(`{"level":"loud"}`))
+	router.ServeHTTP(rec, req)
+	assertProblem(t, rec, http.StatusBadRequest, CodeAdminInvalidLogLevel)
+	assert.Equal(t, logrus.DebugLevel, l.GetLevel())
+}
+
+func assertProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	assert.Equal(t, status, rec.Code)
+	assert.Equal(t, problem.MediaType, rec.Header().Get("Content-Type"))
+
+	var p problem.Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+	assert.Equal(t, code, p.Code)
+	assert.NotEmpty(t, p.Instance)
+}
+
+func TestAudit(t *testing.T) {
+	l, err := audit.Open(afero.NewMemMapFs(), "audit.log", nil)
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+
+	id := uuid.New()
+	for _, op := range []audit.Operation{audit.OperationCreate, audit.OperationGet, audit.OperationUpdate} {
+		require.NoError(t, l.Append(&audit.Entry{Operation: op, Principal: "alice", NoteID: id}))
+	}
+	require.NoError(t, l.Append(&audit.Entry{Operation: audit.OperationCreate, Principal: "bob", NoteID: uuid.New()}))
+
+	route := AuditRoute(l, Admins{"CN=admin"})
+	router := mux.NewRouter()
+	router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	router.Use(asPrincipal("CN=admin"))
+
+	query := func(target string) (int, *AuditResponse) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		var resp AuditResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return rec.Code, &resp
+	}
+
+	code, resp := query("/admin/audit?note_id=" + id.String() + "&limit=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Entries, 2)
+	assert.Equal(t, audit.OperationGet, resp.Entries[1].Operation)
+	assert.Equal(t, uint64(2), resp.Next)
+
+	code, resp = query("/admin/audit?note_id=" + id.String() + "&after=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Entries, 1)
+	assert.Equal(t, audit.OperationUpdate, resp.Entries[0].Operation)
+	assert.Zero(t, resp.Next)
+
+	code, resp = query("/admin/audit?principal=bob&operation=create")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp.Entries, 1)
+
+	for name, status := range map[string]int{"none": http.StatusUnauthorized, "CN=alice": http.StatusForbidden} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit", nil)
+		req.Header.Set("X-Principal", name)
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, name)
+		assert.NotContains(t, rec.Body.String(), "entries")
+	}
+
+	for _, target := range []string{
+		"/admin/audit?note_id=123",
+		"/admin/audit?operation=read",
+		"/admin/audit?since=yesterday",
+		"/admin/audit?limit=0",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assertProblem(t, rec, http.StatusBadRequest, CodeAuditInvalidQuery)
+	}
+}
+
+// asPrincipal returns the middleware authenticating the requests with
+// the X-Principal header, or as the principal with the name when the
+// header is not set. The "none" requests are anonymous.
+func asPrincipal(name string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := principal.Principal{Name: name}
+			if h := r.Header.Get("X-Principal"); h != "" {
+				p.Name = h
+			}
+			if p.Name != "none" {
+				r = r.WithContext(principal.WithContext(r.Context(), p))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestAdmins(t *testing.T) {
+	noContent := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := Admins{"CN=admin", "ops"}.Require(noContent)
+
+	tests := []struct {
+		name      string
+		principal string
+		status    int
+		code      string
+	}{
+		{name: "Anonymous", principal: "none", status: http.StatusUnauthorized, code: CodeAdminUnauthenticated},
+		{name: "Not an admin", principal: "CN=alice", status: http.StatusForbidden, code: CodeAdminForbidden},
+		{name: "Client certificate admin", principal: "CN=admin", status: http.StatusNoContent},
+		{name: "API key admin", principal: "ops", status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			asPrincipal(tt.principal)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/audit", nil))
+			if tt.code != "" {
+				assertProblem(t, rec, tt.status, tt.code)
+				return
+			}
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+
+	t.Run("No admins", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h := asPrincipal("CN=admin")(Admins(nil).Require(noContent))
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/audit", nil))
+		assertProblem(t, rec, http.StatusForbidden, CodeAdminForbidden)
+	})
+}
+
+func TestHealthProbes(t *testing.T) {
+	reg := health.New()
+	reg.AddLivenessCheck("ping", health.CheckerFunc(func(context.Context) error { return nil }))
+
+	router := mux.NewRouter()
+	for _, route := range HealthRoutes(reg) {
+		router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+
+	probe := func(path string) (int, *health.Report) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(rec, req)
+
+		var report health.Report
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		return rec.Code, &report
+	}
+
+	code, report := probe("/health/ready")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusUp, report.Checks["draining"].Status)
+
+	reg.Drain()
+
+	code, report = probe("/health/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.StatusDown, report.Checks["draining"].Status)
+
+	code, report = probe("/health/live")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusUp, report.Checks["ping"].Status)
+}
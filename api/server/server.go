@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
+	"net"
 	"net/http"
 	"noterfy/api"
 	"noterfy/api/server/routes"
+	"noterfy/pkg/health"
+	"noterfy/pkg/lifecycle"
 	"os"
-	"os/signal"
-	"syscall"
 	"text/tabwriter"
 	"time"
 )
@@ -27,6 +27,9 @@ func New(conf *Config) *Server {
 		HTTPRoutes:      conf.HTTPRoutes,
 		ShutdownTimeout: conf.ShutdownTimeout,
 		Metadata:        conf.Metadata,
+		Health:          conf.Health,
+		DrainDelay:      conf.DrainDelay,
+		TLS:             conf.TLS,
 	}
 	return server
 }
@@ -45,6 +48,16 @@ type Config struct {
 	ShutdownTimeout time.Duration
 	// Metadata is the API extra information.
 	Metadata *routes.Metadata
+	// Health is the registry of the health checks. When provided it is
+	// marked as draining when ListenAndServe starts to shut down.
+	Health *health.Registry
+	// DrainDelay is the duration to keep serving the requests after
+	// the server is marked as draining, so the load balancer has time
+	// to notice the failing readiness probe. Default is no delay.
+	DrainDelay time.Duration
+	// TLS is the TLS configuration. When nil the server
+	// serves plain HTTP.
+	TLS *TLSConfig
 }
 
 func (c *Config) checkDefaults() {
@@ -64,9 +77,16 @@ type Server struct {
 	// shutdown. Default is 5 seconds.
 	ShutdownTimeout time.Duration
 	Metadata        *routes.Metadata
+	Health          *health.Registry
+	DrainDelay      time.Duration
+	TLS             *TLSConfig
+	tlsReloader     *TLSReloader
+	onShutdown      []func()
+	cancel          context.CancelFunc
+	errc            chan error
 }
 
-func (s *Server) init() {
+func (s *Server) init() error {
 	router := mux.NewRouter()
 
 	s.printInfo()
@@ -83,8 +103,21 @@ func (s *Server) init() {
 		Addr:    fmt.Sprintf(":%d", s.Port),
 		Handler: router,
 	}
+	for _, f := range s.onShutdown {
+		s.server.RegisterOnShutdown(f)
+	}
+
+	if s.TLS != nil {
+		reloader, err := NewTLSReloader(*s.TLS)
+		if err != nil {
+			return err
+		}
+		s.tlsReloader = reloader
+		s.server.TLSConfig = reloader.TLSConfig()
+	}
 
 	s.isInited = true
+	return nil
 }
 
 func (s *Server) printInfo() {
@@ -119,49 +152,91 @@ func (s *Server) printInfo() {
 	writeToConsole("\n")
 }
 
-// ListenAndServe serves clients request by the server.
-func (s *Server) ListenAndServe() (err error) {
-	if !s.isInited {
-		s.init()
-	}
+// ListenAndServe serves clients request by the server until an
+// interrupt or a termination signal is received, then shuts the
+// server down gracefully. It returns the error that stopped the server.
+func (s *Server) ListenAndServe() error {
+	m := lifecycle.New(&lifecycle.Config{
+		StopTimeout: s.ShutdownTimeout,
+		Health:      s.Health,
+		DrainDelay:  s.DrainDelay,
+	})
+	m.Add("server", s)
+	return m.Run(context.Background())
+}
 
-	go func() {
-		fmt.Printf("\U0001F7E2 Server Started Listening on %s\n", s.server.Addr)
-		serr := s.server.ListenAndServe()
-		if serr != nil && serr != http.ErrServerClosed && err == nil {
-			err = serr
+// Start listens on the port of the server and serves the requests in
+// a goroutine. It returns the error of the listener, e.g. when the
+// port is in use. The error that stops the serving later is sent to
+// Err. It implements lifecycle.Component.
+func (s *Server) Start(context.Context) error {
+	if !s.isInited {
+		if err := s.init(); err != nil {
+			return err
 		}
-	}()
-
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	<-done
-	fmt.Println("🛑 Server Stopped")
+	}
 
-	err = s.gracefulShutdown()
+	ln, err := net.Listen("tcp", s.server.Addr)
 	if err != nil {
-		return err
+		return fmt.Errorf("server: unable to listen on %s: %w", s.server.Addr, err)
 	}
 
-	if err := s.server.Close(); err != nil {
-		logrus.Error("error while closing server:", err)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	if s.tlsReloader != nil {
+		go s.tlsReloader.Watch(ctx)
 	}
 
-	fmt.Println("💯 Server Exited Properly")
-	return
+	s.errc = make(chan error, 1)
+	go func() {
+		var err error
+		if s.tlsReloader != nil {
+			fmt.Printf("\U0001F512 Server Started Listening on %s with TLS\n", s.server.Addr)
+			// The certificates are provided by the TLS config.
+			err = s.server.ServeTLS(ln, "", "")
+		} else {
+			fmt.Printf("\U0001F7E2 Server Started Listening on %s\n", s.server.Addr)
+			err = s.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			s.errc <- err
+		}
+	}()
+
+	return nil
 }
 
-func (s *Server) gracefulShutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Err returns the channel that receives the error that stopped the
+// server from serving. It implements lifecycle.Failer.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
+// Stop shuts the server down gracefully, waiting for the requests in
+// flight until ctx is done. It implements lifecycle.Component.
+func (s *Server) Stop(ctx context.Context) error {
+	fmt.Println("🛑 Server Stopped")
+	defer s.cancel()
 
 	if err := s.server.Shutdown(ctx); err != nil {
+		_ = s.server.Close()
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 
+	fmt.Println("💯 Server Exited Properly")
 	return nil
 }
 
+// RegisterOnShutdown registers a function to call when the server
+// starts to shut down, e.g. to end the long-lived streams that would
+// otherwise delay the shutdown.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.onShutdown = append(s.onShutdown, f)
+	if s.isInited {
+		s.server.RegisterOnShutdown(f)
+	}
+}
+
 // AddRoutes takes routes to register in server.
 func (s *Server) AddRoutes(routes ...api.Route) {
 	s.HTTPRoutes = append(s.HTTPRoutes, routes...)
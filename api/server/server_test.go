@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"noterfy/api/server/routes"
+	"testing"
+)
+
+func TestServerStartStop(t *testing.T) {
+	srv := New(&Config{Metadata: &routes.Metadata{}})
+	shutdown := make(chan struct{})
+	srv.RegisterOnShutdown(func() { close(shutdown) })
+	require.NoError(t, srv.Start(context.Background()))
+	require.NoError(t, srv.Stop(context.Background()))
+
+	select {
+	case err := <-srv.Err():
+		t.Fatalf("unexpected serve error: %v", err)
+	default:
+	}
+	<-shutdown
+}
+
+func TestServerStartPortInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	srv := New(&Config{
+		Port:     ln.Addr().(*net.TCPAddr).Port,
+		Metadata: &routes.Metadata{},
+	})
+	err = srv.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to listen")
+}
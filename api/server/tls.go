@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultTLSReloadInterval = time.Minute
+
+// TLSConfig contains the TLS configuration of the server.
+type TLSConfig struct {
+	// CertFile is the path of the PEM encoded certificate of the
+	// server, including the intermediate certificates.
+	CertFile string
+	// KeyFile is the path of the PEM encoded private key of
+	// the certificate.
+	KeyFile string
+	// ClientCAFile is the path of the PEM encoded bundle of the CAs
+	// that issue the client certificates. When provided the client
+	// certificates are verified (mutual TLS).
+	ClientCAFile string
+	// RequireClientCert rejects the clients without a certificate
+	// when the ClientCAFile is provided. Otherwise a client without
+	// a certificate is accepted but has no principal.
+	RequireClientCert bool
+	// ReloadInterval is how frequently the files are checked for
+	// changes. Default is 1 minute.
+	ReloadInterval time.Duration
+}
+
+// TLSReloader provides the TLS configuration of the server from
+// the files of a TLSConfig and reloads it when the files change,
+// so the certificates can be rotated without restarting the server.
+type TLSReloader struct {
+	conf TLSConfig
+
+	mu      sync.RWMutex
+	config  *tls.Config
+	modTime time.Time
+}
+
+// NewTLSReloader loads the files of conf and returns the reloader.
+func NewTLSReloader(conf TLSConfig) (*TLSReloader, error) {
+	if conf.CertFile == "" || conf.KeyFile == "" {
+		return nil, errors.New("server: the TLS certificate and key files are required")
+	}
+	if conf.ReloadInterval <= 0 {
+		conf.ReloadInterval = defaultTLSReloadInterval
+	}
+
+	r := &TLSReloader{conf: conf}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the files again. The current configuration is
+// kept when the files are invalid.
+func (r *TLSReloader) Reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	config, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	r.modTime = modTime
+	return nil
+}
+
+func (r *TLSReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.conf.CertFile, r.conf.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("server: unable to load the TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if r.conf.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.conf.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("server: unable to read the client CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("server: no certificate in the client CA bundle '%s'", r.conf.ClientCAFile)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.conf.RequireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return config, nil
+}
+
+// latestModTime returns the latest modification time of the files.
+func (r *TLSReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.conf.CertFile, r.conf.KeyFile, r.conf.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("server: unable to stat '%s': %w", path, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reloadIfChanged reloads the files when one of them was
+// modified since the last reload.
+func (r *TLSReloader) reloadIfChanged() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	changed := !modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	if err := r.Reload(); err != nil {
+		return err
+	}
+	logrus.Info("server: reloaded the TLS certificates")
+	return nil
+}
+
+// Watch checks the files for changes every reload interval and
+// reloads them until ctx is done.
+func (r *TLSReloader) Watch(ctx context.Context) {
+	ticker := time.NewTicker(r.conf.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reloadIfChanged(); err != nil {
+				logrus.Error("server: unable to reload the TLS certificates:", err)
+			}
+		}
+	}
+}
+
+// TLSConfig returns the TLS configuration to use in the http.Server.
+// Every connection uses the configuration loaded last.
+//
+// The GetCertificate is set too so http.Server.ServeTLS doesn't load
+// the certificate from its empty file arguments.
+func (r *TLSReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.current().Certificates[0], nil
+		},
+	}
+}
+
+// current returns the configuration loaded last.
+func (r *TLSReloader) current() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/api/middleware"
+	"noterfy/api/server/routes"
+	"noterfy/pkg/principal"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate generated for the tests.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *testCert) keyPEM() []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		panic(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM(), c.keyPEM())
+	if err != nil {
+		panic(err)
+	}
+	return cert
+}
+
+// newTestCert generates a certificate with the common name cn signed
+// by parent. It is self-signed when parent is nil.
+func newTestCert(cn string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"noterfy"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func TestTLS(t *testing.T) {
+	suite.Run(t, new(TLSTestSuite))
+}
+
+type TLSTestSuite struct {
+	suite.Suite
+	dir      string
+	ca       *testCert
+	clientCA *testCert
+	conf     TLSConfig
+}
+
+func (s *TLSTestSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+	s.ca = newTestCert("Test CA", nil, true)
+	s.clientCA = newTestCert("Test Client CA", nil, true)
+	s.conf = TLSConfig{
+		CertFile: filepath.Join(s.dir, "server.crt"),
+		KeyFile:  filepath.Join(s.dir, "server.key"),
+	}
+	s.writeServerCert(newTestCert("server-1", s.ca, false))
+}
+
+func (s *TLSTestSuite) writeServerCert(c *testCert) {
+	s.Require().NoError(os.WriteFile(s.conf.CertFile, c.certPEM(), 0600))
+	s.Require().NoError(os.WriteFile(s.conf.KeyFile, c.keyPEM(), 0600))
+}
+
+func (s *TLSTestSuite) writeClientCA() {
+	s.conf.ClientCAFile = filepath.Join(s.dir, "clients.crt")
+	s.Require().NoError(os.WriteFile(s.conf.ClientCAFile, s.clientCA.certPEM(), 0600))
+}
+
+// touch moves the modification time of the files forward so the
+// change is noticed even within the resolution of the file system.
+func (s *TLSTestSuite) touch(paths ...string) {
+	future := time.Now().Add(time.Minute)
+	for _, path := range paths {
+		s.Require().NoError(os.Chtimes(path, future, future))
+	}
+}
+
+// serve starts a TLS server using the reloader. The handler writes
+// the principal of the request.
+func (s *TLSTestSuite) serve(reloader *TLSReloader) *httptest.Server {
+	handler := middleware.ClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := principal.FromContext(r.Context())
+		if ok {
+			_, _ = fmt.Fprint(w, p.Name)
+		}
+	}))
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = reloader.TLSConfig()
+	srv.StartTLS()
+	s.T().Cleanup(srv.Close)
+	return srv
+}
+
+// get does a request to srv with a new connection and returns the
+// common name of the server certificate and the body.
+func (s *TLSTestSuite) get(srv *httptest.Server, clientCert *testCert) (string, string, error) {
+	return s.getURL(srv.URL, clientCert)
+}
+
+// getURL does a request to the url like get.
+func (s *TLSTestSuite) getURL(url string, clientCert *testCert) (string, string, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(s.ca.cert)
+
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if clientCert != nil {
+		// The certificate is sent even when it is not signed by
+		// the CAs the server asks for.
+		cert := clientCert.tlsCertificate()
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &cert, nil
+		}
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   tlsConfig,
+		DisableKeepAlives: true,
+	}}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body [256]byte
+	n, _ := resp.Body.Read(body[:])
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, string(body[:n]), nil
+}
+
+func (s *TLSTestSuite) TestServe() {
+	reloader, err := NewTLSReloader(s.conf)
+	s.Require().NoError(err)
+	srv := s.serve(reloader)
+
+	cn, body, err := s.get(srv, nil)
+	s.Require().NoError(err)
+	s.Equal("server-1", cn)
+	s.Empty(body)
+}
+
+func (s *TLSTestSuite) TestServer() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	s.Require().NoError(ln.Close())
+
+	srv := New(&Config{Port: port, Metadata: &routes.Metadata{}, TLS: &s.conf})
+	s.Require().NoError(srv.Start(context.Background()))
+	defer func() { s.NoError(srv.Stop(context.Background())) }()
+
+	cn, _, err := s.getURL(fmt.Sprintf("https://127.0.0.1:%d/health", port), nil)
+	s.Require().NoError(err)
+	s.Equal("server-1", cn)
+
+	select {
+	case err := <-srv.Err():
+		s.Failf("unexpected serve error", "%v", err)
+	default:
+	}
+}
+
+func (s *TLSTestSuite) TestReload() {
+	reloader, err := NewTLSReloader(s.conf)
+	s.Require().NoError(err)
+	srv := s.serve(reloader)
+
+	// The files are unchanged.
+	s.Require().NoError(reloader.reloadIfChanged())
+	cn, _, err := s.get(srv, nil)
+	s.Require().NoError(err)
+	s.Equal("server-1", cn)
+
+	s.writeServerCert(newTestCert("server-2", s.ca, false))
+	s.touch(s.conf.CertFile, s.conf.KeyFile)
+	s.Require().NoError(reloader.reloadIfChanged())
+
+	cn, _, err = s.get(srv, nil)
+	s.Require().NoError(err)
+	s.Equal("server-2", cn)
+
+	// The certificate of the http.Server is the reloaded one too.
+	cert, err := reloader.TLSConfig().GetCertificate(&tls.ClientHelloInfo{})
+	s.Require().NoError(err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	s.Require().NoError(err)
+	s.Equal("server-2", leaf.Subject.CommonName)
+}
+
+func (s *TLSTestSuite) TestReloadInvalidKeepsCurrent() {
+	reloader, err := NewTLSReloader(s.conf)
+	s.Require().NoError(err)
+	srv := s.serve(reloader)
+
+	// The key doesn't match the certificate yet.
+	s.Require().NoError(os.WriteFile(s.conf.CertFile, newTestCert("server-2", s.ca, false).certPEM(), 0600))
+	s.touch(s.conf.CertFile)
+	s.Error(reloader.reloadIfChanged())
+
+	cn, _, err := s.get(srv, nil)
+	s.Require().NoError(err)
+	s.Equal("server-1", cn)
+}
+
+func (s *TLSTestSuite) TestNewTLSReloaderInvalid() {
+	_, err := NewTLSReloader(TLSConfig{CertFile: s.conf.CertFile})
+	s.Error(err)
+
+	s.conf.ClientCAFile = filepath.Join(s.dir, "missing.crt")
+	_, err = NewTLSReloader(s.conf)
+	s.Error(err)
+
+	s.Require().NoError(os.WriteFile(s.conf.ClientCAFile, []byte("not a certificate"), 0600))
+	_, err = NewTLSReloader(s.conf)
+	s.Error(err)
+}
+
+func (s *TLSTestSuite) TestClientCert() {
+	s.writeClientCA()
+	reloader, err := NewTLSReloader(s.conf)
+	s.Require().NoError(err)
+	srv := s.serve(reloader)
+
+	_, body, err := s.get(srv, newTestCert("alice", s.clientCA, false))
+	s.Require().NoError(err)
+	s.Equal("CN=alice,O=noterfy", body)
+
+	// The client certificate is optional.
+	_, body, err = s.get(srv, nil)
+	s.Require().NoError(err)
+	s.Empty(body)
+
+	// The client certificate is not signed by the client CA.
+	_, _, err = s.get(srv, newTestCert("mallory", s.ca, false))
+	s.Error(err)
+}
+
+func (s *TLSTestSuite) TestRequireClientCert() {
+	s.writeClientCA()
+	s.conf.RequireClientCert = true
+	reloader, err := NewTLSReloader(s.conf)
+	s.Require().NoError(err)
+	srv := s.serve(reloader)
+
+	_, _, err = s.get(srv, nil)
+	s.Error(err)
+
+	_, body, err := s.get(srv, newTestCert("bob", s.clientCA, false))
+	s.Require().NoError(err)
+	s.Equal("CN=bob,O=noterfy", body)
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"io"
+	"noterfy/note"
+)
+
+// AttachmentService manages the attachments of the notes, e.g. the
+// attachment.Service.
+type AttachmentService interface {
+	Add(ctx context.Context, noteID uuid.UUID, a *note.Attachment, r io.Reader) (*note.Attachment, error)
+	Open(ctx context.Context, noteID, attachmentID uuid.UUID) (io.ReadSeekCloser, *note.Attachment, error)
+	Remove(ctx context.Context, noteID, attachmentID uuid.UUID) error
+}
+
+// AttachmentMiddleware returns the AttachmentService that records the
+// successful additions and removals of the attachments of next to the
+// log l. The attachments are not part of the note updates, so they are
+// audited separately.
+//
+// The change of an addition is the new attachment and the change of a
+// removal is the ID of the removed attachment.
+func AttachmentMiddleware(l *Log, next AttachmentService) AttachmentService {
+	return &auditingAttachments{AttachmentService: next, log: l}
+}
+
+type auditingAttachments struct {
+	AttachmentService
+	log *Log
+}
+
+func (s *auditingAttachments) Add(ctx context.Context, noteID uuid.UUID, a *note.Attachment, r io.Reader) (*note.Attachment, error) {
+	added, err := s.AttachmentService.Add(ctx, noteID, a, r)
+	if err == nil {
+		record(ctx, s.log, OperationAttach, noteID, []Change{{Field: "attachments", New: attachmentJSON(added)}})
+	}
+	return added, err
+}
+
+func (s *auditingAttachments) Remove(ctx context.Context, noteID, attachmentID uuid.UUID) error {
+	err := s.AttachmentService.Remove(ctx, noteID, attachmentID)
+	if err == nil {
+		removed := struct {
+			ID uuid.UUID `json:"id"`
+		}{ID: attachmentID}
+		record(ctx, s.log, OperationDetach, noteID, []Change{{Field: "attachments", Old: attachmentJSON(removed)}})
+	}
+	return err
+}
+
+// attachmentJSON returns the JSON value of the attachment a. The
+// attachments always marshal so the error is ignored.
+func attachmentJSON(a interface{}) json.RawMessage {
+	data, _ := json.Marshal(a)
+	return data
+}
@@ -0,0 +1,289 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"noterfy/note"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GenesisHash is the previous hash of the first entry of a log.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Operation is an audited operation of the note service.
+type Operation string
+
+// The audited operations.
+const (
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationPatch  Operation = "patch"
+	OperationDelete Operation = "delete"
+	OperationGet    Operation = "get"
+	// OperationAttach adds an attachment to a note.
+	OperationAttach Operation = "attach"
+	// OperationDetach removes an attachment from a note.
+	OperationDetach Operation = "detach"
+)
+
+// Operations are all the audited operations.
+var Operations = []Operation{
+	OperationCreate, OperationUpdate, OperationPatch, OperationDelete, OperationGet,
+	OperationAttach, OperationDetach,
+}
+
+// The principals of the operations without an authenticated caller.
+const (
+	// PrincipalAnonymous is the principal of the anonymous requests.
+	PrincipalAnonymous = "anonymous"
+	// PrincipalSystem is the principal of the operations done by the
+	// server itself, e.g. the auto-archive.
+	PrincipalSystem = "system"
+)
+
+// Change is the change of a field of a note. The values are the JSON
+// values of the field and are omitted when the field is not set.
+type Change struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// Entry is an entry of the audit log.
+type Entry struct {
+	// Seq is the sequence number of the entry starting from 1.
+	Seq uint64 `json:"seq"`
+	// Time is when the operation was done.
+	Time time.Time `json:"time"`
+	// Operation is the audited operation.
+	Operation Operation `json:"operation"`
+	// Principal is the caller of the operation. See PrincipalAnonymous
+	// and PrincipalSystem.
+	Principal string `json:"principal"`
+	// RequestID is the ID of the request of the operation. It is
+	// empty when the operation was not requested by a client.
+	RequestID string `json:"request_id,omitempty"`
+	// NoteID is the ID of the note.
+	NoteID uuid.UUID `json:"note_id"`
+	// Changes are the changed fields of the note.
+	Changes []Change `json:"changes,omitempty"`
+	// PrevHash is the hash of the previous entry, or GenesisHash.
+	PrevHash string `json:"prev_hash"`
+	// Hash is the SHA-256 digest of the entry without its hash, which
+	// chains the entry to all the previous entries.
+	Hash string `json:"hash"`
+}
+
+// computeHash returns the hash of e.
+func (e *Entry) computeHash() (string, error) {
+	unhashed := *e
+	unhashed.Hash = ""
+	data, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Filter selects the entries of a log. The zero value selects all
+// the entries.
+type Filter struct {
+	NoteID    uuid.UUID
+	Principal string
+	Operation Operation
+	RequestID string
+	// Since and Until select the entries in the time range [Since, Until).
+	Since time.Time
+	Until time.Time
+	// After selects the entries after the sequence number.
+	After uint64
+	// Limit is the maximum number of the selected entries.
+	Limit int
+}
+
+// Match reports whether the entry e is selected by f, regardless
+// of the limit.
+func (f *Filter) Match(e *Entry) bool {
+	switch {
+	case e.Seq <= f.After:
+		return false
+	case f.NoteID != uuid.Nil && e.NoteID != f.NoteID:
+		return false
+	case f.Principal != "" && e.Principal != f.Principal:
+		return false
+	case f.Operation != "" && e.Operation != f.Operation:
+		return false
+	case f.RequestID != "" && e.RequestID != f.RequestID:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Diff returns the changed fields from the note before to the
+// note after in the order of their names. A nil note has no fields.
+func Diff(before, after *note.Note) ([]Change, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+	return diff(old, updated), nil
+}
+
+func diff(old, updated map[string]json.RawMessage) []Change {
+	names := make([]string, 0, len(old)+len(updated))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range updated {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		if !bytes.Equal(old[name], updated[name]) {
+			changes = append(changes, Change{Field: name, Old: old[name], New: updated[name]})
+		}
+	}
+	return changes
+}
+
+// fields returns the JSON values of the fields of the note n.
+func fields(n *note.Note) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if n == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ChainError is the error of a log with a broken hash chain, i.e.
+// a log that was tampered with or corrupted.
+type ChainError struct {
+	// Line is the line number of the first broken entry.
+	Line int
+	// Seq is the sequence number of the entry, when it can be read.
+	Seq uint64
+	// Reason tells how the chain is broken.
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	if e.Seq == 0 {
+		return fmt.Sprintf("audit: the chain is broken at line %d: %s", e.Line, e.Reason)
+	}
+	return fmt.Sprintf("audit: the chain is broken at line %d (entry %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// VerifyResult is the result of a verified log.
+type VerifyResult struct {
+	// Entries is the number of entries.
+	Entries uint64
+	// Head is the hash of the last entry, or GenesisHash when the
+	// log is empty. Comparing it with a previously noted head detects
+	// the removal of the last entries.
+	Head string
+}
+
+// Verify reads the log in r and checks its hash chain. It returns a
+// *ChainError for the first entry that doesn't match its hash, the
+// previous entry or its sequence number.
+func Verify(r io.Reader) (*VerifyResult, error) {
+	result := &VerifyResult{Head: GenesisHash}
+	err := readEntries(r, func(line int, raw []byte, e *Entry) error {
+		broken := func(reason string) error {
+			return &ChainError{Line: line, Seq: e.Seq, Reason: reason}
+		}
+
+		if e.Seq != result.Entries+1 {
+			return broken(fmt.Sprintf("expected the sequence number %d", result.Entries+1))
+		}
+		if e.PrevHash != result.Head {
+			return broken("the previous hash doesn't match the previous entry")
+		}
+
+		// An entry must be written as it is hashed so any change
+		// to the line, even to an unknown field, is detected.
+		canonical, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(canonical, raw) {
+			return broken("the entry is not in its canonical form")
+		}
+
+		hash, err := e.computeHash()
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return broken("the hash doesn't match the entry")
+		}
+
+		result.Entries++
+		result.Head = e.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// reasonTruncated is the reason of a *ChainError of a last line
+// without its line break.
+const reasonTruncated = "the entry is truncated"
+
+// readEntries reads the JSON lines of the log in r and calls fn with
+// the line number, the line without its line break and the entry of
+// each line. It returns a *ChainError when a line is not an entry.
+func readEntries(r io.Reader, fn func(line int, raw []byte, e *Entry) error) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		raw, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(raw) == 0 && err == io.EOF {
+			return nil
+		}
+		if raw[len(raw)-1] != '\n' {
+			return &ChainError{Line: line, Reason: reasonTruncated}
+		}
+		raw = raw[:len(raw)-1]
+
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return &ChainError{Line: line, Reason: "the entry is malformed"}
+		}
+		if err := fn(line, raw, &e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	blobstore "noterfy/blob/store/file"
+	"noterfy/note"
+	"noterfy/note/attachment"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/requestid"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const logName = "audit.log"
+
+func TestLog(t *testing.T) {
+	suite.Run(t, new(LogTestSuite))
+}
+
+type LogTestSuite struct {
+	suite.Suite
+	fs    afero.Fs
+	clock *clock.Fake
+	log   *Log
+}
+
+func (s *LogTestSuite) SetupTest() {
+	s.fs = afero.NewMemMapFs()
+	s.clock = clock.NewFake(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	s.log = s.open()
+}
+
+func (s *LogTestSuite) TearDownTest() {
+	s.NoError(s.log.Close())
+}
+
+func (s *LogTestSuite) open() *Log {
+	l, err := Open(s.fs, logName, s.clock)
+	s.Require().NoError(err)
+	return l
+}
+
+func (s *LogTestSuite) append(op Operation, id uuid.UUID, p string) *Entry {
+	e := &Entry{Operation: op, NoteID: id, Principal: p}
+	s.Require().NoError(s.log.Append(e))
+	s.clock.Advance(time.Minute)
+	return e
+}
+
+func (s *LogTestSuite) verify() (*VerifyResult, error) {
+	file, err := s.fs.Open(logName)
+	s.Require().NoError(err)
+	defer func() { _ = file.Close() }()
+	return Verify(file)
+}
+
+// tamper replaces the line of the log with the line number by the
+// result of fn.
+func (s *LogTestSuite) tamper(line int, fn func(line string) string) {
+	data, err := afero.ReadFile(s.fs, logName)
+	s.Require().NoError(err)
+	lines := strings.SplitAfter(string(data), "\n")
+	lines[line-1] = fn(lines[line-1])
+	s.Require().NoError(afero.WriteFile(s.fs, logName, []byte(strings.Join(lines, "")), 0600))
+}
+
+func (s *LogTestSuite) assertBroken(err error, line int) {
+	var chainErr *ChainError
+	s.Require().True(errors.As(err, &chainErr), "%v", err)
+	s.Equal(line, chainErr.Line)
+}
+
+func (s *LogTestSuite) TestAppend() {
+	id := uuid.New()
+	first := s.append(OperationCreate, id, "alice")
+	second := s.append(OperationGet, id, "bob")
+
+	s.Equal(uint64(1), first.Seq)
+	s.Equal(GenesisHash, first.PrevHash)
+	s.Equal(uint64(2), second.Seq)
+	s.Equal(first.Hash, second.PrevHash)
+	s.Equal(time.Date(2021, 6, 1, 12, 1, 0, 0, time.UTC), second.Time)
+
+	seq, head := s.log.Head()
+	s.Equal(uint64(2), seq)
+	s.Equal(second.Hash, head)
+
+	result, err := s.verify()
+	s.Require().NoError(err)
+	s.Equal(&VerifyResult{Entries: 2, Head: second.Hash}, result)
+
+	s.Run("Reopening the log should continue the chain", func() {
+		s.Require().NoError(s.log.Close())
+		s.log = s.open()
+
+		third := s.append(OperationDelete, id, "alice")
+		s.Equal(uint64(3), third.Seq)
+		s.Equal(second.Hash, third.PrevHash)
+
+		result, err := s.verify()
+		s.Require().NoError(err)
+		s.Equal(uint64(3), result.Entries)
+	})
+}
+
+func (s *LogTestSuite) TestOpenTruncated() {
+	id := uuid.New()
+	first := s.append(OperationCreate, id, "alice")
+	s.append(OperationUpdate, id, "alice")
+	s.Require().NoError(s.log.Close())
+	s.tamper(2, func(line string) string { return line[:len(line)/2] })
+
+	s.log = s.open()
+	second := s.append(OperationDelete, id, "alice")
+	s.Equal(uint64(2), second.Seq)
+	s.Equal(first.Hash, second.PrevHash)
+
+	result, err := s.verify()
+	s.Require().NoError(err)
+	s.Equal(&VerifyResult{Entries: 2, Head: second.Hash}, result)
+
+	s.Run("A malformed complete entry should fail the opening", func() {
+		s.Require().NoError(s.log.Close())
+		s.tamper(1, func(string) string { return "{\n" })
+
+		_, err := Open(s.fs, logName, s.clock)
+		s.assertBroken(err, 1)
+
+		s.fs = afero.NewMemMapFs()
+		s.log = s.open()
+	})
+}
+
+func (s *LogTestSuite) TestAppendFailure() {
+	fs := &failingFs{Fs: s.fs}
+	s.NoError(s.log.Close())
+	log, err := Open(fs, logName, s.clock)
+	s.Require().NoError(err)
+	s.log = log
+
+	id := uuid.New()
+	first := s.append(OperationCreate, id, "alice")
+	fs.fail = true
+	s.Error(s.log.Append(&Entry{Operation: OperationUpdate, NoteID: id, Principal: "alice"}))
+	fs.fail = false
+	second := s.append(OperationDelete, id, "alice")
+	s.Equal(uint64(2), second.Seq)
+	s.Equal(first.Hash, second.PrevHash)
+
+	result, err := s.verify()
+	s.Require().NoError(err)
+	s.Equal(&VerifyResult{Entries: 2, Head: second.Hash}, result)
+}
+
+// failingFs is a filesystem whose files write only the first half
+// of the data and fail while fail is set.
+type failingFs struct {
+	afero.Fs
+	fail bool
+}
+
+func (fs *failingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	file, err := fs.Fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &failingFile{File: file, fs: fs}, nil
+}
+
+type failingFile struct {
+	afero.File
+	fs *failingFs
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if !f.fs.fail {
+		return f.File.Write(p)
+	}
+	n, _ := f.File.Write(p[:len(p)/2])
+	return n, errors.New("disk full")
+}
+
+func (s *LogTestSuite) TestQuery() {
+	a, b := uuid.New(), uuid.New()
+	s.append(OperationCreate, a, "alice")
+	s.append(OperationCreate, b, "bob")
+	s.append(OperationUpdate, a, "bob")
+	s.append(OperationGet, a, "alice")
+	start := s.clock.Now()
+
+	seqs := func(f Filter) []uint64 {
+		entries, err := s.log.Query(f)
+		s.Require().NoError(err)
+		var seqs []uint64
+		for _, e := range entries {
+			seqs = append(seqs, e.Seq)
+		}
+		return seqs
+	}
+
+	s.Equal([]uint64{1, 2, 3, 4}, seqs(Filter{}))
+	s.Equal([]uint64{1, 3, 4}, seqs(Filter{NoteID: a}))
+	s.Equal([]uint64{2, 3}, seqs(Filter{Principal: "bob"}))
+	s.Equal([]uint64{1, 2}, seqs(Filter{Operation: OperationCreate}))
+	s.Equal([]uint64{2, 3}, seqs(Filter{Since: start.Add(-3 * time.Minute), Until: start.Add(-time.Minute)}))
+	s.Equal([]uint64{3, 4}, seqs(Filter{After: 2}))
+	s.Equal([]uint64{1, 3}, seqs(Filter{NoteID: a, Limit: 2}))
+	s.Empty(seqs(Filter{Principal: "carol"}))
+}
+
+func (s *LogTestSuite) TestVerifyTampered() {
+	for i := 0; i < 3; i++ {
+		s.append(OperationCreate, uuid.New(), "alice")
+	}
+	s.Require().NoError(s.log.Close())
+	data, err := afero.ReadFile(s.fs, logName)
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name   string
+		line   int
+		tamper func(line string) string
+	}{
+		{
+			name:   "Changing a value",
+			line:   2,
+			tamper: func(line string) string { return strings.Replace(line, `"alice"`, `"bob"`, 1) },
+		},
+		{
+			name:   "Adding a field",
+			line:   2,
+			tamper: func(line string) string { return strings.Replace(line, `{`, `{"extra":true,`, 1) },
+		},
+		{
+			name:   "Removing an entry",
+			line:   2,
+			tamper: func(string) string { return "" },
+		},
+		{
+			// The next entry has the original hash.
+			name: "Rewriting an entry with its hash",
+			line: 3,
+			tamper: func(line string) string {
+				var e Entry
+				s.Require().NoError(json.Unmarshal([]byte(line), &e))
+				e.Principal = "bob"
+				e.Hash, _ = e.computeHash()
+				data, _ := json.Marshal(&e)
+				return string(data) + "\n"
+			},
+		},
+		{
+			name:   "Corrupting an entry",
+			line:   2,
+			tamper: func(string) string { return "{\n" },
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.Require().NoError(afero.WriteFile(s.fs, logName, data, 0600))
+			s.tamper(2, tt.tamper)
+
+			_, err := s.verify()
+			s.assertBroken(err, tt.line)
+		})
+	}
+	s.Require().NoError(afero.WriteFile(s.fs, logName, data, 0600))
+	s.log = s.open()
+}
+
+func TestDiff(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
+
+type DiffTestSuite struct {
+	suite.Suite
+}
+
+func (s *DiffTestSuite) TestDiff() {
+	id := uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	before := new(note.Note).SetID(id).SetTitle("Title").SetContent("Content")
+	after := new(note.Note).SetID(id).SetTitle("New Title").SetIsFavorite(true)
+
+	changes, err := Diff(before, after)
+	s.Require().NoError(err)
+	s.Equal([]Change{
+		{Field: "content", Old: json.RawMessage(`"Content"`)},
+		{Field: "is_favorite", New: json.RawMessage(`true`)},
+		{Field: "title", Old: json.RawMessage(`"Title"`), New: json.RawMessage(`"New Title"`)},
+	}, changes)
+
+	s.Run("Creating a note should change all its fields", func() {
+		changes, err := Diff(nil, before)
+		s.Require().NoError(err)
+		s.Len(changes, 3)
+	})
+
+	s.Run("An unchanged note should have no changes", func() {
+		changes, err := Diff(before, before)
+		s.Require().NoError(err)
+		s.Empty(changes)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	log *Log
+	svc note.Service
+}
+
+func (s *MiddlewareTestSuite) SetupTest() {
+	l, err := Open(afero.NewMemMapFs(), logName, nil)
+	s.Require().NoError(err)
+	s.log = l
+	s.svc = Middleware(l)(service.New(memory.New()))
+}
+
+func (s *MiddlewareTestSuite) TearDownTest() {
+	s.NoError(s.log.Close())
+}
+
+func (s *MiddlewareTestSuite) entries() []*Entry {
+	entries, err := s.log.Query(Filter{})
+	s.Require().NoError(err)
+	return entries
+}
+
+func (s *MiddlewareTestSuite) TestOperations() {
+	ctx := requestid.WithContext(context.Background(), "request-1")
+	ctx = principal.WithContext(ctx, principal.Principal{Name: "CN=alice"})
+
+	created, err := s.svc.Create(ctx, new(note.Note).SetTitle("Title"))
+	s.Require().NoError(err)
+	_, err = s.svc.Get(ctx, created.ID)
+	s.Require().NoError(err)
+	_, err = s.svc.Update(ctx, new(note.Note).SetID(created.ID).SetTitle("New Title"))
+	s.Require().NoError(err)
+	_, err = s.svc.Patch(ctx, created.ID, note.Patch{
+		MediaType: note.MediaTypeMergePatch,
+		Document:  []byte(`{"is_favorite":true}`),
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(s.svc.Delete(ctx, created.ID))
+
+	entries := s.entries()
+	s.Require().Len(entries, 5)
+	for i, op := range []Operation{OperationCreate, OperationGet, OperationUpdate, OperationPatch, OperationDelete} {
+		s.Equal(op, entries[i].Operation)
+		s.Equal("CN=alice", entries[i].Principal)
+		s.Equal("request-1", entries[i].RequestID)
+		s.Equal(created.ID, entries[i].NoteID)
+	}
+
+	s.Contains(entries[0].Changes, Change{Field: "title", New: json.RawMessage(`"Title"`)})
+	s.Empty(entries[1].Changes)
+	s.Contains(entries[2].Changes, Change{Field: "title", Old: json.RawMessage(`"Title"`), New: json.RawMessage(`"New Title"`)})
+	s.Contains(entries[3].Changes, Change{Field: "is_favorite", New: json.RawMessage(`true`)})
+	s.Contains(entries[4].Changes, Change{Field: "title", Old: json.RawMessage(`"New Title"`)})
+}
+
+func (s *MiddlewareTestSuite) TestPrincipal() {
+	_, err := s.svc.Create(requestid.WithContext(context.Background(), "request-1"), new(note.Note).SetTitle("Anonymous"))
+	s.Require().NoError(err)
+	_, err = s.svc.Create(context.Background(), new(note.Note).SetTitle("System"))
+	s.Require().NoError(err)
+
+	entries := s.entries()
+	s.Require().Len(entries, 2)
+	s.Equal(PrincipalAnonymous, entries[0].Principal)
+	s.Equal(PrincipalSystem, entries[1].Principal)
+	s.Empty(entries[1].RequestID)
+}
+
+func (s *MiddlewareTestSuite) TestFailedOperations() {
+	_, err := s.svc.Get(context.Background(), uuid.New())
+	s.Equal(note.ErrNotFound, err)
+	_, err = s.svc.Update(context.Background(), new(note.Note).SetID(uuid.New()).SetTitle("Missing"))
+	s.Error(err)
+
+	s.Empty(s.entries())
+}
+
+func (s *MiddlewareTestSuite) TestAttachments() {
+	store := memory.New()
+	svc := service.New(store)
+	attachments := AttachmentMiddleware(s.log, attachment.New(store, blobstore.New(afero.NewMemMapFs())))
+	ctx := principal.WithContext(context.Background(), principal.Principal{Name: "CN=alice"})
+
+	n, err := svc.Create(ctx, new(note.Note).SetTitle("Title"))
+	s.Require().NoError(err)
+	added, err := attachments.Add(ctx, n.ID, &note.Attachment{Name: "a.txt", MediaType: "text/plain"}, strings.NewReader("a"))
+	s.Require().NoError(err)
+	s.Require().NoError(attachments.Remove(ctx, n.ID, added.ID))
+	s.Error(attachments.Remove(ctx, n.ID, added.ID))
+
+	entries := s.entries()
+	s.Require().Len(entries, 2)
+	s.Equal(OperationAttach, entries[0].Operation)
+	s.Equal("CN=alice", entries[0].Principal)
+	s.Equal(n.ID, entries[0].NoteID)
+	s.Require().Len(entries[0].Changes, 1)
+	s.Contains(string(entries[0].Changes[0].New), `"name":"a.txt"`)
+	s.Equal(OperationDetach, entries[1].Operation)
+	s.Equal([]Change{{Field: "attachments", Old: json.RawMessage(`{"id":"` + added.ID.String() + `"}`)}}, entries[1].Changes)
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"noterfy/audit"
+	"noterfy/config"
+	"os"
+)
+
+func init() {
+	Cmd.AddCommand(Verify)
+}
+
+// Cmd is the root command for the audit package.
+var Cmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Parent command for any related operation with the audit log.",
+}
+
+// Verify is a cli cmd that checks the hash chain of the audit log.
+var Verify = &cobra.Command{
+	Use:   "verify [path]",
+	Short: "Verify the hash chain of the audit log",
+	Long: `Verify the hash chain of the audit log.
+
+Every entry of the audit log in path is checked against its hash and
+the hash of the previous entry, and the first broken entry is printed.
+When path is not provided the path of the audit log is read from the
+server configuration.
+
+The removal of the last entries can't be detected from the log alone.
+Keep the printed head hash to compare it with the next verification.
+`,
+	Example: "noterfy_cli audit verify ./audit.log",
+	Args:    cobra.MaximumNArgs(1),
+	// The usage is noise when the chain is broken.
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var path string
+		if len(args) > 0 {
+			path = args[0]
+		} else {
+			conf, err := config.Load("")
+			if err != nil {
+				return err
+			}
+			path = conf.Audit.Path
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+
+		result, err := audit.Verify(file)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "The audit log is valid: %d entries, head %s.\n", result.Entries, result.Head)
+		return err
+	},
+}
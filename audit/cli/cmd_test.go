@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"noterfy/audit"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := audit.Open(afero.NewOsFs(), path, nil)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, l.Append(&audit.Entry{Operation: audit.OperationCreate, Principal: "alice", NoteID: uuid.New()}))
+	}
+	_, head := l.Head()
+	require.NoError(t, l.Close())
+
+	run := func() (string, error) {
+		var out bytes.Buffer
+		Cmd.SetOut(&out)
+		Cmd.SetErr(&out)
+		Cmd.SetArgs([]string{"verify", path})
+		err := Cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run()
+	require.NoError(t, err)
+	assert.Contains(t, out, "The audit log is valid: 2 entries, head "+head+".")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), "alice", "bob", 1)), 0600))
+
+	_, err = run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "audit: the chain is broken at line 1 (entry 1)")
+}
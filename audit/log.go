@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"io"
+	"noterfy/pkg/clock"
+	"os"
+	"sync"
+)
+
+// Open takes a fs filesystem, the name of the log file in fs and the
+// clock c and returns the log appending to the file. The file is
+// created when it doesn't exist. If nil clock is provided it will
+// use the real clock.
+//
+// The existing entries are read to continue the chain but are not
+// verified. See Verify. A truncated last entry, e.g. of a crash during
+// an append, is removed with a warning.
+func Open(fs afero.Fs, name string, c clock.Clock) (*Log, error) {
+	if c == nil {
+		c = clock.New()
+	}
+
+	file, err := fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{fs: fs, name: name, clock: c, file: file, head: GenesisHash}
+	// Some filesystems open the files in append mode at their end.
+	_, err = file.Seek(0, io.SeekStart)
+	if err == nil {
+		err = readEntries(file, func(_ int, raw []byte, e *Entry) error {
+			l.seq = e.Seq
+			l.head = e.Hash
+			l.size += int64(len(raw)) + 1
+			return nil
+		})
+	}
+	var chainErr *ChainError
+	if errors.As(err, &chainErr) && chainErr.Reason == reasonTruncated {
+		logrus.Warnf("audit: removed the truncated entry at line %d of the log %s", chainErr.Line, name)
+		err = file.Truncate(l.size)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("audit: unable to read the log %s: %w", name, err)
+	}
+	return l, nil
+}
+
+// Log is an append-only audit log. It is safe for concurrent use.
+//
+// The underlying implementation writes each entry as a JSON line.
+// Each entry has the hash of the previous entry so changing, adding
+// or removing an entry, except the last ones, breaks the chain.
+type Log struct {
+	fs    afero.Fs
+	name  string
+	clock clock.Clock
+
+	// mu serializes the appends so the entries are
+	// chained in order.
+	mu   sync.Mutex
+	file afero.File
+	seq  uint64
+	head string
+	// size is the size of the complete entries of the file.
+	size int64
+	// partial tells whether the file may end with a partially
+	// written entry after size.
+	partial bool
+}
+
+// Append appends the entry e to the log. The sequence number, the
+// time and the hashes of e are set by the log.
+func (l *Log) Append(e *Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The partial entry of a failed append would break the chain.
+	if l.partial {
+		err := l.file.Truncate(l.size)
+		if err == nil {
+			// Some filesystems write at the offset even in append mode.
+			_, err = l.file.Seek(l.size, io.SeekStart)
+		}
+		if err != nil {
+			return fmt.Errorf("audit: unable to remove a partial entry: %w", err)
+		}
+		l.partial = false
+	}
+
+	e.Seq = l.seq + 1
+	e.Time = l.clock.Now().UTC()
+	e.PrevHash = l.head
+	hash, err := e.computeHash()
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// The line is written at once so a crash can only
+	// leave a truncated last entry.
+	n, err := l.file.Write(append(data, '\n'))
+	if err != nil {
+		l.partial = true
+		return err
+	}
+
+	l.seq = e.Seq
+	l.head = e.Hash
+	l.size += int64(n)
+	return nil
+}
+
+// Head returns the number of entries and the hash of the last
+// entry of the log.
+func (l *Log) Head() (uint64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq, l.head
+}
+
+// Query returns the entries of the log selected by the filter f in
+// the order of their sequence numbers.
+func (l *Log) Query(f Filter) ([]*Entry, error) {
+	l.mu.Lock()
+	size := l.size
+	l.mu.Unlock()
+
+	file, err := l.fs.Open(l.name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []*Entry
+	// The entries appended during the query are ignored.
+	err = readEntries(io.LimitReader(file, size), func(_ int, _ []byte, e *Entry) error {
+		if f.Limit > 0 && len(entries) == f.Limit {
+			return io.EOF
+		}
+		if f.Match(e) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Close syncs and closes the file of the log.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.file.Sync(); err != nil {
+		_ = l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
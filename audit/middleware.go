@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"noterfy/note"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/requestid"
+)
+
+// Middleware returns a note.Service middleware that records the
+// successful creates, updates, patches, deletes and gets of the notes
+// to the log l.
+//
+// The changes of the updates, patches and deletes are computed from
+// the note before the operation, so the changes of concurrent
+// operations on the same note can overlap.
+func Middleware(l *Log) note.Middleware {
+	return func(next note.Service) note.Service {
+		return &auditingService{Service: next, log: l}
+	}
+}
+
+type auditingService struct {
+	note.Service
+	log *Log
+}
+
+func (s *auditingService) Create(ctx context.Context, n *note.Note) (*note.Note, error) {
+	created, err := s.Service.Create(ctx, n)
+	if err == nil {
+		s.record(ctx, OperationCreate, created.ID, nil, created)
+	}
+	return created, err
+}
+
+func (s *auditingService) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	before := s.before(ctx, n.ID)
+	updated, err := s.Service.Update(ctx, n)
+	if err == nil {
+		s.record(ctx, OperationUpdate, updated.ID, before, updated)
+	}
+	return updated, err
+}
+
+func (s *auditingService) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error) {
+	before := s.before(ctx, id)
+	patched, err := s.Service.Patch(ctx, id, p)
+	if err == nil {
+		s.record(ctx, OperationPatch, id, before, patched)
+	}
+	return patched, err
+}
+
+func (s *auditingService) Delete(ctx context.Context, id uuid.UUID) error {
+	before := s.before(ctx, id)
+	err := s.Service.Delete(ctx, id)
+	if err == nil {
+		s.record(ctx, OperationDelete, id, before, nil)
+	}
+	return err
+}
+
+func (s *auditingService) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
+	n, err := s.Service.Get(ctx, id)
+	if err == nil {
+		s.record(ctx, OperationGet, id, nil, nil)
+	}
+	return n, err
+}
+
+// before returns the fields of the note with the id before it is
+// changed. The fields are read at once because the stores can change
+// the notes they return. It returns nil when the note can't be got,
+// in which case the operation is likely to fail too.
+func (s *auditingService) before(ctx context.Context, id uuid.UUID) map[string]json.RawMessage {
+	n, err := s.Service.Get(ctx, id)
+	if err != nil {
+		return nil
+	}
+	before, err := fields(n)
+	if err != nil {
+		return nil
+	}
+	return before
+}
+
+// record appends the operation to the log. The operation is done
+// already so an error is only logged.
+func (s *auditingService) record(ctx context.Context, op Operation, id uuid.UUID, before map[string]json.RawMessage, after *note.Note) {
+	updated, err := fields(after)
+	if err != nil {
+		logger.FromContext(ctx).WithField("note_id", id).Errorf("audit: unable to record the %s operation: %v", op, err)
+		return
+	}
+	record(ctx, s.log, op, id, diff(before, updated))
+}
+
+// record appends the operation of the caller in ctx to the log l.
+// The operation is done already so an error is only logged.
+func record(ctx context.Context, l *Log, op Operation, id uuid.UUID, changes []Change) {
+	err := l.Append(&Entry{
+		Operation: op,
+		Principal: principalOf(ctx),
+		RequestID: requestid.FromContext(ctx),
+		NoteID:    id,
+		Changes:   changes,
+	})
+	if err != nil {
+		logger.FromContext(ctx).WithField("note_id", id).Errorf("audit: unable to record the %s operation: %v", op, err)
+	}
+}
+
+// principalOf returns the name of the caller of the operation in
+// ctx. The operations outside of a request are done by the server.
+func principalOf(ctx context.Context) string {
+	if p, ok := principal.FromContext(ctx); ok {
+		return p.Name
+	}
+	if requestid.FromContext(ctx) != "" {
+		return PrincipalAnonymous
+	}
+	return PrincipalSystem
+}
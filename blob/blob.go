@@ -0,0 +1,51 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrNotFound is an error for any operation where the blob is not found.
+	ErrNotFound = errors.New("blob: blob not found")
+	// ErrInvalidDigest is an error when the digest is not a valid
+	// hex-encoded SHA-256 checksum.
+	ErrInvalidDigest = errors.New("blob: invalid digest")
+)
+
+// Blob describes a content-addressed binary object.
+type Blob struct {
+	// Digest is the hex-encoded SHA-256 checksum of the content.
+	Digest string `json:"digest"`
+	// Size is the size of the content in bytes.
+	Size int64 `json:"size"`
+	// Refs is the number of references to the blob.
+	Refs int `json:"refs"`
+}
+
+// Store is an interface for storing the blobs. The blobs are
+// keyed by the SHA-256 checksum of their content so the same
+// content is only stored once.
+type Store interface {
+	// Put reads the content from r until io.EOF and stores it as a blob.
+	// When a blob with the same content already exists, its reference count
+	// is incremented instead. It takes ctx context in order to let the
+	// caller stop the execution in any form.
+	Put(ctx context.Context, r io.Reader) (*Blob, error)
+
+	// Open opens the content of the blob with digest for reading. The caller
+	// must close the returned reader. An error can also return if encountered
+	// and it can be ErrNotFound or ErrInvalidDigest.
+	Open(ctx context.Context, digest string) (io.ReadSeekCloser, *Blob, error)
+
+	// Release decrements the reference count of the blob with digest.
+	// Blobs without references are removed by GC.
+	Release(ctx context.Context, digest string) error
+
+	// GC removes the blobs without references. It takes an optional
+	// inUse function to reconcile the reference counts with the owners of
+	// the blobs, in which case the blobs that are not in use are removed
+	// as well. It returns the digests of the removed blobs.
+	GC(ctx context.Context, inUse func(digest string) bool) (removed []string, err error)
+}
@@ -0,0 +1,298 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"io"
+	"noterfy/blob"
+	"os"
+	"path"
+	"sort"
+	"sync"
+)
+
+var _ blob.Store = (*Store)(nil)
+
+const (
+	indexFileName = "index.json"
+	blobsDir      = "blobs"
+	tmpDir        = "tmp"
+)
+
+// New takes a fs filesystem that is rooted at the directory of the
+// blobs and returns the store instance.
+func New(fs afero.Fs) *Store {
+	return &Store{
+		fs:    fs,
+		index: make(map[string]*blob.Blob),
+	}
+}
+
+// Store implements the blob.Store interface.
+//
+// The underlying implementation stores each blob as a file
+// named after its digest and keeps the reference counts in
+// an index file in the same directory.
+type Store struct {
+	fs afero.Fs
+
+	mu    sync.Mutex
+	index map[string]*blob.Blob
+
+	// once use to initialize the store only
+	// once.
+	once sync.Once
+}
+
+func (s *Store) lazyInit() (err error) {
+	s.once.Do(func() {
+		for _, dir := range []string{blobsDir, tmpDir} {
+			if err = s.fs.MkdirAll(dir, 0755); err != nil {
+				return
+			}
+		}
+
+		file, oerr := s.fs.Open(indexFileName)
+		if os.IsNotExist(oerr) {
+			return
+		}
+		if oerr != nil {
+			err = oerr
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		var blobs []*blob.Blob
+		if err = json.NewDecoder(file).Decode(&blobs); err != nil {
+			return
+		}
+
+		for _, b := range blobs {
+			s.index[b.Digest] = b
+		}
+	})
+	return
+}
+
+// Put reads the content from r until io.EOF and stores it as a blob.
+func (s *Store) Put(ctx context.Context, r io.Reader) (*blob.Blob, error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Write the content to a temporary file first because the
+	// digest is only known after reading the whole content.
+	tmpPath := path.Join(tmpDir, uuid.New().String())
+	tmpFile, err := s.fs.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(tmpFile, io.TeeReader(r, hash))
+	cerr := tmpFile.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = s.fs.Remove(tmpPath)
+		return nil, err
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, found := s.index[digest]
+	if found {
+		// Deduplicate the content.
+		_ = s.fs.Remove(tmpPath)
+		b.Refs++
+	} else {
+		blobPath := s.blobPath(digest)
+		if err := s.fs.MkdirAll(path.Dir(blobPath), 0755); err != nil {
+			_ = s.fs.Remove(tmpPath)
+			return nil, err
+		}
+
+		if err := s.fs.Rename(tmpPath, blobPath); err != nil {
+			_ = s.fs.Remove(tmpPath)
+			return nil, err
+		}
+
+		b = &blob.Blob{Digest: digest, Size: size, Refs: 1}
+		s.index[digest] = b
+	}
+
+	if err := s.writeIndex(); err != nil {
+		return nil, err
+	}
+
+	cpy := *b
+	return &cpy, nil
+}
+
+// Open opens the content of the blob with digest for reading.
+func (s *Store) Open(ctx context.Context, digest string) (io.ReadSeekCloser, *blob.Blob, error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if !isValidDigest(digest) {
+		return nil, nil, blob.ErrInvalidDigest
+	}
+
+	s.mu.Lock()
+	b, found := s.index[digest]
+	s.mu.Unlock()
+	if !found {
+		return nil, nil, blob.ErrNotFound
+	}
+
+	file, err := s.fs.Open(s.blobPath(digest))
+	if os.IsNotExist(err) {
+		return nil, nil, blob.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cpy := *b
+	return file, &cpy, nil
+}
+
+// Release decrements the reference count of the blob with digest.
+func (s *Store) Release(ctx context.Context, digest string) error {
+	if err := s.lazyInit(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, found := s.index[digest]
+	if !found {
+		return blob.ErrNotFound
+	}
+
+	if b.Refs > 0 {
+		b.Refs--
+	}
+
+	return s.writeIndex()
+}
+
+// GC removes the blobs without references.
+func (s *Store) GC(ctx context.Context, inUse func(digest string) bool) (removed []string, err error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for digest, b := range s.index {
+		if b.Refs > 0 && (inUse == nil || inUse(digest)) {
+			continue
+		}
+
+		err := s.fs.Remove(s.blobPath(digest))
+		if err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+
+		delete(s.index, digest)
+		removed = append(removed, digest)
+	}
+
+	// Remove the files that are not in the index. This could
+	// happen when the process stopped before writing the index.
+	err = afero.Walk(s.fs, blobsDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		digest := path.Base(p)
+		if _, found := s.index[digest]; found {
+			return nil
+		}
+
+		if err := s.fs.Remove(p); err != nil {
+			return err
+		}
+		removed = append(removed, digest)
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, s.writeIndex()
+}
+
+// blobPath returns the path of the blob with digest. The blobs are
+// spread into sub-directories by the first two characters of the
+// digest to avoid having too many files in a single directory.
+func (s *Store) blobPath(digest string) string {
+	return path.Join(blobsDir, digest[:2], digest)
+}
+
+// writeIndex writes the index to a temporary file and then renames it
+// so the index is never left half-written. The caller must hold the lock.
+func (s *Store) writeIndex() error {
+	blobs := make([]*blob.Blob, 0, len(s.index))
+	for _, b := range s.index {
+		blobs = append(blobs, b)
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].Digest < blobs[j].Digest
+	})
+
+	tmpPath := path.Join(tmpDir, indexFileName)
+	file, err := s.fs.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(file).Encode(blobs)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.fs.Rename(tmpPath, indexFileName)
+}
+
+func isValidDigest(digest string) bool {
+	if len(digest) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
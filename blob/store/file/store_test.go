@@ -0,0 +1,121 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"noterfy/blob"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+func digestOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func Test(t *testing.T) {
+	suite.Run(t, new(BlobStoreTestSuite))
+}
+
+type BlobStoreTestSuite struct {
+	suite.Suite
+	fs    afero.Fs
+	store *Store
+}
+
+func (s *BlobStoreTestSuite) SetupTest() {
+	s.fs = afero.NewMemMapFs()
+	s.store = New(s.fs)
+}
+
+func (s *BlobStoreTestSuite) put(content string) *blob.Blob {
+	b, err := s.store.Put(dummyCtx, bytes.NewBufferString(content))
+	s.Require().NoError(err)
+	return b
+}
+
+func (s *BlobStoreTestSuite) TestPut() {
+	s.Run("Putting a new content should store the blob", func() {
+		got := s.put("hello world")
+		s.Equal(&blob.Blob{Digest: digestOf("hello world"), Size: 11, Refs: 1}, got)
+
+		exists, err := afero.Exists(s.fs, s.store.blobPath(got.Digest))
+		s.Require().NoError(err)
+		s.True(exists)
+	})
+
+	s.Run("Putting the same content should increment the references", func() {
+		got := s.put("hello world")
+		s.Equal(2, got.Refs)
+	})
+
+	s.Run("Cancelled context should return an error", func() {
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+		_, err := s.store.Put(ctx, bytes.NewBufferString("cancelled"))
+		s.Equal(context.Canceled, err)
+	})
+}
+
+func (s *BlobStoreTestSuite) TestOpen() {
+	want := s.put("hello world")
+
+	s.Run("Opening an existing blob should return its content", func() {
+		r, got, err := s.store.Open(dummyCtx, want.Digest)
+		s.Require().NoError(err)
+		defer func() { _ = r.Close() }()
+
+		content, err := ioutil.ReadAll(r)
+		s.Require().NoError(err)
+		s.Equal("hello world", string(content))
+		s.Equal(want, got)
+	})
+
+	s.Run("Opening a blob that not exists should return an error", func() {
+		_, _, err := s.store.Open(dummyCtx, digestOf("not exists"))
+		s.Equal(blob.ErrNotFound, err)
+	})
+
+	s.Run("Opening a blob with invalid digest should return an error", func() {
+		_, _, err := s.store.Open(dummyCtx, "../index.json")
+		s.Equal(blob.ErrInvalidDigest, err)
+	})
+}
+
+func (s *BlobStoreTestSuite) TestGC() {
+	kept := s.put("kept")
+	released := s.put("released")
+	orphaned := s.put("orphaned")
+
+	s.Require().NoError(s.store.Release(dummyCtx, released.Digest))
+
+	removed, err := s.store.GC(dummyCtx, func(digest string) bool {
+		return digest != orphaned.Digest
+	})
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{released.Digest, orphaned.Digest}, removed)
+
+	_, _, err = s.store.Open(dummyCtx, kept.Digest)
+	s.NoError(err)
+
+	for _, digest := range removed {
+		_, _, err = s.store.Open(dummyCtx, digest)
+		s.Equal(blob.ErrNotFound, err)
+	}
+}
+
+func (s *BlobStoreTestSuite) TestLoadIndexFromFile() {
+	want := s.put("persisted")
+
+	store := New(s.fs)
+	r, got, err := store.Open(dummyCtx, want.Digest)
+	s.Require().NoError(err)
+	_ = r.Close()
+	s.Equal(want, got)
+}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"log"
+	auditcli "noterfy/audit/cli"
 	"noterfy/cli"
+	configcli "noterfy/config/cli"
 	notecli "noterfy/note/cli"
 )
 
 func main() {
 	cli.RootCmd.AddCommand(notecli.Cmd)
+	cli.RootCmd.AddCommand(configcli.Cmd)
+	cli.RootCmd.AddCommand(auditcli.Cmd)
 	if err := cli.RootCmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
@@ -1,15 +1,41 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	httptransport "github.com/go-kit/kit/transport/http"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"log"
 	"noterfy/api"
 	"noterfy/api/middleware"
 	"noterfy/api/server"
 	"noterfy/api/server/routes"
+	"noterfy/audit"
+	blobstore "noterfy/blob/store/file"
 	"noterfy/config"
+	"noterfy/idempotency"
+	idempotencyfile "noterfy/idempotency/store/file"
+	idempotencymemory "noterfy/idempotency/store/memory"
+	"noterfy/note"
 	"noterfy/note/api/v1/transport/rest"
+	"noterfy/note/archive"
+	"noterfy/note/attachment"
+	"noterfy/note/instrument"
+	"noterfy/note/link"
+	"noterfy/note/order"
+	"noterfy/note/reminder"
 	noteservice "noterfy/note/service"
 	filestore "noterfy/note/store/file"
+	"noterfy/note/template"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/health"
+	"noterfy/pkg/lifecycle"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/trace"
 	"os"
 	"path/filepath"
 	"time"
@@ -32,34 +58,413 @@ func main() {
 
 	conf := config.New()
 
+	logCloser, err := logger.Configure(logrus.StandardLogger(), conf.Log.Level, conf.Log.Format, conf.Log.Output)
+	mustNoError(err)
+
+	healthReg := health.New()
+	healthReg.Timeout = conf.Health.Timeout
+
+	// The components are started in their dependency order and
+	// stopped in the reverse order.
+	lc := lifecycle.New(&lifecycle.Config{
+		StopTimeout: conf.Server.ShutdownTimeout,
+		Health:      healthReg,
+		DrainDelay:  conf.Server.DrainDelay,
+	})
+	lc.Add("logger", lifecycle.Hook{OnStop: func(context.Context) error {
+		if logCloser == nil {
+			return nil
+		}
+		return logCloser.Close()
+	}})
+
 	metadata := &routes.Metadata{
 		Version:     Version,
 		BuildCommit: BuildCommit,
 		BuildDate:   BuildDate,
 	}
 
-	file, err := os.OpenFile(filepath.Join(conf.Store.File.Path, dbFileName), os.O_CREATE|os.O_RDWR, 0666)
+	// The file is locked so the CLI can't write to it while
+	// the server is running.
+	file, err := filestore.Open(filepath.Join(conf.Store.File.Path, dbFileName))
 	mustNoError(err)
-	defer func() { _ = file.Close() }()
+	lc.Add("store", lifecycle.Hook{OnStop: func(context.Context) error {
+		if err := file.Sync(); err != nil {
+			_ = file.Close()
+			return err
+		}
+		return file.Close()
+	}}, "logger")
+
+	mustNoError(os.MkdirAll(conf.Store.Blob.Path, 0755))
+	blobs := blobstore.New(afero.NewBasePathFs(afero.NewOsFs(), conf.Store.Blob.Path))
+
+	var store note.Store = filestore.New(file)
+	store = instrument.NewStore(store, kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "noterfy",
+		Subsystem: "note_store",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the note store operations in seconds.",
+	}, []string{"operation", "error"}))
+	stdprometheus.MustRegister(instrument.NewStoreCollector(store, file))
+	store = instrument.NewTracingStore(store)
+
+	tracer, err := newTracer(conf.Tracing)
+	mustNoError(err)
+	lc.Add("tracer", lifecycle.Hook{OnStop: func(ctx context.Context) error {
+		if tracer == nil {
+			return nil
+		}
+		return tracer.Shutdown(ctx)
+	}}, "logger")
+
+	linkIndex := link.NewIndex()
+	lc.Add("link-index", lifecycle.Hook{OnStart: func(ctx context.Context) error {
+		return linkIndex.Rebuild(ctx, store)
+	}}, "store")
+
+	broker := reminder.NewBroker()
+	lc.Add("reminder-events", lifecycle.Hook{OnStop: func(context.Context) error {
+		broker.Close()
+		return nil
+	}})
+	notifiers := []reminder.Notifier{reminder.NewLogNotifier(), broker}
+	if conf.Reminder.WebhookURL != "" {
+		notifiers = append(notifiers, reminder.NewWebhookNotifier(conf.Reminder.WebhookURL, nil))
+	}
+
+	scheduler := reminder.New(&reminder.Config{
+		Clock:       clock.New(),
+		Notifiers:   notifiers,
+		MissedGrace: conf.Reminder.MissedGrace,
+		FiredStore:  reminder.NewFileFiredStore(afero.NewOsFs(), filepath.Join(conf.Store.File.Path, "reminders-fired.json")),
+	})
+	lc.Add("reminder-index", lifecycle.Hook{OnStart: func(ctx context.Context) error {
+		return scheduler.Rebuild(ctx, store)
+	}}, "store")
+	lc.Add("reminder-scheduler", lifecycle.Go(scheduler.Run), "reminder-index", "reminder-events")
+
+	var svc note.Service = noteservice.New(store)
+	svc = link.Middleware(linkIndex)(svc)
+	svc = reminder.Middleware(scheduler)(svc)
+	// The audit log records the operations of the clients and of
+	// the server, e.g. the auto-archive.
+	var auditLog *audit.Log
+	if conf.Audit.Enabled {
+		auditLog, err = audit.Open(afero.NewOsFs(), conf.Audit.Path, nil)
+		mustNoError(err)
+		svc = audit.Middleware(auditLog)(svc)
+	}
+	lc.Add("audit", lifecycle.Hook{OnStop: func(context.Context) error {
+		if auditLog == nil {
+			return nil
+		}
+		return auditLog.Close()
+	}}, "logger")
+	svc = instrument.Middleware(
+		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "noterfy",
+			Subsystem: "note_service",
+			Name:      "requests_total",
+			Help:      "Number of the note service requests.",
+		}, []string{"method", "error"}),
+		kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: "noterfy",
+			Subsystem: "note_service",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the note service requests in seconds.",
+		}, []string{"method", "error"}),
+	)(svc)
+	svc = instrument.TracingMiddleware()(svc)
+
+	archiveSvc := archive.New(svc, clock.New())
+	lc.Add("auto-archive", lifecycle.Go(func(ctx context.Context) error {
+		autoArchive(ctx, archiveSvc, archiveRules(conf.Archive.Rules), conf.Archive.Interval)
+		return nil
+	}), "store", "link-index", "reminder-scheduler", "audit")
+
+	templateSvc := template.New(afero.NewBasePathFs(afero.NewOsFs(), conf.Store.File.Path), clock.New())
+
+	attachmentSvc := attachment.New(store, blobs)
+	lc.Add("attachment-gc", lifecycle.Go(func(ctx context.Context) error {
+		collectGarbage(ctx, attachmentSvc, conf.Store.Blob.GCInterval)
+		return nil
+	}), "store")
+	// The attachments are not part of the note updates.
+	var attachments audit.AttachmentService = attachmentSvc
+	if auditLog != nil {
+		attachments = audit.AttachmentMiddleware(auditLog, attachmentSvc)
+	}
+
+	healthReg.AddReadinessCheck("store", filestore.NewChecker(file, conf.Health.MinFreeBytes))
+
+	rateLimiter := middleware.NewRateLimiter(rateLimitConfig(conf.Server.RateLimit))
+	corsHandler := middleware.NewCORSHandler(corsConfig(conf.Server.CORS))
+
+	err = config.Watch(func(old, c *config.Config) {
+		// The level set through the admin routes is kept
+		// until the level in the file changes.
+		if c.Log.Level != old.Log.Level {
+			if level, err := logrus.ParseLevel(c.Log.Level); err == nil {
+				logrus.SetLevel(level)
+			}
+		}
+		rateLimiter.Update(rateLimitConfig(c.Server.RateLimit))
+		corsHandler.Update(corsConfig(c.Server.CORS))
+	})
+	if err != nil && err != config.ErrNoConfigFile {
+		mustNoError(err)
+	}
+
+	middlewares := []api.NamedMiddleware{
+		middleware.NewRequestIDMiddleware(),
+		middleware.NewClientCertMiddleware(),
+		middleware.NewAPIKeyMiddleware(apiKeyConfig(conf.Server)),
+		middleware.NewLoggingMiddleware(),
+		middleware.NewMetricsMiddleware(nil),
+		api.NewNamedMiddleware("RateLimit", rateLimiter.Middleware),
+		api.NewNamedMiddleware("CORS", corsHandler.Middleware),
+	}
+	if conf.Server.Compression.Enabled {
+		middlewares = append(middlewares, middleware.NewCompressMiddleware(&middleware.CompressConfig{
+			MinSize: conf.Server.Compression.MinSize,
+		}))
+	}
+	// The idempotency is the innermost middleware so the responses
+	// are recorded before they are compressed for a client.
+	if conf.Server.Idempotency.Enabled {
+		records, err := idempotencyStore(conf.Server.Idempotency)
+		mustNoError(err)
+		lc.Add("idempotency-sweep", lifecycle.Go(func(ctx context.Context) error {
+			sweepIdempotency(ctx, records, conf.Server.Idempotency.SweepInterval)
+			return nil
+		}))
+		middlewares = append(middlewares, middleware.NewIdempotencyMiddleware(middleware.IdempotencyConfig{
+			Store: records,
+			TTL:   conf.Server.Idempotency.TTL,
+		}))
+	}
 
-	svc := noteservice.New(filestore.New(file))
 	srv := server.New(&server.Config{
-		Port:     conf.Server.Port,
-		Metadata: metadata,
-		Middlewares: []api.NamedMiddleware{
-			middleware.NewLoggingMiddleware(),
-			middleware.NewRateLimitMiddleware(middleware.RateLimitConfig{
-				DefaultExpirationTTL: time.Second,
-				ExpireJobInterval:    time.Second,
-				MaxBurst:             1,
-			}),
-			middleware.NewCORSMiddleware(nil),
-		},
+		Port:            conf.Server.Port,
+		Metadata:        metadata,
+		ShutdownTimeout: conf.Server.ShutdownTimeout,
+		TLS:             tlsConfig(conf.Server.TLS),
+		Middlewares:     middlewares,
 	})
 
+	var traceOpts []httptransport.ServerOption
+	if tracer != nil {
+		traceOpts = trace.ServerOptions(tracer)
+	}
+
 	srv.AddRoutes(routes.Routes(metadata)...)
-	srv.AddRoutes(rest.Routes(svc)...)
-	mustNoError(srv.ListenAndServe())
+	srv.AddRoutes(routes.HealthRoutes(healthReg)...)
+	srv.AddRoutes(routes.AdminRoutes(logrus.StandardLogger(), conf.Server.Admins)...)
+	if auditLog != nil {
+		srv.AddRoutes(routes.AuditRoutes(auditLog, conf.Server.Admins)...)
+	}
+	srv.AddRoutes(rest.Routes(svc, templateSvc, &rest.CacheConfig{
+		Versions:     store,
+		CacheControl: conf.Server.CacheControl,
+	}, traceOpts...)...)
+	srv.AddRoutes(rest.AttachmentRoutes(attachments, conf.Store.Blob.MaxSize, traceOpts...)...)
+	srv.AddRoutes(rest.LinkRoutes(linkIndex, traceOpts...)...)
+	srv.AddRoutes(rest.OrderRoutes(order.New(svc), traceOpts...)...)
+	srv.AddRoutes(rest.ArchiveRoutes(archiveSvc, traceOpts...)...)
+	srv.AddRoutes(rest.TemplateRoutes(templateSvc, traceOpts...)...)
+	srv.AddRoutes(rest.ReminderRoutes(scheduler, broker, traceOpts...)...)
+	// The event streams would delay the shutdown until its timeout.
+	srv.RegisterOnShutdown(broker.Close)
+	lc.Add("server", srv, "store", "tracer", "link-index", "reminder-scheduler", "audit")
+
+	mustNoError(lc.Run(context.Background()))
+}
+
+// newTracer returns the tracer provider with the exporter in the
+// config. It returns nil when the tracing is disabled.
+func newTracer(conf config.Tracing) (*sdktrace.TracerProvider, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch conf.Exporter {
+	case "":
+		return nil, nil
+	case "stdout":
+		exporter, err = trace.NewWriterExporter(os.Stdout)
+	case "file":
+		var file *os.File
+		file, err = os.OpenFile(conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err = trace.NewWriterExporter(file)
+	case "otlp":
+		exporter, err = trace.NewOTLPExporter(context.Background(), conf.OTLPEndpoint)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter '%s'", conf.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return trace.NewTracerProvider(conf.ServiceName, exporter), nil
+}
+
+// tlsConfig converts the TLS config. It returns nil when
+// the server serves plain HTTP.
+func tlsConfig(conf config.TLS) *server.TLSConfig {
+	if conf.CertFile == "" {
+		return nil
+	}
+	return &server.TLSConfig{
+		CertFile:          conf.CertFile,
+		KeyFile:           conf.KeyFile,
+		ClientCAFile:      conf.ClientCAFile,
+		RequireClientCert: conf.RequireClientCert,
+		ReloadInterval:    conf.ReloadInterval,
+	}
+}
+
+// rateLimitConfig converts the rate-limit config. The probes and
+// the metrics are never rate-limited.
+func rateLimitConfig(conf config.RateLimit) middleware.RateLimitConfig {
+	var policies []middleware.RateLimitPolicy
+	for _, route := range []string{"/health", "/health/live", "/health/ready", "/metrics"} {
+		policies = append(policies, middleware.RateLimitPolicy{Name: route, Route: route})
+	}
+	for _, p := range conf.Policies {
+		policies = append(policies, middleware.RateLimitPolicy{
+			Name:            p.Name,
+			Route:           p.Route,
+			Methods:         p.Methods,
+			RateLimitBudget: middleware.RateLimitBudget{Limit: p.Limit, Window: p.Window},
+		})
+	}
+
+	return middleware.RateLimitConfig{
+		Policies:  policies,
+		Read:      middleware.RateLimitBudget{Limit: conf.Read.Limit, Window: conf.Read.Window},
+		Write:     middleware.RateLimitBudget{Limit: conf.Write.Limit, Window: conf.Write.Window},
+		IPLookUps: conf.IPLookups,
+		Disabled:  !conf.Enabled,
+	}
+}
+
+// apiKeyConfig converts the API keys of the clients.
+func apiKeyConfig(conf config.Server) middleware.APIKeyConfig {
+	keys := make([]middleware.APIKey, 0, len(conf.APIKeys))
+	for _, k := range conf.APIKeys {
+		keys = append(keys, middleware.APIKey{Name: k.Name, Key: k.Key})
+	}
+	return middleware.APIKeyConfig{Header: conf.RateLimit.APIKeyHeader, Keys: keys}
+}
+
+// corsConfig converts the CORS config. The empty settings use
+// the defaults of the middleware.
+func corsConfig(conf config.CORS) *middleware.CORSConfig {
+	return &middleware.CORSConfig{
+		AllowedOrigins:   conf.AllowedOrigins,
+		AllowedMethods:   conf.AllowedMethods,
+		AllowedHeaders:   conf.AllowedHeaders,
+		ExposedHeaders:   conf.ExposedHeaders,
+		AllowCredentials: conf.AllowCredentials,
+		MaxAge:           conf.MaxAge,
+		Debug:            conf.Debug,
+	}
+}
+
+// idempotencyStore returns the store of the idempotent
+// requests in the config.
+func idempotencyStore(conf config.Idempotency) (idempotency.Store, error) {
+	if conf.Store == "memory" {
+		return idempotencymemory.New(nil), nil
+	}
+	if err := os.MkdirAll(conf.Path, 0755); err != nil {
+		return nil, err
+	}
+	return idempotencyfile.New(afero.NewBasePathFs(afero.NewOsFs(), conf.Path), nil), nil
+}
+
+// sweepIdempotency removes the expired idempotent requests
+// every interval until ctx is done.
+func sweepIdempotency(ctx context.Context, store idempotency.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		removed, err := store.Sweep(ctx)
+		if err != nil && ctx.Err() == nil {
+			logrus.Error("idempotency sweep failed:", err)
+		} else if removed > 0 {
+			logrus.Debugf("idempotency sweep removed %d expired requests", removed)
+		}
+	}
+}
+
+// collectGarbage removes the orphaned blobs of the attachments
+// on startup and then every interval until ctx is done.
+func collectGarbage(ctx context.Context, svc *attachment.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := svc.GC(ctx)
+		if err != nil && ctx.Err() == nil {
+			logrus.Error("attachment garbage collection failed:", err)
+		} else if len(removed) > 0 {
+			logrus.Infof("attachment garbage collection removed %d blobs", len(removed))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// autoArchive applies the auto-archive rules on startup and
+// then every interval until ctx is done.
+func autoArchive(ctx context.Context, svc *archive.Service, rules []archive.Rule, interval time.Duration) {
+	if len(rules) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		archived, err := svc.Apply(ctx, rules)
+		if err != nil && ctx.Err() == nil {
+			logrus.Error("auto-archive failed:", err)
+		} else if len(archived) > 0 {
+			logrus.Infof("auto-archive archived %d notes", len(archived))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// archiveRules converts the auto-archive rules from the config.
+func archiveRules(confRules []config.ArchiveRule) []archive.Rule {
+	rules := make([]archive.Rule, 0, len(confRules))
+	for _, r := range confRules {
+		rules = append(rules, archive.Rule{
+			Name:          r.Name,
+			UntouchedDays: r.UntouchedDays,
+			SkipFavorites: r.SkipFavorites,
+			SkipPinned:    r.SkipPinned,
+		})
+	}
+	return rules
 }
 
 func mustNoError(err error) {
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"noterfy/config"
+)
+
+func init() {
+	Cmd.AddCommand(Check)
+}
+
+// Cmd is the root command for the config package.
+var Cmd = &cobra.Command{
+	Use:   "config",
+	Short: "Parent command for any related operation with the server configuration.",
+}
+
+// Check is a cli cmd that loads and validates the server configuration.
+var Check = &cobra.Command{
+	Use:   "check [path]",
+	Short: "Validate the server configuration",
+	Long: `Validate the server configuration.
+
+The config file in path is loaded the same way as the server does,
+including the NOTERFY_* environment variable overrides, and all the
+invalid settings are printed. When path is not provided the config
+file is searched in the same paths as the server.
+`,
+	Example: "noterfy_cli config check ./config.yaml",
+	Args:    cobra.MaximumNArgs(1),
+	// The usage is noise when the config is invalid.
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var path string
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		if _, err := config.Load(path); err != nil {
+			return err
+		}
+
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), "The configuration is valid.")
+		return err
+	},
+}
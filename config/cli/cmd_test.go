@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	run := func(content string) (string, error) {
+		path := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		var out bytes.Buffer
+		Cmd.SetOut(&out)
+		Cmd.SetErr(&out)
+		Cmd.SetArgs([]string{"check", path})
+		err := Cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("server:\n  port: 8080")
+	require.NoError(t, err)
+	assert.Contains(t, out, "The configuration is valid.")
+
+	_, err = run("server:\n  port: 0\nlog:\n  format: xml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.port: must be between 1 and 65535, got 0")
+	assert.Contains(t, err.Error(), "log.format: must be one of [text/json], got 'xml'")
+}
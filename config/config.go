@@ -1,14 +1,29 @@
 package config
 
 import (
+	"errors"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
+// EnvPrefix is the prefix of the environment variables that override
+// the settings of the config file. The key of the setting is in upper
+// case with the dots replaced by underscores, e.g. NOTERFY_SERVER_PORT
+// overrides "server.port".
+const EnvPrefix = "NOTERFY"
+
 var (
-	once sync.Once
-	conf *Config
+	once  sync.Once
+	conf  *Config
+	confV *viper.Viper
 )
 
 // New initializes the configuration setting. It searches for the
@@ -18,11 +33,15 @@ var (
 // - "/etc/noterfy"
 // - "/run/secrets"
 // - "."
+//
+// The settings can be overridden by the environment variables, see
+// EnvPrefix. It panics when the config is invalid.
 func New() *Config {
 	// Do singleton
 	once.Do(func() {
 		var err error
-		conf, err = newConfig(afero.NewOsFs())
+		confV = newViper(afero.NewOsFs(), "")
+		conf, err = load(confV)
 		if err != nil {
 			panic(err)
 		}
@@ -31,46 +50,177 @@ func New() *Config {
 	return conf
 }
 
+// Load loads and validates the config in the file path. If path is
+// empty it searches for the config file in the same paths as New.
+// The settings can be overridden by the environment variables, see
+// EnvPrefix.
+func Load(path string) (*Config, error) {
+	return load(newViper(afero.NewOsFs(), path))
+}
+
 func newConfig(fs afero.Fs) (*Config, error) {
-	viper.SetFs(fs)
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-
-	viper.AddConfigPath("/etc/noterfy")
-	// For Docker Compose default path for mounting the secret.
-	// see. https://docs.docker.com/compose/compose-file/compose-file-v3/#secrets
-	viper.AddConfigPath("/run/secrets")
-	viper.AddConfigPath("/")
-	viper.AddConfigPath(".")
-
-	err := viper.ReadInConfig()
+	return load(newViper(fs, ""))
+}
+
+func newViper(fs afero.Fs, path string) *viper.Viper {
+	v := viper.New()
+	v.SetFs(fs)
+	v.SetConfigType("yaml")
+
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName("config")
+		v.AddConfigPath("/etc/noterfy")
+		// For Docker Compose default path for mounting the secret.
+		// see. https://docs.docker.com/compose/compose-file/compose-file-v3/#secrets
+		v.AddConfigPath("/run/secrets")
+		v.AddConfigPath("/")
+		v.AddConfigPath(".")
+	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	setDefaults(v)
+	return v
+}
+
+// setDefaults sets the defaults of all the settings. Every setting
+// needs a default, even the zero one, so viper knows the key when
+// it is only set in the environment.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("server.port", 50001)
+	v.SetDefault("server.shutdowntimeout", 5*time.Second)
+	v.SetDefault("server.draindelay", time.Duration(0))
+	v.SetDefault("server.cachecontrol", "no-cache")
+	v.SetDefault("server.tls.certfile", "")
+	v.SetDefault("server.tls.keyfile", "")
+	v.SetDefault("server.tls.clientcafile", "")
+	v.SetDefault("server.tls.requireclientcert", false)
+	v.SetDefault("server.tls.reloadinterval", time.Minute)
+	v.SetDefault("server.ratelimit.enabled", true)
+	v.SetDefault("server.ratelimit.read.limit", 300)
+	v.SetDefault("server.ratelimit.read.window", time.Minute)
+	v.SetDefault("server.ratelimit.write.limit", 60)
+	v.SetDefault("server.ratelimit.write.window", time.Minute)
+	v.SetDefault("server.ratelimit.policies", []interface{}{})
+	v.SetDefault("server.ratelimit.apikeyheader", "")
+	v.SetDefault("server.ratelimit.iplookups", []string{})
+	v.SetDefault("server.ratelimit.maxburst", 0)
+	v.SetDefault("server.ratelimit.ttl", time.Duration(0))
+	v.SetDefault("server.ratelimit.expireinterval", time.Duration(0))
+	v.SetDefault("server.ratelimit.methods", []string{})
+	v.SetDefault("server.apikeys", []interface{}{})
+	v.SetDefault("server.admins", []string{})
+	v.SetDefault("server.compression.enabled", true)
+	v.SetDefault("server.compression.minsize", 1024)
+	v.SetDefault("server.idempotency.enabled", true)
+	v.SetDefault("server.idempotency.ttl", 24*time.Hour)
+	v.SetDefault("server.idempotency.store", "file")
+	v.SetDefault("server.idempotency.path", "")
+	v.SetDefault("server.idempotency.sweepinterval", time.Hour)
+	v.SetDefault("server.cors.allowedorigins", []string{})
+	v.SetDefault("server.cors.allowedmethods", []string{})
+	v.SetDefault("server.cors.allowedheaders", []string{})
+	v.SetDefault("server.cors.exposedheaders", []string{})
+	v.SetDefault("server.cors.allowcredentials", false)
+	v.SetDefault("server.cors.maxage", 0)
+	v.SetDefault("server.cors.debug", false)
+	v.SetDefault("store.file.path", ".")
+	v.SetDefault("store.blob.path", "")
+	v.SetDefault("store.blob.maxsize", 10<<20)
+	v.SetDefault("store.blob.gcinterval", time.Hour)
+	v.SetDefault("reminder.webhookurl", "")
+	v.SetDefault("reminder.missedgrace", time.Hour)
+	v.SetDefault("archive.interval", time.Hour)
+	v.SetDefault("audit.enabled", true)
+	v.SetDefault("audit.path", "")
+	v.SetDefault("tracing.exporter", "")
+	v.SetDefault("tracing.servicename", "noterfy")
+	v.SetDefault("tracing.file", "traces.json")
+	v.SetDefault("tracing.otlpendpoint", "http://localhost:4318/v1/traces")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "text")
+	v.SetDefault("log.output", "stdout")
+	v.SetDefault("health.timeout", 2*time.Second)
+	v.SetDefault("health.minfreebytes", 64<<20)
+}
+
+// load reads the config file of v, when there is one, and returns
+// the validated config.
+func load(v *viper.Viper) (*Config, error) {
+	err := v.ReadInConfig()
 	if err != nil {
-		return nil, err
+		// The config can be entirely in the environment.
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, err
+		}
 	}
 
-	if viper.Get("store.file.path") == nil {
-		viper.Set("store.file.path", ".")
+	return decode(v)
+}
+
+// decode returns the validated config from the settings of v.
+func decode(v *viper.Viper) (*Config, error) {
+	var conf Config
+	if err := v.UnmarshalExact(&conf); err != nil {
+		return nil, decodeError(err)
 	}
 
-	if viper.Get("server.port") == nil {
-		viper.Set("server.port", 50001)
+	if conf.Store.Blob.Path == "" {
+		conf.Store.Blob.Path = filepath.Join(conf.Store.File.Path, "blobs")
 	}
+	if conf.Server.Idempotency.Path == "" {
+		conf.Server.Idempotency.Path = filepath.Join(conf.Store.File.Path, "idempotency")
+	}
+	if conf.Audit.Path == "" {
+		conf.Audit.Path = filepath.Join(conf.Store.File.Path, "audit.log")
+	}
+	conf.Server.RateLimit.applyDeprecated()
 
-	var conf Config
-	err = viper.Unmarshal(&conf)
-	if err != nil {
+	if err := conf.Validate(); err != nil {
 		return nil, err
 	}
 
 	return &conf, nil
 }
 
+// decodeError converts the error of decoding the settings, e.g. an
+// unknown setting or a setting of the wrong type, to a *ValidationError.
+func decodeError(err error) error {
+	var merr *mapstructure.Error
+	if !errors.As(err, &merr) {
+		return &ValidationError{Errors: []FieldError{{Field: "config", Message: err.Error()}}}
+	}
+
+	verr := &ValidationError{}
+	for _, msg := range merr.Errors {
+		verr.Errors = append(verr.Errors, FieldError{Field: "config", Message: msg})
+	}
+	return verr
+}
+
 // Config is the application-level configuration containing
 // all the information for running the application.
 type Config struct {
 	Server Server
 	// Store Database Configuration
 	Store Store
+	// Reminder is the reminder scheduler configuration.
+	Reminder Reminder
+	// Archive is the auto-archive configuration.
+	Archive Archive
+	// Audit is the audit log configuration.
+	Audit Audit
+	// Tracing is the distributed tracing configuration.
+	Tracing Tracing
+	// Log is the logging configuration.
+	Log Log
+	// Health is the health checks configuration.
+	Health Health
 }
 
 // Server contains the server configuration.
@@ -78,11 +228,244 @@ type Server struct {
 	// Port is the port of the server when its value is empty
 	// in config file the default "50001" will be use.
 	Port int
+	// ShutdownTimeout is how long the server waits for the requests
+	// in flight during the shutdown. When its value is empty in config
+	// file the default "5s" will be use.
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long the server keeps serving the requests
+	// after it reports draining in the readiness probe during the
+	// shutdown. When its value is empty in config file the server
+	// shuts down right away.
+	DrainDelay time.Duration
+	// CacheControl is the Cache-Control header of the notes. When its
+	// value is empty in config file the default "no-cache" will be use,
+	// so the clients revalidate the notes with a conditional request.
+	CacheControl string
+	// TLS is the TLS configuration of the server.
+	TLS TLS
+	// RateLimit is the rate limit middleware configuration. It can
+	// be changed without restarting the server.
+	RateLimit RateLimit
+	// CORS is the CORS middleware configuration. It can be changed
+	// without restarting the server.
+	CORS CORS
+	// Compression is the configuration of the compression
+	// of the responses.
+	Compression Compression
+	// Idempotency is the configuration of the idempotency
+	// keys of the requests.
+	Idempotency Idempotency
+	// APIKeys are the API keys of the clients. A client sending one
+	// of them in the API key header is identified by the name of the
+	// key. The unknown keys are ignored.
+	APIKeys []APIKey
+	// Admins are the principals allowed to use the admin routes, i.e.
+	// the subjects of the client certificates and the names of the
+	// API keys. When its value is empty in config file nobody can use
+	// the admin routes.
+	Admins []string
+}
+
+// APIKey is the API key of a client.
+type APIKey struct {
+	// Name is the name of the client, which is its principal.
+	Name string
+	// Key is the secret API key.
+	Key string
+}
+
+// TLS contains the TLS configuration of the server. The certificate
+// files are reloaded when they change without restarting the server.
+type TLS struct {
+	// CertFile is the path of the PEM encoded certificate of the server.
+	// When its value is empty in config file the server serves plain HTTP.
+	CertFile string
+	// KeyFile is the path of the PEM encoded private key of the certificate.
+	KeyFile string
+	// ClientCAFile is the path of the PEM encoded bundle of the CAs of the
+	// client certificates. When its value is empty in config file the
+	// client certificates are not verified.
+	ClientCAFile string
+	// RequireClientCert rejects the clients without a certificate signed
+	// by the client CAs. When its value is empty in config file the clients
+	// without a certificate are accepted as anonymous.
+	RequireClientCert bool
+	// ReloadInterval is how frequently the files are checked for changes.
+	// When its value is empty in config file the default "1m" will be use.
+	ReloadInterval time.Duration
+}
+
+// RateLimit contains the rate limit middleware configuration. The
+// requests are limited per client, identified by its principal, i.e.
+// its client certificate or its configured API key, then its IP.
+type RateLimit struct {
+	// Enabled tells whether the requests are rate limited. When its
+	// value is empty in config file the default "true" will be use.
+	Enabled bool
+	// Read is the budget of the GET, HEAD and OPTIONS requests without
+	// a policy. When its values are empty in config file the default
+	// "300" requests per "1m" will be use.
+	Read RateLimitBudget
+	// Write is the budget of the other requests without a policy. When
+	// its values are empty in config file the default "60" requests
+	// per "1m" will be use.
+	Write RateLimitBudget
+	// Policies are the budgets of specific routes and methods. The
+	// first matching policy is used.
+	Policies []RateLimitPolicy
+	// APIKeyHeader is the header of the configured API keys of the
+	// clients. See Server.APIKeys. When its value is empty in config
+	// file the default of the middleware will be use.
+	APIKeyHeader string
+	// IPLookups are where the IP of a client is looked up, e.g.
+	// "RemoteAddr", "X-Forwarded-For" and "X-Real-IP". When its value
+	// is empty in config file the defaults of the middleware will be use.
+	IPLookups []string
+
+	// MaxBurst is the maximum number of requests per second of a
+	// client.
+	//
+	// Deprecated: Use Read and Write. It sets both budgets.
+	MaxBurst float64
+	// TTL is how long the tokens of a client were kept.
+	//
+	// Deprecated: The budgets are windows and it is ignored.
+	TTL time.Duration
+	// ExpireInterval is how frequently the expired tokens were removed.
+	//
+	// Deprecated: The budgets are windows and it is ignored.
+	ExpireInterval time.Duration
+	// Methods are the only methods that are rate limited.
+	//
+	// Deprecated: Use Policies. The other methods are not limited.
+	Methods []string
+}
+
+// limitedMethods are the methods of the requests that are rate
+// limited by default.
+var limitedMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// applyDeprecated converts the deprecated settings of r to the
+// current ones, with a warning, and clears them.
+func (r *RateLimit) applyDeprecated() {
+	if r.MaxBurst > 0 {
+		logrus.Warn("config: server.ratelimit.maxburst is deprecated, use server.ratelimit.read and server.ratelimit.write")
+		budget := RateLimitBudget{Limit: int(math.Ceil(r.MaxBurst)), Window: time.Second}
+		r.Read, r.Write = budget, budget
+	}
+	if r.TTL != 0 || r.ExpireInterval != 0 {
+		logrus.Warn("config: server.ratelimit.ttl and server.ratelimit.expireinterval are deprecated and ignored")
+	}
+	if len(r.Methods) > 0 {
+		logrus.Warn("config: server.ratelimit.methods is deprecated, use server.ratelimit.policies")
+		var unlimited []string
+		for _, m := range limitedMethods {
+			if !containsFold(r.Methods, m) {
+				unlimited = append(unlimited, m)
+			}
+		}
+		if len(unlimited) > 0 {
+			policy := RateLimitPolicy{Name: "deprecated-methods", Methods: unlimited}
+			r.Policies = append([]RateLimitPolicy{policy}, r.Policies...)
+		}
+	}
+	// The negative burst is left to the validation.
+	if r.MaxBurst > 0 {
+		r.MaxBurst = 0
+	}
+	r.TTL, r.ExpireInterval, r.Methods = 0, 0, nil
+}
+
+// RateLimitBudget is the number of requests a client can do in a window.
+type RateLimitBudget struct {
+	// Limit is the maximum number of requests in the window. Zero
+	// means the requests are not limited.
+	Limit int
+	// Window is the duration of the window.
+	Window time.Duration
+}
+
+// RateLimitPolicy is the budget of the requests matching a route
+// and methods.
+type RateLimitPolicy struct {
+	// Name identifies the policy. Every policy has its own budget.
+	Name string
+	// Route is the path template of the route, e.g. "/v1/note/{id}".
+	// When its value is empty in config file all the routes match.
+	Route string
+	// Methods are the methods of the requests. When its value is
+	// empty in config file all the methods match.
+	Methods []string
+	// Limit is the maximum number of requests in the window. Zero
+	// means the requests are not limited.
+	Limit int
+	// Window is the duration of the window.
+	Window time.Duration
+}
+
+// Compression contains the configuration of the compression of the
+// responses with gzip or brotli.
+type Compression struct {
+	// Enabled tells whether the responses are compressed. When its
+	// value is empty in config file the default "true" will be use.
+	Enabled bool
+	// MinSize is the minimum size in bytes of the compressed responses.
+	// When its value is empty in config file the default "1024" will
+	// be use.
+	MinSize int
+}
+
+// Idempotency contains the configuration of the Idempotency-Key header
+// of the requests. The response of a request with a key is replayed to
+// the retries of the request.
+type Idempotency struct {
+	// Enabled tells whether the idempotency keys are supported. When its
+	// value is empty in config file the default "true" will be use.
+	Enabled bool
+	// TTL is how long the responses are replayed. When its value is
+	// empty in config file the default "24h" will be use.
+	TTL time.Duration
+	// Store is where the responses are stored [memory/file]. When its
+	// value is empty in config file the default "file" will be use.
+	Store string
+	// Path is the path of the directory of the file store. When its
+	// value is empty in config file the "idempotency" directory under
+	// the file store path will be use.
+	Path string
+	// SweepInterval is how frequently the expired responses are removed.
+	// When its value is empty in config file the default "1h" will be use.
+	SweepInterval time.Duration
+}
+
+// CORS contains the CORS middleware configuration. When its values
+// are empty in config file the defaults of the middleware will be use.
+type CORS struct {
+	// AllowedOrigins are the origins that can do cross-domain
+	// requests, e.g. "https://example.com" or "*".
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in cross-domain requests.
+	AllowedMethods []string
+	// AllowedHeaders are the headers allowed in cross-domain requests.
+	AllowedHeaders []string
+	// ExposedHeaders are the headers exposed to the client.
+	ExposedHeaders []string
+	// AllowCredentials tells whether the requests can include
+	// credentials like cookies.
+	AllowCredentials bool
+	// MaxAge is how long in seconds the results of a preflight
+	// request can be cached.
+	MaxAge int
+	// Debug logs the CORS decisions.
+	Debug bool
 }
 
 // Store contains the store database configuration.
 type Store struct {
 	File File
+	Blob Blob
 }
 
 // File contains the file store configuration.
@@ -91,3 +474,108 @@ type File struct {
 	// When its value is empty in config file the default "." will be use.
 	Path string
 }
+
+// Blob contains the blob store configuration for the attachments.
+type Blob struct {
+	// Path is the path where the blobs will be store. When its value
+	// is empty in config file the "blobs" directory under the file
+	// store path will be use.
+	Path string
+	// MaxSize is the maximum size in bytes of an uploaded attachment.
+	// When its value is empty in config file the default 10MiB will be use.
+	MaxSize int64
+	// GCInterval is how frequently the unreferenced blobs are removed.
+	// When its value is empty in config file the default "1h" will be use.
+	GCInterval time.Duration
+}
+
+// Reminder contains the reminder scheduler configuration.
+type Reminder struct {
+	// WebhookURL is the URL where the fired reminders are posted.
+	// When its value is empty in config file the reminders won't
+	// be posted to a webhook.
+	WebhookURL string
+	// MissedGrace is how old a reminder missed while the server was
+	// down can be and still be fired on startup. When its value is
+	// empty in config file the default "1h" will be use.
+	MissedGrace time.Duration
+}
+
+// Archive contains the auto-archive configuration.
+type Archive struct {
+	// Interval is how frequently the auto-archive rules are applied.
+	// When its value is empty in config file the default "1h" will be use.
+	Interval time.Duration
+	// Rules are the auto-archive rules. When its value is empty in
+	// config file the notes won't be archived automatically.
+	Rules []ArchiveRule
+}
+
+// ArchiveRule contains an auto-archive rule that archives the notes
+// that were not touched for a number of days.
+type ArchiveRule struct {
+	// Name is the name of the rule use in the logs.
+	Name string
+	// UntouchedDays is the number of days since the note was last
+	// updated before the note is archived.
+	UntouchedDays int
+	// SkipFavorites leaves the favorite notes untouched.
+	SkipFavorites bool
+	// SkipPinned leaves the pinned notes untouched.
+	SkipPinned bool
+}
+
+// Audit contains the configuration of the audit log of the note
+// operations.
+type Audit struct {
+	// Enabled records the note operations to the audit log. When its
+	// value is empty in config file the default true will be use.
+	Enabled bool
+	// Path is the path of the audit log file. When its value is empty
+	// in config file the "audit.log" file under the file store path
+	// will be use.
+	Path string
+}
+
+// Tracing contains the distributed tracing configuration.
+type Tracing struct {
+	// Exporter is where the spans are exported [stdout/file/otlp].
+	// When its value is empty in config file the requests won't
+	// be traced.
+	Exporter string
+	// ServiceName is the name of the service in the spans. When its
+	// value is empty in config file the default "noterfy" will be use.
+	ServiceName string
+	// File is the path of the file where the spans are written when
+	// the exporter is "file". When its value is empty in config file
+	// the default "traces.json" will be use.
+	File string
+	// OTLPEndpoint is the URL of the OTLP/HTTP traces endpoint of the
+	// collector when the exporter is "otlp". When its value is empty in
+	// config file the default "http://localhost:4318/v1/traces" will be use.
+	OTLPEndpoint string
+}
+
+// Log contains the logging configuration.
+type Log struct {
+	// Level is the minimum level of the logs [trace/debug/info/warn/error].
+	// When its value is empty in config file the default "info" will be use.
+	Level string
+	// Format is the format of the logs [text/json]. When its value is
+	// empty in config file the default "text" will be use.
+	Format string
+	// Output is where the logs are written [stdout/stderr/path of a file].
+	// When its value is empty in config file the default "stdout" will be use.
+	Output string
+}
+
+// Health contains the health checks configuration.
+type Health struct {
+	// Timeout is the maximum duration of a health check. When its
+	// value is empty in config file the default "2s" will be use.
+	Timeout time.Duration
+	// MinFreeBytes is the minimum free space in bytes of the disk of
+	// the file store before the server is reported not ready. When its
+	// value is empty in config file the default 64MiB will be use.
+	MinFreeBytes uint64
+}
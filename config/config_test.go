@@ -1,10 +1,13 @@
 package config
 
 import (
+	"errors"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/suite"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func Test(t *testing.T) {
@@ -47,15 +50,130 @@ store:
   file:
     path: /test
 server:
-  port: 8080`,
+  port: 8080
+  drainDelay: 10s
+  cachecontrol: private, max-age=60
+  ratelimit:
+    write:
+      limit: 10
+      window: 1s
+    policies:
+      - name: upload
+        route: /v1/note/{id}/attachments
+        methods: [POST]
+        limit: 5
+        window: 1m
+  apikeys:
+    - name: ops
+      key: secret
+  admins: [ops, CN=alice]
+  cors:
+    allowedorigins:
+      - https://example.com
+    maxage: 600
+  compression:
+    minsize: 512
+  idempotency:
+    ttl: 1h
+    store: memory
+health:
+  timeout: 500ms
+  minfreebytes: 1024
+reminder:
+  webhookurl: http://localhost/hook
+  missedgrace: 5m
+archive:
+  interval: 24h
+  rules:
+    - name: stale
+      untoucheddays: 90
+      skipfavorites: true
+    - name: pinned
+      untoucheddays: 365
+audit:
+  path: /var/log/noterfy/audit.log
+log:
+  level: debug
+  format: json
+  output: stderr
+tracing:
+  exporter: otlp
+  otlpendpoint: http://collector:4318/v1/traces`,
 			want: &Config{
 				Server: Server{
-					Port: 8080,
+					Port:            8080,
+					ShutdownTimeout: 5 * time.Second,
+					DrainDelay:      10 * time.Second,
+					CacheControl:    "private, max-age=60",
+					TLS:             TLS{ReloadInterval: time.Minute},
+					RateLimit: RateLimit{
+						Enabled: true,
+						Read:    RateLimitBudget{Limit: 300, Window: time.Minute},
+						Write:   RateLimitBudget{Limit: 10, Window: time.Second},
+						Policies: []RateLimitPolicy{
+							{
+								Name:    "upload",
+								Route:   "/v1/note/{id}/attachments",
+								Methods: []string{"POST"},
+								Limit:   5,
+								Window:  time.Minute,
+							},
+						},
+					},
+					CORS: CORS{
+						AllowedOrigins: []string{"https://example.com"},
+						MaxAge:         600,
+					},
+					APIKeys:     []APIKey{{Name: "ops", Key: "secret"}},
+					Admins:      []string{"ops", "CN=alice"},
+					Compression: Compression{Enabled: true, MinSize: 512},
+					Idempotency: Idempotency{
+						Enabled:       true,
+						TTL:           time.Hour,
+						Store:         "memory",
+						Path:          "/test/idempotency",
+						SweepInterval: time.Hour,
+					},
 				},
 				Store: Store{
 					File: File{
 						Path: "/test",
 					},
+					Blob: Blob{
+						Path:       "/test/blobs",
+						MaxSize:    10 << 20,
+						GCInterval: time.Hour,
+					},
+				},
+				Reminder: Reminder{
+					WebhookURL:  "http://localhost/hook",
+					MissedGrace: 5 * time.Minute,
+				},
+				Archive: Archive{
+					Interval: 24 * time.Hour,
+					Rules: []ArchiveRule{
+						{Name: "stale", UntouchedDays: 90, SkipFavorites: true},
+						{Name: "pinned", UntouchedDays: 365},
+					},
+				},
+				Audit: Audit{
+					Enabled: true,
+					Path:    "/var/log/noterfy/audit.log",
+				},
+				Tracing: Tracing{
+					Exporter:     "otlp",
+					ServiceName:  "noterfy",
+					File:         "traces.json",
+					OTLPEndpoint: "http://collector:4318/v1/traces",
+				},
+				Log: Log{
+					Level:  "debug",
+					Format: "json",
+					Output: "stderr",
+				},
+				Health: Health{
+					Timeout:      500 * time.Millisecond,
+					MinFreeBytes: 1024,
 				},
 			},
 		},
@@ -65,12 +183,57 @@ server:
 			input:    ``,
 			want: &Config{
 				Server: Server{
-					Port: 50001,
+					Port:            50001,
+					ShutdownTimeout: 5 * time.Second,
+					CacheControl:    "no-cache",
+					TLS:             TLS{ReloadInterval: time.Minute},
+					RateLimit: RateLimit{
+						Enabled: true,
+						Read:    RateLimitBudget{Limit: 300, Window: time.Minute},
+						Write:   RateLimitBudget{Limit: 60, Window: time.Minute},
+					},
+					Compression: Compression{Enabled: true, MinSize: 1024},
+					Idempotency: Idempotency{
+						Enabled:       true,
+						TTL:           24 * time.Hour,
+						Store:         "file",
+						Path:          "idempotency",
+						SweepInterval: time.Hour,
+					},
 				},
 				Store: Store{
 					File: File{
 						Path: ".",
 					},
+					Blob: Blob{
+						Path:       "blobs",
+						MaxSize:    10 << 20,
+						GCInterval: time.Hour,
+					},
+				},
+				Reminder: Reminder{
+					MissedGrace: time.Hour,
+				},
+				Archive: Archive{
+					Interval: time.Hour,
+				},
+				Audit: Audit{
+					Enabled: true,
+					Path:    "audit.log",
+				},
+				Tracing: Tracing{
+					ServiceName:  "noterfy",
+					File:         "traces.json",
+					OTLPEndpoint: "http://localhost:4318/v1/traces",
+				},
+				Log: Log{
+					Level:  "info",
+					Format: "text",
+					Output: "stdout",
+				},
+				Health: Health{
+					Timeout:      2 * time.Second,
+					MinFreeBytes: 64 << 20,
 				},
 			},
 		},
@@ -106,3 +269,180 @@ server:
 		})
 	}
 }
+
+func (t *TestSuite) newFs(yamlContent string) afero.Fs {
+	fs := afero.NewMemMapFs()
+	t.Require().NoError(afero.WriteFile(fs, "/etc/noterfy/config.yaml", []byte(yamlContent), 0644))
+	return fs
+}
+
+func (t *TestSuite) TestEnvOverrides() {
+	for k, v := range map[string]string{
+		"NOTERFY_SERVER_PORT":                   "9090",
+		"NOTERFY_SERVER_RATELIMIT_WRITE_LIMIT":  "5",
+		"NOTERFY_SERVER_CORS_ALLOWEDORIGINS":    "https://a.com,https://b.com",
+		"NOTERFY_LOG_LEVEL":                     "debug",
+		"NOTERFY_STORE_FILE_PATH":               "/data",
+		"NOTERFY_SERVER_RATELIMIT_WRITE_WINDOW": "1s",
+	} {
+		t.Require().NoError(os.Setenv(k, v))
+		defer func(k string) { _ = os.Unsetenv(k) }(k)
+	}
+
+	got, err := newConfig(t.newFs("server:\n  port: 8080\nlog:\n  level: warn"))
+	t.Require().NoError(err)
+	t.Equal(9090, got.Server.Port)
+	t.Equal(RateLimitBudget{Limit: 5, Window: time.Second}, got.Server.RateLimit.Write)
+	t.Equal([]string{"https://a.com", "https://b.com"}, got.Server.CORS.AllowedOrigins)
+	t.Equal("debug", got.Log.Level)
+	t.Equal("/data", got.Store.File.Path)
+	t.Equal("/data/blobs", got.Store.Blob.Path)
+}
+
+func (t *TestSuite) TestDeprecatedRateLimit() {
+	got, err := newConfig(t.newFs(`
+server:
+  ratelimit:
+    maxburst: 2.5
+    ttl: 1s
+    expireinterval: 1s
+    methods: [POST, put]
+    policies:
+      - name: upload
+        limit: 5
+        window: 1m`))
+	t.Require().NoError(err)
+
+	t.Equal(RateLimit{
+		Enabled: true,
+		Read:    RateLimitBudget{Limit: 3, Window: time.Second},
+		Write:   RateLimitBudget{Limit: 3, Window: time.Second},
+		Policies: []RateLimitPolicy{
+			{Name: "deprecated-methods", Methods: []string{"GET", "HEAD", "PATCH", "DELETE", "OPTIONS"}},
+			{Name: "upload", Limit: 5, Window: time.Minute},
+		},
+	}, got.Server.RateLimit)
+
+	_, err = newConfig(t.newFs("server:\n  ratelimit:\n    maxburst: -1"))
+	t.Require().Error(err)
+	t.Contains(err.Error(), "server.ratelimit.maxburst: must not be negative, got -1")
+}
+
+func (t *TestSuite) TestWithoutConfigFile() {
+	got, err := newConfig(afero.NewMemMapFs())
+	t.Require().NoError(err)
+	t.Equal(50001, got.Server.Port)
+}
+
+func (t *TestSuite) TestValidation() {
+	_, err := newConfig(t.newFs(`
+server:
+  port: 70000
+  ratelimit:
+    read:
+      limit: -1
+    policies:
+      - name: upload
+        limit: 5
+        window: 0s
+      - name: upload
+        limit: 0
+  idempotency:
+    store: redis
+  apikeys:
+    - name: mobile
+      key: secret
+    - name: mobile
+log:
+  level: loud
+tracing:
+  exporter: otlp
+  otlpendpoint: collector`))
+
+	var verr *ValidationError
+	t.Require().True(errors.As(err, &verr), "got %v", err)
+	t.Equal([]FieldError{
+		{Field: "server.port", Message: "must be between 1 and 65535, got 70000"},
+		{Field: "server.ratelimit.read.limit", Message: "must not be negative, got -1"},
+		{Field: "server.ratelimit.policies[0].window", Message: "must be positive, got 0s"},
+		{Field: "server.ratelimit.policies[1].name", Message: "must be unique, got 'upload' again"},
+		{Field: "server.apikeys[1].name", Message: "must be unique, got 'mobile' again"},
+		{Field: "server.apikeys[1].key", Message: "must not be empty"},
+		{Field: "server.idempotency.store", Message: "must be one of [memory/file], got 'redis'"},
+		{Field: "tracing.otlpendpoint", Message: "must be an absolute URL, got 'collector'"},
+		{Field: "log.level", Message: "must be one of [panic/fatal/error/warn/info/debug/trace], got 'loud'"},
+	}, verr.Errors)
+	t.Contains(err.Error(), "\n  - server.port: must be between 1 and 65535, got 70000")
+}
+
+func (t *TestSuite) TestUnknownSetting() {
+	_, err := newConfig(t.newFs("server:\n  prot: 8080"))
+	t.Require().Error(err)
+	t.Contains(err.Error(), "prot")
+}
+
+func (t *TestSuite) TestRestartRequired() {
+	old, err := newConfig(afero.NewMemMapFs())
+	t.Require().NoError(err)
+
+	next := *old
+	next.Log.Level = "debug"
+	next.Server.RateLimit.Read.Limit = 100
+	next.Server.CORS.AllowedOrigins = []string{"*"}
+	t.Empty(restartRequired(old, &next))
+
+	next.Server.Port = 8080
+	next.Tracing.Exporter = "stdout"
+	t.Equal([]string{"server", "tracing"}, restartRequired(old, &next))
+}
+
+func (t *TestSuite) TestWatch() {
+	path := filepath.Join(t.T().TempDir(), "config.yaml")
+	t.Require().NoError(os.WriteFile(path, []byte("log:\n  level: info"), 0644))
+
+	v := newViper(afero.NewOsFs(), path)
+	current, err := load(v)
+	t.Require().NoError(err)
+
+	type change struct{ old, new *Config }
+	changes := make(chan change, 10)
+	watch(v, current, func(old, new *Config) { changes <- change{old, new} })
+
+	// waitFor returns the first change that matches ok.
+	waitFor := func(ok func(c change) bool) change {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case c := <-changes:
+				if ok(c) {
+					return c
+				}
+			case <-timeout:
+				t.FailNow("the config change was not applied")
+			}
+		}
+	}
+
+	// The file is replaced at once so the watcher can't
+	// read it partially written.
+	writeConfig := func(content string) {
+		tmp := path + ".tmp"
+		t.Require().NoError(os.WriteFile(tmp, []byte(content), 0644))
+		t.Require().NoError(os.Rename(tmp, path))
+	}
+
+	// The invalid config is ignored.
+	writeConfig("log:\n  level: loud")
+	writeConfig("log:\n  level: debug")
+
+	c := waitFor(func(c change) bool { return c.new.Log.Level == "debug" })
+	t.Equal("info", c.old.Log.Level)
+
+	// The level is unchanged when only the other settings change, so
+	// the level set through the admin routes isn't reset.
+	writeConfig("log:\n  level: debug\nserver:\n  cors:\n    allowedorigins: ['*']")
+
+	c = waitFor(func(c change) bool { return len(c.new.Server.CORS.AllowedOrigins) == 1 })
+	t.Equal("debug", c.old.Log.Level)
+	t.Equal(c.old.Log.Level, c.new.Log.Level)
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FieldError is an invalid setting of the config.
+type FieldError struct {
+	// Field is the key of the setting, e.g. "server.port".
+	Field string
+	// Message tells why the setting is invalid.
+	Message string
+}
+
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError is an error when one or more settings of
+// the config are invalid.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("config: invalid configuration:")
+	for _, fe := range e.Errors {
+		b.WriteString("\n  - ")
+		b.WriteString(fe.String())
+	}
+	return b.String()
+}
+
+// validator collects the invalid settings of the config.
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) addf(field, format string, a ...interface{}) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+func (v *validator) check(ok bool, field, format string, a ...interface{}) {
+	if !ok {
+		v.addf(field, format, a...)
+	}
+}
+
+func (v *validator) oneOf(field, value string, values ...string) {
+	for _, allowed := range values {
+		if value == allowed {
+			return
+		}
+	}
+	v.addf(field, "must be one of [%s], got '%s'", strings.Join(values, "/"), value)
+}
+
+func (v *validator) url(field, value string) {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		v.addf(field, "must be an absolute URL, got '%s'", value)
+	}
+}
+
+// budget checks the limit and the window of the rate limit budget
+// of field. The window of an unlimited budget is not used.
+func (v *validator) budget(field string, limit int, window time.Duration) {
+	v.check(limit >= 0, field+".limit", "must not be negative, got %d", limit)
+	v.check(limit == 0 || window > 0, field+".window", "must be positive, got %s", window)
+}
+
+// Validate checks the settings of the config. It returns a
+// *ValidationError listing all the invalid settings.
+func (c *Config) Validate() error {
+	var v validator
+
+	v.check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	v.check(c.Server.ShutdownTimeout > 0, "server.shutdowntimeout", "must be positive, got %s", c.Server.ShutdownTimeout)
+	v.check(c.Server.DrainDelay >= 0, "server.draindelay", "must not be negative, got %s", c.Server.DrainDelay)
+	v.check((c.Server.TLS.CertFile == "") == (c.Server.TLS.KeyFile == ""),
+		"server.tls", "the certfile and the keyfile must be both set or both empty")
+	v.check(c.Server.TLS.ClientCAFile == "" || c.Server.TLS.CertFile != "",
+		"server.tls.clientcafile", "requires the certfile and the keyfile")
+	v.check(!c.Server.TLS.RequireClientCert || c.Server.TLS.ClientCAFile != "",
+		"server.tls.requireclientcert", "requires the clientcafile")
+	v.check(c.Server.TLS.ReloadInterval > 0, "server.tls.reloadinterval", "must be positive, got %s", c.Server.TLS.ReloadInterval)
+	v.check(c.Server.RateLimit.MaxBurst >= 0, "server.ratelimit.maxburst", "must not be negative, got %g", c.Server.RateLimit.MaxBurst)
+	if c.Server.RateLimit.Enabled {
+		v.budget("server.ratelimit.read", c.Server.RateLimit.Read.Limit, c.Server.RateLimit.Read.Window)
+		v.budget("server.ratelimit.write", c.Server.RateLimit.Write.Limit, c.Server.RateLimit.Write.Window)
+		names := make(map[string]bool, len(c.Server.RateLimit.Policies))
+		for i, p := range c.Server.RateLimit.Policies {
+			field := fmt.Sprintf("server.ratelimit.policies[%d]", i)
+			v.check(p.Name != "", field+".name", "must not be empty")
+			v.check(p.Name == "" || !names[p.Name], field+".name", "must be unique, got '%s' again", p.Name)
+			names[p.Name] = true
+			v.budget(field, p.Limit, p.Window)
+		}
+	}
+	for i, lookup := range c.Server.RateLimit.IPLookups {
+		v.oneOf(fmt.Sprintf("server.ratelimit.iplookups[%d]", i), lookup, "RemoteAddr", "X-Forwarded-For", "X-Real-IP")
+	}
+	keyNames := make(map[string]bool, len(c.Server.APIKeys))
+	keys := make(map[string]bool, len(c.Server.APIKeys))
+	for i, k := range c.Server.APIKeys {
+		field := fmt.Sprintf("server.apikeys[%d]", i)
+		v.check(k.Name != "", field+".name", "must not be empty")
+		v.check(k.Name == "" || !keyNames[k.Name], field+".name", "must be unique, got '%s' again", k.Name)
+		v.check(k.Key != "", field+".key", "must not be empty")
+		v.check(k.Key == "" || !keys[k.Key], field+".key", "must be unique")
+		keyNames[k.Name] = true
+		keys[k.Key] = true
+	}
+	v.check(c.Server.Compression.MinSize >= 0, "server.compression.minsize", "must not be negative, got %d", c.Server.Compression.MinSize)
+	if c.Server.Idempotency.Enabled {
+		v.check(c.Server.Idempotency.TTL > 0, "server.idempotency.ttl", "must be positive, got %s", c.Server.Idempotency.TTL)
+		v.oneOf("server.idempotency.store", c.Server.Idempotency.Store, "memory", "file")
+		v.check(c.Server.Idempotency.SweepInterval > 0, "server.idempotency.sweepinterval", "must be positive, got %s", c.Server.Idempotency.SweepInterval)
+	}
+	v.check(c.Server.CORS.MaxAge >= 0, "server.cors.maxage", "must not be negative, got %d", c.Server.CORS.MaxAge)
+	v.check(!c.Server.CORS.AllowCredentials || !contains(c.Server.CORS.AllowedOrigins, "*"),
+		"server.cors.allowcredentials", "can't be used with the '*' allowed origin")
+
+	v.check(c.Store.File.Path != "", "store.file.path", "must not be empty")
+	v.check(c.Store.Blob.MaxSize > 0, "store.blob.maxsize", "must be positive, got %d", c.Store.Blob.MaxSize)
+	v.check(c.Store.Blob.GCInterval > 0, "store.blob.gcinterval", "must be positive, got %s", c.Store.Blob.GCInterval)
+
+	if c.Reminder.WebhookURL != "" {
+		v.url("reminder.webhookurl", c.Reminder.WebhookURL)
+	}
+	v.check(c.Reminder.MissedGrace >= 0, "reminder.missedgrace", "must not be negative, got %s", c.Reminder.MissedGrace)
+
+	v.check(c.Archive.Interval > 0, "archive.interval", "must be positive, got %s", c.Archive.Interval)
+	for i, r := range c.Archive.Rules {
+		v.check(r.Name != "", fmt.Sprintf("archive.rules[%d].name", i), "must not be empty")
+		v.check(r.UntouchedDays > 0, fmt.Sprintf("archive.rules[%d].untoucheddays", i), "must be positive, got %d", r.UntouchedDays)
+	}
+
+	switch c.Tracing.Exporter {
+	case "":
+	case "stdout":
+	case "file":
+		v.check(c.Tracing.File != "", "tracing.file", "must not be empty")
+	case "otlp":
+		v.url("tracing.otlpendpoint", c.Tracing.OTLPEndpoint)
+	default:
+		v.addf("tracing.exporter", "must be one of [stdout/file/otlp] or empty, got '%s'", c.Tracing.Exporter)
+	}
+
+	_, err := logrus.ParseLevel(c.Log.Level)
+	v.check(err == nil, "log.level", "must be one of [panic/fatal/error/warn/info/debug/trace], got '%s'", c.Log.Level)
+	v.oneOf("log.format", strings.ToLower(c.Log.Format), "text", "json")
+	v.check(c.Log.Output != "", "log.output", "must not be empty")
+
+	v.check(c.Health.Timeout > 0, "health.timeout", "must be positive, got %s", c.Health.Timeout)
+
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
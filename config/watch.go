@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrNoConfigFile is an error when the config can't be watched
+// because it was not loaded from a file.
+var ErrNoConfigFile = errors.New("config: no config file to watch")
+
+// Watch watches the config file loaded by New and calls onChange with
+// the old and the new config every time the file changes, so onChange
+// can apply only the settings that changed.
+//
+// Only the following settings can be changed without restarting the
+// server and onChange is expected to apply them:
+// - "log.level"
+// - "server.ratelimit"
+// - "server.cors"
+//
+// A change of the other settings is logged as requiring a restart. A
+// config that fails to load or to validate is logged and ignored.
+func Watch(onChange func(old, new *Config)) error {
+	New()
+	if confV.ConfigFileUsed() == "" {
+		return ErrNoConfigFile
+	}
+	watch(confV, conf, onChange)
+	return nil
+}
+
+func watch(v *viper.Viper, current *Config, onChange func(old, new *Config)) {
+	var mu sync.Mutex
+	v.OnConfigChange(func(fsnotify.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Viper already read the file but it doesn't report the error.
+		if err := v.ReadInConfig(); err != nil {
+			logrus.Error("config: ignored the change of the config file: ", err)
+			return
+		}
+
+		next, err := decode(v)
+		if err != nil {
+			logrus.Error("config: ignored the change of the config file: ", err)
+			return
+		}
+
+		for _, section := range restartRequired(current, next) {
+			logrus.Warnf("config: the '%s' settings changed, restart the server to apply them", section)
+		}
+		old := current
+		current = next
+
+		logrus.Info("config: applied the change of the config file")
+		onChange(old, next)
+	})
+	v.WatchConfig()
+}
+
+// restartRequired returns the sections of the config with a change
+// that can't be applied without restarting the server.
+func restartRequired(old, new *Config) []string {
+	a, b := withoutReloadable(*old), withoutReloadable(*new)
+
+	var sections []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			sections = append(sections, strings.ToLower(va.Type().Field(i).Name))
+		}
+	}
+	return sections
+}
+
+// withoutReloadable returns c with the settings that can be
+// changed without restarting the server cleared.
+func withoutReloadable(c Config) Config {
+	c.Log.Level = ""
+	c.Server.RateLimit = RateLimit{}
+	c.Server.CORS = CORS{}
+	return c
+}
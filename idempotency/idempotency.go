@@ -0,0 +1,90 @@
+// Package idempotency keeps the responses of the requests with an
+// idempotency key so the retries of a request are not processed
+// twice and get the response of the first request instead.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrNotFound is an error when there is no record with the key
+	// or the record is expired.
+	ErrNotFound = errors.New("idempotency: record not found")
+	// ErrExists is an error when a record with the key exists
+	// and is not expired.
+	ErrExists = errors.New("idempotency: record exists")
+)
+
+// Record is the record of a request with an idempotency key.
+type Record struct {
+	// Key identifies the record. It is the idempotency key of the
+	// request scoped to its client.
+	Key string `json:"key"`
+	// Fingerprint identifies the request, e.g. a digest of its method,
+	// its URI and its body. The retries of a request have the same
+	// fingerprint.
+	Fingerprint string `json:"fingerprint"`
+	// Status is the status code of the response. It is zero while
+	// the request is processed.
+	Status int `json:"status,omitempty"`
+	// Header is the header of the response.
+	Header http.Header `json:"header,omitempty"`
+	// Body is the body of the response.
+	Body []byte `json:"body,omitempty"`
+	// CreatedTime is when the request was received.
+	CreatedTime time.Time `json:"created_time"`
+	// ExpireTime is when the record expires. The expired records
+	// are treated as missing.
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+// Completed tells whether the response of the request is recorded.
+func (r *Record) Completed() bool {
+	return r.Status != 0
+}
+
+// Expired tells whether the record is expired at now.
+func (r *Record) Expired(now time.Time) bool {
+	return !now.Before(r.ExpireTime)
+}
+
+// Copy returns a deep copy of the record.
+func (r *Record) Copy() *Record {
+	cpy := *r
+	cpy.Header = r.Header.Clone()
+	if r.Body != nil {
+		cpy.Body = append([]byte{}, r.Body...)
+	}
+	return &cpy
+}
+
+// Store is an interface for storing the records. Specific storage
+// drivers should implement the following methods.
+type Store interface {
+	// Create stores the record r when there's no record with the
+	// same key. It is atomic so only one of the concurrent requests
+	// with the same key is processed. It will return ErrExists when
+	// a record with the key exists and is not expired.
+	Create(ctx context.Context, r *Record) error
+
+	// Get gets the record with key. It will return ErrNotFound when
+	// there is no record with the key or the record is expired.
+	Get(ctx context.Context, key string) (*Record, error)
+
+	// Put replaces the record with the same key as r, e.g. to
+	// record the response of the request.
+	Put(ctx context.Context, r *Record) error
+
+	// Delete deletes the record with key, e.g. to let the request
+	// be retried when it failed. Deleting a missing record is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+
+	// Sweep removes the expired records and returns the
+	// number of removed records.
+	Sweep(ctx context.Context) (int, error)
+}
@@ -0,0 +1,229 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"noterfy/idempotency"
+	"noterfy/pkg/clock"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+var _ idempotency.Store = (*Store)(nil)
+
+const (
+	recordsDir = "records"
+	tmpDir     = "tmp"
+	recordExt  = ".json"
+)
+
+// New takes a fs filesystem that is rooted at the directory of the
+// records and the clock c and returns the store instance. If nil
+// clock is provided it will use the real clock.
+func New(fs afero.Fs, c clock.Clock) *Store {
+	if c == nil {
+		c = clock.New()
+	}
+	return &Store{fs: fs, clock: c}
+}
+
+// Store implements the idempotency.Store interface.
+//
+// The underlying implementation stores each record as a JSON
+// file named after the digest of its key. The files are replaced
+// atomically so a crash doesn't leave a partial record.
+type Store struct {
+	fs    afero.Fs
+	clock clock.Clock
+
+	// mu serializes the changes of the records so the
+	// creation of a record is atomic.
+	mu sync.Mutex
+
+	// once use to initialize the store only
+	// once.
+	once    sync.Once
+	initErr error
+}
+
+func (s *Store) lazyInit() error {
+	s.once.Do(func() {
+		for _, dir := range []string{recordsDir, tmpDir} {
+			if s.initErr = s.fs.MkdirAll(dir, 0755); s.initErr != nil {
+				return
+			}
+		}
+	})
+	return s.initErr
+}
+
+// Create stores the record r when there's no unexpired
+// record with the same key.
+func (s *Store) Create(ctx context.Context, r *idempotency.Record) error {
+	if err := s.lazyInit(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exist, err := s.read(s.recordPath(r.Key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if exist != nil && !exist.Expired(s.clock.Now()) {
+		return idempotency.ErrExists
+	}
+	return s.write(r)
+}
+
+// Get gets the unexpired record with key.
+func (s *Store) Get(ctx context.Context, key string) (*idempotency.Record, error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.read(s.recordPath(key))
+	if os.IsNotExist(err) {
+		return nil, idempotency.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Expired(s.clock.Now()) {
+		return nil, idempotency.ErrNotFound
+	}
+	return r, nil
+}
+
+// Put replaces the record with the same key as r.
+func (s *Store) Put(ctx context.Context, r *idempotency.Record) error {
+	if err := s.lazyInit(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(r)
+}
+
+// Delete deletes the record with key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if err := s.lazyInit(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.fs.Remove(s.recordPath(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Sweep removes the expired records. The records that can't be
+// read are removed too.
+func (s *Store) Sweep(ctx context.Context) (int, error) {
+	if err := s.lazyInit(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos, err := afero.ReadDir(s.fs, recordsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.clock.Now()
+	var removed int
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), recordExt) {
+			continue
+		}
+
+		p := path.Join(recordsDir, info.Name())
+		r, err := s.read(p)
+		if err == nil && !r.Expired(now) {
+			continue
+		}
+		if err := s.fs.Remove(p); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// recordPath returns the path of the record with key. The key is
+// hashed so any key is a valid file name.
+func (s *Store) recordPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return path.Join(recordsDir, hex.EncodeToString(sum[:])+recordExt)
+}
+
+func (s *Store) read(p string) (*idempotency.Record, error) {
+	data, err := afero.ReadFile(s.fs, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var r idempotency.Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// write writes the record r to a temporary file first
+// and then renames it to the path of the record.
+func (s *Store) write(r *idempotency.Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path.Join(tmpDir, uuid.New().String())
+	if err := afero.WriteFile(s.fs, tmpPath, data, 0644); err != nil {
+		_ = s.fs.Remove(tmpPath)
+		return err
+	}
+
+	if err := s.fs.Rename(tmpPath, s.recordPath(r.Key)); err != nil {
+		_ = s.fs.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
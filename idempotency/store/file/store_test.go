@@ -0,0 +1,60 @@
+package file
+
+import (
+	"context"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"noterfy/idempotency"
+	"noterfy/idempotency/store/storetest"
+	"noterfy/pkg/clock"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(FileStoreTestSuite))
+}
+
+type FileStoreTestSuite struct {
+	storetest.TestSuite
+	fs    afero.Fs
+	clock *clock.Fake
+}
+
+func (s *FileStoreTestSuite) SetupTest() {
+	s.fs = afero.NewMemMapFs()
+	s.clock = clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	s.SetStore(New(s.fs, s.clock), s.clock)
+}
+
+func (s *FileStoreTestSuite) TestPersistence() {
+	now := s.clock.Now()
+	want := &idempotency.Record{
+		Key:         "persisted",
+		Fingerprint: "fingerprint",
+		Status:      201,
+		Body:        []byte("created"),
+		CreatedTime: now,
+		ExpireTime:  now.Add(time.Hour),
+	}
+	s.Require().NoError(New(s.fs, s.clock).Create(context.Background(), want))
+
+	got, err := New(s.fs, s.clock).Get(context.Background(), want.Key)
+	s.Require().NoError(err)
+	s.Equal(want.Body, got.Body)
+
+	tmp, err := afero.ReadDir(s.fs, tmpDir)
+	s.NoError(err)
+	s.Empty(tmp)
+}
+
+func (s *FileStoreTestSuite) TestSweepUnreadableRecord() {
+	store := New(s.fs, s.clock)
+	_, err := store.Sweep(context.Background())
+	s.Require().NoError(err)
+	s.Require().NoError(afero.WriteFile(s.fs, store.recordPath("corrupted"), []byte("{"), 0644))
+
+	removed, err := store.Sweep(context.Background())
+	s.NoError(err)
+	s.Equal(1, removed)
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"noterfy/idempotency"
+	"noterfy/pkg/clock"
+	"sync"
+)
+
+var _ idempotency.Store = (*Store)(nil)
+
+// New takes the clock c and returns a store that keeps the records
+// in memory. If nil is provided it will use the real clock.
+func New(c clock.Clock) *Store {
+	if c == nil {
+		c = clock.New()
+	}
+	return &Store{
+		clock:   c,
+		records: make(map[string]*idempotency.Record),
+	}
+}
+
+// Store is the in-memory implementation for idempotency.Store.
+// This is safe for concurrent use.
+type Store struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+}
+
+// Create stores the record r when there's no unexpired
+// record with the same key.
+func (s *Store) Create(ctx context.Context, r *idempotency.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exist, found := s.records[r.Key]; found && !exist.Expired(s.clock.Now()) {
+		return idempotency.ErrExists
+	}
+	s.records[r.Key] = r.Copy()
+	return nil
+}
+
+// Get gets the unexpired record with key.
+func (s *Store) Get(ctx context.Context, key string) (*idempotency.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, found := s.records[key]
+	if !found || r.Expired(s.clock.Now()) {
+		return nil, idempotency.ErrNotFound
+	}
+	return r.Copy(), nil
+}
+
+// Put replaces the record with the same key as r.
+func (s *Store) Put(ctx context.Context, r *idempotency.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[r.Key] = r.Copy()
+	return nil
+}
+
+// Delete deletes the record with key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// Sweep removes the expired records.
+func (s *Store) Sweep(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int
+	for key, r := range s.records {
+		if r.Expired(now) {
+			delete(s.records, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,22 @@
+package memory
+
+import (
+	"github.com/stretchr/testify/suite"
+	"noterfy/idempotency/store/storetest"
+	"noterfy/pkg/clock"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(MemoryStoreTestSuite))
+}
+
+type MemoryStoreTestSuite struct {
+	storetest.TestSuite
+}
+
+func (m *MemoryStoreTestSuite) SetupTest() {
+	c := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	m.SetStore(New(c), c)
+}
@@ -0,0 +1,154 @@
+package storetest
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"noterfy/idempotency"
+	"noterfy/pkg/clock"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+// TestSuite is a shared tests for implementing the idempotency.Store.
+type TestSuite struct {
+	suite.Suite
+	store idempotency.Store
+	clock *clock.Fake
+}
+
+// SetStore sets store and the fake clock c of the store
+// to the test suite to use.
+func (s *TestSuite) SetStore(store idempotency.Store, c *clock.Fake) {
+	s.store = store
+	s.clock = c
+}
+
+func (s *TestSuite) newRecord(key string) *idempotency.Record {
+	now := s.clock.Now()
+	return &idempotency.Record{
+		Key:         key,
+		Fingerprint: "fingerprint-" + key,
+		CreatedTime: now,
+		ExpireTime:  now.Add(time.Hour),
+	}
+}
+
+// TestCreate tests the store create method.
+func (s *TestSuite) TestCreate() {
+	s.Run("Creating a new record", func() {
+		want := s.newRecord("create")
+		s.Require().NoError(s.store.Create(dummyCtx, want))
+
+		got, err := s.store.Get(dummyCtx, want.Key)
+		s.NoError(err)
+		s.Equal(want.Fingerprint, got.Fingerprint)
+		s.False(got.Completed())
+	})
+
+	s.Run("Creating an existing record should return an error", func() {
+		s.Require().NoError(s.store.Create(dummyCtx, s.newRecord("exists")))
+		s.Equal(idempotency.ErrExists, s.store.Create(dummyCtx, s.newRecord("exists")))
+	})
+
+	s.Run("Creating over an expired record", func() {
+		expired := s.newRecord("expired")
+		expired.ExpireTime = s.clock.Now()
+		s.Require().NoError(s.store.Create(dummyCtx, expired))
+
+		s.NoError(s.store.Create(dummyCtx, s.newRecord("expired")))
+	})
+
+	s.Run("Only one of the concurrent creations succeeds", func() {
+		var (
+			wg      sync.WaitGroup
+			created int32
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if s.store.Create(dummyCtx, s.newRecord("concurrent")) == nil {
+					atomic.AddInt32(&created, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		s.Equal(int32(1), created)
+	})
+
+	s.Run("Calling context cancel should return an error", func() {
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+		s.Equal(context.Canceled, s.store.Create(ctx, s.newRecord("cancelled")))
+	})
+}
+
+// TestGet tests the store get method.
+func (s *TestSuite) TestGet() {
+	s.Run("Getting a missing record should return an error", func() {
+		got, err := s.store.Get(dummyCtx, "missing")
+		s.Equal(idempotency.ErrNotFound, err)
+		s.Nil(got)
+	})
+
+	s.Run("Getting an expired record should return an error", func() {
+		s.Require().NoError(s.store.Create(dummyCtx, s.newRecord("expiring")))
+		s.clock.Advance(time.Hour)
+
+		got, err := s.store.Get(dummyCtx, "expiring")
+		s.Equal(idempotency.ErrNotFound, err)
+		s.Nil(got)
+	})
+}
+
+// TestPut tests the store put method.
+func (s *TestSuite) TestPut() {
+	r := s.newRecord("put")
+	s.Require().NoError(s.store.Create(dummyCtx, r))
+
+	r.Status = http.StatusCreated
+	r.Header = http.Header{"Content-Type": {"application/json"}}
+	r.Body = []byte(`{"note":{}}`)
+	s.Require().NoError(s.store.Put(dummyCtx, r))
+
+	got, err := s.store.Get(dummyCtx, r.Key)
+	s.Require().NoError(err)
+	s.True(got.Completed())
+	s.Equal(r.Status, got.Status)
+	s.Equal(r.Header, got.Header)
+	s.Equal(r.Body, got.Body)
+	s.True(r.ExpireTime.Equal(got.ExpireTime))
+}
+
+// TestDelete tests the store delete method.
+func (s *TestSuite) TestDelete() {
+	s.Require().NoError(s.store.Create(dummyCtx, s.newRecord("delete")))
+	s.Require().NoError(s.store.Delete(dummyCtx, "delete"))
+
+	_, err := s.store.Get(dummyCtx, "delete")
+	s.Equal(idempotency.ErrNotFound, err)
+	s.NoError(s.store.Create(dummyCtx, s.newRecord("delete")))
+
+	s.Run("Deleting a missing record", func() {
+		s.NoError(s.store.Delete(dummyCtx, "missing"))
+	})
+}
+
+// TestSweep tests the store sweep method.
+func (s *TestSuite) TestSweep() {
+	s.Require().NoError(s.store.Create(dummyCtx, s.newRecord("old")))
+	s.clock.Advance(30 * time.Minute)
+	s.Require().NoError(s.store.Create(dummyCtx, s.newRecord("new")))
+	s.clock.Advance(30 * time.Minute)
+
+	removed, err := s.store.Sweep(dummyCtx)
+	s.Require().NoError(err)
+	s.Equal(1, removed)
+
+	_, err = s.store.Get(dummyCtx, "new")
+	s.NoError(err)
+}
@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"net/http"
+	"noterfy/api"
+	"noterfy/note"
+	nhttp "noterfy/pkg/http"
+)
+
+type archiveService interface {
+	Archive(ctx context.Context, id uuid.UUID) (*note.Note, error)
+	Unarchive(ctx context.Context, id uuid.UUID) (*note.Note, error)
+}
+
+// ArchiveRoutes returns all the routes for archiving and
+// restoring the notes. The opts are applied to the server
+// of every route.
+func ArchiveRoutes(svc archiveService, opts ...httptransport.ServerOption) []api.Route {
+	archiveHandler := httptransport.NewServer(
+		makeArchiveEndpoint(svc),
+		decodeArchiveRequest,
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	unarchiveHandler := httptransport.NewServer(
+		makeUnarchiveEndpoint(svc),
+		decodeUnarchiveRequest,
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: archiveHandler, MethodValue: http.MethodPost, PathValue: "/v1/note/{id}/archive"},
+		&nhttp.Route{HandlerValue: unarchiveHandler, MethodValue: http.MethodPost, PathValue: "/v1/note/{id}/unarchive"},
+	}
+}
+
+// ArchiveRequest is a container for the archive request API.
+type ArchiveRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// ArchiveResponse is a container for the archive response API.
+type ArchiveResponse struct {
+	Note *note.Note `json:"note"`
+}
+
+func decodeArchiveRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	return ArchiveRequest{ID: id}, nil
+}
+
+// ArchiveRequest godoc
+// @Summary Archive a note.
+// @Description Archive a note so it is hidden from the default listing of the notes. The archived note is kept and can be restored.
+// @Produce json
+// @Param id path string true "ID of the note"
+// @Success 200 {object} ArchiveResponse "Successfully archived the note"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID"
+// @Failure 404 {object} problem.Problem "Note is not found in the service"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /note/{id}/archive [post]
+func makeArchiveEndpoint(svc archiveService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(ArchiveRequest)
+		n, err := svc.Archive(ctx, request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return ArchiveResponse{Note: n}, nil
+	}
+}
+
+// UnarchiveRequest is a container for the unarchive request API.
+type UnarchiveRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// UnarchiveResponse is a container for the unarchive response API.
+type UnarchiveResponse struct {
+	Note *note.Note `json:"note"`
+}
+
+func decodeUnarchiveRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	return UnarchiveRequest{ID: id}, nil
+}
+
+// UnarchiveRequest godoc
+// @Summary Restore an archived note.
+// @Description Restore an archived note so it is listed again.
+// @Produce json
+// @Param id path string true "ID of the note"
+// @Success 200 {object} UnarchiveResponse "Successfully restored the note"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID"
+// @Failure 404 {object} problem.Problem "Note is not found in the service"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /note/{id}/unarchive [post]
+func makeUnarchiveEndpoint(svc archiveService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(UnarchiveRequest)
+		n, err := svc.Unarchive(ctx, request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return UnarchiveResponse{Note: n}, nil
+	}
+}
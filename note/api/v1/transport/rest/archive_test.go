@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/archive"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/problem"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	suite.Run(t, new(ArchiveTestSuite))
+}
+
+type ArchiveTestSuite struct {
+	suite.Suite
+	svc    note.Service
+	router *mux.Router
+}
+
+func (s *ArchiveTestSuite) SetupTest() {
+	s.svc = service.New(memory.New())
+
+	s.router = mux.NewRouter()
+	for _, route := range ArchiveRoutes(archive.New(s.svc, clock.NewFake(time.Now()))) {
+		s.router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+}
+
+func (s *ArchiveTestSuite) post(target string, resp interface{}) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(resp))
+	return rec
+}
+
+func (s *ArchiveTestSuite) TestArchive() {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle("Note"))
+	s.Require().NoError(err)
+
+	s.Run("Archiving a note", func() {
+		var resp ArchiveResponse
+		rec := s.post("/v1/note/"+n.ID.String()+"/archive", &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.True(resp.Note.GetIsArchived())
+		s.NotNil(resp.Note.ArchivedTime)
+	})
+
+	s.Run("Unarchiving a note", func() {
+		var resp UnarchiveResponse
+		rec := s.post("/v1/note/"+n.ID.String()+"/unarchive", &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.False(resp.Note.GetIsArchived())
+		s.Nil(resp.Note.ArchivedTime)
+	})
+
+	s.Run("Archiving a note that not exists", func() {
+		var resp problem.Problem
+		rec := s.post("/v1/note/"+uuid.New().String()+"/archive", &resp)
+		s.Equal(http.StatusNotFound, rec.Code)
+		s.Equal(CodeNoteNotFound, resp.Code)
+	})
+}
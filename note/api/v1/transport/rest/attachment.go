@@ -0,0 +1,307 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net/http"
+	"noterfy/api"
+	"noterfy/note"
+	nhttp "noterfy/pkg/http"
+	"noterfy/pkg/validation"
+	"path/filepath"
+)
+
+// attachmentFormField is the multipart form field that contains
+// the uploaded file.
+const attachmentFormField = "file"
+
+// sniffLen is the number of bytes use to sniff the MIME type
+// of the attachment. See http.DetectContentType.
+const sniffLen = 512
+
+// multipartOverhead is the extra bytes allowed in the request body
+// for the multipart boundaries and headers.
+const multipartOverhead = 1 << 20
+
+// DefaultMaxAttachmentSize is the default maximum size of an
+// attachment in bytes.
+const DefaultMaxAttachmentSize = 10 << 20
+
+var errMissingAttachment = errors.New("rest: missing attachment file in the request")
+
+type attachmentService interface {
+	Add(ctx context.Context, noteID uuid.UUID, a *note.Attachment, r io.Reader) (*note.Attachment, error)
+	Open(ctx context.Context, noteID, attachmentID uuid.UUID) (io.ReadSeekCloser, *note.Attachment, error)
+	Remove(ctx context.Context, noteID, attachmentID uuid.UUID) error
+}
+
+// AttachmentRoutes returns all the routes for managing the attachments
+// of the notes. The maxSize is the maximum size of an uploaded attachment
+// in bytes. If maxSize is 0 the DefaultMaxAttachmentSize will be use.
+// The opts are applied to the server of every route.
+func AttachmentRoutes(svc attachmentService, maxSize int64, opts ...httptransport.ServerOption) []api.Route {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+
+	addHandler := httptransport.NewServer(
+		makeAddAttachmentEndpoint(svc),
+		makeDecodeAddAttachmentRequest(maxSize),
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	downloadHandler := httptransport.NewServer(
+		makeDownloadAttachmentEndpoint(svc),
+		decodeDownloadAttachmentRequest,
+		encodeDownloadAttachmentResponse,
+		withOptions(opts,
+			httptransport.ServerBefore(withRequest),
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	removeHandler := httptransport.NewServer(
+		makeRemoveAttachmentEndpoint(svc),
+		decodeRemoveAttachmentRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: addHandler, MethodValue: http.MethodPost, PathValue: "/v1/note/{id}/attachments"},
+		&nhttp.Route{HandlerValue: downloadHandler, MethodValue: http.MethodGet, PathValue: "/v1/note/{id}/attachments/{attachment_id}"},
+		&nhttp.Route{HandlerValue: removeHandler, MethodValue: http.MethodDelete, PathValue: "/v1/note/{id}/attachments/{attachment_id}"},
+	}
+}
+
+// AddAttachmentRequest is a container for the add attachment request.
+type AddAttachmentRequest struct {
+	NoteID     uuid.UUID
+	Attachment *note.Attachment
+	Content    io.Reader
+}
+
+// AddAttachmentResponse is a container for the add attachment response.
+type AddAttachmentResponse struct {
+	Attachment *note.Attachment `json:"attachment"`
+}
+
+func makeDecodeAddAttachmentRequest(maxSize int64) httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		noteID, err := decodeID(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		// Give an extra room for the multipart boundaries and headers.
+		r.Body = http.MaxBytesReader(nil, r.Body, maxSize+multipartOverhead)
+
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil, errMissingAttachment
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if part.FormName() != attachmentFormField {
+				continue
+			}
+
+			// Sniff the MIME type from the content instead of trusting
+			// the client provided Content-Type of the part.
+			content := bufio.NewReaderSize(part, sniffLen)
+			head, err := content.Peek(sniffLen)
+			if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+				return nil, err
+			}
+
+			return AddAttachmentRequest{
+				NoteID: noteID,
+				Attachment: &note.Attachment{
+					Name:      filepath.Base(part.FileName()),
+					MediaType: http.DetectContentType(head),
+				},
+				Content: &limitedReader{r: content, n: maxSize},
+			}, nil
+		}
+	}
+}
+
+// AddAttachmentRequest godoc
+// @Summary Upload an attachment to a note.
+// @Description Upload a file as an attachment to an existing note. The file is sent as a multipart form with the "file" field. The media type of the attachment is sniffed from its content.
+// @Accept mpfd
+// @Produce json
+// @Param id path string true "ID of the note"
+// @Param file formData file true "The file to attach"
+// @Success 200 {object} AddAttachmentResponse "Successfully uploaded the attachment"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID or the request has no file"
+// @Failure 404 {object} problem.Problem "Note is not found in the service"
+// @Failure 413 {object} problem.Problem "The attachment exceeds the maximum size"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /note/{id}/attachments [post]
+func makeAddAttachmentEndpoint(svc attachmentService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(AddAttachmentRequest)
+		a, err := svc.Add(ctx, request.NoteID, request.Attachment, request.Content)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return AddAttachmentResponse{Attachment: a}, nil
+	}
+}
+
+// DownloadAttachmentRequest is a container for the download attachment request.
+type DownloadAttachmentRequest struct {
+	NoteID       uuid.UUID
+	AttachmentID uuid.UUID
+}
+
+// downloadAttachmentResponse is a container for the download
+// attachment response. The content must be closed after writing.
+type downloadAttachmentResponse struct {
+	attachment *note.Attachment
+	content    io.ReadSeekCloser
+}
+
+func decodeDownloadAttachmentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var v validation.Validator
+	vars := mux.Vars(r)
+	request := DownloadAttachmentRequest{
+		NoteID:       v.UUID("id", vars["id"]),
+		AttachmentID: v.UUID("attachment_id", vars["attachment_id"]),
+	}
+	return request, v.Err()
+}
+
+// DownloadAttachmentRequest godoc
+// @Summary Download an attachment of a note.
+// @Description Download the content of an attachment. Partial downloads are supported through the Range header.
+// @Produce octet-stream
+// @Param id path string true "ID of the note"
+// @Param attachment_id path string true "ID of the attachment"
+// @Param Range header string false "The byte range of the content to download"
+// @Success 200 {file} file "The content of the attachment"
+// @Success 206 {file} file "The requested range of the content of the attachment"
+// @Failure 400 {object} problem.Problem "The ID of the note or the attachment is not a UUID"
+// @Failure 404 {object} problem.Problem "Note or attachment is not found in the service"
+// @Failure 416 {string} string "The requested range is not satisfiable"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /note/{id}/attachments/{attachment_id} [get]
+func makeDownloadAttachmentEndpoint(svc attachmentService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(DownloadAttachmentRequest)
+		content, a, err := svc.Open(ctx, request.NoteID, request.AttachmentID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return downloadAttachmentResponse{attachment: a, content: content}, nil
+	}
+}
+
+func encodeDownloadAttachmentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp, ok := response.(downloadAttachmentResponse)
+	if !ok {
+		return encodeResponse(ctx, w, response)
+	}
+	defer func() { _ = resp.content.Close() }()
+
+	a := resp.attachment
+	w.Header().Set("Content-Type", a.MediaType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, a.Digest))
+
+	// http.ServeContent handles the Range and the conditional
+	// request headers.
+	http.ServeContent(w, requestFromContext(ctx), a.Name, a.CreatedTime, resp.content)
+	return nil
+}
+
+// RemoveAttachmentRequest is a container for the remove attachment request.
+type RemoveAttachmentRequest struct {
+	NoteID       uuid.UUID
+	AttachmentID uuid.UUID
+}
+
+// RemoveAttachmentResponse is a container for the remove attachment response.
+type RemoveAttachmentResponse struct {
+	Message string `json:"message"`
+}
+
+func decodeRemoveAttachmentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var v validation.Validator
+	vars := mux.Vars(r)
+	request := RemoveAttachmentRequest{
+		NoteID:       v.UUID("id", vars["id"]),
+		AttachmentID: v.UUID("attachment_id", vars["attachment_id"]),
+	}
+	return request, v.Err()
+}
+
+// RemoveAttachmentRequest godoc
+// @Summary Delete an attachment of a note.
+// @Description Delete an attachment of a note. The content is removed once it isn't referenced by any other attachment.
+// @Produce json
+// @Param id path string true "ID of the note"
+// @Param attachment_id path string true "ID of the attachment"
+// @Success 200 {object} RemoveAttachmentResponse "Successfully deleted the attachment"
+// @Failure 400 {object} problem.Problem "The ID of the note or the attachment is not a UUID"
+// @Failure 404 {object} problem.Problem "Note or attachment is not found in the service"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /note/{id}/attachments/{attachment_id} [delete]
+func makeRemoveAttachmentEndpoint(svc attachmentService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(RemoveAttachmentRequest)
+		err := svc.Remove(ctx, request.NoteID, request.AttachmentID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return RemoveAttachmentResponse{Message: "Successfully Deleted"}, nil
+	}
+}
+
+// limitedReader reads from r but returns note.ErrAttachmentTooLarge
+// when there's more than n bytes to read.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, note.ErrAttachmentTooLarge
+	}
+
+	// Read one more byte than the limit to detect the excess.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, note.ErrAttachmentTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	blobstore "noterfy/blob/store/file"
+	"noterfy/note"
+	"noterfy/note/attachment"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/problem"
+	"testing"
+)
+
+func TestAttachment(t *testing.T) {
+	suite.Run(t, new(AttachmentTestSuite))
+}
+
+type AttachmentTestSuite struct {
+	suite.Suite
+	store  note.Store
+	router *mux.Router
+	note   *note.Note
+}
+
+func (s *AttachmentTestSuite) SetupTest() {
+	s.store = memory.New()
+	svc := attachment.New(s.store, blobstore.New(afero.NewMemMapFs()))
+
+	s.router = mux.NewRouter()
+	for _, route := range AttachmentRoutes(svc, 16) {
+		s.router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+
+	s.note = new(note.Note).SetID(uuid.New()).SetTitle("Note with attachments")
+	s.Require().NoError(s.store.Insert(dummyCtx, s.note))
+}
+
+func (s *AttachmentTestSuite) upload(noteID uuid.UUID, field, filename, content string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, filename)
+	s.Require().NoError(err)
+	_, err = part.Write([]byte(content))
+	s.Require().NoError(err)
+	s.Require().NoError(writer.Close())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v1/note/%s/attachments", noteID), &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *AttachmentTestSuite) mustUpload(content string) *note.Attachment {
+	rec := s.upload(s.note.ID, "file", "hello.txt", content)
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp AddAttachmentResponse
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Attachment
+}
+
+func (s *AttachmentTestSuite) decodeCode(rec *httptest.ResponseRecorder) string {
+	var resp problem.Problem
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(&resp))
+	return resp.Code
+}
+
+func (s *AttachmentTestSuite) TestUpload() {
+	s.Run("Uploading an attachment successfully", func() {
+		got := s.mustUpload("hello world")
+		s.Equal("hello.txt", got.Name)
+		s.Equal("text/plain; charset=utf-8", got.MediaType)
+		s.Equal(int64(11), got.Size)
+	})
+
+	s.Run("Uploading an attachment that exceeds the maximum size", func() {
+		rec := s.upload(s.note.ID, "file", "large.txt", "this content is too large")
+		s.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+		s.Equal(CodeAttachmentTooLarge, s.decodeCode(rec))
+	})
+
+	s.Run("Uploading without a file", func() {
+		rec := s.upload(s.note.ID, "other", "hello.txt", "hello world")
+		s.Equal(http.StatusBadRequest, rec.Code)
+		s.Equal(CodeAttachmentMissing, s.decodeCode(rec))
+	})
+
+	s.Run("Uploading to a note that not exists", func() {
+		rec := s.upload(uuid.New(), "file", "hello.txt", "hello world")
+		s.Equal(http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *AttachmentTestSuite) TestDownload() {
+	a := s.mustUpload("hello world")
+	target := fmt.Sprintf("/v1/note/%s/attachments/%s", s.note.ID, a.ID)
+
+	s.Run("Downloading an attachment successfully", func() {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("hello world", rec.Body.String())
+		s.Equal("text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+		s.Equal(`attachment; filename=hello.txt`, rec.Header().Get("Content-Disposition"))
+	})
+
+	s.Run("Downloading a range of an attachment", func() {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Range", "bytes=6-")
+		s.router.ServeHTTP(rec, req)
+		s.Equal(http.StatusPartialContent, rec.Code)
+		s.Equal("world", rec.Body.String())
+		s.Equal("bytes 6-10/11", rec.Header().Get("Content-Range"))
+	})
+
+	s.Run("Downloading an attachment that not exists", func() {
+		rec := httptest.NewRecorder()
+		target := fmt.Sprintf("/v1/note/%s/attachments/%s", s.note.ID, uuid.New())
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		s.Equal(http.StatusNotFound, rec.Code)
+		s.Equal(CodeAttachmentNotFound, s.decodeCode(rec))
+	})
+}
+
+func (s *AttachmentTestSuite) TestDelete() {
+	a := s.mustUpload("hello world")
+	target := fmt.Sprintf("/v1/note/%s/attachments/%s", s.note.ID, a.ID)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, target, nil))
+	s.Equal(http.StatusOK, rec.Code)
+
+	n, err := s.store.Get(dummyCtx, s.note.ID)
+	s.Require().NoError(err)
+	s.Empty(n.Attachments)
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	s.Equal(http.StatusNotFound, rec.Code)
+}
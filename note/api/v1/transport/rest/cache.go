@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"hash/fnv"
+	"mime"
+	"net/http"
+	"noterfy/note"
+	"noterfy/note/proto/protoutil"
+	"strings"
+	"time"
+)
+
+// DefaultCacheControl is the default Cache-Control header of the
+// notes. The clients can keep the notes but must revalidate them
+// with a conditional request before using them.
+const DefaultCacheControl = "no-cache"
+
+// CacheConfig contains the configuration of the HTTP caching
+// of the notes.
+type CacheConfig struct {
+	// Versions gets the version of the notes. When provided the fetch
+	// responses have validators and the notes are not fetched when
+	// the client has them already.
+	Versions versionService
+	// CacheControl is the Cache-Control header of the get and fetch
+	// responses. If empty the DefaultCacheControl will be use.
+	CacheControl string
+}
+
+type versionService interface {
+	Version(ctx context.Context) (note.Version, error)
+}
+
+func (c *CacheConfig) check() *CacheConfig {
+	conf := CacheConfig{}
+	if c != nil {
+		conf = *c
+	}
+	if conf.CacheControl == "" {
+		conf.CacheControl = DefaultCacheControl
+	}
+	return &conf
+}
+
+// cacheHeaders are the validators and the caching directives of a
+// response. The responses embedding it support the conditional
+// requests with If-None-Match and If-Modified-Since.
+type cacheHeaders struct {
+	// tag identifies the version of the resource. The entity tag
+	// also identifies the representation of the resource.
+	tag string
+	// modified is the time of the last change of the resource.
+	modified time.Time
+	// control is the Cache-Control header.
+	control string
+}
+
+func (c cacheHeaders) cache() cacheHeaders {
+	return c
+}
+
+// cacheable is implemented by the responses embedding cacheHeaders.
+type cacheable interface {
+	cache() cacheHeaders
+}
+
+// notModifiedResponse is the response to a conditional request
+// when the client has the representation already.
+type notModifiedResponse struct {
+	cacheHeaders
+	mediaType string
+}
+
+// noteCacheHeaders returns the cache headers of the note n. The
+// modification times are truncated to the second, so the tag is a
+// hash of the whole note to tell apart the changes within a second
+// and the changes that don't update the modification time, e.g. the
+// position of the note.
+func noteCacheHeaders(n *note.Note, control string) cacheHeaders {
+	modified := n.GetUpdatedTime()
+	if modified.IsZero() {
+		modified = n.GetCreatedTime()
+	}
+
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(protoutil.NoteToProto(n))
+	return cacheHeaders{
+		tag:      hashTag(string(b)),
+		modified: modified,
+		control:  control,
+	}
+}
+
+// fetchCacheHeaders returns the cache headers of the page p of the
+// notes with the version v.
+func fetchCacheHeaders(v note.Version, p *note.Pagination, control string) cacheHeaders {
+	return cacheHeaders{
+		tag: hashTag("v", v.Counter, v.Modified.UnixNano(),
+			p.Page, p.Size, string(p.SortBy), p.Ascending, string(p.Archived)),
+		modified: v.Modified,
+		control:  control,
+	}
+}
+
+// hashTag returns a hash of the values of fixed-size types
+// and strings in hex.
+func hashTag(values ...interface{}) string {
+	h := fnv.New64a()
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			_, _ = h.Write([]byte(v))
+			// The separator keeps the consecutive strings apart.
+			_, _ = h.Write([]byte{0})
+		default:
+			_ = binary.Write(h, binary.LittleEndian, v)
+		}
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// etag returns the entity tag of the representation of the
+// resource in the media type.
+func (c cacheHeaders) etag(mediaType string) string {
+	return fmt.Sprintf(`"%s-%s"`, c.tag, representation(mediaType))
+}
+
+// representation returns the short name of the media type,
+// e.g. "json" for "application/json; charset=utf-8".
+func representation(mediaType string) string {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		mt = mediaType
+	}
+	if i := strings.LastIndex(mt, "/"); i >= 0 {
+		mt = mt[i+1:]
+	}
+	return strings.TrimPrefix(mt, "x-")
+}
+
+// writeCacheHeaders writes the cache headers of the representation
+// of the resource in the media type to w.
+func writeCacheHeaders(w http.ResponseWriter, c cacheHeaders, mediaType string) {
+	h := w.Header()
+	h.Set("ETag", c.etag(mediaType))
+	if !c.modified.IsZero() {
+		h.Set("Last-Modified", c.modified.UTC().Format(http.TimeFormat))
+	}
+	if c.control != "" {
+		h.Set("Cache-Control", c.control)
+	}
+}
+
+// notModified tells whether the client of the conditional request r
+// has the representation of the resource in the media type already.
+// If-Modified-Since is ignored when If-None-Match is present.
+func notModified(r *http.Request, c cacheHeaders, mediaType string) bool {
+	if r == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, c.etag(mediaType))
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !c.modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// The HTTP dates have a precision of a second.
+		return !c.modified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// etagMatches tells whether the entity tag matches one of the entity
+// tags of the If-None-Match header value. It uses the weak comparison
+// as the compressed responses have a weak entity tag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/noteutil"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+type CacheTestSuite struct {
+	suite.Suite
+	store  *memory.Store
+	svc    note.Service
+	routes http.Handler
+}
+
+func (s *CacheTestSuite) SetupTest() {
+	s.store = memory.New()
+	s.svc = service.New(s.store)
+
+	router := mux.NewRouter()
+	for _, route := range getRoutes(s.svc, nil, &CacheConfig{Versions: s.store, CacheControl: "private, no-cache"}) {
+		router.Handle(route.Path(), route.Handler()).Methods(route.Method())
+	}
+	s.routes = router
+}
+
+func (s *CacheTestSuite) create() *note.Note {
+	n, err := s.svc.Create(dummyCtx, noteutil.Copy(dummyNote))
+	s.Require().NoError(err)
+	return n
+}
+
+// get does a get request to target with the headers in pairs.
+func (s *CacheTestSuite) get(target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.routes.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *CacheTestSuite) TestGet() {
+	n := s.create()
+	target := "/v1/note/" + n.ID.String()
+
+	rec := s.get(target)
+	s.Require().Equal(http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	s.Regexp(`^"[0-9a-f]{16}-json"$`, etag)
+	s.Equal(n.GetCreatedTime().UTC().Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+	s.Equal("private, no-cache", rec.Header().Get("Cache-Control"))
+
+	s.Run("Unchanged note", func() {
+		rec := s.get(target, "If-None-Match", etag)
+		s.Equal(http.StatusNotModified, rec.Code)
+		s.Empty(rec.Body.String())
+		s.Equal(etag, rec.Header().Get("ETag"))
+		s.Equal("private, no-cache", rec.Header().Get("Cache-Control"))
+
+		// The compressed responses have a weak entity tag.
+		s.Equal(http.StatusNotModified, s.get(target, "If-None-Match", `"other", W/`+etag).Code)
+		s.Equal(http.StatusNotModified, s.get(target, "If-None-Match", "*").Code)
+
+		since := n.GetCreatedTime().Add(time.Second).UTC().Format(http.TimeFormat)
+		s.Equal(http.StatusNotModified, s.get(target, "If-Modified-Since", since).Code)
+	})
+
+	s.Run("Other representations", func() {
+		rec := s.get(target, "Accept", MediaTypeProtobuf, "If-None-Match", etag)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Regexp(`-protobuf"$`, rec.Header().Get("ETag"))
+
+		rec = s.get(target+"?format=html", "If-None-Match", etag)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Regexp(`-html"$`, rec.Header().Get("ETag"))
+	})
+
+	s.Run("Changed note", func() {
+		_, err := s.svc.Update(dummyCtx, noteutil.Copy(n).SetTitle("Changed"))
+		s.Require().NoError(err)
+
+		rec := s.get(target, "If-None-Match", etag)
+		s.Equal(http.StatusOK, rec.Code)
+		s.NotEqual(etag, rec.Header().Get("ETag"))
+
+		since := n.GetCreatedTime().Add(-time.Second).UTC().Format(http.TimeFormat)
+		s.Equal(http.StatusOK, s.get(target, "If-Modified-Since", since).Code)
+	})
+}
+
+func (s *CacheTestSuite) TestFetch() {
+	s.create()
+
+	rec := s.get("/v1/notes")
+	s.Require().Equal(http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	s.NotEmpty(etag)
+	s.NotEmpty(rec.Header().Get("Last-Modified"))
+	s.Equal("private, no-cache", rec.Header().Get("Cache-Control"))
+
+	rec = s.get("/v1/notes", "If-None-Match", etag)
+	s.Equal(http.StatusNotModified, rec.Code)
+	s.Equal(etag, rec.Header().Get("ETag"))
+
+	// Every page of every size has its own entity tag.
+	rec = s.get("/v1/notes?size=5", "If-None-Match", etag)
+	s.Equal(http.StatusOK, rec.Code)
+	s.NotEqual(etag, rec.Header().Get("ETag"))
+
+	rec = s.get("/v1/notes", "If-Modified-Since", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	s.Equal(http.StatusNotModified, rec.Code)
+
+	// A new note changes the version of the notes.
+	s.create()
+	rec = s.get("/v1/notes", "If-None-Match", etag)
+	s.Equal(http.StatusOK, rec.Code)
+	s.NotEqual(etag, rec.Header().Get("ETag"))
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a-json"`, `"a-json"`))
+	assert.True(t, etagMatches(`W/"a-json"`, `"a-json"`))
+	assert.True(t, etagMatches(`"b-json", "a-json"`, `"a-json"`))
+	assert.True(t, etagMatches(` * `, `"a-json"`))
+	assert.False(t, etagMatches(`"a-protobuf"`, `"a-json"`))
+	assert.False(t, etagMatches(`"b-json"`, `"a-json"`))
+}
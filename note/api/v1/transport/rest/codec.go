@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"mime"
+	"net/http"
+	"noterfy/note"
+	"noterfy/pkg/validation"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MediaTypeJSON is the media type of the JSON requests and
+	// responses. It is the default media type.
+	MediaTypeJSON = "application/json"
+	// MediaTypeProtobuf is the media type of the protocol buffer
+	// requests and responses. The messages are defined in note.proto.
+	MediaTypeProtobuf = "application/x-protobuf"
+)
+
+// maxBodySize is the maximum size of the body of a request. A
+// content of note.MaxContentLength characters takes up to 4 bytes
+// per character and the rest leaves room for the other fields.
+const maxBodySize = 4*note.MaxContentLength + 1<<16
+
+// errBodyTooLarge is an error when the body of a request is
+// larger than maxBodySize.
+var errBodyTooLarge = errors.New("rest: request body too large")
+
+// protoResponse is implemented by the responses that can be
+// encoded to a protocol buffer message.
+type protoResponse interface {
+	toProto() proto.Message
+}
+
+// negotiate returns the media type of the response to r from its
+// Accept header. It returns MediaTypeJSON when the header is missing
+// or doesn't accept any of the supported media types.
+func negotiate(r *http.Request) string {
+	if r == nil {
+		return MediaTypeJSON
+	}
+
+	accept := r.Header.Values("Accept")
+	best, bestQ := MediaTypeJSON, 0.0
+	for _, mediaType := range []string{MediaTypeJSON, MediaTypeProtobuf} {
+		if q := acceptQuality(accept, mediaType); q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality of mediaType in the values of
+// the Accept header. The most specific media range matching the
+// media type is used, e.g. "application/*" before "*/*".
+func acceptQuality(accept []string, mediaType string) float64 {
+	quality, specificity := 0.0, -1
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mt, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+
+			var s int
+			switch {
+			case mt == mediaType:
+				s = 2
+			case strings.HasSuffix(mt, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mt, "*")):
+				s = 1
+			case mt == "*/*":
+				s = 0
+			default:
+				continue
+			}
+			if s < specificity {
+				continue
+			}
+
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+			quality, specificity = q, s
+		}
+	}
+	return quality
+}
+
+// isProtobuf tells whether the body of r is a protocol buffer message.
+func isProtobuf(r *http.Request) bool {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mt == MediaTypeProtobuf
+}
+
+// decodeProto decodes the protocol buffer body of r into m.
+func decodeProto(r *http.Request, m proto.Message) (err error) {
+	defer func() {
+		cerr := r.Body.Close()
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	body, err := io.ReadAll(limitBody(r))
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(body, m); err != nil {
+		return validation.Malformed("body", err)
+	}
+	return nil
+}
+
+// limitBody limits the body of r to maxBodySize bytes and returns
+// it. Reading past the limit fails with errBodyTooLarge and makes
+// the server close the connection.
+func limitBody(r *http.Request) io.Reader {
+	r.Body = &limitedBody{
+		ReadCloser: http.MaxBytesReader(nil, r.Body, maxBodySize),
+		n:          maxBodySize,
+	}
+	return r.Body
+}
+
+// limitedBody reports the error of http.MaxBytesReader as
+// errBodyTooLarge.
+type limitedBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n -= int64(n)
+	// The limit is reached only when the body has more bytes.
+	if err != nil && err != io.EOF && b.n <= 0 {
+		err = errBodyTooLarge
+	}
+	return n, err
+}
+
+// malformedBody returns the error of decoding the body of a request.
+// The body over the limit isn't malformed but too large.
+func malformedBody(err error) error {
+	if errors.Is(err, errBodyTooLarge) {
+		return err
+	}
+	return validation.Malformed("body", err)
+}
+
+// encodeProto writes the protocol buffer message of response to w.
+func encodeProto(w http.ResponseWriter, response protoResponse) error {
+	body, err := proto.Marshal(response.toProto())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", MediaTypeProtobuf)
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/noteutil"
+	pb "noterfy/note/proto"
+	"noterfy/note/proto/protoutil"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/problem"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: MediaTypeJSON},
+		{accept: "*/*", want: MediaTypeJSON},
+		{accept: "application/json", want: MediaTypeJSON},
+		{accept: "application/x-protobuf", want: MediaTypeProtobuf},
+		{accept: "application/x-protobuf, application/json;q=0.9", want: MediaTypeProtobuf},
+		{accept: "application/json;q=0.5, application/x-protobuf", want: MediaTypeProtobuf},
+		{accept: "application/x-protobuf;q=0.5, */*", want: MediaTypeJSON},
+		{accept: "application/*;q=0.2, application/x-protobuf;q=0.8", want: MediaTypeProtobuf},
+		{accept: "text/html", want: MediaTypeJSON},
+		{accept: "invalid;;, application/x-protobuf", want: MediaTypeProtobuf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/notes", nil)
+			r.Header.Set("Accept", tt.accept)
+			assert.Equal(t, tt.want, negotiate(r))
+		})
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	svc := service.New(memory.New())
+	handler := makeHandler(svc)
+
+	do := func(method, target string, body proto.Message) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			b, err := proto.Marshal(body)
+			require.NoError(t, err)
+			buf.Write(b)
+		}
+
+		req := httptest.NewRequest(method, target, &buf)
+		req.Header.Set("Accept", MediaTypeProtobuf)
+		if body != nil {
+			req.Header.Set("Content-Type", MediaTypeProtobuf)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, MediaTypeProtobuf, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+		return rec
+	}
+
+	// The note in the create request has no ID.
+	newNote := protoutil.NoteToProto(noteutil.Copy(dummyNote))
+	newNote.Id = nil
+	newNote.CreatedTime = nil
+	newNote.UpdatedTime = nil
+
+	var created pb.NoteResponse
+	rec := do(http.MethodPost, "/note", &pb.CreateRequest{Note: newNote})
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &created))
+	require.NotNil(t, created.Note)
+	assert.NotEmpty(t, created.Note.Id)
+	assert.Equal(t, "Unit Test", created.Note.Title)
+	assert.True(t, created.Note.IsFavorite)
+
+	created.Note.Title = "Updated"
+	var updated pb.NoteResponse
+	rec = do(http.MethodPut, "/note", &pb.UpdateRequest{Note: created.Note})
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.Equal(t, "Updated", updated.Note.Title)
+
+	var got pb.NoteResponse
+	rec = do(http.MethodGet, "/note/"+string(created.Note.Id), nil)
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, created.Note.Id, got.Note.Id)
+	assert.Equal(t, "Updated", got.Note.Title)
+
+	var fetched pb.FetchResponse
+	rec = do(http.MethodGet, "/notes", nil)
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &fetched))
+	require.Len(t, fetched.Notes, 1)
+	assert.Equal(t, created.Note.Id, fetched.Notes[0].Id)
+	assert.Equal(t, uint64(1), fetched.TotalCount)
+
+	var deleted pb.DeleteResponse
+	rec = do(http.MethodDelete, "/note/"+string(created.Note.Id), nil)
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &deleted))
+	assert.Equal(t, "Successfully Deleted", deleted.Message)
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	svc := service.New(memory.New())
+	handler := makeHandler(svc)
+
+	do := func(method, contentType string, body []byte) (int, string) {
+		req := httptest.NewRequest(method, "/note", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var resp problem.Problem
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return rec.Code, resp.Code
+	}
+
+	tooLarge := strings.Repeat("a", maxBodySize)
+	jsonBody, err := json.Marshal(CreateRequest{Note: new(note.Note).SetContent(tooLarge)})
+	require.NoError(t, err)
+	protoBody, err := proto.Marshal(&pb.CreateRequest{Note: &pb.Note{Content: tooLarge}})
+	require.NoError(t, err)
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		code, problemCode := do(method, MediaTypeJSON, jsonBody)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, code, method)
+		assert.Equal(t, CodeRequestTooLarge, problemCode, method)
+
+		code, problemCode = do(method, MediaTypeProtobuf, protoBody)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, code, method)
+		assert.Equal(t, CodeRequestTooLarge, problemCode, method)
+	}
+
+	// The content over the maximum length within the limit
+	// of the body is invalid.
+	content := strings.Repeat("a", note.MaxContentLength+1)
+	jsonBody, err = json.Marshal(CreateRequest{Note: new(note.Note).SetContent(content)})
+	require.NoError(t, err)
+	code, _ := do(http.MethodPost, MediaTypeJSON, jsonBody)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
@@ -3,11 +3,12 @@ package rest
 import (
 	"context"
 	"encoding/json"
-	"github.com/sirupsen/logrus"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"net/http"
-	"noterfy/note"
-	"noterfy/pkg/util/errorutil"
-	"strconv"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/problem"
+	"noterfy/pkg/validation"
 )
 
 // StatusClientClosed is an http status where the client cancels a request.
@@ -15,84 +16,120 @@ const StatusClientClosed = 499
 
 func newErrorWrapper(err error) errorWrapper {
 	return errorWrapper{
-		origErr:    err,
-		message:    getMessage(err),
-		statusCode: getStatusCode(err),
+		origErr: err,
+		kind:    errorKind(err),
 	}
 }
 
+// errorWrapper is the response of the endpoints that fail. It
+// is encoded as the problem of its kind.
 type errorWrapper struct {
-	origErr    error
-	message    string
-	statusCode int
+	origErr error
+	kind    problem.Kind
 }
 
 func (e errorWrapper) error() error {
-	return errorutil.TryUnwrapErr(e.origErr)
+	return e.origErr
 }
 
 func (e errorWrapper) Error() string {
 	return e.origErr.Error()
 }
 
-func encodeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+// rawResponse is implemented by the responses that are not
+// encoded to JSON and are written as is.
+type rawResponse interface {
+	// ContentType returns the content type of the body.
+	ContentType() string
+	// Body returns the raw body of the response.
+	Body() []byte
+}
+
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	e, ok := response.(errorWrapper)
 	if ok && e.error() != nil {
-		encodeError(e, w)
+		encodeError(ctx, e, w)
+		return nil
+	}
+
+	r := requestFromContext(ctx)
+
+	if nm, ok := response.(notModifiedResponse); ok {
+		w.Header().Add("Vary", "Accept")
+		writeCacheHeaders(w, nm.cacheHeaders, nm.mediaType)
+		w.WriteHeader(http.StatusNotModified)
 		return nil
 	}
 
+	mediaType := MediaTypeJSON
+	switch resp := response.(type) {
+	case rawResponse:
+		mediaType = resp.ContentType()
+	case protoResponse:
+		// The responses in protocol buffer are negotiated
+		// with the Accept header of the request.
+		w.Header().Add("Vary", "Accept")
+		mediaType = negotiate(r)
+	}
+
+	if c, ok := response.(cacheable); ok {
+		writeCacheHeaders(w, c.cache(), mediaType)
+		if notModified(r, c.cache(), mediaType) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	if raw, ok := response.(rawResponse); ok {
+		w.Header().Set("Content-Type", raw.ContentType())
+		_, err := w.Write(raw.Body())
+		return err
+	}
+
+	if pr, ok := response.(protoResponse); ok && mediaType == MediaTypeProtobuf {
+		return encodeProto(w, pr)
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(w).Encode(response)
 }
 
-func encodeError(ew errorWrapper, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-	w.WriteHeader(ew.statusCode)
-
-	logrus.Error(ew.origErr)
+// decodeID parses the UUID in the path variable name of r. It
+// returns a *validation.Error when the UUID is missing or malformed.
+func decodeID(r *http.Request, name string) (uuid.UUID, error) {
+	var v validation.Validator
+	id := v.UUID(name, mux.Vars(r)[name])
+	return id, v.Err()
+}
 
-	_ = json.NewEncoder(w).Encode(ResponseError{
-		Message: ew.message,
-	})
+// encodeTransportError encodes the errors returned by the decoders
+// in the same format as the errors returned by the endpoints.
+func encodeTransportError(ctx context.Context, err error, w http.ResponseWriter) {
+	encodeError(ctx, newErrorWrapper(err), w)
 }
 
-func getStatusCode(err error) (statusCode int) {
-	err = errorutil.TryUnwrapErr(err)
-	switch err {
-	case note.ErrNotFound:
-		statusCode = http.StatusNotFound
-	case note.ErrNilID:
-		statusCode = http.StatusBadRequest
-	case note.ErrExists:
-		statusCode = http.StatusConflict
-	case note.ErrCancelled:
-		statusCode = StatusClientClosed
-	default:
-		statusCode = http.StatusInternalServerError
+// encodeError writes the error of ew as a problem+json response.
+// The instance of the problem is the request in ctx, if any.
+func encodeError(ctx context.Context, ew errorWrapper, w http.ResponseWriter) {
+	logger.FromContext(ctx).WithField("status", ew.kind.Status).Error(ew.origErr)
+
+	p := problem.FromError(ew.origErr, ew.kind)
+	if r := requestFromContext(ctx); r != nil {
+		p.Instance = r.URL.RequestURI()
 	}
-	return
+	problem.Write(w, p)
 }
 
-func getMessage(err error) (message string) {
-	causeErr := errorutil.TryUnwrapErr(err)
-	switch causeErr {
-	case note.ErrExists:
-		message = "Note already exists"
-	case note.ErrCancelled, context.Canceled:
-		message = "Request cancelled"
-	case note.ErrNotFound:
-		message = "Note not found"
-	case note.ErrNilID:
-		message = "Empty note identifier"
-	default:
-		message = "Unexpected error"
-	}
-	return
+type requestContextKey struct{}
+
+// withRequest is a go-kit ServerBefore function that stores the
+// http request in the context for the encoders that need it.
+func withRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, r)
 }
 
-func convertAtoU(s string) uint64 {
-	v, _ := strconv.Atoi(s)
-	return uint64(v)
+// requestFromContext gets the http request stored by withRequest.
+func requestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestContextKey{}).(*http.Request)
+	return r
 }
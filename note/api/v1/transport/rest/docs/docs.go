@@ -32,14 +32,50 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/graph": {
+            "get": {
+                "description": "Get all the notes as nodes and the resolved links between them as edges for visualisation.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the graph of the notes.",
+                "responses": {
+                    "200": {
+                        "description": "Successfully getting the graph",
+                        "schema": {
+                            "$ref": "#/definitions/rest.GraphResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/links/broken": {
+            "get": {
+                "description": "Get all the links that don't resolve to any note.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the broken links report.",
+                "responses": {
+                    "200": {
+                        "description": "Successfully getting the broken links",
+                        "schema": {
+                            "$ref": "#/definitions/rest.BrokenLinksResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/note": {
             "put": {
-                "description": "Updating an existing note. If the note to be updated is not found the API will respond a NotFound status.",
+                "description": "Updating an existing note. If the note to be updated is not found the API will respond a NotFound status. The attachments, is_archived and archived_time fields are ignored.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/x-protobuf"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-protobuf"
                 ],
                 "summary": "Update an existing note.",
                 "parameters": [
@@ -60,32 +96,64 @@ var doc = `{
                             "$ref": "#/definitions/rest.UpdateResponse"
                         }
                     },
+                    "400": {
+                        "description": "The body is malformed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
                     "404": {
                         "description": "Note to be update is not found in the service",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "The body exceeds the maximum size",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "The note has invalid fields such as a title that is too long",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "499": {
                         "description": "Cancel error when the request was aborted",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Creating a new note. The client can assign the note ID with a UUID value but the service will return a conflict error when the note with the ID provided is already exists.",
+                "description": "Creating a new note. The client can assign the note ID with a UUID value but the service will return a conflict error when the note with the ID provided is already exists. When the template is given, the title and the content are rendered from the template and the fields of the note in the body take precedence. The updated_time, attachments, is_archived and archived_time fields are ignored.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/x-protobuf"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-protobuf"
                 ],
                 "summary": "Create a new note.",
                 "parameters": [
                     {
-                        "description": "A body containing the new note",
+                        "type": "string",
+                        "description": "Name of the template to create the note from",
+                        "name": "template",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The user creating the note that is available to the template as {{user}}. It is ignored when the request is authenticated, then the user is the authenticated client",
+                        "name": "X-User",
+                        "in": "header"
+                    },
+                    {
+                        "description": "A body containing the new note and the values of the template prompts",
                         "name": "CreateRequest",
                         "in": "body",
                         "required": true,
@@ -101,16 +169,40 @@ var doc = `{
                             "$ref": "#/definitions/rest.CreateResponse"
                         }
                     },
+                    "400": {
+                        "description": "The body is malformed or the template prompt has no value",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Template is not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
                     "409": {
                         "description": "Conflict error due to the new note with an ID already exists in the service",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "The body exceeds the maximum size",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "The note has invalid fields such as a title that is too long",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "499": {
                         "description": "Cancel error when the request was aborted",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
@@ -119,6 +211,11 @@ var doc = `{
         "/note/{id}": {
             "get": {
                 "description": "Get the note from the service if exists. When the note is not exists it will return a NotFound response status.",
+                "produces": [
+                    "application/json",
+                    "text/html",
+                    "application/x-protobuf"
+                ],
                 "summary": "Get the note from the service.",
                 "parameters": [
                     {
@@ -127,6 +224,24 @@ var doc = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The format of the response. When format=html the note content is rendered from Markdown to a sanitized HTML. The format can also be negotiated with the 'Accept: text/html' header. Default is format=json. [json/html]",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The ETag of the note the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The Last-Modified time of the note the client has",
+                        "name": "If-Modified-Since",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -136,34 +251,44 @@ var doc = `{
                             "$ref": "#/definitions/rest.GetResponse"
                         }
                     },
+                    "304": {
+                        "description": "The note didn't change",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "400": {
-                        "description": "Note's ID parameter is not provided in the path",
+                        "description": "The ID of the note is not a UUID",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Note is not found in the service",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "499": {
                         "description": "Cancel error when the request was aborted",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Unexpected server internal error",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "delete": {
                 "description": "Delete an existing note.",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
                 "summary": "Delete an existing note.",
                 "parameters": [
                     {
@@ -182,135 +307,1224 @@ var doc = `{
                         }
                     },
                     "400": {
-                        "description": "Note's ID parameter is not provided in the path",
+                        "description": "The ID of the note is not a UUID",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "499": {
                         "description": "Cancel error when the request was aborted",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Unexpected server internal error",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/notes": {
-            "get": {
-                "description": "Fetches notes from the service.",
+            },
+            "patch": {
+                "description": "Patching an existing note with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902). The fields set to null by a merge patch or removed by a JSON Patch are cleared. The patch is applied atomically and a failed JSON Patch test operation leaves the note unchanged. The id, created_time, updated_time, attachments, is_archived and archived_time fields are read-only.",
                 "consumes": [
-                    "application/json"
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-protobuf"
                 ],
-                "summary": "Fetches notes from the service.",
+                "summary": "Patch an existing note.",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "The page number of the fetch pagination. Default is page=1.",
-                        "name": "page",
-                        "in": "query"
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "The page size of the fetch pagination. Default is size=25.",
-                        "name": "size",
-                        "in": "query"
+                        "description": "The patch document, e.g. {\\",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully patched the note",
+                        "schema": {
+                            "$ref": "#/definitions/rest.PatchResponse"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "An option for sorting the notes in the response. Default is sort_by=title. [title/id/created_date]",
-                        "name": "sort_by",
-                        "in": "query"
+                    "400": {
+                        "description": "The ID of the note is not a UUID or the patch is malformed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note to be patched is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A test operation of the JSON Patch failed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "The media type of the patch is not supported",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "The patch doesn't apply, changes a read-only field or makes the note invalid",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
+                    "499": {
+                        "description": "Cancel error when the request was aborted",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/note/{id}/archive": {
+            "post": {
+                "description": "Archive a note so it is hidden from the default listing of the notes. The archived note is kept and can be restored.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Archive a note.",
+                "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "An option for sorting the results in ascending or descending. Default is ascending=true",
-                        "name": "ascending",
-                        "in": "query"
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully fetches notes",
+                        "description": "Successfully archived the note",
                         "schema": {
-                            "$ref": "#/definitions/rest.FetchResponse"
+                            "$ref": "#/definitions/rest.ArchiveResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note is not a UUID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "499": {
                         "description": "Cancel error when the request was aborted",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Unexpected server internal error",
                         "schema": {
-                            "$ref": "#/definitions/rest.ResponseError"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "note.Note": {
-            "type": "object",
-            "properties": {
-                "content": {
-                    "description": "Content is the content of the note",
-                    "type": "string",
-                    "example": "Writing an effective note is hard"
-                },
-                "created_time": {
-                    "description": "CreatedTime is the timestamp when the note was created.",
-                    "type": "string",
-                    "example": "2016-02-24 11:12:13"
-                },
-                "id": {
-                    "description": "ID is a unique identifier UUID of the note.",
-                    "type": "string",
-                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
-                },
-                "is_favorite": {
-                    "description": "IsFavorite is a flag when then the note is marked as favorite",
-                    "type": "boolean",
-                    "example": true
-                },
-                "title": {
-                    "description": "Title is the title of the note",
-                    "type": "string",
-                    "example": "How to Write a Note"
-                },
-                "updated_time": {
-                    "description": "UpdateTime is the timestamp when the note last updated.",
-                    "type": "string",
-                    "example": "2016-02-24 11:12:13"
-                }
-            }
         },
-        "rest.CreateRequest": {
-            "type": "object",
-            "properties": {
-                "note": {
-                    "$ref": "#/definitions/note.Note"
+        "/note/{id}/attachments": {
+            "post": {
+                "description": "Upload a file as an attachment to an existing note. The file is sent as a multipart form with the \"file\" field. The media type of the attachment is sniffed from its content.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Upload an attachment to a note.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "The file to attach",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully uploaded the attachment",
+                        "schema": {
+                            "$ref": "#/definitions/rest.AddAttachmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note is not a UUID or the request has no file",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "The attachment exceeds the maximum size",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "499": {
+                        "description": "Cancel error when the request was aborted",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
                 }
             }
         },
-        "rest.CreateResponse": {
-            "type": "object",
-            "properties": {
-                "note": {
-                    "$ref": "#/definitions/note.Note"
-                }
+        "/note/{id}/attachments/{attachment_id}": {
+            "get": {
+                "description": "Download the content of an attachment. Partial downloads are supported through the Range header.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "summary": "Download an attachment of a note.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the attachment",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The byte range of the content to download",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The content of the attachment",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "The requested range of the content of the attachment",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note or the attachment is not a UUID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note or attachment is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "416": {
+                        "description": "The requested range is not satisfiable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "499": {
+                        "description": "Cancel error when the request was aborted",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an attachment of a note. The content is removed once it isn't referenced by any other attachment.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete an attachment of a note.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the attachment",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted the attachment",
+                        "schema": {
+                            "$ref": "#/definitions/rest.RemoveAttachmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note or the attachment is not a UUID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note or attachment is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "499": {
+                        "description": "Cancel error when the request was aborted",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/note/{id}/backlinks": {
+            "get": {
+                "description": "Get the notes that link to the note.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the backlinks of a note.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully getting the backlinks",
+                        "schema": {
+                            "$ref": "#/definitions/rest.BacklinksResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note is not a UUID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/note/{id}/links": {
+            "get": {
+                "description": "Get the wiki-style links such as [[Other Note]] written in the content of the note. A link target can either be the title or the ID of the linked note.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the outgoing links of a note.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully getting the links",
+                        "schema": {
+                            "$ref": "#/definitions/rest.LinksResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note is not a UUID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/note/{id}/move": {
+            "post": {
+                "description": "Place the note between two adjacent notes in the manual order. Only the moved note gets a new position so the other notes are not renumbered. Omit \"before\" to move the note to the end and omit \"after\" to move the note to the start.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Move a note in the manual order.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the note to move",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the note that will be placed right after the moved note",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the note that will be placed right before the moved note",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully moved the note",
+                        "schema": {
+                            "$ref": "#/definitions/rest.MoveResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note is not a UUID or the move is invalid such as moving the note relative to itself",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "499": {
+                        "description": "Cancel error when the request was aborted",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/note/{id}/unarchive": {
+            "post": {
+                "description": "Restore an archived note so it is listed again.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Restore an archived note.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the note",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully restored the note",
+                        "schema": {
+                            "$ref": "#/definitions/rest.UnarchiveResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The ID of the note is not a UUID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Note is not found in the service",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "499": {
+                        "description": "Cancel error when the request was aborted",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes": {
+            "get": {
+                "description": "Fetches notes from the service. The notes are in protocol buffer with the 'Accept: application/x-protobuf' header. The response has an ETag and a Last-Modified header that change with the notes, so the client can poll with If-None-Match or If-Modified-Since and receive a 304 Not Modified when nothing changed.",
+                "produces": [
+                    "application/json",
+                    "application/x-protobuf"
+                ],
+                "summary": "Fetches notes from the service.",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "The page number of the fetch pagination. Default is page=1.",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The page size of the fetch pagination. Default is size=25.",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "An option for sorting the notes in the response. Default is sort_by=title. [title/id/created_date/manual]. The manual order places the pinned notes first.",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "An option for sorting the results in ascending or descending. Default is ascending=true",
+                        "name": "ascending",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "An option for listing the archived notes. Default is archived=exclude. [exclude/include/only]",
+                        "name": "archived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The ETag of the notes the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The Last-Modified time of the notes the client has",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully fetches notes",
+                        "schema": {
+                            "$ref": "#/definitions/rest.FetchResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "The notes didn't change",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "The page or the size is not a number",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "The page or the size is out of bounds",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "499": {
+                        "description": "Cancel error when the request was aborted",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reminders/events": {
+            "get": {
+                "description": "Stream the fired reminder events as server-sent events. Each event is named \"reminder\" and its data is the JSON encoded reminder.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "summary": "Stream the reminder events.",
+                "responses": {
+                    "200": {
+                        "description": "The stream of the reminder events",
+                        "schema": {
+                            "$ref": "#/definitions/reminder.Event"
+                        }
+                    },
+                    "500": {
+                        "description": "Streaming is not supported",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reminders/upcoming": {
+            "get": {
+                "description": "Get the pending reminders of the notes sorted by their reminder time.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the upcoming reminders.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only the reminders that fire within the duration from now such as 24h. Default is all the pending reminders.",
+                        "name": "within",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The maximum number of reminders. Default is no limit.",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully getting the upcoming reminders",
+                        "schema": {
+                            "$ref": "#/definitions/rest.UpcomingRemindersResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates": {
+            "get": {
+                "description": "List all the note templates sorted by name.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the note templates.",
+                "responses": {
+                    "200": {
+                        "description": "Successfully listed the templates",
+                        "schema": {
+                            "$ref": "#/definitions/rest.ListTemplatesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a named note template. The title and the content are Go text/template sources restricted to the {{date}}, {{time}}, {{user}}, {{prompt \"name\"}}, {{upper}}, {{lower}} and {{trim}} functions and the comparison builtins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a note template.",
+                "parameters": [
+                    {
+                        "description": "A body containing the new template",
+                        "name": "CreateTemplateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.CreateTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully created the template",
+                        "schema": {
+                            "$ref": "#/definitions/rest.CreateTemplateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The template is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A template with the same name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "The body exceeds the maximum size",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{name}": {
+            "get": {
+                "description": "Get the note template with the name.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a note template.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the template",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully getting the template",
+                        "schema": {
+                            "$ref": "#/definitions/rest.GetTemplateResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template is not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the title, the content and the description of the note template with the name.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update a note template.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the template",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "A body containing the updated template",
+                        "name": "UpdateTemplateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.UpdateTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated the template",
+                        "schema": {
+                            "$ref": "#/definitions/rest.UpdateTemplateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "The template is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Template is not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "The body exceeds the maximum size",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete the note template with the name. The notes created from the template are not affected.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a note template.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the template",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted the template",
+                        "schema": {
+                            "$ref": "#/definitions/rest.DeleteTemplateResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template is not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected server internal error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "link.Backlink": {
+            "type": "object",
+            "properties": {
+                "note_id": {
+                    "description": "NoteID is the ID of the note that contains the link.",
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "title": {
+                    "description": "Title is the title of the note that contains the link.",
+                    "type": "string",
+                    "example": "How to Write a Note"
+                }
+            }
+        },
+        "link.BrokenLink": {
+            "type": "object",
+            "properties": {
+                "source": {
+                    "description": "Source is the ID of the note that contains the link.",
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "target": {
+                    "description": "Target is the raw target of the link as written in the content.",
+                    "type": "string",
+                    "example": "Missing Note"
+                }
+            }
+        },
+        "link.Edge": {
+            "type": "object",
+            "properties": {
+                "source": {
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "target": {
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                }
+            }
+        },
+        "link.Link": {
+            "type": "object",
+            "properties": {
+                "broken": {
+                    "description": "Broken indicates that the target doesn't resolve to any note.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "note_id": {
+                    "description": "NoteID is the ID of the linked note. It is a nil UUID when\nthe link is broken.",
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "target": {
+                    "description": "Target is the raw target of the link as written in the content.",
+                    "type": "string",
+                    "example": "Other Note"
+                },
+                "title": {
+                    "description": "Title is the title of the linked note.",
+                    "type": "string",
+                    "example": "Other Note"
+                }
+            }
+        },
+        "link.Node": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "How to Write a Note"
+                }
+            }
+        },
+        "note.Attachment": {
+            "type": "object",
+            "properties": {
+                "created_time": {
+                    "description": "CreatedTime is the timestamp when the attachment was uploaded.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "digest": {
+                    "description": "Digest is the hex-encoded SHA-256 checksum of the attachment content.",
+                    "type": "string",
+                    "example": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+                },
+                "id": {
+                    "description": "ID is a unique identifier UUID of the attachment.",
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "media_type": {
+                    "description": "MediaType is the sniffed MIME type of the attachment.",
+                    "type": "string",
+                    "example": "image/png"
+                },
+                "name": {
+                    "description": "Name is the original filename of the attachment.",
+                    "type": "string",
+                    "example": "diagram.png"
+                },
+                "size": {
+                    "description": "Size is the size of the attachment in bytes.",
+                    "type": "integer",
+                    "example": 1024
+                }
+            }
+        },
+        "note.Note": {
+            "type": "object",
+            "properties": {
+                "archived_time": {
+                    "description": "ArchivedTime is the timestamp when the note was archived.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "attachments": {
+                    "description": "Attachments are the files attached to the note.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/note.Attachment"
+                    }
+                },
+                "content": {
+                    "description": "Content is the content of the note",
+                    "type": "string",
+                    "example": "Writing an effective note is hard"
+                },
+                "created_time": {
+                    "description": "CreatedTime is the timestamp when the note was created.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "due_time": {
+                    "description": "DueTime is the timestamp when the note is due.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "id": {
+                    "description": "ID is a unique identifier UUID of the note.",
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "is_archived": {
+                    "description": "IsArchived is a flag when the note is archived. The archived\nnotes are hidden from the default listing but are not deleted.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "is_favorite": {
+                    "description": "IsFavorite is a flag when then the note is marked as favorite",
+                    "type": "boolean",
+                    "example": true
+                },
+                "is_pinned": {
+                    "description": "IsPinned is a flag when the note is pinned on top of the\nmanually ordered notes.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "position": {
+                    "description": "Position is the fractional key of the note in the manual order.\nA note without a position is placed after the positioned notes.",
+                    "type": "number",
+                    "example": 1.5
+                },
+                "remind_at": {
+                    "description": "RemindAt is the timestamp when the reminder of the note will fire.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "title": {
+                    "description": "Title is the title of the note",
+                    "type": "string",
+                    "example": "How to Write a Note"
+                },
+                "updated_time": {
+                    "description": "UpdateTime is the timestamp when the note last updated.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is the stable machine-readable code of the problem type.",
+                    "type": "string",
+                    "example": "note.not_found"
+                },
+                "detail": {
+                    "description": "Detail is the explanation of this occurrence of the problem.",
+                    "type": "string",
+                    "example": "No note has the ID ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "instance": {
+                    "description": "Instance is the URI of the request with the problem.",
+                    "type": "string",
+                    "example": "/v1/note/ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "status": {
+                    "description": "Status is the http status code of the response.",
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "description": "Title is the short human-readable summary of the problem type.",
+                    "type": "string",
+                    "example": "Note not found"
+                },
+                "type": {
+                    "description": "Type is the URI identifying the problem type.",
+                    "type": "string",
+                    "example": "urn:noterfy:problem:note.not_found"
+                },
+                "violations": {
+                    "description": "Violations are the invalid fields of the request.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/validation.Violation"
+                    }
+                }
+            }
+        },
+        "reminder.Event": {
+            "type": "object",
+            "properties": {
+                "due_time": {
+                    "description": "DueTime is the timestamp when the note is due.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "note_id": {
+                    "description": "NoteID is the ID of the note of the reminder.",
+                    "type": "string",
+                    "example": "ffffffff-ffff-ffff-ffff-ffffffffffff"
+                },
+                "remind_at": {
+                    "description": "RemindAt is the timestamp when the reminder fires.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "title": {
+                    "description": "Title is the title of the note of the reminder.",
+                    "type": "string",
+                    "example": "How to Write a Note"
+                }
+            }
+        },
+        "rest.AddAttachmentResponse": {
+            "type": "object",
+            "properties": {
+                "attachment": {
+                    "$ref": "#/definitions/note.Attachment"
+                }
+            }
+        },
+        "rest.ArchiveResponse": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "$ref": "#/definitions/note.Note"
+                }
+            }
+        },
+        "rest.BacklinksResponse": {
+            "type": "object",
+            "properties": {
+                "backlinks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/link.Backlink"
+                    }
+                }
+            }
+        },
+        "rest.BrokenLinksResponse": {
+            "type": "object",
+            "properties": {
+                "broken_links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/link.BrokenLink"
+                    }
+                }
+            }
+        },
+        "rest.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "$ref": "#/definitions/note.Note"
+                },
+                "variables": {
+                    "description": "Variables are the values of the prompts of the template.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "rest.CreateResponse": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "$ref": "#/definitions/note.Note"
+                }
+            }
+        },
+        "rest.CreateTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "template": {
+                    "$ref": "#/definitions/template.Template"
+                }
+            }
+        },
+        "rest.CreateTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "template": {
+                    "$ref": "#/definitions/template.Template"
+                }
+            }
+        },
+        "rest.DeleteTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
             }
         },
         "rest.FetchResponse": {
@@ -340,12 +1554,93 @@ var doc = `{
                 }
             }
         },
-        "rest.ResponseError": {
+        "rest.GetTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "template": {
+                    "$ref": "#/definitions/template.Template"
+                }
+            }
+        },
+        "rest.GraphResponse": {
+            "type": "object",
+            "properties": {
+                "edges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/link.Edge"
+                    }
+                },
+                "nodes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/link.Node"
+                    }
+                }
+            }
+        },
+        "rest.LinksResponse": {
+            "type": "object",
+            "properties": {
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/link.Link"
+                    }
+                }
+            }
+        },
+        "rest.ListTemplatesResponse": {
+            "type": "object",
+            "properties": {
+                "templates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/template.Template"
+                    }
+                }
+            }
+        },
+        "rest.MoveResponse": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "$ref": "#/definitions/note.Note"
+                }
+            }
+        },
+        "rest.PatchResponse": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "$ref": "#/definitions/note.Note"
+                }
+            }
+        },
+        "rest.RemoveAttachmentResponse": {
             "type": "object",
             "properties": {
                 "message": {
-                    "type": "string",
-                    "example": "Note not found"
+                    "type": "string"
+                }
+            }
+        },
+        "rest.UnarchiveResponse": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "$ref": "#/definitions/note.Note"
+                }
+            }
+        },
+        "rest.UpcomingRemindersResponse": {
+            "type": "object",
+            "properties": {
+                "reminders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reminder.Event"
+                    }
                 }
             }
         },
@@ -364,6 +1659,87 @@ var doc = `{
                     "$ref": "#/definitions/note.Note"
                 }
             }
+        },
+        "rest.UpdateTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "template": {
+                    "$ref": "#/definitions/template.Template"
+                }
+            }
+        },
+        "rest.UpdateTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "template": {
+                    "$ref": "#/definitions/template.Template"
+                }
+            }
+        },
+        "template.Template": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "description": "Content is the template of the note content.",
+                    "type": "string",
+                    "example": "Reported by {{user}}. Severity: {{prompt \"severity\"}}"
+                },
+                "created_time": {
+                    "description": "CreatedTime is the timestamp when the template was created.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                },
+                "description": {
+                    "description": "Description is the description of the template.",
+                    "type": "string",
+                    "example": "Incident report"
+                },
+                "name": {
+                    "description": "Name is the unique name of the template.",
+                    "type": "string",
+                    "example": "incident"
+                },
+                "prompts": {
+                    "description": "Prompts are the variables asked by the template. They are\ncollected from the template when it is saved.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "severity"
+                    ]
+                },
+                "title": {
+                    "description": "Title is the template of the note title.",
+                    "type": "string",
+                    "example": "Incident {{date}}"
+                },
+                "updated_time": {
+                    "description": "UpdatedTime is the timestamp when the template last updated.",
+                    "type": "string",
+                    "example": "2016-02-24 11:12:13"
+                }
+            }
+        },
+        "validation.Violation": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code identifies the kind of the violation, e.g. CodeTooLong.",
+                    "type": "string",
+                    "example": "too_long"
+                },
+                "field": {
+                    "description": "Field is the name of the field, e.g. \"title\" or \"page\".",
+                    "type": "string",
+                    "example": "title"
+                },
+                "message": {
+                    "description": "Message tells why the field is invalid.",
+                    "type": "string",
+                    "example": "must be at most 256 characters, got 300"
+                }
+            }
         }
     },
     "tags": [
@@ -390,7 +1766,7 @@ var SwaggerInfo = swaggerInfo{
 	BasePath:    "/v1",
 	Schemes:     []string{"http", "https"},
 	Title:       "Noterfy Note Service",
-	Description: "Noterfy Note Service.",
+	Description: "Noterfy Note Service. The error responses are problem details (RFC 7807) in application/problem+json with a stable \"code\" such as \"note.not_found\".",
 }
 
 type s struct{}
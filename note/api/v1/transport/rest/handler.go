@@ -7,14 +7,24 @@ import (
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"mime"
 	"net/http"
 	"noterfy/note"
+	"noterfy/note/noteutil"
+	pb "noterfy/note/proto"
+	"noterfy/note/proto/protoutil"
+	"noterfy/note/render"
+	"noterfy/note/template"
+	"noterfy/pkg/validation"
 	"strconv"
+	"strings"
 )
 
 // @title Noterfy Note Service
 // @version 0.2.1
-// @description  Noterfy Note Service.
+// @description  Noterfy Note Service. The error responses are problem details (RFC 7807) in application/problem+json with a stable "code" such as "note.not_found".
 // @termsOfService http://swagger.io/terms/
 //
 // @contact.name Jayson Vibandor
@@ -37,39 +47,62 @@ import (
 // handlers and return the routed handler.
 func makeHandler(svc note.Service) http.Handler {
 	router := mux.NewRouter()
+	renderer := render.New()
+	svc = render.Middleware(renderer)(svc)
+	cache := (*CacheConfig)(nil).check()
+
 	getHandler := httptransport.NewServer(
-		makeGetEndpoint(svc),
+		makeGetEndpoint(svc, renderer, cache.CacheControl),
 		decodeGetRequest,
 		encodeResponse,
+		httptransport.ServerBefore(withRequest),
+		httptransport.ServerErrorEncoder(encodeTransportError),
 	)
 
 	createHandler := httptransport.NewServer(
-		makeCreateEndpoint(svc),
+		makeCreateEndpoint(svc, nil),
 		decodeCreateRequest,
 		encodeResponse,
+		httptransport.ServerBefore(withRequest),
+		httptransport.ServerErrorEncoder(encodeTransportError),
 	)
 
 	updateHandler := httptransport.NewServer(
 		makeUpdateEndpoint(svc),
 		decodeUpdateRequest,
 		encodeResponse,
+		httptransport.ServerBefore(withRequest),
+		httptransport.ServerErrorEncoder(encodeTransportError),
+	)
+
+	patchHandler := httptransport.NewServer(
+		makePatchEndpoint(svc),
+		decodePatchRequest,
+		encodeResponse,
+		httptransport.ServerBefore(withRequest),
+		httptransport.ServerErrorEncoder(encodeTransportError),
 	)
 
 	deleteHandler := httptransport.NewServer(
 		makeDeleteEndpoint(svc),
 		decodeDeleteRequest,
 		encodeResponse,
+		httptransport.ServerBefore(withRequest),
+		httptransport.ServerErrorEncoder(encodeTransportError),
 	)
 
 	fetchHandler := httptransport.NewServer(
-		makeFetchEndpoint(svc),
+		makeFetchEndpoint(svc, cache),
 		decodeFetchRequest,
 		encodeResponse,
+		httptransport.ServerBefore(withRequest),
+		httptransport.ServerErrorEncoder(encodeTransportError),
 	)
 
 	router.Handle("/note/{id}", getHandler).Methods(http.MethodGet)
 	router.Handle("/note", createHandler).Methods(http.MethodPost)
 	router.Handle("/note", updateHandler).Methods(http.MethodPut)
+	router.Handle("/note/{id}", patchHandler).Methods(http.MethodPatch)
 	router.Handle("/note/{id}", deleteHandler).Methods(http.MethodDelete)
 	router.Handle("/notes", fetchHandler).Methods(http.MethodGet)
 
@@ -79,6 +112,12 @@ func makeHandler(svc note.Service) http.Handler {
 // CreateRequest is a container for the create request.
 type CreateRequest struct {
 	Note *note.Note `json:"note"`
+	// Variables are the values of the prompts of the template.
+	Variables map[string]string `json:"variables,omitempty"`
+	// Template is the name of the template to create the note from.
+	Template string `json:"-"`
+	// User is the user creating the note.
+	User string `json:"-"`
 }
 
 // CreateResponse is a container fo a successful create response.
@@ -86,11 +125,35 @@ type CreateResponse struct {
 	Note *note.Note `json:"note"`
 }
 
+func (r CreateResponse) toProto() proto.Message {
+	return &pb.NoteResponse{Note: noteToProto(r.Note)}
+}
+
 func decodeCreateRequest(_ context.Context, r *http.Request) (response interface{}, err error) {
-	var req CreateRequest
-	err = json.NewDecoder(r.Body).Decode(&req)
+	req := CreateRequest{
+		Template: r.URL.Query().Get("template"),
+		User:     requestUser(r),
+	}
+
+	if isProtobuf(r) {
+		var msg pb.CreateRequest
+		if err := decodeProto(r, &msg); err != nil {
+			return nil, err
+		}
+		if req.Note, err = protoToNote(msg.Note); err != nil {
+			return nil, err
+		}
+		req.Variables = msg.Variables
+		return req, nil
+	}
+
+	err = json.NewDecoder(limitBody(r)).Decode(&req)
+	// The body is optional when creating the note from a template.
+	if err == io.EOF && req.Template != "" {
+		err = nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, malformedBody(err)
 	}
 	defer func() {
 		cerr := r.Body.Close()
@@ -104,29 +167,66 @@ func decodeCreateRequest(_ context.Context, r *http.Request) (response interface
 
 // CreateRequest godoc
 // @Summary Create a new note.
-// @Description Creating a new note. The client can assign the note ID with a UUID value but the service will return a conflict error when the note with the ID provided is already exists.
+// @Description Creating a new note. The client can assign the note ID with a UUID value but the service will return a conflict error when the note with the ID provided is already exists. When the template is given, the title and the content are rendered from the template and the fields of the note in the body take precedence. The updated_time, attachments, is_archived and archived_time fields are ignored.
 // @Accept json
+// @Accept application/x-protobuf
 // @Produce json
-// @Param CreateRequest body CreateRequest true "A body containing the new note"
+// @Produce application/x-protobuf
+// @Param template query string false "Name of the template to create the note from"
+// @Param X-User header string false "The user creating the note that is available to the template as {{user}}. It is ignored when the request is authenticated, then the user is the authenticated client"
+// @Param CreateRequest body CreateRequest true "A body containing the new note and the values of the template prompts"
 // @Success 200 {object} CreateResponse "Successfully created a new note"
-// @Failure 409 {object} ResponseError "Conflict error due to the new note with an ID already exists in the service"
-// @Failure 499 {object} ResponseError "Cancel error when the request was aborted"
+// @Failure 400 {object} problem.Problem "The body is malformed or the template prompt has no value"
+// @Failure 404 {object} problem.Problem "Template is not found"
+// @Failure 409 {object} problem.Problem "Conflict error due to the new note with an ID already exists in the service"
+// @Failure 413 {object} problem.Problem "The body exceeds the maximum size"
+// @Failure 422 {object} problem.Problem "The note has invalid fields such as a title that is too long"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
 // @Router /note [post]
-func makeCreateEndpoint(svc createService) endpoint.Endpoint {
+func makeCreateEndpoint(svc createService, templates templateRenderer) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		request := req.(CreateRequest)
-		newNote, err := svc.Create(ctx, request.Note)
+
+		n := request.Note
+		if request.Template != "" {
+			var err error
+			n, err = renderTemplate(ctx, templates, request)
+			if err != nil {
+				return newErrorWrapper(err), nil
+			}
+		}
+
+		newNote, err := svc.Create(ctx, n)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				message:    getMessage(err),
-				statusCode: getStatusCode(err),
-			}, nil
+			return newErrorWrapper(err), nil
 		}
 		return CreateResponse{Note: newNote}, nil
 	}
 }
 
+// renderTemplate renders the template of the request into a note and
+// merges the note from the request body into it.
+func renderTemplate(ctx context.Context, templates templateRenderer, request CreateRequest) (*note.Note, error) {
+	if templates == nil {
+		return nil, template.ErrNotFound
+	}
+
+	n, err := templates.Render(ctx, request.Template, template.Data{
+		User:      request.User,
+		Variables: request.Variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Note != nil {
+		if err := noteutil.Merge(n, request.Note); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
 // DeleteRequest is a container for the delete request.
 type DeleteRequest struct {
 	ID uuid.UUID `json:"id"`
@@ -137,31 +237,35 @@ type DeleteResponse struct {
 	Message string `json:"message"`
 }
 
+func (r DeleteResponse) toProto() proto.Message {
+	return &pb.DeleteResponse{Message: r.Message}
+}
+
 func decodeDeleteRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	return DeleteRequest{ID: uuid.MustParse(id)}, nil
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	return DeleteRequest{ID: id}, nil
 }
 
 // DeleteRequest godoc
 // @Summary Delete an existing note.
 // @Description Delete an existing note.
+// @Produce json
+// @Produce application/x-protobuf
 // @Param id path string true "ID of the note"
 // @Success 200 {string} string "Successful deleting a note"
-// @Failure 400 {object} ResponseError "Note's ID parameter is not provided in the path"
-// @Failure 499 {object} ResponseError "Cancel error when the request was aborted"
-// @Failure 500 {object} ResponseError "Unexpected server internal error"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
 // @Router /note/{id} [delete]
 func makeDeleteEndpoint(svc deleteService) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		request := req.(DeleteRequest)
 		err := svc.Delete(ctx, request.ID)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				message:    getMessage(err),
-				statusCode: getStatusCode(err),
-			}, nil
+			return newErrorWrapper(err), nil
 		}
 		return DeleteResponse{"Successfully Deleted"}, nil
 	}
@@ -177,13 +281,31 @@ type FetchResponse struct {
 	Notes      []*note.Note `json:"notes"`
 	TotalCount uint64       `json:"total_count" example:"2"`
 	TotalPage  uint64       `json:"total_page" example:"5"`
+	cacheHeaders
+}
+
+func (r FetchResponse) toProto() proto.Message {
+	notes := make([]*pb.Note, 0, len(r.Notes))
+	for _, n := range r.Notes {
+		notes = append(notes, noteToProto(n))
+	}
+	return &pb.FetchResponse{
+		Notes:      notes,
+		TotalCount: r.TotalCount,
+		TotalPage:  r.TotalPage,
+	}
 }
 
 func decodeFetchRequest(_ context.Context, r *http.Request) (response interface{}, err error) {
+	var v validation.Validator
+	page := v.Uint("page", r.URL.Query().Get("page"), 1, note.MaxPage)
+	size := v.Uint("size", r.URL.Query().Get("size"), 1, note.MaxPageSize)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
 
-	page := r.URL.Query().Get("page")
-	size := r.URL.Query().Get("size")
 	sortBy := r.URL.Query().Get("sort_by")
+	archived := r.URL.Query().Get("archived")
 	ascendRaw := r.URL.Query().Get("ascending")
 	if ascendRaw == "" {
 		// Default will be ascend=true
@@ -197,10 +319,11 @@ func decodeFetchRequest(_ context.Context, r *http.Request) (response interface{
 
 	response = FetchRequest{
 		Pagination: &note.Pagination{
-			Size:      convertAtoU(size),
-			Page:      convertAtoU(page),
+			Size:      size,
+			Page:      page,
 			SortBy:    note.GetSortBy(sortBy),
 			Ascending: ascend,
+			Archived:  note.GetArchivedFilter(archived),
 		},
 	}
 
@@ -209,20 +332,44 @@ func decodeFetchRequest(_ context.Context, r *http.Request) (response interface{
 
 // FetchRequest godoc
 // @Summary Fetches notes from the service.
-// @Description Fetches notes from the service.
-// @Accept json
+// @Description Fetches notes from the service. The notes are in protocol buffer with the 'Accept: application/x-protobuf' header. The response has an ETag and a Last-Modified header that change with the notes, so the client can poll with If-None-Match or If-Modified-Since and receive a 304 Not Modified when nothing changed.
 // @Produce json
+// @Produce application/x-protobuf
 // @Param page query int false "The page number of the fetch pagination. Default is page=1."
 // @Param size query int false "The page size of the fetch pagination. Default is size=25."
-// @Param sort_by query string false "An option for sorting the notes in the response. Default is sort_by=title. [title/id/created_date]"
+// @Param sort_by query string false "An option for sorting the notes in the response. Default is sort_by=title. [title/id/created_date/manual]. The manual order places the pinned notes first."
 // @Param ascending query bool false "An option for sorting the results in ascending or descending. Default is ascending=true"
+// @Param archived query string false "An option for listing the archived notes. Default is archived=exclude. [exclude/include/only]"
+// @Param If-None-Match header string false "The ETag of the notes the client has"
+// @Param If-Modified-Since header string false "The Last-Modified time of the notes the client has"
 // @Success 200 {object} FetchResponse "Successfully fetches notes"
-// @Failure 499 {object} ResponseError "Cancel error when the request was aborted"
-// @Failure 500 {object} ResponseError "Unexpected server internal error"
+// @Success 304 {string} string "The notes didn't change"
+// @Failure 400 {object} problem.Problem "The page or the size is not a number"
+// @Failure 422 {object} problem.Problem "The page or the size is out of bounds"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
 // @Router /notes [get]
-func makeFetchEndpoint(svc fetchService) endpoint.Endpoint {
+func makeFetchEndpoint(svc fetchService, cache *CacheConfig) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (resp interface{}, err error) {
 		request := req.(FetchRequest)
+
+		var headers cacheHeaders
+		if cache.Versions != nil {
+			// The version is got before the fetch so a change in between
+			// is not hidden behind the validators of the older version.
+			v, err := cache.Versions.Version(ctx)
+			if err != nil {
+				return newErrorWrapper(err), nil
+			}
+			request.Pagination.Check()
+			headers = fetchCacheHeaders(v, request.Pagination, cache.CacheControl)
+
+			r := requestFromContext(ctx)
+			if mediaType := negotiate(r); notModified(r, headers, mediaType) {
+				return notModifiedResponse{cacheHeaders: headers, mediaType: mediaType}, nil
+			}
+		}
+
 		iter, err := svc.Fetch(ctx, request.Pagination)
 		if err != nil {
 			return newErrorWrapper(err), nil
@@ -246,54 +393,120 @@ func makeFetchEndpoint(svc fetchService) endpoint.Endpoint {
 		}
 
 		resp = FetchResponse{
-			Notes:      notes,
-			TotalCount: iter.TotalCount(),
-			TotalPage:  iter.TotalPage(),
+			Notes:        notes,
+			TotalCount:   iter.TotalCount(),
+			TotalPage:    iter.TotalPage(),
+			cacheHeaders: headers,
 		}
 
 		return
 	}
 }
 
+const (
+	// FormatJSON is the default format of the get response.
+	FormatJSON = "json"
+	// FormatHTML is the format of the get response where the note
+	// content is rendered from Markdown to HTML.
+	FormatHTML = "html"
+)
+
 // GetRequest is a container for the get request API.
 type GetRequest struct {
 	ID uuid.UUID `json:"id"`
+	// Format is the format of the response. It can be either
+	// FormatJSON or FormatHTML.
+	Format string `json:"format"`
 }
 
 // GetResponse is a container for the get response API.
 type GetResponse struct {
 	Note *note.Note `json:"note"`
+	cacheHeaders
+}
+
+func (r GetResponse) toProto() proto.Message {
+	return &pb.NoteResponse{Note: noteToProto(r.Note)}
+}
+
+// GetHTMLResponse is a container for the get response API when
+// the note is requested in FormatHTML.
+type GetHTMLResponse struct {
+	HTML []byte
+	cacheHeaders
+}
+
+// ContentType implements the rawResponse.
+func (r GetHTMLResponse) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+// Body implements the rawResponse.
+func (r GetHTMLResponse) Body() []byte {
+	return r.HTML
 }
 
 // GetRequest godoc
 // @Summary Get the note from the service.
 // @Description Get the note from the service if exists. When the note is not exists it will return a NotFound response status.
+// @Produce json
+// @Produce html
+// @Produce application/x-protobuf
 // @Param id path string true "ID of the note"
+// @Param format query string false "The format of the response. When format=html the note content is rendered from Markdown to a sanitized HTML. The format can also be negotiated with the 'Accept: text/html' header. Default is format=json. [json/html]"
+// @Param If-None-Match header string false "The ETag of the note the client has"
+// @Param If-Modified-Since header string false "The Last-Modified time of the note the client has"
 // @Success 200 {object} GetResponse "Successful getting the note"
-// @Failure 404 {object} ResponseError "Note is not found in the service"
-// @Failure 400 {object} ResponseError "Note's ID parameter is not provided in the path"
-// @Failure 499 {object} ResponseError "Cancel error when the request was aborted"
-// @Failure 500 {object} ResponseError "Unexpected server internal error"
+// @Success 304 {string} string "The note didn't change"
+// @Failure 404 {object} problem.Problem "Note is not found in the service"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
 // @Router /note/{id} [get]
-func makeGetEndpoint(svc getService) endpoint.Endpoint {
+func makeGetEndpoint(svc getService, renderer *render.Renderer, cacheControl string) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		request := req.(GetRequest)
 		v, err := svc.Get(ctx, request.ID)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				statusCode: getStatusCode(err),
-				message:    getMessage(err),
-			}, nil
+			return newErrorWrapper(err), nil
+		}
+
+		headers := noteCacheHeaders(v, cacheControl)
+		if request.Format == FormatHTML {
+			html, err := renderer.Render(v)
+			if err != nil {
+				return newErrorWrapper(err), nil
+			}
+			return GetHTMLResponse{HTML: html, cacheHeaders: headers}, nil
 		}
-		return GetResponse{Note: v}, nil
+
+		return GetResponse{Note: v, cacheHeaders: headers}, nil
 	}
 }
 
 func decodeGetRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	return GetRequest{ID: uuid.MustParse(id)}, nil
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	return GetRequest{ID: id, Format: getFormat(r)}, nil
+}
+
+// getFormat gets the response format from the "format" query parameter
+// and fallback to the Accept header when the parameter is not provided.
+func getFormat(r *http.Request) string {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	switch format {
+	case FormatHTML, FormatJSON:
+		return format
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json") {
+		return FormatHTML
+	}
+
+	return FormatJSON
 }
 
 type updateService interface {
@@ -310,11 +523,27 @@ type UpdateResponse struct {
 	Note *note.Note `json:"note"`
 }
 
+func (r UpdateResponse) toProto() proto.Message {
+	return &pb.NoteResponse{Note: noteToProto(r.Note)}
+}
+
 func decodeUpdateRequest(_ context.Context, r *http.Request) (reqOut interface{}, err error) {
 	var req UpdateRequest
-	err = json.NewDecoder(r.Body).Decode(&req)
+	if isProtobuf(r) {
+		var msg pb.UpdateRequest
+		if err := decodeProto(r, &msg); err != nil {
+			return nil, err
+		}
+		if req.Note, err = protoToNote(msg.Note); err != nil {
+			return nil, err
+		}
+		clearArchived(req.Note)
+		return req, nil
+	}
+
+	err = json.NewDecoder(limitBody(r)).Decode(&req)
 	if err != nil {
-		return nil, err
+		return nil, malformedBody(err)
 	}
 	defer func() {
 		cerr := r.Body.Close()
@@ -323,18 +552,35 @@ func decodeUpdateRequest(_ context.Context, r *http.Request) (reqOut interface{}
 		}
 	}()
 
+	clearArchived(req.Note)
 	return req, nil
 }
 
+// clearArchived clears the archived fields of the note n from the
+// client. The notes are archived and restored only by the archive
+// routes, as the patches can't change the fields.
+func clearArchived(n *note.Note) {
+	if n == nil {
+		return
+	}
+	n.IsArchived = nil
+	n.ArchivedTime = nil
+}
+
 // UpdateRequest godoc
 // @Summary Update an existing note.
-// @Description Updating an existing note. If the note to be updated is not found the API will respond a NotFound status.
+// @Description Updating an existing note. If the note to be updated is not found the API will respond a NotFound status. The attachments, is_archived and archived_time fields are ignored.
 // @Accept json
+// @Accept application/x-protobuf
 // @Produce json
+// @Produce application/x-protobuf
 // @Param UpdateRequest body UpdateRequest true "A body containing the updated note"
 // @Success 200 {object} UpdateResponse "Successfully updated the note"
-// @Failure 404 {object} ResponseError "Note to be update is not found in the service"
-// @Failure 499 {object} ResponseError "Cancel error when the request was aborted"
+// @Failure 400 {object} problem.Problem "The body is malformed"
+// @Failure 404 {object} problem.Problem "Note to be update is not found in the service"
+// @Failure 413 {object} problem.Problem "The body exceeds the maximum size"
+// @Failure 422 {object} problem.Problem "The note has invalid fields such as a title that is too long"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
 // @Router /note [put]
 func makeUpdateEndpoint(svc updateService) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -342,12 +588,122 @@ func makeUpdateEndpoint(svc updateService) endpoint.Endpoint {
 
 		updatedNote, err := svc.Update(ctx, request.Note)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				message:    getMessage(err),
-				statusCode: getStatusCode(err),
-			}, nil
+			return newErrorWrapper(err), nil
 		}
 		return UpdateResponse{Note: updatedNote}, nil
 	}
 }
+
+// maxPatchSize is the maximum size of a patch document. It leaves
+// room for the operations of a patch replacing the whole content.
+const maxPatchSize = 2 * note.MaxContentLength
+
+type patchService interface {
+	Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error)
+}
+
+// PatchRequest is a container for the patch request API. The
+// body of the request is the patch document.
+type PatchRequest struct {
+	ID    uuid.UUID
+	Patch note.Patch
+}
+
+// PatchResponse is a container for the patch response of the API.
+type PatchResponse struct {
+	Note *note.Note `json:"note"`
+}
+
+func (r PatchResponse) toProto() proto.Message {
+	return &pb.NoteResponse{Note: noteToProto(r.Note)}
+}
+
+func decodePatchRequest(_ context.Context, r *http.Request) (reqOut interface{}, err error) {
+	defer func() {
+		cerr := r.Body.Close()
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, note.ErrUnsupportedPatch
+	}
+	switch mediaType {
+	case note.MediaTypeMergePatch, note.MediaTypeJSONPatch:
+	default:
+		return nil, note.ErrUnsupportedPatch
+	}
+
+	doc, err := io.ReadAll(io.LimitReader(r.Body, maxPatchSize+1))
+	if err != nil {
+		return nil, validation.Malformed("body", err)
+	}
+	if len(doc) > maxPatchSize {
+		var v validation.Validator
+		v.Addf("body", validation.CodeTooLong, "must be at most %d bytes", maxPatchSize)
+		return nil, v.Err()
+	}
+
+	return PatchRequest{ID: id, Patch: note.Patch{MediaType: mediaType, Document: doc}}, nil
+}
+
+// PatchRequest godoc
+// @Summary Patch an existing note.
+// @Description Patching an existing note with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902). The fields set to null by a merge patch or removed by a JSON Patch are cleared. The patch is applied atomically and a failed JSON Patch test operation leaves the note unchanged. The id, created_time, updated_time, attachments, is_archived and archived_time fields are read-only.
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Produce application/x-protobuf
+// @Param id path string true "ID of the note"
+// @Param patch body string true "The patch document, e.g. {\"content\": null} or [{\"op\": \"replace\", \"path\": \"/title\", \"value\": \"New title\"}]"
+// @Success 200 {object} PatchResponse "Successfully patched the note"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID or the patch is malformed"
+// @Failure 404 {object} problem.Problem "Note to be patched is not found in the service"
+// @Failure 409 {object} problem.Problem "A test operation of the JSON Patch failed"
+// @Failure 415 {object} problem.Problem "The media type of the patch is not supported"
+// @Failure 422 {object} problem.Problem "The patch doesn't apply, changes a read-only field or makes the note invalid"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Router /note/{id} [patch]
+func makePatchEndpoint(svc patchService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(PatchRequest)
+
+		patchedNote, err := svc.Patch(ctx, request.ID, request.Patch)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return PatchResponse{Note: patchedNote}, nil
+	}
+}
+
+// noteToProto converts n to a protocol buffer message. A nil
+// note is converted to nil.
+func noteToProto(n *note.Note) *pb.Note {
+	if n == nil {
+		return nil
+	}
+	return protoutil.NoteToProto(n)
+}
+
+// protoToNote converts the protocol buffer message p to a note. A nil
+// message is converted to nil and an empty ID to uuid.Nil.
+func protoToNote(p *pb.Note) (*note.Note, error) {
+	if p == nil {
+		return nil, nil
+	}
+	if len(p.Id) == 0 {
+		p.Id = []byte(uuid.Nil.String())
+	}
+	n, err := protoutil.ProtoToNote(p)
+	if err != nil {
+		return nil, validation.Malformed("note", err)
+	}
+	return n, nil
+}
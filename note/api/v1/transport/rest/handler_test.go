@@ -15,9 +15,13 @@ import (
 	"noterfy/note/noteutil"
 	"noterfy/note/service"
 	"noterfy/note/store/memory"
+	"noterfy/pkg/problem"
 	"noterfy/pkg/ptrconv"
 	"noterfy/pkg/timestamp"
+	"noterfy/pkg/validation"
+	"strings"
 	"testing"
+	"time"
 )
 
 var dummyCtx = context.TODO()
@@ -33,8 +37,8 @@ type request struct {
 }
 
 type response struct {
-	Note    *note.Note `json:"note"`
-	Message string     `json:"message,omitempty"`
+	Note *note.Note `json:"note"`
+	Code string     `json:"code,omitempty"`
 }
 
 func TestHandler(t *testing.T) {
@@ -67,8 +71,8 @@ func (s *HandlerTestSuite) decodeResponse(rec *httptest.ResponseRecorder) respon
 	return resp
 }
 
-func (s *HandlerTestSuite) assertMessage(resp response, want string) {
-	s.Equal(want, resp.Message)
+func (s *HandlerTestSuite) assertProblem(resp response, wantCode string) {
+	s.Equal(wantCode, resp.Code)
 }
 
 func (s *HandlerTestSuite) assertStatusCode(rec *httptest.ResponseRecorder, want int) {
@@ -119,7 +123,7 @@ func (s *HandlerTestSuite) TestCreate() {
 		responseRecorder := makeRequest(dummyCtx, newNote)
 		s.assertStatusCode(responseRecorder, http.StatusConflict)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Note already exists")
+		s.assertProblem(resp, CodeNoteConflict)
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -129,7 +133,7 @@ func (s *HandlerTestSuite) TestCreate() {
 		responseRecorder := makeRequest(cancelledCtx, inputNote)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertProblem(resp, CodeRequestCancelled)
 	})
 }
 
@@ -159,8 +163,7 @@ func (s *HandlerTestSuite) TestDelete() {
 		responseRecorder := makeRequest(dummyCtx, uuid.Nil)
 		s.Equal(http.StatusBadRequest, responseRecorder.Code)
 		got := s.decodeResponse(responseRecorder)
-		want := "Empty note identifier"
-		s.assertMessage(got, want)
+		s.assertProblem(got, CodeNoteIDRequired)
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -170,7 +173,7 @@ func (s *HandlerTestSuite) TestDelete() {
 		responseRecorder := makeRequest(cancelledCtx, newNote.ID)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertProblem(resp, CodeRequestCancelled)
 	})
 }
 
@@ -247,6 +250,23 @@ func (s *HandlerTestSuite) TestFetch() {
 		s.Len(resp.Notes, 3)
 		s.Equal(notes, resp.Notes)
 	})
+
+	s.Run("Fetch with archived notes", func() {
+		s.resetStore()
+		notes := insertNotes(3)
+		archived, err := s.svc.Update(dummyCtx, new(note.Note).SetID(notes[0].ID).SetIsArchived(true))
+		s.require.NoError(err)
+
+		resp := doRequest("/notes?page=1&size=5")
+		s.Equal([]*note.Note{notes[2], notes[1]}, resp.Notes)
+
+		resp = doRequest("/notes?page=1&size=5&archived=only")
+		s.Len(resp.Notes, 1)
+		s.Equal(archived.ID, resp.Notes[0].ID)
+
+		resp = doRequest("/notes?page=1&size=5&archived=include")
+		s.Len(resp.Notes, 3)
+	})
 }
 
 func (s *HandlerTestSuite) TestGet() {
@@ -289,16 +309,52 @@ func (s *HandlerTestSuite) TestGet() {
 		responseRecorder := makeRequest(dummyCtx, uuid.New())
 		s.assertStatusCode(responseRecorder, http.StatusNotFound)
 		got := s.decodeResponse(responseRecorder)
-		want := "Note not found"
-		s.assertMessage(got, want)
+		s.assertProblem(got, CodeNoteNotFound)
 	})
 
 	s.Run("Requesting a note but the ID is nil", func() {
 		responseRecorder := makeRequest(dummyCtx, uuid.Nil)
 		s.assertStatusCode(responseRecorder, http.StatusBadRequest)
 		got := s.decodeResponse(responseRecorder)
-		want := "Empty note identifier"
-		s.assertMessage(got, want)
+		s.assertProblem(got, CodeNoteIDRequired)
+	})
+
+	s.Run("Requesting a note in HTML format", func() {
+		testNote := noteutil.Copy(dummyNote)
+		testNote.SetContent("# Heading\n\n<script>alert(1)</script>")
+		newNote, err := s.svc.Create(dummyCtx, testNote)
+		s.require.NoError(err)
+
+		for _, target := range []string{
+			"/note/" + newNote.ID.String() + "?format=html",
+			"/note/" + newNote.ID.String(),
+		} {
+			responseRecorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.Header.Set("Accept", "text/html")
+			s.routes.ServeHTTP(responseRecorder, req)
+
+			s.assertStatusCode(responseRecorder, http.StatusOK)
+			s.Equal("text/html; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+			s.Contains(responseRecorder.Body.String(), "<h1>Heading</h1>")
+			s.NotContains(responseRecorder.Body.String(), "<script>")
+		}
+	})
+
+	s.Run("Requesting a note in HTML format after update should render the updated content", func() {
+		newNote := setupNewNote()
+		target := "/note/" + newNote.ID.String() + "?format=html"
+
+		rec := httptest.NewRecorder()
+		s.routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		s.Contains(rec.Body.String(), newNote.GetContent())
+
+		_, err := s.svc.Update(dummyCtx, new(note.Note).SetID(newNote.ID).SetContent("Updated **content**"))
+		s.require.NoError(err)
+
+		rec = httptest.NewRecorder()
+		s.routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		s.Contains(rec.Body.String(), "<strong>content</strong>")
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -308,7 +364,7 @@ func (s *HandlerTestSuite) TestGet() {
 		responseRecorder := makeRequest(cancelledCtx, inputNote.ID)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertProblem(resp, CodeRequestCancelled)
 	})
 }
 
@@ -354,13 +410,24 @@ func (s *HandlerTestSuite) TestUpdate() {
 		assertNote(want, resp.Note)
 	})
 
+	s.Run("Request for update can't archive the note", func() {
+		updatedNote := noteutil.Copy(setup())
+		updatedNote.SetIsArchived(true).SetArchivedTime(time.Now())
+
+		responseRecorder := makeRequest(dummyCtx, updatedNote)
+		s.assertStatusCode(responseRecorder, http.StatusOK)
+		resp := s.decodeResponse(responseRecorder)
+		s.False(resp.Note.GetIsArchived())
+		s.Nil(resp.Note.ArchivedTime)
+	})
+
 	s.Run("Request for update note that is not exist should return an error", func() {
 		updatedNote := noteutil.Copy(dummyNote)
 		updatedNote.ID = uuid.New()
 		responseRecorder := makeRequest(dummyCtx, updatedNote)
 		s.assertStatusCode(responseRecorder, http.StatusNotFound)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Note not found")
+		s.assertProblem(resp, CodeNoteNotFound)
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -373,6 +440,228 @@ func (s *HandlerTestSuite) TestUpdate() {
 		responseRecorder := makeRequest(cancelledCtx, updatedNote)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertProblem(resp, CodeRequestCancelled)
+	})
+}
+
+func (s *HandlerTestSuite) TestPatch() {
+
+	setup := func() *note.Note {
+		newNote, err := s.svc.Create(dummyCtx, noteutil.Copy(dummyNote))
+		s.require.NoError(err)
+		return newNote
+	}
+
+	makeRequest := func(id uuid.UUID, contentType, body string) *httptest.ResponseRecorder {
+		responseRecorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/note/"+id.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		s.routes.ServeHTTP(responseRecorder, req)
+		return responseRecorder
+	}
+
+	s.Run("Request for merge patch successfully", func() {
+		n := setup()
+
+		responseRecorder := makeRequest(n.ID, note.MediaTypeMergePatch, `{"title": "Patched", "content": null}`)
+		s.assertStatusCode(responseRecorder, http.StatusOK)
+		resp := s.decodeResponse(responseRecorder)
+		s.Equal("Patched", resp.Note.GetTitle())
+		s.Nil(resp.Note.Content)
+		s.Equal(n.IsFavorite, resp.Note.IsFavorite)
+
+		got, err := s.svc.Get(dummyCtx, n.ID)
+		s.require.NoError(err)
+		s.Nil(got.Content)
+	})
+
+	s.Run("Request for JSON Patch with a charset successfully", func() {
+		n := setup()
+
+		responseRecorder := makeRequest(n.ID, note.MediaTypeJSONPatch+"; charset=utf-8",
+			`[{"op": "test", "path": "/title", "value": "Unit Test"}, {"op": "replace", "path": "/is_favorite", "value": false}]`)
+		s.assertStatusCode(responseRecorder, http.StatusOK)
+		resp := s.decodeResponse(responseRecorder)
+		s.False(resp.Note.GetIsFavorite())
+	})
+
+	tests := []struct {
+		name        string
+		id          uuid.UUID
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{
+			name:        "Failing test operation",
+			contentType: note.MediaTypeJSONPatch,
+			body:        `[{"op": "test", "path": "/title", "value": "Other"}]`,
+			status:      http.StatusConflict,
+			code:        CodeNotePatchTestFailed,
+		},
+		{
+			name:        "Unsupported media type",
+			contentType: "application/json",
+			body:        `{"title": "Patched"}`,
+			status:      http.StatusUnsupportedMediaType,
+			code:        CodeRequestUnsupportedMedia,
+		},
+		{
+			name:        "Malformed patch",
+			contentType: note.MediaTypeMergePatch,
+			body:        `{"title":`,
+			status:      http.StatusBadRequest,
+			code:        CodeRequestMalformed,
+		},
+		{
+			name:        "Read-only field",
+			contentType: note.MediaTypeMergePatch,
+			body:        `{"attachments": []}`,
+			status:      http.StatusUnprocessableEntity,
+			code:        CodeRequestInvalid,
+		},
+		{
+			name:        "Note that is not exist",
+			id:          uuid.New(),
+			contentType: note.MediaTypeMergePatch,
+			body:        `{"title": "Patched"}`,
+			status:      http.StatusNotFound,
+			code:        CodeNoteNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			id := tt.id
+			if id == uuid.Nil {
+				id = setup().ID
+			}
+			responseRecorder := makeRequest(id, tt.contentType, tt.body)
+			s.assertStatusCode(responseRecorder, tt.status)
+			s.Equal(problem.MediaType, responseRecorder.Header().Get("Content-Type"))
+			s.assertProblem(s.decodeResponse(responseRecorder), tt.code)
+		})
+	}
+
+	s.Run("Patch larger than the maximum size should return an error", func() {
+		body := `{"content": "` + strings.Repeat("a", maxPatchSize) + `"}`
+		responseRecorder := makeRequest(setup().ID, note.MediaTypeMergePatch, body)
+		s.assertStatusCode(responseRecorder, http.StatusUnprocessableEntity)
+		s.assertProblem(s.decodeResponse(responseRecorder), CodeRequestInvalid)
+	})
+}
+
+func (s *HandlerTestSuite) TestValidation() {
+	makeRequest := func(method, target, body string) (*httptest.ResponseRecorder, problem.Problem) {
+		responseRecorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		s.routes.ServeHTTP(responseRecorder, req)
+
+		var resp problem.Problem
+		s.Require().NoError(json.NewDecoder(responseRecorder.Body).Decode(&resp))
+		return responseRecorder, resp
+	}
+
+	tooLong := strings.Repeat("a", note.MaxTitleLength+1)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		want   []validation.Violation
+	}{
+		{
+			name:   "Get a note with a malformed ID",
+			method: http.MethodGet,
+			target: "/note/1234",
+			status: http.StatusBadRequest,
+			want:   []validation.Violation{{Field: "id", Code: validation.CodeMalformed, Message: "must be a UUID, got '1234'"}},
+		},
+		{
+			name:   "Delete a note with a malformed ID",
+			method: http.MethodDelete,
+			target: "/note/1234",
+			status: http.StatusBadRequest,
+			want:   []validation.Violation{{Field: "id", Code: validation.CodeMalformed, Message: "must be a UUID, got '1234'"}},
+		},
+		{
+			name:   "Create a note with a malformed body",
+			method: http.MethodPost,
+			target: "/note",
+			body:   `{"note":`,
+			status: http.StatusBadRequest,
+			want:   []validation.Violation{{Field: "body", Code: validation.CodeMalformed, Message: "unexpected EOF"}},
+		},
+		{
+			name:   "Create a note with a title too long",
+			method: http.MethodPost,
+			target: "/note",
+			body:   fmt.Sprintf(`{"note":{"title":%q}}`, tooLong),
+			status: http.StatusUnprocessableEntity,
+			want: []validation.Violation{{
+				Field:   "title",
+				Code:    validation.CodeTooLong,
+				Message: fmt.Sprintf("must be at most %d characters, got %d", note.MaxTitleLength, len(tooLong)),
+			}},
+		},
+		{
+			name:   "Update a note with a control character in the content",
+			method: http.MethodPut,
+			target: "/note",
+			body:   fmt.Sprintf(`{"note":{"id":%q,"content":"Lorem\u0007Ipsum"}}`, uuid.New()),
+			status: http.StatusUnprocessableEntity,
+			want: []validation.Violation{{
+				Field:   "content",
+				Code:    validation.CodeControlCharacter,
+				Message: "must not contain the control character U+0007 at byte 5",
+			}},
+		},
+		{
+			name:   "Fetch notes with a malformed page and a size out of bounds",
+			method: http.MethodGet,
+			target: "/notes?page=first&size=1000",
+			status: http.StatusBadRequest,
+			want: []validation.Violation{
+				{Field: "page", Code: validation.CodeMalformed, Message: "must be a positive integer, got 'first'"},
+				{Field: "size", Code: validation.CodeOutOfRange, Message: fmt.Sprintf("must be between 1 and %d, got 1000", note.MaxPageSize)},
+			},
+		},
+		{
+			name:   "Fetch notes with a size out of bounds",
+			method: http.MethodGet,
+			target: "/notes?size=0",
+			status: http.StatusUnprocessableEntity,
+			want: []validation.Violation{
+				{Field: "size", Code: validation.CodeOutOfRange, Message: fmt.Sprintf("must be between 1 and %d, got 0", note.MaxPageSize)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			responseRecorder, resp := makeRequest(tt.method, tt.target, tt.body)
+			s.assertStatusCode(responseRecorder, tt.status)
+			if tt.status == http.StatusBadRequest {
+				s.Equal(CodeRequestMalformed, resp.Code)
+			} else {
+				s.Equal(CodeRequestInvalid, resp.Code)
+			}
+			s.Equal(problem.MediaType, responseRecorder.Header().Get("Content-Type"))
+			s.Equal(tt.target, resp.Instance)
+			s.Equal(tt.want, resp.Violations)
+		})
+	}
+
+	s.Run("Fetch a page past the last note", func() {
+		responseRecorder := httptest.NewRecorder()
+		s.routes.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/notes?page=100", nil))
+		s.assertStatusCode(responseRecorder, http.StatusOK)
+
+		var resp FetchResponse
+		s.Require().NoError(json.NewDecoder(responseRecorder.Body).Decode(&resp))
+		s.Empty(resp.Notes)
 	})
 }
@@ -0,0 +1,187 @@
+package rest
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"net/http"
+	"noterfy/api"
+	"noterfy/note/link"
+	nhttp "noterfy/pkg/http"
+)
+
+type linkService interface {
+	Links(id uuid.UUID) ([]link.Link, error)
+	Backlinks(id uuid.UUID) ([]link.Backlink, error)
+	BrokenLinks() []link.BrokenLink
+	Graph() *link.Graph
+}
+
+// LinkRoutes returns all the routes for querying the wiki-style
+// links between the notes. The opts are applied to the server of
+// every route.
+func LinkRoutes(svc linkService, opts ...httptransport.ServerOption) []api.Route {
+	linksHandler := httptransport.NewServer(
+		makeLinksEndpoint(svc),
+		decodeLinksRequest,
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	backlinksHandler := httptransport.NewServer(
+		makeBacklinksEndpoint(svc),
+		decodeBacklinksRequest,
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	brokenLinksHandler := httptransport.NewServer(
+		makeBrokenLinksEndpoint(svc),
+		decodeBrokenLinksRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	graphHandler := httptransport.NewServer(
+		makeGraphEndpoint(svc),
+		decodeGraphRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: linksHandler, MethodValue: http.MethodGet, PathValue: "/v1/note/{id}/links"},
+		&nhttp.Route{HandlerValue: backlinksHandler, MethodValue: http.MethodGet, PathValue: "/v1/note/{id}/backlinks"},
+		&nhttp.Route{HandlerValue: brokenLinksHandler, MethodValue: http.MethodGet, PathValue: "/v1/links/broken"},
+		&nhttp.Route{HandlerValue: graphHandler, MethodValue: http.MethodGet, PathValue: "/v1/graph"},
+	}
+}
+
+// LinksRequest is a container for the links request API.
+type LinksRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// LinksResponse is a container for the links response API.
+type LinksResponse struct {
+	Links []link.Link `json:"links"`
+}
+
+func decodeLinksRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	return LinksRequest{ID: id}, nil
+}
+
+// LinksRequest godoc
+// @Summary Get the outgoing links of a note.
+// @Description Get the wiki-style links such as [[Other Note]] written in the content of the note. A link target can either be the title or the ID of the linked note.
+// @Produce json
+// @Param id path string true "ID of the note"
+// @Success 200 {object} LinksResponse "Successfully getting the links"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID"
+// @Failure 404 {object} problem.Problem "Note is not found in the service"
+// @Router /note/{id}/links [get]
+func makeLinksEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(LinksRequest)
+		links, err := svc.Links(request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return LinksResponse{Links: links}, nil
+	}
+}
+
+// BacklinksRequest is a container for the backlinks request API.
+type BacklinksRequest struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// BacklinksResponse is a container for the backlinks response API.
+type BacklinksResponse struct {
+	Backlinks []link.Backlink `json:"backlinks"`
+}
+
+func decodeBacklinksRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	return BacklinksRequest{ID: id}, nil
+}
+
+// BacklinksRequest godoc
+// @Summary Get the backlinks of a note.
+// @Description Get the notes that link to the note.
+// @Produce json
+// @Param id path string true "ID of the note"
+// @Success 200 {object} BacklinksResponse "Successfully getting the backlinks"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID"
+// @Failure 404 {object} problem.Problem "Note is not found in the service"
+// @Router /note/{id}/backlinks [get]
+func makeBacklinksEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(BacklinksRequest)
+		backlinks, err := svc.Backlinks(request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return BacklinksResponse{Backlinks: backlinks}, nil
+	}
+}
+
+// BrokenLinksRequest is a container for the broken links request API.
+type BrokenLinksRequest struct{}
+
+// BrokenLinksResponse is a container for the broken links response API.
+type BrokenLinksResponse struct {
+	BrokenLinks []link.BrokenLink `json:"broken_links"`
+}
+
+func decodeBrokenLinksRequest(context.Context, *http.Request) (interface{}, error) {
+	return BrokenLinksRequest{}, nil
+}
+
+// BrokenLinksRequest godoc
+// @Summary Get the broken links report.
+// @Description Get all the links that don't resolve to any note.
+// @Produce json
+// @Success 200 {object} BrokenLinksResponse "Successfully getting the broken links"
+// @Router /links/broken [get]
+func makeBrokenLinksEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return BrokenLinksResponse{BrokenLinks: svc.BrokenLinks()}, nil
+	}
+}
+
+// GraphRequest is a container for the graph request API.
+type GraphRequest struct{}
+
+// GraphResponse is a container for the graph response API.
+type GraphResponse struct {
+	*link.Graph
+}
+
+func decodeGraphRequest(context.Context, *http.Request) (interface{}, error) {
+	return GraphRequest{}, nil
+}
+
+// GraphRequest godoc
+// @Summary Get the graph of the notes.
+// @Description Get all the notes as nodes and the resolved links between them as edges for visualisation.
+// @Produce json
+// @Success 200 {object} GraphResponse "Successfully getting the graph"
+// @Router /graph [get]
+func makeGraphEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return GraphResponse{Graph: svc.Graph()}, nil
+	}
+}
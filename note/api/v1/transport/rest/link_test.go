@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/link"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/problem"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	suite.Run(t, new(LinkTestSuite))
+}
+
+type LinkTestSuite struct {
+	suite.Suite
+	svc    note.Service
+	router *mux.Router
+}
+
+func (s *LinkTestSuite) SetupTest() {
+	index := link.NewIndex()
+	s.svc = link.Middleware(index)(service.New(memory.New()))
+
+	s.router = mux.NewRouter()
+	for _, route := range LinkRoutes(index) {
+		s.router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+}
+
+func (s *LinkTestSuite) create(title, content string) *note.Note {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle(title).SetContent(content))
+	s.Require().NoError(err)
+	return n
+}
+
+func (s *LinkTestSuite) get(target string, resp interface{}) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if resp != nil {
+		s.Require().NoError(json.NewDecoder(rec.Body).Decode(resp))
+	}
+	return rec
+}
+
+func (s *LinkTestSuite) TestLinks() {
+	target := s.create("Target", "")
+	source := s.create("Source", "[[Target]] [[Missing]]")
+
+	s.Run("Getting the links of a note", func() {
+		var resp LinksResponse
+		rec := s.get("/v1/note/"+source.ID.String()+"/links", &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal([]link.Link{
+			{Target: "Target", NoteID: target.ID, Title: "Target"},
+			{Target: "Missing", Broken: true},
+		}, resp.Links)
+	})
+
+	s.Run("Getting the backlinks of a note", func() {
+		var resp BacklinksResponse
+		rec := s.get("/v1/note/"+target.ID.String()+"/backlinks", &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal([]link.Backlink{{NoteID: source.ID, Title: "Source"}}, resp.Backlinks)
+	})
+
+	s.Run("Getting the links of a note that not exists", func() {
+		var resp problem.Problem
+		rec := s.get("/v1/note/"+uuid.New().String()+"/links", &resp)
+		s.Equal(http.StatusNotFound, rec.Code)
+		s.Equal(CodeNoteNotFound, resp.Code)
+	})
+
+	s.Run("Getting the broken links", func() {
+		var resp BrokenLinksResponse
+		rec := s.get("/v1/links/broken", &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal([]link.BrokenLink{{Source: source.ID, Target: "Missing"}}, resp.BrokenLinks)
+	})
+
+	s.Run("Getting the graph", func() {
+		var resp struct {
+			Nodes []link.Node `json:"nodes"`
+			Edges []link.Edge `json:"edges"`
+		}
+		rec := s.get("/v1/graph", &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Len(resp.Nodes, 2)
+		s.Equal([]link.Edge{{Source: source.ID, Target: target.ID}}, resp.Edges)
+	})
+}
@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"net/http"
+	"noterfy/api"
+	"noterfy/note"
+	"noterfy/note/order"
+	nhttp "noterfy/pkg/http"
+)
+
+type orderService interface {
+	Move(ctx context.Context, id, before, after uuid.UUID) (*note.Note, error)
+}
+
+// OrderRoutes returns all the routes for arranging the manual
+// order of the notes. The opts are applied to the server of
+// every route.
+func OrderRoutes(svc orderService, opts ...httptransport.ServerOption) []api.Route {
+	moveHandler := httptransport.NewServer(
+		makeMoveEndpoint(svc),
+		decodeMoveRequest,
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: moveHandler, MethodValue: http.MethodPost, PathValue: "/v1/note/{id}/move"},
+	}
+}
+
+// MoveRequest is a container for the move request API.
+type MoveRequest struct {
+	ID     uuid.UUID `json:"id"`
+	Before uuid.UUID `json:"before"`
+	After  uuid.UUID `json:"after"`
+}
+
+// MoveResponse is a container for the move response API.
+type MoveResponse struct {
+	Note *note.Note `json:"note"`
+}
+
+func decodeMoveRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeID(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	request := MoveRequest{ID: id}
+
+	for param, id := range map[string]*uuid.UUID{"before": &request.Before, "after": &request.After} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("rest: invalid %s parameter: %w", param, order.ErrInvalidMove)
+		}
+		*id = parsed
+	}
+
+	return request, nil
+}
+
+// MoveRequest godoc
+// @Summary Move a note in the manual order.
+// @Description Place the note between two adjacent notes in the manual order. Only the moved note gets a new position so the other notes are not renumbered. Omit "before" to move the note to the end and omit "after" to move the note to the start.
+// @Produce json
+// @Param id path string true "ID of the note to move"
+// @Param before query string false "ID of the note that will be placed right after the moved note"
+// @Param after query string false "ID of the note that will be placed right before the moved note"
+// @Success 200 {object} MoveResponse "Successfully moved the note"
+// @Failure 400 {object} problem.Problem "The ID of the note is not a UUID or the move is invalid such as moving the note relative to itself"
+// @Failure 404 {object} problem.Problem "Note is not found in the service"
+// @Failure 499 {object} problem.Problem "Cancel error when the request was aborted"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /note/{id}/move [post]
+func makeMoveEndpoint(svc orderService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(MoveRequest)
+		n, err := svc.Move(ctx, request.ID, request.Before, request.After)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return MoveResponse{Note: n}, nil
+	}
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/order"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/problem"
+	"noterfy/pkg/trace"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	suite.Run(t, new(OrderTestSuite))
+}
+
+type OrderTestSuite struct {
+	suite.Suite
+	svc      note.Service
+	router   *mux.Router
+	recorder *tracetest.SpanRecorder
+}
+
+func (s *OrderTestSuite) SetupTest() {
+	store := memory.New()
+	s.svc = service.New(store)
+	s.recorder = tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder))
+
+	s.router = mux.NewRouter()
+	for _, route := range OrderRoutes(order.New(s.svc), trace.ServerOptions(provider)...) {
+		s.router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+}
+
+func (s *OrderTestSuite) create(title string) *note.Note {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle(title))
+	s.Require().NoError(err)
+	return n
+}
+
+func (s *OrderTestSuite) move(target string, resp interface{}) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(resp))
+	return rec
+}
+
+func (s *OrderTestSuite) TestMove() {
+	first := s.create("First")
+	second := s.create("Second")
+
+	s.Run("Moving a note to the start", func() {
+		var resp MoveResponse
+		rec := s.move("/v1/note/"+second.ID.String()+"/move?before="+first.ID.String(), &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal(second.ID, resp.Note.ID)
+
+		got, err := s.svc.Get(dummyCtx, first.ID)
+		s.Require().NoError(err)
+		s.Less(resp.Note.GetPosition(), got.GetPosition())
+	})
+
+	s.Run("Moving a note relative to itself", func() {
+		var resp problem.Problem
+		rec := s.move("/v1/note/"+first.ID.String()+"/move?after="+first.ID.String(), &resp)
+		s.Equal(http.StatusBadRequest, rec.Code)
+		s.Equal(CodeOrderInvalidMove, resp.Code)
+	})
+
+	s.Run("Moving a note with an invalid parameter", func() {
+		var resp problem.Problem
+		rec := s.move("/v1/note/"+first.ID.String()+"/move?after=invalid", &resp)
+		s.Equal(http.StatusBadRequest, rec.Code)
+		s.Equal(CodeOrderInvalidMove, resp.Code)
+	})
+
+	s.Run("Moving a note that not exists", func() {
+		var resp problem.Problem
+		rec := s.move("/v1/note/"+uuid.New().String()+"/move?before="+first.ID.String(), &resp)
+		s.Equal(http.StatusNotFound, rec.Code)
+		s.Equal(CodeNoteNotFound, resp.Code)
+	})
+}
+
+func (s *OrderTestSuite) TestTracing() {
+	first := s.create("First")
+	second := s.create("Second")
+
+	var resp MoveResponse
+	s.move("/v1/note/"+second.ID.String()+"/move?before="+first.ID.String(), &resp)
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("POST /v1/note/{id}/move", spans[0].Name())
+}
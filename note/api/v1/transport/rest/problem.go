@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"noterfy/blob"
+	"noterfy/note"
+	"noterfy/note/order"
+	"noterfy/note/template"
+	"noterfy/pkg/problem"
+	"noterfy/pkg/validation"
+)
+
+// The codes of the problems of the note API. The codes are stable
+// and the clients can rely on them, unlike the titles.
+const (
+	CodeNoteNotFound            = "note.not_found"
+	CodeNoteConflict            = "note.conflict"
+	CodeNoteIDRequired          = "note.id_required"
+	CodeNotePatchTestFailed     = "note.patch_test_failed"
+	CodeAttachmentNotFound      = "attachment.not_found"
+	CodeAttachmentTooLarge      = "attachment.too_large"
+	CodeAttachmentMissing       = "attachment.missing"
+	CodeOrderInvalidMove        = "order.invalid_move"
+	CodeTemplateNotFound        = "template.not_found"
+	CodeTemplateConflict        = "template.conflict"
+	CodeTemplateInvalid         = "template.invalid"
+	CodeTemplateMissingVariable = "template.missing_variable"
+	CodeRequestMalformed        = "request.malformed"
+	CodeRequestTooLarge         = "request.too_large"
+	CodeRequestInvalid          = "request.invalid"
+	CodeRequestUnsupportedMedia = "request.unsupported_media_type"
+	CodeRequestCancelled        = "request.cancelled"
+)
+
+var (
+	requestMalformed = problem.Kind{Code: CodeRequestMalformed, Status: http.StatusBadRequest, Title: "Malformed request"}
+	requestInvalid   = problem.Kind{Code: CodeRequestInvalid, Status: http.StatusUnprocessableEntity, Title: "Invalid input"}
+)
+
+// errorKinds maps the errors of the services to the kinds
+// of problems of the API.
+var errorKinds = problem.Mapping{
+	{Err: note.ErrNotFound, Kind: problem.Kind{Code: CodeNoteNotFound, Status: http.StatusNotFound, Title: "Note not found"}},
+	{Err: note.ErrExists, Kind: problem.Kind{Code: CodeNoteConflict, Status: http.StatusConflict, Title: "Note already exists"}},
+	{Err: note.ErrNilID, Kind: problem.Kind{Code: CodeNoteIDRequired, Status: http.StatusBadRequest, Title: "Empty note identifier"}},
+	{Err: note.ErrPatchTestFailed, Kind: problem.Kind{Code: CodeNotePatchTestFailed, Status: http.StatusConflict, Title: "Patch test failed"}},
+	{Err: note.ErrUnsupportedPatch, Kind: problem.Kind{Code: CodeRequestUnsupportedMedia, Status: http.StatusUnsupportedMediaType, Title: "Unsupported patch media type"}},
+	{Err: note.ErrAttachmentNotFound, Kind: problem.Kind{Code: CodeAttachmentNotFound, Status: http.StatusNotFound, Title: "Attachment not found"}},
+	{Err: blob.ErrNotFound, Kind: problem.Kind{Code: CodeAttachmentNotFound, Status: http.StatusNotFound, Title: "Attachment not found"}},
+	{Err: note.ErrAttachmentTooLarge, Kind: problem.Kind{Code: CodeAttachmentTooLarge, Status: http.StatusRequestEntityTooLarge, Title: "Attachment exceeds the maximum size"}},
+	{Err: errMissingAttachment, Kind: problem.Kind{Code: CodeAttachmentMissing, Status: http.StatusBadRequest, Title: "Missing attachment file"}},
+	{Err: order.ErrInvalidMove, Kind: problem.Kind{Code: CodeOrderInvalidMove, Status: http.StatusBadRequest, Title: "Invalid move of the note"}},
+	{Err: template.ErrNotFound, Kind: problem.Kind{Code: CodeTemplateNotFound, Status: http.StatusNotFound, Title: "Template not found"}},
+	{Err: template.ErrExists, Kind: problem.Kind{Code: CodeTemplateConflict, Status: http.StatusConflict, Title: "Template already exists"}},
+	{Err: template.ErrInvalid, Kind: problem.Kind{Code: CodeTemplateInvalid, Status: http.StatusBadRequest, Title: "Invalid template"}},
+	{Err: template.ErrMissingVariable, Kind: problem.Kind{Code: CodeTemplateMissingVariable, Status: http.StatusBadRequest, Title: "Missing template variable"}},
+	{Err: errBodyTooLarge, Kind: problem.Kind{Code: CodeRequestTooLarge, Status: http.StatusRequestEntityTooLarge, Title: "Request body too large"}},
+	{Err: note.ErrCancelled, Kind: problem.Kind{Code: CodeRequestCancelled, Status: StatusClientClosed, Title: "Request cancelled"}},
+}
+
+// errorKind returns the kind of problem of err. The malformed
+// requests are bad requests while the well-formed requests with
+// invalid values are unprocessable.
+func errorKind(err error) problem.Kind {
+	var verr *validation.Error
+	if errors.As(err, &verr) {
+		if verr.Malformed() {
+			return requestMalformed
+		}
+		return requestInvalid
+	}
+	return errorKinds.Kind(err)
+}
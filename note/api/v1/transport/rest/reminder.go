@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"noterfy/api"
+	"noterfy/note/reminder"
+	nhttp "noterfy/pkg/http"
+	"strconv"
+	"time"
+)
+
+// sseKeepAliveInterval is the interval of the comments sent to
+// the server-sent events clients to keep the connection open.
+const sseKeepAliveInterval = 30 * time.Second
+
+var errStreamingUnsupported = errors.New("rest: streaming is not supported by the response writer")
+
+type reminderService interface {
+	Upcoming(within time.Duration, limit int) []reminder.Event
+}
+
+type reminderBroker interface {
+	Subscribe() (<-chan reminder.Event, func())
+}
+
+// ReminderRoutes returns all the routes for the reminders of the notes.
+// The reminder events are streamed from the broker as server-sent events.
+// The opts are applied to the server of every route but the event stream.
+func ReminderRoutes(svc reminderService, broker reminderBroker, opts ...httptransport.ServerOption) []api.Route {
+	upcomingHandler := httptransport.NewServer(
+		makeUpcomingRemindersEndpoint(svc),
+		decodeUpcomingRemindersRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: upcomingHandler, MethodValue: http.MethodGet, PathValue: "/v1/reminders/upcoming"},
+		&nhttp.Route{HandlerValue: makeReminderEventsHandler(broker), MethodValue: http.MethodGet, PathValue: "/v1/reminders/events"},
+	}
+}
+
+// UpcomingRemindersRequest is a container for the upcoming reminders request API.
+type UpcomingRemindersRequest struct {
+	Within time.Duration `json:"within"`
+	Limit  int           `json:"limit"`
+}
+
+// UpcomingRemindersResponse is a container for the upcoming reminders response API.
+type UpcomingRemindersResponse struct {
+	Reminders []reminder.Event `json:"reminders"`
+}
+
+func decodeUpcomingRemindersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	// Invalid values fall back to the defaults the same
+	// way as the fetch request.
+	within, err := time.ParseDuration(r.URL.Query().Get("within"))
+	if err != nil || within < 0 {
+		within = 0
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 0 {
+		limit = 0
+	}
+
+	return UpcomingRemindersRequest{Within: within, Limit: limit}, nil
+}
+
+// UpcomingRemindersRequest godoc
+// @Summary Get the upcoming reminders.
+// @Description Get the pending reminders of the notes sorted by their reminder time.
+// @Produce json
+// @Param within query string false "Only the reminders that fire within the duration from now such as 24h. Default is all the pending reminders."
+// @Param limit query int false "The maximum number of reminders. Default is no limit."
+// @Success 200 {object} UpcomingRemindersResponse "Successfully getting the upcoming reminders"
+// @Router /reminders/upcoming [get]
+func makeUpcomingRemindersEndpoint(svc reminderService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(UpcomingRemindersRequest)
+		return UpcomingRemindersResponse{
+			Reminders: svc.Upcoming(request.Within, request.Limit),
+		}, nil
+	}
+}
+
+// makeReminderEventsHandler returns a handler that streams the fired
+// reminder events as server-sent events until the client disconnects.
+//
+// ReminderEvents godoc
+// @Summary Stream the reminder events.
+// @Description Stream the fired reminder events as server-sent events. Each event is named "reminder" and its data is the JSON encoded reminder.
+// @Produce text/event-stream
+// @Success 200 {object} reminder.Event "The stream of the reminder events"
+// @Failure 500 {object} problem.Problem "Streaming is not supported"
+// @Router /reminders/events [get]
+func makeReminderEventsHandler(broker reminderBroker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			encodeError(r.Context(), newErrorWrapper(errStreamingUnsupported), w)
+			return
+		}
+
+		events, unsubscribe := broker.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					return
+				}
+				if _, err := fmt.Fprintf(w, "event: reminder\ndata: %s\n\n", data); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
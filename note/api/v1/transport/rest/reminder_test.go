@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/reminder"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/clock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReminder(t *testing.T) {
+	suite.Run(t, new(ReminderTestSuite))
+}
+
+type ReminderTestSuite struct {
+	suite.Suite
+	now    time.Time
+	broker *reminder.Broker
+	svc    note.Service
+	router *mux.Router
+}
+
+func (s *ReminderTestSuite) SetupTest() {
+	s.now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.broker = reminder.NewBroker()
+	scheduler := reminder.New(&reminder.Config{
+		Clock:     clock.NewFake(s.now),
+		Notifiers: []reminder.Notifier{s.broker},
+	})
+	s.svc = reminder.Middleware(scheduler)(service.New(memory.New()))
+
+	s.router = mux.NewRouter()
+	for _, route := range ReminderRoutes(scheduler, s.broker) {
+		s.router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+}
+
+func (s *ReminderTestSuite) create(title string, remindAt time.Time) *note.Note {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle(title).SetRemindAt(remindAt))
+	s.Require().NoError(err)
+	return n
+}
+
+func (s *ReminderTestSuite) TestUpcoming() {
+	later := s.create("Later", s.now.Add(48*time.Hour))
+	soon := s.create("Soon", s.now.Add(time.Hour))
+
+	table := []struct {
+		name   string
+		target string
+		want   []*note.Note
+	}{
+		{name: "All the upcoming reminders", target: "/v1/reminders/upcoming", want: []*note.Note{soon, later}},
+		{name: "Within a duration", target: "/v1/reminders/upcoming?within=24h", want: []*note.Note{soon}},
+		{name: "With a limit", target: "/v1/reminders/upcoming?limit=1", want: []*note.Note{soon}},
+		{name: "Invalid parameters", target: "/v1/reminders/upcoming?within=soon&limit=-1", want: []*note.Note{soon, later}},
+	}
+
+	for _, row := range table {
+		s.Run(row.name, func() {
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, row.target, nil))
+			s.Equal(http.StatusOK, rec.Code)
+
+			var resp UpcomingRemindersResponse
+			s.Require().NoError(json.NewDecoder(rec.Body).Decode(&resp))
+			s.Require().Len(resp.Reminders, len(row.want))
+			for i, n := range row.want {
+				s.Equal(n.ID, resp.Reminders[i].NoteID)
+				s.Equal(n.GetTitle(), resp.Reminders[i].Title)
+			}
+		})
+	}
+}
+
+func (s *ReminderTestSuite) TestEvents() {
+	srv := httptest.NewServer(s.router)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(dummyCtx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/reminders/events", nil)
+	s.Require().NoError(err)
+
+	resp, err := srv.Client().Do(req)
+	s.Require().NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	s.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	event := reminder.Event{NoteID: s.create("Note", s.now.Add(time.Hour)).ID, Title: "Note", RemindAt: s.now}
+	s.Require().NoError(s.broker.Notify(dummyCtx, event))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	s.Require().NoError(err)
+	s.Equal("event: reminder\n", line)
+
+	line, err = reader.ReadString('\n')
+	s.Require().NoError(err)
+
+	var got reminder.Event
+	s.Require().NoError(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &got))
+	s.Equal(event.NoteID, got.NoteID)
+	s.True(event.RemindAt.Equal(got.RemindAt))
+}
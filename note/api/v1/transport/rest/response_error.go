@@ -1,6 +0,0 @@
-package rest
-
-// ResponseError is the container to any error response.
-type ResponseError struct {
-	Message string `json:"message,omitempty" example:"Note not found"`
-}
@@ -8,51 +8,86 @@ import (
 	"noterfy/api"
 	"noterfy/note"
 	_ "noterfy/note/api/v1/transport/rest/docs" // To register the Swagger documentation
+	"noterfy/note/render"
 	nhttp "noterfy/pkg/http"
+	"noterfy/pkg/trace"
 )
 
 // Routes returns all the routes that is part of the
-// note API service.
-func Routes(svc note.Service) []api.Route {
-	return getRoutes(svc)
+// note API service. The templates are use to create the
+// notes from a template and can be nil. The cache is the
+// HTTP caching of the notes and can be nil to use the
+// defaults. The opts are applied to the server of every
+// route, e.g. trace.ServerOptions.
+func Routes(svc note.Service, templates templateRenderer, cache *CacheConfig, opts ...httptransport.ServerOption) []api.Route {
+	return getRoutes(svc, templates, cache, opts...)
 }
 
-func getRoutes(svc note.Service) []api.Route {
+// withOptions returns the options of a server followed by opts,
+// the options of every route.
+func withOptions(opts []httptransport.ServerOption, server ...httptransport.ServerOption) []httptransport.ServerOption {
+	return append(server, opts...)
+}
+
+func getRoutes(svc note.Service, templates templateRenderer, cache *CacheConfig, opts ...httptransport.ServerOption) []api.Route {
+	renderer := render.New()
+	svc = render.Middleware(renderer)(svc)
+	cache = cache.check()
+
+	// The encoder negotiates the format of the responses
+	// with the request.
+	opts = append([]httptransport.ServerOption{
+		httptransport.ServerBefore(withRequest),
+		httptransport.ServerErrorEncoder(encodeTransportError),
+	}, opts...)
 
 	getHandler := httptransport.NewServer(
-		makeGetEndpoint(svc),
-		decodeGetRequest,
+		makeGetEndpoint(svc, renderer, cache.CacheControl),
+		trace.DecodeRequestFunc(decodeGetRequest),
 		encodeResponse,
+		opts...,
 	)
 
 	createHandler := httptransport.NewServer(
-		makeCreateEndpoint(svc),
-		decodeCreateRequest,
+		makeCreateEndpoint(svc, templates),
+		trace.DecodeRequestFunc(decodeCreateRequest),
 		encodeResponse,
+		opts...,
 	)
 
 	updateHandler := httptransport.NewServer(
 		makeUpdateEndpoint(svc),
-		decodeUpdateRequest,
+		trace.DecodeRequestFunc(decodeUpdateRequest),
+		encodeResponse,
+		opts...,
+	)
+
+	patchHandler := httptransport.NewServer(
+		makePatchEndpoint(svc),
+		trace.DecodeRequestFunc(decodePatchRequest),
 		encodeResponse,
+		opts...,
 	)
 
 	deleteHandler := httptransport.NewServer(
 		makeDeleteEndpoint(svc),
-		decodeDeleteRequest,
+		trace.DecodeRequestFunc(decodeDeleteRequest),
 		encodeResponse,
+		opts...,
 	)
 
 	fetchHandler := httptransport.NewServer(
-		makeFetchEndpoint(svc),
-		decodeFetchRequest,
+		makeFetchEndpoint(svc, cache),
+		trace.DecodeRequestFunc(decodeFetchRequest),
 		encodeResponse,
+		opts...,
 	)
 
 	routes := []api.Route{
 		&nhttp.Route{HandlerValue: getHandler, MethodValue: http.MethodGet, PathValue: "/v1/note/{id}"},
 		&nhttp.Route{HandlerValue: createHandler, MethodValue: http.MethodPost, PathValue: "/v1/note"},
 		&nhttp.Route{HandlerValue: updateHandler, MethodValue: http.MethodPut, PathValue: "/v1/note"},
+		&nhttp.Route{HandlerValue: patchHandler, MethodValue: http.MethodPatch, PathValue: "/v1/note/{id}"},
 		&nhttp.Route{HandlerValue: deleteHandler, MethodValue: http.MethodDelete, PathValue: "/v1/note/{id}"},
 		&nhttp.Route{HandlerValue: fetchHandler, MethodValue: http.MethodGet, PathValue: "/v1/notes"},
 	}
@@ -0,0 +1,282 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"net/http"
+	"noterfy/api"
+	"noterfy/note"
+	"noterfy/note/template"
+	nhttp "noterfy/pkg/http"
+	"noterfy/pkg/principal"
+)
+
+// userHeader is the header that contains the user creating the note
+// which is available to the templates as {{user}}. It's only used for
+// the anonymous requests, see requestUser.
+const userHeader = "X-User"
+
+// requestUser returns the user creating the note from r. The
+// authenticated principal takes precedence over the userHeader that
+// any client can set.
+func requestUser(r *http.Request) string {
+	if p, ok := principal.FromContext(r.Context()); ok {
+		return p.Name
+	}
+	return r.Header.Get(userHeader)
+}
+
+type templateService interface {
+	Create(ctx context.Context, t *template.Template) (*template.Template, error)
+	Update(ctx context.Context, t *template.Template) (*template.Template, error)
+	Delete(ctx context.Context, name string) error
+	Get(ctx context.Context, name string) (*template.Template, error)
+	List(ctx context.Context) ([]*template.Template, error)
+}
+
+type templateRenderer interface {
+	Render(ctx context.Context, name string, data template.Data) (*note.Note, error)
+}
+
+// TemplateRoutes returns all the routes for managing the note templates.
+// The opts are applied to the server of every route.
+func TemplateRoutes(svc templateService, opts ...httptransport.ServerOption) []api.Route {
+	listHandler := httptransport.NewServer(
+		makeListTemplatesEndpoint(svc),
+		decodeListTemplatesRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	createHandler := httptransport.NewServer(
+		makeCreateTemplateEndpoint(svc),
+		decodeCreateTemplateRequest,
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	getHandler := httptransport.NewServer(
+		makeGetTemplateEndpoint(svc),
+		decodeGetTemplateRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	updateHandler := httptransport.NewServer(
+		makeUpdateTemplateEndpoint(svc),
+		decodeUpdateTemplateRequest,
+		encodeResponse,
+		withOptions(opts,
+			httptransport.ServerErrorEncoder(encodeTransportError),
+		)...,
+	)
+
+	deleteHandler := httptransport.NewServer(
+		makeDeleteTemplateEndpoint(svc),
+		decodeDeleteTemplateRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: listHandler, MethodValue: http.MethodGet, PathValue: "/v1/templates"},
+		&nhttp.Route{HandlerValue: createHandler, MethodValue: http.MethodPost, PathValue: "/v1/templates"},
+		&nhttp.Route{HandlerValue: getHandler, MethodValue: http.MethodGet, PathValue: "/v1/templates/{name}"},
+		&nhttp.Route{HandlerValue: updateHandler, MethodValue: http.MethodPut, PathValue: "/v1/templates/{name}"},
+		&nhttp.Route{HandlerValue: deleteHandler, MethodValue: http.MethodDelete, PathValue: "/v1/templates/{name}"},
+	}
+}
+
+// ListTemplatesRequest is a container for the list templates request API.
+type ListTemplatesRequest struct{}
+
+// ListTemplatesResponse is a container for the list templates response API.
+type ListTemplatesResponse struct {
+	Templates []*template.Template `json:"templates"`
+}
+
+func decodeListTemplatesRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return ListTemplatesRequest{}, nil
+}
+
+// ListTemplatesRequest godoc
+// @Summary List the note templates.
+// @Description List all the note templates sorted by name.
+// @Produce json
+// @Success 200 {object} ListTemplatesResponse "Successfully listed the templates"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /templates [get]
+func makeListTemplatesEndpoint(svc templateService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		templates, err := svc.List(ctx)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return ListTemplatesResponse{Templates: templates}, nil
+	}
+}
+
+// CreateTemplateRequest is a container for the create template request API.
+type CreateTemplateRequest struct {
+	Template *template.Template `json:"template"`
+}
+
+// CreateTemplateResponse is a container for the create template response API.
+type CreateTemplateResponse struct {
+	Template *template.Template `json:"template"`
+}
+
+func decodeCreateTemplateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req CreateTemplateRequest
+	if err := json.NewDecoder(limitBody(r)).Decode(&req); err != nil {
+		return nil, malformedBody(err)
+	}
+
+	if req.Template == nil {
+		return nil, template.ErrInvalid
+	}
+	return req, nil
+}
+
+// CreateTemplateRequest godoc
+// @Summary Create a note template.
+// @Description Create a named note template. The title and the content are Go text/template sources restricted to the {{date}}, {{time}}, {{user}}, {{prompt "name"}}, {{upper}}, {{lower}} and {{trim}} functions and the comparison builtins.
+// @Accept json
+// @Produce json
+// @Param CreateTemplateRequest body CreateTemplateRequest true "A body containing the new template"
+// @Success 200 {object} CreateTemplateResponse "Successfully created the template"
+// @Failure 400 {object} problem.Problem "The template is invalid"
+// @Failure 409 {object} problem.Problem "A template with the same name already exists"
+// @Failure 413 {object} problem.Problem "The body exceeds the maximum size"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /templates [post]
+func makeCreateTemplateEndpoint(svc templateService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(CreateTemplateRequest)
+		t, err := svc.Create(ctx, request.Template)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return CreateTemplateResponse{Template: t}, nil
+	}
+}
+
+// GetTemplateRequest is a container for the get template request API.
+type GetTemplateRequest struct {
+	Name string `json:"name"`
+}
+
+// GetTemplateResponse is a container for the get template response API.
+type GetTemplateResponse struct {
+	Template *template.Template `json:"template"`
+}
+
+func decodeGetTemplateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return GetTemplateRequest{Name: mux.Vars(r)["name"]}, nil
+}
+
+// GetTemplateRequest godoc
+// @Summary Get a note template.
+// @Description Get the note template with the name.
+// @Produce json
+// @Param name path string true "Name of the template"
+// @Success 200 {object} GetTemplateResponse "Successfully getting the template"
+// @Failure 404 {object} problem.Problem "Template is not found"
+// @Router /templates/{name} [get]
+func makeGetTemplateEndpoint(svc templateService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(GetTemplateRequest)
+		t, err := svc.Get(ctx, request.Name)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return GetTemplateResponse{Template: t}, nil
+	}
+}
+
+// UpdateTemplateRequest is a container for the update template request API.
+type UpdateTemplateRequest struct {
+	Template *template.Template `json:"template"`
+}
+
+// UpdateTemplateResponse is a container for the update template response API.
+type UpdateTemplateResponse struct {
+	Template *template.Template `json:"template"`
+}
+
+func decodeUpdateTemplateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req UpdateTemplateRequest
+	if err := json.NewDecoder(limitBody(r)).Decode(&req); err != nil {
+		return nil, malformedBody(err)
+	}
+
+	if req.Template == nil {
+		return nil, template.ErrInvalid
+	}
+
+	// The name in the path takes precedence over the body.
+	req.Template.Name = mux.Vars(r)["name"]
+	return req, nil
+}
+
+// UpdateTemplateRequest godoc
+// @Summary Update a note template.
+// @Description Replace the title, the content and the description of the note template with the name.
+// @Accept json
+// @Produce json
+// @Param name path string true "Name of the template"
+// @Param UpdateTemplateRequest body UpdateTemplateRequest true "A body containing the updated template"
+// @Success 200 {object} UpdateTemplateResponse "Successfully updated the template"
+// @Failure 400 {object} problem.Problem "The template is invalid"
+// @Failure 404 {object} problem.Problem "Template is not found"
+// @Failure 413 {object} problem.Problem "The body exceeds the maximum size"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /templates/{name} [put]
+func makeUpdateTemplateEndpoint(svc templateService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(UpdateTemplateRequest)
+		t, err := svc.Update(ctx, request.Template)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return UpdateTemplateResponse{Template: t}, nil
+	}
+}
+
+// DeleteTemplateRequest is a container for the delete template request API.
+type DeleteTemplateRequest struct {
+	Name string `json:"name"`
+}
+
+// DeleteTemplateResponse is a container for the delete template response API.
+type DeleteTemplateResponse struct {
+	Message string `json:"message"`
+}
+
+func decodeDeleteTemplateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return DeleteTemplateRequest{Name: mux.Vars(r)["name"]}, nil
+}
+
+// DeleteTemplateRequest godoc
+// @Summary Delete a note template.
+// @Description Delete the note template with the name. The notes created from the template are not affected.
+// @Produce json
+// @Param name path string true "Name of the template"
+// @Success 200 {object} DeleteTemplateResponse "Successfully deleted the template"
+// @Failure 404 {object} problem.Problem "Template is not found"
+// @Failure 500 {object} problem.Problem "Unexpected server internal error"
+// @Router /templates/{name} [delete]
+func makeDeleteTemplateEndpoint(svc templateService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(DeleteTemplateRequest)
+		if err := svc.Delete(ctx, request.Name); err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return DeleteTemplateResponse{Message: "Successfully Deleted"}, nil
+	}
+}
@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/note/template"
+	"noterfy/pkg/clock"
+	"noterfy/pkg/principal"
+	"noterfy/pkg/problem"
+	"testing"
+	"time"
+)
+
+func TestTemplate(t *testing.T) {
+	suite.Run(t, new(TemplateTestSuite))
+}
+
+type TemplateTestSuite struct {
+	suite.Suite
+	router *mux.Router
+}
+
+func (s *TemplateTestSuite) SetupTest() {
+	templates := template.New(afero.NewMemMapFs(), clock.NewFake(time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC)))
+
+	routes := TemplateRoutes(templates)
+	routes = append(routes, getRoutes(service.New(memory.New()), templates, nil)...)
+
+	s.router = mux.NewRouter()
+	for _, route := range routes {
+		s.router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+}
+
+func (s *TemplateTestSuite) do(method, target string, body interface{}, header http.Header, resp interface{}) *httptest.ResponseRecorder {
+	return s.doWithContext(context.Background(), method, target, body, header, resp)
+}
+
+func (s *TemplateTestSuite) doWithContext(ctx context.Context, method, target string, body interface{}, header http.Header, resp interface{}) *httptest.ResponseRecorder {
+	var buff bytes.Buffer
+	if body != nil {
+		s.Require().NoError(json.NewEncoder(&buff).Encode(body))
+	}
+
+	req := httptest.NewRequest(method, target, &buff).WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(resp))
+	return rec
+}
+
+func (s *TemplateTestSuite) TestCRUD() {
+	incident := &template.Template{
+		Name:    "incident",
+		Title:   "Incident {{date}}",
+		Content: "Severity: {{prompt \"severity\"}}",
+	}
+
+	s.Run("Creating a template", func() {
+		var resp CreateTemplateResponse
+		rec := s.do(http.MethodPost, "/v1/templates", CreateTemplateRequest{Template: incident}, nil, &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal([]string{"severity"}, resp.Template.Prompts)
+	})
+
+	s.Run("Creating an existing template", func() {
+		var resp problem.Problem
+		rec := s.do(http.MethodPost, "/v1/templates", CreateTemplateRequest{Template: incident}, nil, &resp)
+		s.Equal(http.StatusConflict, rec.Code)
+		s.Equal(CodeTemplateConflict, resp.Code)
+	})
+
+	s.Run("Creating an invalid template", func() {
+		var resp problem.Problem
+		rec := s.do(http.MethodPost, "/v1/templates", CreateTemplateRequest{Template: &template.Template{Name: "bad", Content: "{{range 1}}{{end}}"}}, nil, &resp)
+		s.Equal(http.StatusBadRequest, rec.Code)
+		s.Equal(CodeTemplateInvalid, resp.Code)
+	})
+
+	s.Run("Updating a template", func() {
+		var resp UpdateTemplateResponse
+		rec := s.do(http.MethodPut, "/v1/templates/incident", UpdateTemplateRequest{Template: &template.Template{
+			Title:   incident.Title,
+			Content: "Severity: {{prompt \"severity\"}} by {{user}}",
+		}}, nil, &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("incident", resp.Template.Name)
+	})
+
+	s.Run("Listing the templates", func() {
+		var resp ListTemplatesResponse
+		rec := s.do(http.MethodGet, "/v1/templates", nil, nil, &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Require().Len(resp.Templates, 1)
+		s.Equal("incident", resp.Templates[0].Name)
+	})
+
+	s.Run("Creating a note from the template as an anonymous client", func() {
+		var resp CreateResponse
+		rec := s.do(http.MethodPost, "/v1/note?template=incident", CreateRequest{
+			Variables: map[string]string{"severity": "high"},
+		}, http.Header{userHeader: {"jayson"}}, &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("Incident 2021-01-31", resp.Note.GetTitle())
+		s.Equal("Severity: high by jayson", resp.Note.GetContent())
+	})
+
+	s.Run("Creating a note from the template as an authenticated client", func() {
+		ctx := principal.WithContext(context.Background(), principal.Principal{
+			Name:   "billing",
+			Method: principal.MethodAPIKey,
+		})
+
+		// The header can't impersonate another user.
+		var resp CreateResponse
+		rec := s.doWithContext(ctx, http.MethodPost, "/v1/note?template=incident", CreateRequest{
+			Variables: map[string]string{"severity": "low"},
+		}, http.Header{userHeader: {"jayson"}}, &resp)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("Severity: low by billing", resp.Note.GetContent())
+	})
+
+	s.Run("Creating a note from the template with a missing variable", func() {
+		var resp problem.Problem
+		rec := s.do(http.MethodPost, "/v1/note?template=incident", nil, nil, &resp)
+		s.Equal(http.StatusBadRequest, rec.Code)
+		s.Equal(CodeTemplateMissingVariable, resp.Code)
+	})
+
+	s.Run("Deleting a template", func() {
+		var resp DeleteTemplateResponse
+		rec := s.do(http.MethodDelete, "/v1/templates/incident", nil, nil, &resp)
+		s.Equal(http.StatusOK, rec.Code)
+
+		var errResp problem.Problem
+		rec = s.do(http.MethodGet, "/v1/templates/incident", nil, nil, &errResp)
+		s.Equal(http.StatusNotFound, rec.Code)
+		s.Equal(CodeTemplateNotFound, errResp.Code)
+	})
+
+	s.Run("Creating a note from a template that not exists", func() {
+		var resp problem.Problem
+		rec := s.do(http.MethodPost, "/v1/note?template=incident", nil, nil, &resp)
+		s.Equal(http.StatusNotFound, rec.Code)
+		s.Equal(CodeTemplateNotFound, resp.Code)
+	})
+}
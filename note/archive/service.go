@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"noterfy/note"
+	"noterfy/note/noteutil"
+	"noterfy/pkg/clock"
+	"time"
+)
+
+// scanPageSize is the page size use when scanning the notes
+// for the auto-archive rules.
+const scanPageSize = 100
+
+// New takes the note service and the clock use to tell the archived
+// time and returns an archive service instance.
+func New(svc note.Service, c clock.Clock) *Service {
+	return &Service{svc: svc, clock: c}
+}
+
+// Service manages the archived state of the notes. The archived
+// notes are kept in the store but are hidden from the default
+// fetch of the notes.
+type Service struct {
+	svc   note.Service
+	clock clock.Clock
+}
+
+// Archive archives the note with id. Archiving an archived note
+// keeps its original archived time.
+func (s *Service) Archive(ctx context.Context, id uuid.UUID) (*note.Note, error) {
+	n, err := s.svc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.GetIsArchived() {
+		return n, nil
+	}
+
+	return s.svc.Update(ctx, new(note.Note).
+		SetID(id).
+		SetIsArchived(true).
+		SetArchivedTime(s.clock.Now()))
+}
+
+// Unarchive restores the archived note with id.
+func (s *Service) Unarchive(ctx context.Context, id uuid.UUID) (*note.Note, error) {
+	n, err := s.svc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.GetIsArchived() {
+		return n, nil
+	}
+
+	return s.svc.Update(ctx, new(note.Note).SetID(id).SetIsArchived(false))
+}
+
+// Apply archives the notes that match any of the rules. It returns
+// the IDs of the archived notes.
+func (s *Service) Apply(ctx context.Context, rules []Rule) ([]uuid.UUID, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	now := s.clock.Now()
+
+	// Collect the matching notes first so the updates
+	// don't shift the pages being scanned.
+	var matched []uuid.UUID
+	err := noteutil.ForEach(ctx, s.svc, note.Pagination{
+		Size:      scanPageSize,
+		SortBy:    note.SortByID,
+		Ascending: true,
+		Archived:  note.ArchivedExclude,
+	}, func(n *note.Note) error {
+		for _, rule := range rules {
+			if rule.Match(n, now) {
+				matched = append(matched, n.ID)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	archived := make([]uuid.UUID, 0, len(matched))
+	for _, id := range matched {
+		if _, err := s.Archive(ctx, id); err != nil {
+			return archived, err
+		}
+		archived = append(archived, id)
+	}
+
+	return archived, nil
+}
+
+// Rule is an auto-archive rule that matches the notes that were
+// not touched for a number of days.
+type Rule struct {
+	// Name is the name of the rule use in the logs.
+	Name string
+	// UntouchedDays is the number of days since the note was last
+	// updated, or created when it was never updated, before the
+	// note is archived. A rule with 0 days never matches.
+	UntouchedDays int
+	// SkipFavorites leaves the favorite notes untouched.
+	SkipFavorites bool
+	// SkipPinned leaves the pinned notes untouched.
+	SkipPinned bool
+}
+
+// Match reports whether the rule archives the note n at the time now.
+func (r Rule) Match(n *note.Note, now time.Time) bool {
+	if r.UntouchedDays <= 0 || n.GetIsArchived() {
+		return false
+	}
+
+	if (r.SkipFavorites && n.GetIsFavorite()) || (r.SkipPinned && n.GetIsPinned()) {
+		return false
+	}
+
+	touched := n.GetUpdatedTime()
+	if touched.IsZero() {
+		touched = n.GetCreatedTime()
+	}
+
+	// Don't guess the age of a note without timestamps.
+	if touched.IsZero() {
+		return false
+	}
+
+	return !touched.After(now.AddDate(0, 0, -r.UntouchedDays))
+}
@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noterfy/note"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/clock"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+func TestService(t *testing.T) {
+	suite.Run(t, new(ServiceTestSuite))
+}
+
+type ServiceTestSuite struct {
+	suite.Suite
+	clock *clock.Fake
+	notes note.Service
+	svc   *Service
+}
+
+func (s *ServiceTestSuite) SetupTest() {
+	s.clock = clock.NewFake(time.Now())
+	s.notes = service.New(memory.New())
+	s.svc = New(s.notes, s.clock)
+}
+
+func (s *ServiceTestSuite) create(n *note.Note) *note.Note {
+	created, err := s.notes.Create(dummyCtx, n)
+	s.Require().NoError(err)
+	return created
+}
+
+func (s *ServiceTestSuite) fetch(filter note.ArchivedFilter) []uuid.UUID {
+	iter, err := s.notes.Fetch(dummyCtx, &note.Pagination{SortBy: note.SortByTitle, Ascending: true, Archived: filter})
+	s.Require().NoError(err)
+	defer func() { _ = iter.Close() }()
+
+	var ids []uuid.UUID
+	for iter.Next() {
+		ids = append(ids, iter.Note().ID)
+	}
+	return ids
+}
+
+func (s *ServiceTestSuite) TestArchive() {
+	first := s.create(new(note.Note).SetTitle("First"))
+	second := s.create(new(note.Note).SetTitle("Second"))
+
+	s.Run("Archiving a note", func() {
+		archived, err := s.svc.Archive(dummyCtx, first.ID)
+		s.Require().NoError(err)
+		s.True(archived.GetIsArchived())
+		s.Equal(s.clock.Now(), archived.GetArchivedTime())
+
+		s.Equal([]uuid.UUID{second.ID}, s.fetch(note.ArchivedExclude))
+		s.Equal([]uuid.UUID{first.ID}, s.fetch(note.ArchivedOnly))
+		s.Equal([]uuid.UUID{first.ID, second.ID}, s.fetch(note.ArchivedInclude))
+	})
+
+	s.Run("Archiving an archived note keeps the archived time", func() {
+		archivedTime := s.clock.Now()
+		s.clock.Advance(time.Hour)
+
+		archived, err := s.svc.Archive(dummyCtx, first.ID)
+		s.Require().NoError(err)
+		s.Equal(archivedTime, archived.GetArchivedTime())
+	})
+
+	s.Run("Unarchiving a note", func() {
+		unarchived, err := s.svc.Unarchive(dummyCtx, first.ID)
+		s.Require().NoError(err)
+		s.False(unarchived.GetIsArchived())
+		s.Nil(unarchived.ArchivedTime)
+		s.Equal([]uuid.UUID{first.ID, second.ID}, s.fetch(note.ArchivedExclude))
+	})
+
+	s.Run("Archiving a note that not exists", func() {
+		_, err := s.svc.Archive(dummyCtx, uuid.New())
+		s.ErrorIs(err, note.ErrNotFound)
+	})
+}
+
+func (s *ServiceTestSuite) TestApply() {
+	stale := s.create(new(note.Note).SetTitle("Stale"))
+	favorite := s.create(new(note.Note).SetTitle("Favorite").SetIsFavorite(true))
+	pinned := s.create(new(note.Note).SetTitle("Pinned").SetIsPinned(true))
+
+	rules := []Rule{{Name: "stale", UntouchedDays: 30, SkipFavorites: true}}
+
+	s.Run("Notes touched recently are not archived", func() {
+		s.clock.Set(time.Now().AddDate(0, 0, 29))
+		archived, err := s.svc.Apply(dummyCtx, rules)
+		s.Require().NoError(err)
+		s.Empty(archived)
+	})
+
+	s.Run("Untouched notes are archived", func() {
+		s.clock.Set(time.Now().AddDate(0, 0, 31))
+		archived, err := s.svc.Apply(dummyCtx, rules)
+		s.Require().NoError(err)
+		s.ElementsMatch([]uuid.UUID{stale.ID, pinned.ID}, archived)
+		s.Equal([]uuid.UUID{favorite.ID}, s.fetch(note.ArchivedExclude))
+	})
+
+	s.Run("Archived notes are not archived again", func() {
+		archived, err := s.svc.Apply(dummyCtx, rules)
+		s.Require().NoError(err)
+		s.Empty(archived)
+	})
+}
+
+func (s *ServiceTestSuite) TestRuleMatch() {
+	now := time.Now()
+	old := now.AddDate(0, 0, -10)
+
+	table := []struct {
+		name string
+		rule Rule
+		note *note.Note
+		want bool
+	}{
+		{name: "Untouched note", rule: Rule{UntouchedDays: 7}, note: new(note.Note).SetCreatedTime(old), want: true},
+		{name: "Recently updated note", rule: Rule{UntouchedDays: 7}, note: new(note.Note).SetCreatedTime(old).SetUpdatedTime(now), want: false},
+		{name: "Rule without days", rule: Rule{}, note: new(note.Note).SetCreatedTime(old), want: false},
+		{name: "Skipped favorite note", rule: Rule{UntouchedDays: 7, SkipFavorites: true}, note: new(note.Note).SetCreatedTime(old).SetIsFavorite(true), want: false},
+		{name: "Skipped pinned note", rule: Rule{UntouchedDays: 7, SkipPinned: true}, note: new(note.Note).SetCreatedTime(old).SetIsPinned(true), want: false},
+		{name: "Note without timestamps", rule: Rule{UntouchedDays: 7}, note: new(note.Note), want: false},
+		{name: "Archived note", rule: Rule{UntouchedDays: 7}, note: new(note.Note).SetCreatedTime(old).SetIsArchived(true), want: false},
+	}
+
+	for _, row := range table {
+		s.Run(row.name, func() {
+			s.Equal(row.want, row.rule.Match(row.note, now))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package note
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+var (
+	// ErrAttachmentNotFound is an error for any operation where the
+	// attachment of the note is not found.
+	ErrAttachmentNotFound = errors.New("note: attachment not found")
+	// ErrAttachmentTooLarge is an error when the attachment exceeds
+	// the maximum allowed size.
+	ErrAttachmentTooLarge = errors.New("note: attachment exceeds the maximum size")
+)
+
+// Attachment represents a file that is attached to a note. The
+// content of the file is stored in the blob store and is addressed
+// by its Digest.
+type Attachment struct {
+	// ID is a unique identifier UUID of the attachment.
+	ID uuid.UUID `json:"id,omitempty" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	// Name is the original filename of the attachment.
+	Name string `json:"name,omitempty" example:"diagram.png"`
+	// MediaType is the sniffed MIME type of the attachment.
+	MediaType string `json:"media_type,omitempty" example:"image/png"`
+	// Size is the size of the attachment in bytes.
+	Size int64 `json:"size,omitempty" example:"1024"`
+	// Digest is the hex-encoded SHA-256 checksum of the attachment content.
+	Digest string `json:"digest,omitempty" example:"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"`
+	// CreatedTime is the timestamp when the attachment was uploaded.
+	CreatedTime time.Time `json:"created_time,omitempty" example:"2016-02-24 11:12:13"`
+}
+
+// GetAttachment gets the attachment of the note with id. It returns
+// nil when the attachment is not found.
+func (n *Note) GetAttachment(id uuid.UUID) *Attachment {
+	for _, a := range n.Attachments {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package attachment
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"noterfy/blob"
+	"noterfy/note"
+	"noterfy/note/noteutil"
+	"noterfy/pkg/logger"
+	"noterfy/pkg/timestamp"
+	"sync"
+)
+
+// gcPageSize is the page size use when scanning the notes
+// for the referenced blobs.
+const gcPageSize = 100
+
+// New takes the note store and the blob store and returns
+// an attachment service instance.
+func New(store note.Store, blobs blob.Store) *Service {
+	return &Service{store: store, blobs: blobs}
+}
+
+// Service manages the attachments of the notes. The attachment
+// metadata is stored in the note while the content is stored in
+// the blob store.
+type Service struct {
+	store note.Store
+	blobs blob.Store
+
+	// mu serializes the changes to the attachments of the notes
+	// and the garbage collection so a newly stored blob can't be
+	// collected before it's referenced by its note.
+	mu sync.Mutex
+}
+
+// Add stores the content from r as a new attachment a of the
+// note with noteID. It returns the attachment with the generated
+// ID, digest and size.
+func (s *Service) Add(ctx context.Context, noteID uuid.UUID, a *note.Attachment, r io.Reader) (*note.Attachment, error) {
+	if noteID == uuid.Nil {
+		return nil, note.ErrNilID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.store.Get(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.blobs.Put(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	newAttachment := &note.Attachment{
+		ID:          uuid.New(),
+		Name:        a.Name,
+		MediaType:   a.MediaType,
+		Size:        b.Size,
+		Digest:      b.Digest,
+		CreatedTime: *timestamp.GenerateTimestamp(),
+	}
+
+	attachments := make([]*note.Attachment, 0, len(n.Attachments)+1)
+	attachments = append(attachments, n.Attachments...)
+	attachments = append(attachments, newAttachment)
+
+	if err := s.updateAttachments(ctx, noteID, attachments); err != nil {
+		if rerr := s.blobs.Release(ctx, b.Digest); rerr != nil {
+			logger.FromContext(ctx).WithField("digest", b.Digest).Error("attachment: unable to release blob: ", rerr)
+		}
+		return nil, err
+	}
+
+	return newAttachment, nil
+}
+
+// Open opens the content of the attachment with attachmentID of the note
+// with noteID. The caller must close the returned reader.
+func (s *Service) Open(ctx context.Context, noteID, attachmentID uuid.UUID) (io.ReadSeekCloser, *note.Attachment, error) {
+	if noteID == uuid.Nil {
+		return nil, nil, note.ErrNilID
+	}
+
+	n, err := s.store.Get(ctx, noteID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a := n.GetAttachment(attachmentID)
+	if a == nil {
+		return nil, nil, note.ErrAttachmentNotFound
+	}
+
+	r, _, err := s.blobs.Open(ctx, a.Digest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("attachment: unable to open attachment '%s': %w", a.ID, err)
+	}
+
+	return r, a, nil
+}
+
+// Remove removes the attachment with attachmentID from the note with noteID.
+// The content is removed from the blob store by the garbage collection
+// once it isn't referenced anymore.
+func (s *Service) Remove(ctx context.Context, noteID, attachmentID uuid.UUID) error {
+	if noteID == uuid.Nil {
+		return note.ErrNilID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.store.Get(ctx, noteID)
+	if err != nil {
+		return err
+	}
+
+	removed := n.GetAttachment(attachmentID)
+	if removed == nil {
+		return note.ErrAttachmentNotFound
+	}
+
+	// Use a non-nil slice so that removing the last attachment
+	// is not ignored when merging the note in the store.
+	attachments := make([]*note.Attachment, 0, len(n.Attachments))
+	for _, a := range n.Attachments {
+		if a.ID != attachmentID {
+			attachments = append(attachments, a)
+		}
+	}
+
+	if err := s.updateAttachments(ctx, noteID, attachments); err != nil {
+		return err
+	}
+
+	return s.blobs.Release(ctx, removed.Digest)
+}
+
+// GC removes the blobs that are not referenced by any note. This also
+// cleans up the blobs of the deleted notes. It returns the digests of
+// the removed blobs.
+func (s *Service) GC(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inUse := make(map[string]bool)
+
+	err := noteutil.ForEach(ctx, s.store, note.Pagination{
+		Size:      gcPageSize,
+		SortBy:    note.SortByID,
+		Ascending: true,
+		Archived:  note.ArchivedInclude,
+	}, func(n *note.Note) error {
+		for _, a := range n.Attachments {
+			inUse[a.Digest] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.blobs.GC(ctx, func(digest string) bool {
+		return inUse[digest]
+	})
+}
+
+func (s *Service) updateAttachments(ctx context.Context, noteID uuid.UUID, attachments []*note.Attachment) error {
+	_, err := s.store.Update(ctx, &note.Note{
+		ID:          noteID,
+		Attachments: attachments,
+		UpdatedTime: timestamp.GenerateTimestamp(),
+	})
+	return err
+}
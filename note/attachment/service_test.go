@@ -0,0 +1,128 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"noterfy/blob"
+	blobstore "noterfy/blob/store/file"
+	"noterfy/note"
+	"noterfy/note/store/memory"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+	store note.Store
+	blobs blob.Store
+	svc   *Service
+	note  *note.Note
+}
+
+func (s *TestSuite) SetupTest() {
+	s.store = memory.New()
+	s.blobs = blobstore.New(afero.NewMemMapFs())
+	s.svc = New(s.store, s.blobs)
+
+	s.note = new(note.Note).SetID(uuid.New()).SetTitle("Note with attachments")
+	s.Require().NoError(s.store.Insert(dummyCtx, s.note))
+}
+
+func (s *TestSuite) add(content string) *note.Attachment {
+	a, err := s.svc.Add(dummyCtx, s.note.ID, &note.Attachment{
+		Name:      "hello.txt",
+		MediaType: "text/plain; charset=utf-8",
+	}, bytes.NewBufferString(content))
+	s.Require().NoError(err)
+	return a
+}
+
+func (s *TestSuite) TestAdd() {
+	s.Run("Adding an attachment should store the metadata in the note", func() {
+		got := s.add("hello")
+		s.NotEqual(uuid.Nil, got.ID)
+		s.Equal(int64(5), got.Size)
+		s.NotEmpty(got.Digest)
+
+		n, err := s.store.Get(dummyCtx, s.note.ID)
+		s.Require().NoError(err)
+		s.Equal([]*note.Attachment{got}, n.Attachments)
+		s.NotNil(n.UpdatedTime)
+	})
+
+	s.Run("Adding an attachment to a note that not exists should return an error", func() {
+		_, err := s.svc.Add(dummyCtx, uuid.New(), &note.Attachment{}, bytes.NewBufferString("hello"))
+		s.Equal(note.ErrNotFound, err)
+	})
+}
+
+func (s *TestSuite) TestOpen() {
+	a := s.add("hello")
+
+	s.Run("Opening an attachment should return its content", func() {
+		r, got, err := s.svc.Open(dummyCtx, s.note.ID, a.ID)
+		s.Require().NoError(err)
+		defer func() { _ = r.Close() }()
+
+		content, err := ioutil.ReadAll(r)
+		s.Require().NoError(err)
+		s.Equal("hello", string(content))
+		s.Equal(a, got)
+	})
+
+	s.Run("Opening an attachment that not exists should return an error", func() {
+		_, _, err := s.svc.Open(dummyCtx, s.note.ID, uuid.New())
+		s.Equal(note.ErrAttachmentNotFound, err)
+	})
+}
+
+func (s *TestSuite) TestRemove() {
+	first := s.add("first")
+	second := s.add("second")
+
+	s.Require().NoError(s.svc.Remove(dummyCtx, s.note.ID, first.ID))
+	n, err := s.store.Get(dummyCtx, s.note.ID)
+	s.Require().NoError(err)
+	s.Equal([]*note.Attachment{second}, n.Attachments)
+
+	s.Require().NoError(s.svc.Remove(dummyCtx, s.note.ID, second.ID))
+	n, err = s.store.Get(dummyCtx, s.note.ID)
+	s.Require().NoError(err)
+	s.Empty(n.Attachments)
+
+	err = s.svc.Remove(dummyCtx, s.note.ID, second.ID)
+	s.Equal(note.ErrAttachmentNotFound, err)
+}
+
+func (s *TestSuite) TestGC() {
+	removed := s.add("removed")
+	deduplicated := s.add("deduplicated")
+	s.add("deduplicated")
+
+	s.Require().NoError(s.svc.Remove(dummyCtx, s.note.ID, removed.ID))
+	s.Require().NoError(s.svc.Remove(dummyCtx, s.note.ID, deduplicated.ID))
+
+	// The attachment of a deleted note is orphaned.
+	other := new(note.Note).SetID(uuid.New())
+	s.Require().NoError(s.store.Insert(dummyCtx, other))
+	orphaned, err := s.svc.Add(dummyCtx, other.ID, &note.Attachment{}, bytes.NewBufferString("orphaned"))
+	s.Require().NoError(err)
+	s.Require().NoError(s.store.Delete(dummyCtx, other.ID))
+
+	got, err := s.svc.GC(dummyCtx)
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{removed.Digest, orphaned.Digest}, got)
+
+	// The deduplicated content is still referenced by the other attachment.
+	_, _, err = s.blobs.Open(dummyCtx, deduplicated.Digest)
+	s.NoError(err)
+}
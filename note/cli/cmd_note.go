@@ -0,0 +1,16 @@
+package cli
+
+import "noterfy/note/cli/notecmd"
+
+func init() {
+	Cmd.PersistentFlags().StringVar(&notecmd.ServerURL, "server", notecmd.ServerURL, "The URL of the noterfy server. Default is $NOTERFY_SERVER or "+notecmd.DefaultServerURL+".")
+	Cmd.PersistentFlags().StringVar(&notecmd.File, "file", "", "The path of the note file, e.g. ./note.pb, to operate on directly instead of the server. The file is locked while in use.")
+	Cmd.AddCommand(
+		notecmd.Create,
+		notecmd.Get,
+		notecmd.List,
+		notecmd.Update,
+		notecmd.Delete,
+		notecmd.Favorite,
+	)
+}
@@ -0,0 +1,79 @@
+package notecmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultEditor is the editor use when the $EDITOR isn't set.
+const defaultEditor = "vi"
+
+// editFile opens the file in the editor of the user and waits
+// until the editor exits. It's a variable so the tests can
+// replace the editor.
+var editFile = func(path string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	// The $EDITOR may contain arguments, e.g. "code --wait".
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// edit writes the title and the content into a temporary file and
+// opens it in the editor. It returns the title and content from the
+// edited file.
+func edit(title, content string) (string, string, error) {
+	file, err := ioutil.TempFile("", "noterfy-*.md")
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+
+	_, err = file.WriteString(formatNote(title, content))
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := editFile(file.Name()); err != nil {
+		return "", "", err
+	}
+
+	b, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return "", "", err
+	}
+	title, content = parseNote(string(b))
+	return title, content, nil
+}
+
+// formatNote formats the note for editing. The first line
+// is the title followed by a blank line and the content.
+func formatNote(title, content string) string {
+	return title + "\n\n" + content
+}
+
+// parseNote parses the title and content from s that was
+// formatted by formatNote.
+func parseNote(s string) (title, content string) {
+	parts := strings.SplitN(s, "\n", 2)
+	title = strings.TrimSpace(parts[0])
+	if len(parts) < 2 {
+		return title, ""
+	}
+	// Only the blank line that separates the title is removed
+	// so the content is kept as written.
+	content = strings.TrimPrefix(strings.TrimPrefix(parts[1], "\r"), "\n")
+	return title, strings.TrimRight(content, "\r\n")
+}
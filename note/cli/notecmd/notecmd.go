@@ -0,0 +1,329 @@
+// Package notecmd contains the cli commands that manage the notes
+// through the REST API of a noterfy server.
+package notecmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"io"
+	"noterfy/note"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// DefaultServerURL is the server URL use when the NOTERFY_SERVER
+// environment variable isn't set.
+const DefaultServerURL = "http://localhost:50001"
+
+// ServerURL is the URL of the noterfy server that the commands
+// talk to.
+var ServerURL = DefaultServerURL
+
+// File is the path of the note file that the commands operate on
+// directly instead of the server. The file is locked so it can't be
+// used while the server is running.
+var File string
+
+var (
+	title   string
+	content string
+
+	page      uint64
+	size      uint64
+	sortBy    string
+	ascending bool
+	archived  string
+
+	unset bool
+)
+
+func init() {
+	if server := os.Getenv("NOTERFY_SERVER"); server != "" {
+		ServerURL = server
+	}
+
+	for _, cmd := range []*cobra.Command{Create, Update} {
+		cmd.Flags().StringVarP(&title, "title", "t", "", "The title of the note. The editor is skipped when the title or the content is given.")
+		cmd.Flags().StringVarP(&content, "content", "c", "", "The content of the note. The editor is skipped when the title or the content is given.")
+	}
+
+	List.Flags().Uint64Var(&page, "page", 1, "The page number of the notes.")
+	List.Flags().Uint64Var(&size, "size", 25, "The number of notes in a page.")
+	List.Flags().StringVar(&sortBy, "sort-by", string(note.SortByTitle), "Sort the notes by [title/id/created_date/manual].")
+	List.Flags().BoolVar(&ascending, "ascending", true, "Sort the notes in ascending order.")
+	List.Flags().StringVar(&archived, "archived", string(note.ArchivedExclude), "List the archived notes [exclude/include/only].")
+
+	Favorite.Flags().BoolVar(&unset, "unset", false, "Unmark the note as favorite.")
+}
+
+// Create is a cli cmd that creates a new note.
+var Create = &cobra.Command{
+	Use:   "create",
+	Short: "Create a new note",
+	Long: `Create a new note.
+
+The note is written in the $EDITOR where the first line is the
+title and the content follows after a blank line. The editor is
+skipped when the --title or --content is given.
+`,
+	Example: "noterfy_cli note create --title \"Groceries\" --content \"Milk and eggs\"",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newTitle, newContent := title, content
+		if !cmd.Flags().Changed("title") && !cmd.Flags().Changed("content") {
+			var err error
+			newTitle, newContent, err = edit("", "")
+			if err != nil {
+				return err
+			}
+		}
+		if newTitle == "" && newContent == "" {
+			return errors.New("aborting the create due to an empty note")
+		}
+
+		svc, closer, err := openService()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = closer.Close() }()
+
+		n, err := svc.Create(cmd.Context(), new(note.Note).SetTitle(newTitle).SetContent(newContent))
+		if err != nil {
+			return mapError(err)
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), n.ID)
+		return err
+	},
+}
+
+// Get is a cli cmd that prints a note.
+var Get = &cobra.Command{
+	Use:     "get <id>",
+	Short:   "Print a note",
+	Example: "noterfy_cli note get ffffffff-ffff-ffff-ffff-ffffffffffff",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+
+		svc, closer, err := openService()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = closer.Close() }()
+
+		n, err := svc.Get(cmd.Context(), id)
+		if err != nil {
+			return mapError(err)
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), n)
+		return err
+	},
+}
+
+// List is a cli cmd that prints a page of the notes.
+var List = &cobra.Command{
+	Use:     "list",
+	Short:   "List the notes",
+	Example: "noterfy_cli note list --page 2 --size 10 --sort-by created_date --ascending=false",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The server falls back to the defaults for the unknown
+		// values so they are rejected here instead.
+		if string(note.GetSortBy(sortBy)) != strings.ToLower(sortBy) {
+			return fmt.Errorf("invalid sort-by %q", sortBy)
+		}
+		if string(note.GetArchivedFilter(archived)) != strings.ToLower(archived) {
+			return fmt.Errorf("invalid archived %q", archived)
+		}
+
+		svc, closer, err := openService()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = closer.Close() }()
+
+		iter, err := svc.Fetch(cmd.Context(), &note.Pagination{
+			Page:      page,
+			Size:      size,
+			SortBy:    note.GetSortBy(sortBy),
+			Ascending: ascending,
+			Archived:  note.GetArchivedFilter(archived),
+		})
+		if err != nil {
+			return mapError(err)
+		}
+		return printPage(cmd.OutOrStdout(), iter, page, size)
+	},
+}
+
+// Update is a cli cmd that updates a note.
+var Update = &cobra.Command{
+	Use:   "update <id>",
+	Short: "Update a note",
+	Long: `Update a note.
+
+The note is opened in the $EDITOR where the first line is the
+title and the content follows after a blank line. The editor is
+skipped when the --title or --content is given.
+`,
+	Example: "noterfy_cli note update ffffffff-ffff-ffff-ffff-ffffffffffff",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+
+		svc, closer, err := openService()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = closer.Close() }()
+
+		n, err := svc.Get(cmd.Context(), id)
+		if err != nil {
+			return mapError(err)
+		}
+
+		newTitle, newContent := n.GetTitle(), n.GetContent()
+		if cmd.Flags().Changed("title") || cmd.Flags().Changed("content") {
+			if cmd.Flags().Changed("title") {
+				newTitle = title
+			}
+			if cmd.Flags().Changed("content") {
+				newContent = content
+			}
+		} else {
+			newTitle, newContent, err = edit(newTitle, newContent)
+			if err != nil {
+				return err
+			}
+		}
+
+		if newTitle == n.GetTitle() && newContent == n.GetContent() {
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "Nothing has changed")
+			return err
+		}
+
+		// Only send the changes so the other fields are kept.
+		update := new(note.Note).SetID(id).SetTitle(newTitle).SetContent(newContent)
+		if _, err := svc.Update(cmd.Context(), update); err != nil {
+			return mapError(err)
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "Successfully updated")
+		return err
+	},
+}
+
+// Delete is a cli cmd that deletes a note.
+var Delete = &cobra.Command{
+	Use:     "delete <id>",
+	Short:   "Delete a note",
+	Example: "noterfy_cli note delete ffffffff-ffff-ffff-ffff-ffffffffffff",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+
+		svc, closer, err := openService()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = closer.Close() }()
+
+		if err := svc.Delete(cmd.Context(), id); err != nil {
+			return mapError(err)
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "Successfully deleted")
+		return err
+	},
+}
+
+// Favorite is a cli cmd that marks a note as favorite.
+var Favorite = &cobra.Command{
+	Use:     "favorite <id>",
+	Short:   "Mark a note as favorite",
+	Example: "noterfy_cli note favorite ffffffff-ffff-ffff-ffff-ffffffffffff --unset",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+
+		svc, closer, err := openService()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = closer.Close() }()
+
+		update := new(note.Note).SetID(id).SetIsFavorite(!unset)
+		if _, err := svc.Update(cmd.Context(), update); err != nil {
+			return mapError(err)
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "Successfully updated")
+		return err
+	},
+}
+
+func parseID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid note id %q: %w", s, err)
+	}
+	return id, nil
+}
+
+// mapError maps the API errors into the messages for the user.
+func mapError(err error) error {
+	switch {
+	case errors.Is(err, note.ErrNotFound):
+		return errors.New("note not found")
+	case errors.Is(err, note.ErrExists):
+		return errors.New("note already exists")
+	case errors.Is(err, note.ErrNilID):
+		return errors.New("note id must not be empty")
+	case errors.Is(err, note.ErrCancelled):
+		return errors.New("request was cancelled")
+	}
+	return err
+}
+
+// printPage prints the notes of the page from iter. Only the notes of
+// the page are read because the client iterator continues to the next
+// pages.
+func printPage(w io.Writer, iter note.Iterator, page, size uint64) error {
+	var totalPage, totalCount uint64
+	var notes []*note.Note
+	defer func() { _ = iter.Close() }()
+	for uint64(len(notes)) < size && iter.Next() {
+		notes = append(notes, iter.Note())
+	}
+	if err := iter.Error(); err != nil {
+		return mapError(err)
+	}
+	totalPage, totalCount = iter.TotalPage(), iter.TotalCount()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tTITLE\tFAVORITE\tPINNED\tUPDATED")
+	for _, n := range notes {
+		updated := ""
+		if n.UpdatedTime != nil {
+			updated = n.GetUpdatedTime().Format("2006-01-02 15:04:05")
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%t\t%t\t%s\n", n.ID, n.GetTitle(), n.GetIsFavorite(), n.GetIsPinned(), updated)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nPage %d of %d (%d notes)\n", page, totalPage, totalCount)
+	return err
+}
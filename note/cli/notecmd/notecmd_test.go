@@ -0,0 +1,189 @@
+package notecmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/api/v1/transport/rest"
+	"noterfy/note/client"
+	"noterfy/note/service"
+	filestore "noterfy/note/store/file"
+	"noterfy/note/store/memory"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNoteCmd(t *testing.T) {
+	suite.Run(t, new(NoteCmdTestSuite))
+}
+
+type NoteCmdTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	client *client.Client
+}
+
+func (s *NoteCmdTestSuite) SetupTest() {
+	router := mux.NewRouter()
+	for _, route := range rest.Routes(service.New(memory.New()), nil, nil) {
+		router.Handle(route.Path(), route.Handler()).Methods(route.Method())
+	}
+	s.server = httptest.NewServer(router)
+	var err error
+	s.client, err = client.New(&client.Config{BaseURL: s.server.URL})
+	s.Require().NoError(err)
+	ServerURL = s.server.URL
+}
+
+func (s *NoteCmdTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *NoteCmdTestSuite) createNote(title, content string) *note.Note {
+	n, err := s.client.Create(context.Background(), new(note.Note).SetTitle(title).SetContent(content))
+	s.Require().NoError(err)
+	return n
+}
+
+func (s *NoteCmdTestSuite) execute(cmd *cobra.Command, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func (s *NoteCmdTestSuite) TestGetAndDelete() {
+	created := s.createNote("First", "First content")
+
+	out, err := s.execute(Get, created.ID.String())
+	s.Require().NoError(err)
+	s.Contains(out, "First content")
+
+	out, err = s.execute(Delete, created.ID.String())
+	s.Require().NoError(err)
+	s.Contains(out, "Successfully deleted")
+
+	_, err = s.execute(Get, created.ID.String())
+	s.EqualError(err, "note not found")
+
+	_, err = s.execute(Get, "not-an-id")
+	s.Error(err)
+}
+
+func (s *NoteCmdTestSuite) TestCreateWithEditor() {
+	defer func(f func(string) error) { editFile = f }(editFile)
+	editFile = func(path string) error {
+		return ioutil.WriteFile(path, []byte("From Editor\n\nWritten in the editor\n"), 0600)
+	}
+
+	out, err := s.execute(Create)
+	s.Require().NoError(err)
+
+	id, err := uuid.Parse(out[:len(out)-1])
+	s.Require().NoError(err)
+
+	got, err := s.client.Get(context.Background(), id)
+	s.Require().NoError(err)
+	s.Equal("From Editor", got.GetTitle())
+	s.Equal("Written in the editor", got.GetContent())
+}
+
+func (s *NoteCmdTestSuite) TestUpdateWithFlags() {
+	created := s.createNote("Before", "Content")
+
+	out, err := s.execute(Update, created.ID.String(), "--title", "After")
+	s.Require().NoError(err)
+	s.Contains(out, "Successfully updated")
+
+	got, err := s.client.Get(context.Background(), created.ID)
+	s.Require().NoError(err)
+	s.Equal("After", got.GetTitle())
+	s.Equal("Content", got.GetContent())
+
+	_, err = s.execute(Update, uuid.New().String(), "--title", "After")
+	s.EqualError(err, "note not found")
+}
+
+func (s *NoteCmdTestSuite) TestList() {
+	s.createNote("B", "Second")
+	s.createNote("A", "First")
+
+	out, err := s.execute(List, "--size", "1", "--sort-by", "title")
+	s.Require().NoError(err)
+	s.Contains(out, "A")
+	s.NotContains(out, "Second")
+	s.Contains(out, "Page 1 of 2 (2 notes)")
+
+	_, err = s.execute(List, "--sort-by", "color")
+	s.Error(err)
+}
+
+func (s *NoteCmdTestSuite) TestFavorite() {
+	created := s.createNote("Note", "Content")
+
+	_, err := s.execute(Favorite, created.ID.String())
+	s.Require().NoError(err)
+
+	got, err := s.client.Get(context.Background(), created.ID)
+	s.Require().NoError(err)
+	s.True(got.GetIsFavorite())
+	s.Equal("Note", got.GetTitle())
+}
+
+func TestParseNote(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantTitle   string
+		wantContent string
+	}{
+		{name: "Title and content", input: formatNote("Title", "Line 1\n\nLine 2"), wantTitle: "Title", wantContent: "Line 1\n\nLine 2"},
+		{name: "Title only", input: "Title\n", wantTitle: "Title"},
+		{name: "Without blank line", input: "Title\nContent\n", wantTitle: "Title", wantContent: "Content"},
+		{name: "Windows line ending", input: "Title\r\n\r\nContent\r\n", wantTitle: "Title", wantContent: "Content"},
+		{name: "Empty", input: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTitle, gotContent := parseNote(tt.input)
+			if gotTitle != tt.wantTitle || gotContent != tt.wantContent {
+				t.Errorf("parseNote() = (%q, %q), want (%q, %q)", gotTitle, gotContent, tt.wantTitle, tt.wantContent)
+			}
+		})
+	}
+}
+
+func (s *NoteCmdTestSuite) TestLocalFile() {
+	File = filepath.Join(s.T().TempDir(), "note.pb")
+	defer func() { File = "" }()
+
+	out, err := s.execute(Create, "--title", "Offline", "--content", "Written without the server")
+	s.Require().NoError(err)
+	id := strings.TrimSpace(out)
+
+	out, err = s.execute(Get, id)
+	s.Require().NoError(err)
+	s.Contains(out, "Offline")
+
+	// The note is written to the file instead of the server.
+	_, err = s.client.Get(context.Background(), uuid.MustParse(id))
+	s.True(errors.Is(err, note.ErrNotFound), err)
+
+	// The file can't be used while it's locked, e.g. by the server.
+	locked, err := filestore.Open(File)
+	s.Require().NoError(err)
+	defer func() { _ = locked.Close() }()
+
+	_, err = s.execute(Get, id)
+	s.True(errors.Is(err, filestore.ErrLocked), err)
+}
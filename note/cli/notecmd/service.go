@@ -0,0 +1,35 @@
+package notecmd
+
+import (
+	"io"
+	"noterfy/note"
+	"noterfy/note/client"
+	"noterfy/note/service"
+	filestore "noterfy/note/store/file"
+)
+
+// openService returns the service that the commands use. It's the
+// service of the note file in File when it's set otherwise the client
+// of the server in ServerURL. The closer must be closed after use to
+// release the lock of the file.
+func openService() (note.Service, io.Closer, error) {
+	if File == "" {
+		c, err := client.New(&client.Config{BaseURL: ServerURL})
+		if err != nil {
+			return nil, nil, err
+		}
+		return c, nopCloser{}, nil
+	}
+
+	file, err := filestore.Open(File)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service.New(filestore.New(file)), file, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
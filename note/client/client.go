@@ -0,0 +1,247 @@
+// Package client is a Go client of the note REST API. The
+// Client implements the note.Service so it can be use in
+// place of a local service.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"noterfy/note"
+	"noterfy/pkg/validation"
+	"time"
+)
+
+var _ note.Service = (*Client)(nil)
+
+const (
+	// DefaultTimeout is the default timeout of each attempt of a request.
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxRetries is the default number of retries of a failed request.
+	DefaultMaxRetries = 3
+	// DefaultBackoff is the default wait before the first retry.
+	DefaultBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff is the default maximum wait between the retries.
+	DefaultMaxBackoff = 5 * time.Second
+)
+
+// Config contains the settings of the client.
+type Config struct {
+	// BaseURL is the URL of the noterfy server,
+	// e.g. http://localhost:50001.
+	BaseURL string
+	// HTTPClient is the client use to send the requests. If HTTPClient
+	// is nil the http.DefaultClient will be use.
+	HTTPClient *http.Client
+	// Timeout is the timeout of each attempt of a request. If Timeout
+	// is 0 the DefaultTimeout will be use.
+	Timeout time.Duration
+	// MaxRetries is the number of retries of a failed request. If
+	// MaxRetries is 0 the DefaultMaxRetries will be use and if it's
+	// negative the requests are not retried.
+	MaxRetries int
+	// Backoff is the wait before the first retry which doubles on
+	// every retry. If Backoff is 0 the DefaultBackoff will be use.
+	Backoff time.Duration
+	// MaxBackoff is the maximum wait between the retries. If MaxBackoff
+	// is 0 the DefaultMaxBackoff will be use.
+	MaxBackoff time.Duration
+}
+
+func (c *Config) check() {
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultMaxRetries
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.Backoff == 0 {
+		c.Backoff = DefaultBackoff
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = DefaultMaxBackoff
+	}
+}
+
+// New takes the config and returns a client of the server in
+// the config BaseURL.
+func New(conf *Config) (*Client, error) {
+	cfg := *conf
+	cfg.check()
+
+	baseURL, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base url: %w", err)
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("client: invalid base url '%s'", cfg.BaseURL)
+	}
+
+	// The non-idempotent create and patch are only retried when
+	// the server didn't process the request.
+	wrap := func(e endpoint.Endpoint, idempotent bool) endpoint.Endpoint {
+		e = timeout(cfg.Timeout)(e)
+		return retry(cfg.MaxRetries, cfg.Backoff, cfg.MaxBackoff, idempotent)(e)
+	}
+
+	return &Client{
+		create: wrap(makeCreateEndpoint(baseURL, cfg.HTTPClient), false),
+		get:    wrap(makeGetEndpoint(baseURL, cfg.HTTPClient), true),
+		update: wrap(makeUpdateEndpoint(baseURL, cfg.HTTPClient), true),
+		patch:  wrap(makePatchEndpoint(baseURL, cfg.HTTPClient), false),
+		delete: wrap(makeDeleteEndpoint(baseURL, cfg.HTTPClient), true),
+		fetch:  wrap(makeFetchEndpoint(baseURL, cfg.HTTPClient), true),
+	}, nil
+}
+
+// Client implements the note.Service through the REST API
+// of a noterfy server.
+type Client struct {
+	create endpoint.Endpoint
+	get    endpoint.Endpoint
+	update endpoint.Endpoint
+	patch  endpoint.Endpoint
+	delete endpoint.Endpoint
+	fetch  endpoint.Endpoint
+}
+
+// Create creates a new note n with optional value in ID field.
+// It takes ctx to let the caller stop the execution.
+func (c *Client) Create(ctx context.Context, n *note.Note) (*note.Note, error) {
+	resp, err := c.create(ctx, createRequest{Note: n})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(noteResponse).Note, nil
+}
+
+// Update updates an existing note. It takes ctx to let the
+// caller stop the execution.
+func (c *Client) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	resp, err := c.update(ctx, updateRequest{Note: n})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(noteResponse).Note, nil
+}
+
+// Patch applies the patch p to the existing note with an id
+// atomically. It takes ctx to let the caller stop the execution.
+func (c *Client) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error) {
+	resp, err := c.patch(ctx, patchRequest{ID: id, Patch: p})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(noteResponse).Note, nil
+}
+
+// Delete deletes an existing note with an id.
+func (c *Client) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := c.delete(ctx, id)
+	return err
+}
+
+// Get gets the note with an id.
+func (c *Client) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
+	resp, err := c.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(noteResponse).Note, nil
+}
+
+// Fetch fetches the notes using the pagination setting. The first
+// page is fetched right away and the next pages are fetched lazily
+// while the returned iterator is iterated.
+func (c *Client) Fetch(ctx context.Context, pagination *note.Pagination) (note.Iterator, error) {
+	p := *pagination
+	p.Check()
+
+	iter := &iterator{ctx: ctx, fetch: c.fetch, pagination: p}
+	if err := iter.load(); err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// StatusError is an error response of the server. It unwraps
+// to the note error that the response stands for so the caller
+// can use errors.Is, e.g. errors.Is(err, note.ErrNotFound).
+type StatusError struct {
+	// StatusCode is the http status code of the response.
+	StatusCode int
+	// Code is the code of the problem of the response, e.g.
+	// "note.not_found". It is empty when the response isn't
+	// a problem, e.g. the response of a proxy.
+	Code string
+	// Message is the title of the problem of the response.
+	Message string
+	// Violations are the invalid fields of the request.
+	Violations []validation.Violation
+}
+
+func (e *StatusError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("client: %s (status %d)", e.Message, e.StatusCode)
+	}
+	return fmt.Sprintf("client: %s (status %d, code %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// Unwrap returns the note error of the response or nil when
+// there's none.
+func (e *StatusError) Unwrap() error {
+	switch e.Code {
+	case codeNoteNotFound:
+		return note.ErrNotFound
+	case codeNoteConflict:
+		return note.ErrExists
+	case codeNoteIDRequired:
+		return note.ErrNilID
+	case codeNotePatchTestFailed:
+		return note.ErrPatchTestFailed
+	case codeUnsupportedMediaType:
+		return note.ErrUnsupportedPatch
+	case codeCancelled:
+		return note.ErrCancelled
+	case "":
+		// The responses that aren't a problem are told
+		// apart with their status code.
+		switch e.StatusCode {
+		case http.StatusNotFound:
+			return note.ErrNotFound
+		case http.StatusConflict:
+			return note.ErrExists
+		case statusClientClosed:
+			return note.ErrCancelled
+		}
+	}
+	if len(e.Violations) > 0 {
+		return &validation.Error{Violations: e.Violations}
+	}
+	return nil
+}
+
+// isStatus reports whether err is a StatusError with one of
+// the status codes.
+func isStatus(err error, codes ...int) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	for _, code := range codes {
+		if statusErr.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
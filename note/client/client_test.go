@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/api/v1/transport/rest"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/validation"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
+
+type ClientTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	client *Client
+
+	// requests is the number of requests received by the server.
+	requests int32
+	// failures is the number of requests to fail before the
+	// requests are handled.
+	failures int32
+	// failStatus is the status code of the failed requests.
+	failStatus int
+}
+
+func (s *ClientTestSuite) SetupTest() {
+	router := mux.NewRouter()
+	for _, route := range rest.Routes(service.New(memory.New()), nil, nil) {
+		router.Handle(route.Path(), route.Handler()).Methods(route.Method())
+	}
+
+	s.requests = 0
+	s.failures = 0
+	s.failStatus = http.StatusServiceUnavailable
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		if atomic.AddInt32(&s.failures, -1) >= 0 {
+			w.WriteHeader(s.failStatus)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+
+	var err error
+	s.client, err = New(&Config{BaseURL: s.server.URL, Backoff: time.Millisecond})
+	s.Require().NoError(err)
+}
+
+func (s *ClientTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ClientTestSuite) createNotes(count int) {
+	for i := 0; i < count; i++ {
+		n := new(note.Note).SetTitle(fmt.Sprintf("Note %02d", i)).SetContent("Content")
+		_, err := s.client.Create(context.Background(), n)
+		s.Require().NoError(err)
+	}
+	atomic.StoreInt32(&s.requests, 0)
+}
+
+func (s *ClientTestSuite) TestCRUD() {
+	ctx := context.Background()
+
+	created, err := s.client.Create(ctx, new(note.Note).SetTitle("Title").SetContent("Content"))
+	s.Require().NoError(err)
+	s.NotEqual(uuid.Nil, created.ID)
+
+	got, err := s.client.Get(ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal("Title", got.GetTitle())
+
+	updated, err := s.client.Update(ctx, new(note.Note).SetID(created.ID).SetTitle("Updated"))
+	s.Require().NoError(err)
+	s.Equal("Updated", updated.GetTitle())
+	s.Equal("Content", updated.GetContent())
+
+	s.Require().NoError(s.client.Delete(ctx, created.ID))
+
+	_, err = s.client.Get(ctx, created.ID)
+	s.True(errors.Is(err, note.ErrNotFound), err)
+}
+
+func (s *ClientTestSuite) TestErrors() {
+	ctx := context.Background()
+
+	created, err := s.client.Create(ctx, new(note.Note).SetTitle("Title"))
+	s.Require().NoError(err)
+
+	_, err = s.client.Create(ctx, created)
+	s.True(errors.Is(err, note.ErrExists), err)
+
+	err = s.client.Delete(ctx, uuid.Nil)
+	s.True(errors.Is(err, note.ErrNilID), err)
+
+	_, err = s.client.Update(ctx, new(note.Note).SetID(uuid.New()).SetTitle("Title"))
+	s.True(errors.Is(err, note.ErrNotFound), err)
+
+	var statusErr *StatusError
+	s.Require().True(errors.As(err, &statusErr))
+	s.Equal(http.StatusNotFound, statusErr.StatusCode)
+	s.Equal("note.not_found", statusErr.Code)
+
+	_, err = s.client.Create(ctx, new(note.Note).SetTitle("Line 1\nLine 2"))
+	s.True(errors.Is(err, validation.ErrInvalid), err)
+	s.Require().True(errors.As(err, &statusErr))
+	s.Equal(http.StatusUnprocessableEntity, statusErr.StatusCode)
+	s.Equal("title", statusErr.Violations[0].Field)
+}
+
+func (s *ClientTestSuite) TestPatch() {
+	ctx := context.Background()
+
+	created, err := s.client.Create(ctx, new(note.Note).SetTitle("Title").SetContent("Content"))
+	s.Require().NoError(err)
+
+	patched, err := s.client.Patch(ctx, created.ID, note.Patch{
+		MediaType: note.MediaTypeMergePatch,
+		Document:  []byte(`{"content": null}`),
+	})
+	s.Require().NoError(err)
+	s.Equal("Title", patched.GetTitle())
+	s.Nil(patched.Content)
+
+	_, err = s.client.Patch(ctx, created.ID, note.Patch{
+		MediaType: note.MediaTypeJSONPatch,
+		Document:  []byte(`[{"op": "test", "path": "/title", "value": "Other"}]`),
+	})
+	s.True(errors.Is(err, note.ErrPatchTestFailed), err)
+
+	_, err = s.client.Patch(ctx, created.ID, note.Patch{MediaType: "text/plain", Document: []byte("title")})
+	s.True(errors.Is(err, note.ErrUnsupportedPatch), err)
+}
+
+func (s *ClientTestSuite) TestRetry() {
+	s.failures = 2
+
+	_, err := s.client.Create(context.Background(), new(note.Note).SetTitle("Title"))
+	s.Require().NoError(err)
+	s.Equal(int32(3), atomic.LoadInt32(&s.requests))
+}
+
+func (s *ClientTestSuite) TestRetryGivesUp() {
+	s.failures = 10
+
+	_, err := s.client.Get(context.Background(), uuid.New())
+	s.True(isStatus(err, http.StatusServiceUnavailable), err)
+	s.Equal(int32(DefaultMaxRetries+1), atomic.LoadInt32(&s.requests))
+}
+
+func (s *ClientTestSuite) TestNoRetryOnCreate() {
+	// The server may have created the note behind the gateway.
+	s.failures = 1
+	s.failStatus = http.StatusBadGateway
+
+	_, err := s.client.Create(context.Background(), new(note.Note).SetTitle("Title"))
+	s.True(isStatus(err, http.StatusBadGateway), err)
+	s.Equal(int32(1), atomic.LoadInt32(&s.requests))
+}
+
+func (s *ClientTestSuite) TestTimeout() {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	c, err := New(&Config{BaseURL: slow.URL, Timeout: 10 * time.Millisecond, MaxRetries: -1})
+	s.Require().NoError(err)
+
+	_, err = c.Get(context.Background(), uuid.New())
+	s.True(errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func (s *ClientTestSuite) TestFetchPagesLazily() {
+	s.createNotes(5)
+
+	iter, err := s.client.Fetch(context.Background(), &note.Pagination{Size: 2, SortBy: note.SortByTitle, Ascending: true})
+	s.Require().NoError(err)
+	defer func() { _ = iter.Close() }()
+	s.Equal(uint64(5), iter.TotalCount())
+	s.Equal(int32(1), atomic.LoadInt32(&s.requests))
+
+	var titles []string
+	for iter.Next() {
+		titles = append(titles, iter.Note().GetTitle())
+		// The next page is fetched only after the current page.
+		s.Equal(int32((len(titles)+1)/2), atomic.LoadInt32(&s.requests))
+	}
+	s.Require().NoError(iter.Error())
+	s.Equal([]string{"Note 00", "Note 01", "Note 02", "Note 03", "Note 04"}, titles)
+}
+
+func (s *ClientTestSuite) TestFetchFullLastPage() {
+	s.createNotes(4)
+
+	iter, err := s.client.Fetch(context.Background(), &note.Pagination{Size: 2})
+	s.Require().NoError(err)
+
+	count := 0
+	for iter.Next() {
+		count++
+	}
+	s.Require().NoError(iter.Error())
+	s.Equal(4, count)
+	// The empty third page marks the end.
+	s.Equal(int32(3), atomic.LoadInt32(&s.requests))
+}
+
+func (s *ClientTestSuite) TestNewInvalidURL() {
+	_, err := New(&Config{BaseURL: "localhost"})
+	s.Error(err)
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	"noterfy/note"
+)
+
+var _ note.Iterator = (*iterator)(nil)
+
+// iterator iterates the notes from the pagination page onwards.
+// The next page is fetched only when the notes of the current
+// page are exhausted.
+type iterator struct {
+	ctx        context.Context
+	fetch      endpoint.Endpoint
+	pagination note.Pagination
+
+	notes      []*note.Note
+	curIndex   int
+	totalCount uint64
+	totalPage  uint64
+	// last is true when there are no more pages to fetch.
+	last bool
+	err  error
+}
+
+// load fetches the notes of the current page.
+func (i *iterator) load() error {
+	resp, err := i.fetch(i.ctx, i.pagination)
+	if err != nil {
+		return err
+	}
+
+	r := resp.(fetchResponse)
+	i.notes = r.Notes
+	i.curIndex = 0
+	i.totalCount = r.TotalCount
+	i.totalPage = r.TotalPage
+	// A partial page is the last page.
+	i.last = uint64(len(r.Notes)) < i.pagination.Size
+	return nil
+}
+
+// Next implements the note.Iterator.
+func (i *iterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+
+	if i.curIndex >= len(i.notes) {
+		if i.last {
+			return false
+		}
+
+		i.pagination.Page++
+		if err := i.load(); err != nil {
+			i.err = err
+			return false
+		}
+		if len(i.notes) == 0 {
+			return false
+		}
+	}
+
+	i.curIndex++
+	return true
+}
+
+// Note implements the note.Iterator.
+func (i *iterator) Note() *note.Note {
+	return i.notes[i.curIndex-1]
+}
+
+// Error implements the note.Iterator.
+func (i *iterator) Error() error {
+	return i.err
+}
+
+// Close implements the note.Iterator.
+func (i *iterator) Close() error {
+	return nil
+}
+
+// TotalCount implements the note.Iterator.
+func (i *iterator) TotalCount() uint64 {
+	return i.totalCount
+}
+
+// TotalPage implements the note.Iterator.
+func (i *iterator) TotalPage() uint64 {
+	return i.totalPage
+}
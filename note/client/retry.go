@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/go-kit/kit/endpoint"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// timeout returns a middleware that limits each call of the
+// endpoint to d.
+func timeout(d time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, request)
+		}
+	}
+}
+
+// retry returns a middleware that retries the failed calls of
+// the endpoint up to maxRetries times with an exponential backoff.
+// The responses of an overloaded or unavailable server are always
+// retried, while the other errors, e.g. a connection reset or a
+// timeout, are only retried when the endpoint is idempotent because
+// the server may have processed the request.
+func retry(maxRetries int, backoff, maxBackoff time.Duration, idempotent bool) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			wait := backoff
+			for attempt := 0; ; attempt++ {
+				response, err = next(ctx, request)
+				if err == nil || attempt >= maxRetries || !isRetryable(ctx, err, idempotent) {
+					return response, err
+				}
+
+				// Add a jitter so the clients don't retry at the same time.
+				d := wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(d):
+				}
+
+				if wait *= 2; wait > maxBackoff {
+					wait = maxBackoff
+				}
+			}
+		}
+	}
+}
+
+func isRetryable(ctx context.Context, err error, idempotent bool) bool {
+	// The caller gave up.
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if isStatus(err,
+		http.StatusTooManyRequests,
+		http.StatusServiceUnavailable,
+	) {
+		return true
+	}
+
+	if isStatus(err,
+		http.StatusBadGateway,
+		http.StatusGatewayTimeout,
+	) {
+		return idempotent
+	}
+
+	// The other responses of the server are final.
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return false
+	}
+
+	return idempotent
+}
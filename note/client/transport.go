@@ -0,0 +1,202 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"noterfy/note"
+	"noterfy/pkg/problem"
+	"noterfy/pkg/trace"
+	"path"
+	"strconv"
+)
+
+// statusClientClosed is the status code of the server when the
+// request was cancelled.
+const statusClientClosed = 499
+
+// The codes of the problems of the server that stand for
+// the note errors.
+const (
+	codeNoteNotFound         = "note.not_found"
+	codeNoteConflict         = "note.conflict"
+	codeNoteIDRequired       = "note.id_required"
+	codeNotePatchTestFailed  = "note.patch_test_failed"
+	codeUnsupportedMediaType = "request.unsupported_media_type"
+	codeCancelled            = "request.cancelled"
+)
+
+// The requests and responses mirror the ones of the rest package.
+
+type createRequest struct {
+	Note *note.Note `json:"note"`
+}
+
+type updateRequest struct {
+	Note *note.Note `json:"note"`
+}
+
+// patchRequest is sent as the patch document with its media
+// type rather than as JSON.
+type patchRequest struct {
+	ID    uuid.UUID
+	Patch note.Patch
+}
+
+type noteResponse struct {
+	Note *note.Note `json:"note"`
+}
+
+type deleteResponse struct {
+	Message string `json:"message"`
+}
+
+type fetchResponse struct {
+	Notes      []*note.Note `json:"notes"`
+	TotalCount uint64       `json:"total_count"`
+	TotalPage  uint64       `json:"total_page"`
+}
+
+func makeCreateEndpoint(baseURL *url.URL, client *http.Client) endpoint.Endpoint {
+	return httptransport.NewClient(
+		http.MethodPost,
+		target(baseURL, "/v1/note"),
+		httptransport.EncodeJSONRequest,
+		decodeNoteResponse,
+		clientOptions(client)...,
+	).Endpoint()
+}
+
+func makeGetEndpoint(baseURL *url.URL, client *http.Client) endpoint.Endpoint {
+	return httptransport.NewClient(
+		http.MethodGet,
+		target(baseURL, "/v1/note"),
+		encodeIDRequest,
+		decodeNoteResponse,
+		clientOptions(client)...,
+	).Endpoint()
+}
+
+func makeUpdateEndpoint(baseURL *url.URL, client *http.Client) endpoint.Endpoint {
+	return httptransport.NewClient(
+		http.MethodPut,
+		target(baseURL, "/v1/note"),
+		httptransport.EncodeJSONRequest,
+		decodeNoteResponse,
+		clientOptions(client)...,
+	).Endpoint()
+}
+
+func makePatchEndpoint(baseURL *url.URL, client *http.Client) endpoint.Endpoint {
+	return httptransport.NewClient(
+		http.MethodPatch,
+		target(baseURL, "/v1/note"),
+		encodePatchRequest,
+		decodeNoteResponse,
+		clientOptions(client)...,
+	).Endpoint()
+}
+
+func makeDeleteEndpoint(baseURL *url.URL, client *http.Client) endpoint.Endpoint {
+	return httptransport.NewClient(
+		http.MethodDelete,
+		target(baseURL, "/v1/note"),
+		encodeIDRequest,
+		decodeDeleteResponse,
+		clientOptions(client)...,
+	).Endpoint()
+}
+
+func makeFetchEndpoint(baseURL *url.URL, client *http.Client) endpoint.Endpoint {
+	return httptransport.NewClient(
+		http.MethodGet,
+		target(baseURL, "/v1/notes"),
+		encodeFetchRequest,
+		decodeFetchResponse,
+		clientOptions(client)...,
+	).Endpoint()
+}
+
+// clientOptions returns the options of the endpoints. The requests
+// are traced when the context has a span.
+func clientOptions(client *http.Client) []httptransport.ClientOption {
+	return append(trace.ClientOptions(), httptransport.SetClient(client))
+}
+
+// target returns the URL of the endpoint path relative
+// to the baseURL.
+func target(baseURL *url.URL, p string) *url.URL {
+	tgt := *baseURL
+	tgt.Path = path.Join("/", baseURL.Path, p)
+	return &tgt
+}
+
+func encodeIDRequest(_ context.Context, r *http.Request, request interface{}) error {
+	r.URL.Path = path.Join(r.URL.Path, request.(uuid.UUID).String())
+	r.Header.Set("Accept", "application/json")
+	return nil
+}
+
+func encodePatchRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(patchRequest)
+	r.URL.Path = path.Join(r.URL.Path, req.ID.String())
+	r.Header.Set("Content-Type", req.Patch.MediaType)
+	r.Header.Set("Accept", "application/json")
+	r.ContentLength = int64(len(req.Patch.Document))
+	r.Body = io.NopCloser(bytes.NewReader(req.Patch.Document))
+	return nil
+}
+
+func encodeFetchRequest(_ context.Context, r *http.Request, request interface{}) error {
+	p := request.(note.Pagination)
+	query := r.URL.Query()
+	query.Set("page", strconv.FormatUint(p.Page, 10))
+	query.Set("size", strconv.FormatUint(p.Size, 10))
+	query.Set("sort_by", string(p.SortBy))
+	query.Set("ascending", strconv.FormatBool(p.Ascending))
+	query.Set("archived", string(p.Archived))
+	r.URL.RawQuery = query.Encode()
+	r.Header.Set("Accept", "application/json")
+	return nil
+}
+
+func decodeNoteResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp noteResponse
+	return resp, decodeResponse(r, &resp)
+}
+
+func decodeDeleteResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp deleteResponse
+	return resp, decodeResponse(r, &resp)
+}
+
+func decodeFetchResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp fetchResponse
+	return resp, decodeResponse(r, &resp)
+}
+
+// decodeResponse decodes the JSON body of r into v. The error
+// responses are decoded into a *StatusError.
+func decodeResponse(r *http.Response, v interface{}) error {
+	if r.StatusCode < 200 || r.StatusCode >= 300 {
+		var p problem.Problem
+		// Fallback to the status text when the body isn't a problem,
+		// e.g. the response of a proxy.
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.Title == "" {
+			p.Title = http.StatusText(r.StatusCode)
+		}
+		return &StatusError{
+			StatusCode: r.StatusCode,
+			Code:       p.Code,
+			Message:    p.Title,
+			Violations: p.Violations,
+		}
+	}
+	return json.NewDecoder(r.Body).Decode(v)
+}
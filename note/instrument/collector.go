@@ -0,0 +1,80 @@
+package instrument
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"io/fs"
+	"noterfy/note"
+	"time"
+)
+
+// collectTimeout is the timeout to count the notes on a scrape.
+const collectTimeout = 5 * time.Second
+
+var _ prometheus.Collector = (*StoreCollector)(nil)
+
+// NewStoreCollector takes the store and its file and returns a
+// collector of the number of notes in the store and the size of
+// the file on disk. The file can be nil when the store has no file.
+func NewStoreCollector(store note.Store, file fs.File) *StoreCollector {
+	return &StoreCollector{
+		store: store,
+		file:  file,
+		noteCount: prometheus.NewDesc(
+			"noterfy_store_notes",
+			"Number of notes in the store including the archived notes.",
+			nil, nil,
+		),
+		fileSize: prometheus.NewDesc(
+			"noterfy_store_file_size_bytes",
+			"Size of the store file on disk in bytes.",
+			nil, nil,
+		),
+	}
+}
+
+// StoreCollector implements the prometheus.Collector. The gauges
+// are read from the store on every scrape. The store keeps the
+// count of the notes so a scrape doesn't scan the notes.
+type StoreCollector struct {
+	store     note.Store
+	file      fs.File
+	noteCount *prometheus.Desc
+	fileSize  *prometheus.Desc
+}
+
+// Describe implements the prometheus.Collector.
+func (c *StoreCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.noteCount
+	if c.file != nil {
+		ch <- c.fileSize
+	}
+}
+
+// Collect implements the prometheus.Collector.
+func (c *StoreCollector) Collect(ch chan<- prometheus.Metric) {
+	if count, err := c.countNotes(); err != nil {
+		logrus.Error("instrument: unable to count the notes: ", err)
+		ch <- prometheus.NewInvalidMetric(c.noteCount, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.noteCount, prometheus.GaugeValue, float64(count))
+	}
+
+	if c.file == nil {
+		return
+	}
+
+	info, err := c.file.Stat()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.fileSize, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.fileSize, prometheus.GaugeValue, float64(info.Size()))
+}
+
+func (c *StoreCollector) countNotes() (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	return c.store.Count(ctx)
+}
@@ -0,0 +1,97 @@
+package instrument
+
+import (
+	"context"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"noterfy/note"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"strings"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	suite.Run(t, new(InstrumentTestSuite))
+}
+
+type InstrumentTestSuite struct {
+	suite.Suite
+	store        note.Store
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	storeLatency *prometheus.HistogramVec
+	svc          note.Service
+}
+
+func (s *InstrumentTestSuite) SetupTest() {
+	s.requests = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests"}, []string{"method", "error"})
+	s.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "latency"}, []string{"method", "error"})
+	s.storeLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "store_latency"}, []string{"operation", "error"})
+	s.store = NewStore(memory.New(), kitprometheus.NewHistogram(s.storeLatency))
+	s.svc = Middleware(
+		kitprometheus.NewCounter(s.requests),
+		kitprometheus.NewHistogram(s.latency),
+	)(service.New(s.store))
+}
+
+func (s *InstrumentTestSuite) TestMiddleware() {
+	ctx := context.Background()
+
+	n, err := s.svc.Create(ctx, new(note.Note).SetTitle("Title"))
+	s.Require().NoError(err)
+	_, err = s.svc.Get(ctx, n.ID)
+	s.Require().NoError(err)
+	_, err = s.svc.Get(ctx, uuid.New())
+	s.Require().Error(err)
+
+	s.Equal(1.0, testutil.ToFloat64(s.requests.WithLabelValues("create", "false")))
+	s.Equal(1.0, testutil.ToFloat64(s.requests.WithLabelValues("get", "false")))
+	s.Equal(1.0, testutil.ToFloat64(s.requests.WithLabelValues("get", "true")))
+	s.Equal(3, testutil.CollectAndCount(s.latency))
+}
+
+func (s *InstrumentTestSuite) TestStore() {
+	err := s.store.Insert(context.Background(), new(note.Note).SetID(uuid.New()).SetTitle("Title"))
+	s.Require().NoError(err)
+
+	s.Equal(1, testutil.CollectAndCount(s.storeLatency))
+	s.Equal(uint64(1), sampleCount(s.storeLatency.WithLabelValues("insert", "false")))
+}
+
+func (s *InstrumentTestSuite) TestStoreCollector() {
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := s.svc.Create(ctx, new(note.Note).SetTitle("Title").SetIsArchived(i == 0))
+		s.Require().NoError(err)
+	}
+
+	file, err := afero.TempFile(afero.NewMemMapFs(), "", "note.pb")
+	s.Require().NoError(err)
+	_, err = file.WriteString("12345")
+	s.Require().NoError(err)
+
+	reg := prometheus.NewPedanticRegistry()
+	s.Require().NoError(reg.Register(NewStoreCollector(s.store, file)))
+
+	want := `
+# HELP noterfy_store_file_size_bytes Size of the store file on disk in bytes.
+# TYPE noterfy_store_file_size_bytes gauge
+noterfy_store_file_size_bytes 5
+# HELP noterfy_store_notes Number of notes in the store including the archived notes.
+# TYPE noterfy_store_notes gauge
+noterfy_store_notes 3
+`
+	s.NoError(testutil.GatherAndCompare(reg, strings.NewReader(want)))
+}
+
+func sampleCount(o prometheus.Observer) uint64 {
+	var m dto.Metric
+	_ = o.(prometheus.Metric).Write(&m)
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,67 @@
+// Package instrument contains the metrics instrumentation of
+// the note service and store.
+package instrument
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"github.com/google/uuid"
+	"noterfy/note"
+	"time"
+)
+
+// Middleware takes the counter and the latency histogram and returns
+// a middleware that records every call of the service. Both metrics
+// are labeled with the "method" and "error" label values.
+func Middleware(requestCount metrics.Counter, requestLatency metrics.Histogram) note.Middleware {
+	return func(next note.Service) note.Service {
+		return &instrumentingService{
+			next:           next,
+			requestCount:   requestCount,
+			requestLatency: requestLatency,
+		}
+	}
+}
+
+type instrumentingService struct {
+	next           note.Service
+	requestCount   metrics.Counter
+	requestLatency metrics.Histogram
+}
+
+func (s *instrumentingService) record(method string, begin time.Time, err error) {
+	lvs := []string{"method", method, "error", fmt.Sprint(err != nil)}
+	s.requestCount.With(lvs...).Add(1)
+	s.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+}
+
+func (s *instrumentingService) Create(ctx context.Context, n *note.Note) (created *note.Note, err error) {
+	defer func(begin time.Time) { s.record("create", begin, err) }(time.Now())
+	return s.next.Create(ctx, n)
+}
+
+func (s *instrumentingService) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	defer func(begin time.Time) { s.record("update", begin, err) }(time.Now())
+	return s.next.Update(ctx, n)
+}
+
+func (s *instrumentingService) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (patched *note.Note, err error) {
+	defer func(begin time.Time) { s.record("patch", begin, err) }(time.Now())
+	return s.next.Patch(ctx, id, p)
+}
+
+func (s *instrumentingService) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(begin time.Time) { s.record("delete", begin, err) }(time.Now())
+	return s.next.Delete(ctx, id)
+}
+
+func (s *instrumentingService) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	defer func(begin time.Time) { s.record("get", begin, err) }(time.Now())
+	return s.next.Get(ctx, id)
+}
+
+func (s *instrumentingService) Fetch(ctx context.Context, p *note.Pagination) (iter note.Iterator, err error) {
+	defer func(begin time.Time) { s.record("fetch", begin, err) }(time.Now())
+	return s.next.Fetch(ctx, p)
+}
@@ -0,0 +1,68 @@
+package instrument
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"github.com/google/uuid"
+	"noterfy/note"
+	"time"
+)
+
+// NewStore takes the store and the latency histogram and returns
+// a store that records the duration of every operation of store.
+// The histogram is labeled with the "operation" and "error" label
+// values.
+func NewStore(store note.Store, latency metrics.Histogram) note.Store {
+	return &instrumentingStore{next: store, latency: latency}
+}
+
+type instrumentingStore struct {
+	next    note.Store
+	latency metrics.Histogram
+}
+
+func (s *instrumentingStore) record(operation string, begin time.Time, err error) {
+	s.latency.With("operation", operation, "error", fmt.Sprint(err != nil)).
+		Observe(time.Since(begin).Seconds())
+}
+
+func (s *instrumentingStore) Insert(ctx context.Context, n *note.Note) (err error) {
+	defer func(begin time.Time) { s.record("insert", begin, err) }(time.Now())
+	return s.next.Insert(ctx, n)
+}
+
+func (s *instrumentingStore) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	defer func(begin time.Time) { s.record("update", begin, err) }(time.Now())
+	return s.next.Update(ctx, n)
+}
+
+func (s *instrumentingStore) Modify(ctx context.Context, id uuid.UUID, fn func(n *note.Note) error) (modified *note.Note, err error) {
+	defer func(begin time.Time) { s.record("modify", begin, err) }(time.Now())
+	return s.next.Modify(ctx, id, fn)
+}
+
+func (s *instrumentingStore) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(begin time.Time) { s.record("delete", begin, err) }(time.Now())
+	return s.next.Delete(ctx, id)
+}
+
+func (s *instrumentingStore) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	defer func(begin time.Time) { s.record("get", begin, err) }(time.Now())
+	return s.next.Get(ctx, id)
+}
+
+func (s *instrumentingStore) Version(ctx context.Context) (v note.Version, err error) {
+	defer func(begin time.Time) { s.record("version", begin, err) }(time.Now())
+	return s.next.Version(ctx)
+}
+
+func (s *instrumentingStore) Count(ctx context.Context) (count uint64, err error) {
+	defer func(begin time.Time) { s.record("count", begin, err) }(time.Now())
+	return s.next.Count(ctx)
+}
+
+func (s *instrumentingStore) Fetch(ctx context.Context, p *note.Pagination) (iter note.Iterator, err error) {
+	defer func(begin time.Time) { s.record("fetch", begin, err) }(time.Now())
+	return s.next.Fetch(ctx, p)
+}
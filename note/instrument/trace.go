@@ -0,0 +1,169 @@
+package instrument
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"noterfy/note"
+	"noterfy/pkg/trace"
+)
+
+// TracingMiddleware returns a middleware that traces every call of
+// the service in a child span of the span in the context. The calls
+// without a span in the context are not traced.
+func TracingMiddleware() note.Middleware {
+	return func(next note.Service) note.Service {
+		return &tracingService{next: next}
+	}
+}
+
+type tracingService struct {
+	next note.Service
+}
+
+func (s *tracingService) Create(ctx context.Context, n *note.Note) (created *note.Note, err error) {
+	ctx, span := trace.Start(ctx, "note.Service/Create")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Create(ctx, n)
+}
+
+func (s *tracingService) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	ctx, span := trace.Start(ctx, "note.Service/Update")
+	span.SetAttributes(attribute.Stringer("note.id", n.ID))
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Update(ctx, n)
+}
+
+func (s *tracingService) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (patched *note.Note, err error) {
+	ctx, span := trace.Start(ctx, "note.Service/Patch")
+	span.SetAttributes(attribute.Stringer("note.id", id))
+	span.SetAttributes(attribute.String("patch.media_type", p.MediaType))
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Patch(ctx, id, p)
+}
+
+func (s *tracingService) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := trace.Start(ctx, "note.Service/Delete")
+	span.SetAttributes(attribute.Stringer("note.id", id))
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Delete(ctx, id)
+}
+
+func (s *tracingService) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	ctx, span := trace.Start(ctx, "note.Service/Get")
+	span.SetAttributes(attribute.Stringer("note.id", id))
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Get(ctx, id)
+}
+
+func (s *tracingService) Fetch(ctx context.Context, p *note.Pagination) (iter note.Iterator, err error) {
+	ctx, span := trace.Start(ctx, "note.Service/Fetch")
+	setPaginationAttributes(span, p)
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Fetch(ctx, p)
+}
+
+// NewTracingStore takes the store and returns a store that traces
+// every operation of store in a child span of the span in the context.
+// The iterators of the fetch are traced from the fetch until they are
+// closed.
+func NewTracingStore(store note.Store) note.Store {
+	return &tracingStore{next: store}
+}
+
+type tracingStore struct {
+	next note.Store
+}
+
+func (s *tracingStore) Insert(ctx context.Context, n *note.Note) (err error) {
+	ctx, span := trace.Start(ctx, "note.Store/Insert")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Insert(ctx, n)
+}
+
+func (s *tracingStore) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	ctx, span := trace.Start(ctx, "note.Store/Update")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Update(ctx, n)
+}
+
+func (s *tracingStore) Modify(ctx context.Context, id uuid.UUID, fn func(n *note.Note) error) (modified *note.Note, err error) {
+	ctx, span := trace.Start(ctx, "note.Store/Modify")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Modify(ctx, id, fn)
+}
+
+func (s *tracingStore) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := trace.Start(ctx, "note.Store/Delete")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Delete(ctx, id)
+}
+
+func (s *tracingStore) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	ctx, span := trace.Start(ctx, "note.Store/Get")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Get(ctx, id)
+}
+
+func (s *tracingStore) Version(ctx context.Context) (v note.Version, err error) {
+	ctx, span := trace.Start(ctx, "note.Store/Version")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Version(ctx)
+}
+
+func (s *tracingStore) Count(ctx context.Context) (count uint64, err error) {
+	ctx, span := trace.Start(ctx, "note.Store/Count")
+	defer func() { trace.SetError(span, err); span.End() }()
+	return s.next.Count(ctx)
+}
+
+func (s *tracingStore) Fetch(ctx context.Context, p *note.Pagination) (iter note.Iterator, err error) {
+	fetchCtx, span := trace.Start(ctx, "note.Store/Fetch")
+	setPaginationAttributes(span, p)
+	iter, err = s.next.Fetch(fetchCtx, p)
+	trace.SetError(span, err)
+	span.End()
+
+	if err != nil || iter == nil {
+		return iter, err
+	}
+
+	// The iteration is a sibling of the fetch.
+	_, iterSpan := trace.Start(ctx, "note.Iterator")
+	if !iterSpan.IsRecording() {
+		return iter, nil
+	}
+	return &tracingIterator{Iterator: iter, span: iterSpan}, nil
+}
+
+func setPaginationAttributes(span oteltrace.Span, p *note.Pagination) {
+	span.SetAttributes(
+		attribute.Int64("pagination.page", int64(p.Page)),
+		attribute.Int64("pagination.size", int64(p.Size)),
+		attribute.String("pagination.sort_by", string(p.SortBy)),
+	)
+}
+
+// tracingIterator ends the span of the iteration when it's closed.
+type tracingIterator struct {
+	note.Iterator
+	span  oteltrace.Span
+	count int
+}
+
+func (i *tracingIterator) Next() bool {
+	ok := i.Iterator.Next()
+	if ok {
+		i.count++
+	}
+	return ok
+}
+
+func (i *tracingIterator) Close() error {
+	err := i.Iterator.Close()
+	i.span.SetAttributes(attribute.Int("iterator.count", i.count))
+	trace.SetError(i.span, i.Iterator.Error())
+	i.span.End()
+	return err
+}
@@ -0,0 +1,90 @@
+package instrument
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/api/v1/transport/rest"
+	"noterfy/note/client"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/trace"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	store := NewTracingStore(memory.New())
+	svc := TracingMiddleware()(service.New(store))
+
+	router := mux.NewRouter()
+	for _, route := range rest.Routes(svc, nil, nil, trace.ServerOptions(provider)...) {
+		router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	_, err := svc.Create(context.Background(), new(note.Note).SetTitle("Title"))
+	require.NoError(t, err)
+	// The calls without a span are not traced.
+	require.Empty(t, recorder.Ended())
+
+	c, err := client.New(&client.Config{BaseURL: server.URL})
+	require.NoError(t, err)
+
+	// The client propagates the trace of the caller to the server.
+	ctx, caller := provider.Tracer("test").Start(context.Background(), "caller")
+	iter, err := c.Fetch(ctx, &note.Pagination{Size: 10, Page: 1})
+	require.NoError(t, err)
+	for iter.Next() {
+	}
+	require.NoError(t, iter.Close())
+	caller.End()
+
+	spans := recorder.Ended()
+	find := func(name string, kind oteltrace.SpanKind) sdktrace.ReadOnlySpan {
+		for _, s := range spans {
+			if s.Name() == name && s.SpanKind() == kind {
+				assert.Equal(t, caller.SpanContext().TraceID(), s.SpanContext().TraceID(), name)
+				return s
+			}
+		}
+		require.Failf(t, "span not found", "%s %s", kind, name)
+		return nil
+	}
+	attr := func(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+		for _, kv := range s.Attributes() {
+			if kv.Key == key {
+				return kv.Value
+			}
+		}
+		return attribute.Value{}
+	}
+
+	clientSpan := find("GET /v1/notes", oteltrace.SpanKindClient)
+	serverSpan := find("GET /v1/notes", oteltrace.SpanKindServer)
+	decodeSpan := find("decode", oteltrace.SpanKindInternal)
+	serviceSpan := find("note.Service/Fetch", oteltrace.SpanKindInternal)
+	storeSpan := find("note.Store/Fetch", oteltrace.SpanKindInternal)
+	iterSpan := find("note.Iterator", oteltrace.SpanKindInternal)
+
+	assert.Equal(t, caller.SpanContext().SpanID(), clientSpan.Parent().SpanID())
+	assert.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), decodeSpan.Parent().SpanID())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), serviceSpan.Parent().SpanID())
+	assert.Equal(t, serviceSpan.SpanContext().SpanID(), storeSpan.Parent().SpanID())
+	assert.Equal(t, serviceSpan.SpanContext().SpanID(), iterSpan.Parent().SpanID())
+
+	assert.Equal(t, int64(200), attr(serverSpan, "http.status_code").AsInt64())
+	assert.Equal(t, int64(10), attr(serviceSpan, "pagination.size").AsInt64())
+	assert.Equal(t, int64(1), attr(iterSpan, "iterator.count").AsInt64())
+}
@@ -0,0 +1,265 @@
+package link
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"noterfy/note"
+	"noterfy/note/noteutil"
+	"sort"
+	"sync"
+)
+
+// rebuildPageSize is the page size use when reading the notes
+// from the store to rebuild the index.
+const rebuildPageSize = 100
+
+// Link is a link from a note to a target.
+type Link struct {
+	// Target is the raw target of the link as written in the content.
+	Target string `json:"target" example:"Other Note"`
+	// NoteID is the ID of the linked note. It is a nil UUID when
+	// the link is broken.
+	NoteID uuid.UUID `json:"note_id" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	// Title is the title of the linked note.
+	Title string `json:"title,omitempty" example:"Other Note"`
+	// Broken indicates that the target doesn't resolve to any note.
+	Broken bool `json:"broken,omitempty" example:"false"`
+}
+
+// Backlink is a note that links to another note.
+type Backlink struct {
+	// NoteID is the ID of the note that contains the link.
+	NoteID uuid.UUID `json:"note_id" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	// Title is the title of the note that contains the link.
+	Title string `json:"title,omitempty" example:"How to Write a Note"`
+}
+
+// BrokenLink is a link that doesn't resolve to any note.
+type BrokenLink struct {
+	// Source is the ID of the note that contains the link.
+	Source uuid.UUID `json:"source" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	// Target is the raw target of the link as written in the content.
+	Target string `json:"target" example:"Missing Note"`
+}
+
+// Node is a note in the graph.
+type Node struct {
+	ID    uuid.UUID `json:"id" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	Title string    `json:"title,omitempty" example:"How to Write a Note"`
+}
+
+// Edge is a resolved link between two notes in the graph.
+type Edge struct {
+	Source uuid.UUID `json:"source" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	Target uuid.UUID `json:"target" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+}
+
+// Graph contains the notes and the links between them.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// entry is the indexed data of a note.
+type entry struct {
+	title   string
+	targets []string
+}
+
+// NewIndex returns an empty link index.
+func NewIndex() *Index {
+	return &Index{notes: make(map[uuid.UUID]entry)}
+}
+
+// Index maintains the links between the notes. The links are stored by
+// their raw targets and are resolved on query so renaming a note fixes
+// or breaks the links to it. This is safe for concurrent use.
+type Index struct {
+	mu    sync.RWMutex
+	notes map[uuid.UUID]entry
+}
+
+// Rebuild replaces the content of the index with the notes
+// from the store.
+func (i *Index) Rebuild(ctx context.Context, store note.Store) error {
+	notes := make(map[uuid.UUID]entry)
+
+	err := noteutil.ForEach(ctx, store, note.Pagination{
+		Size:      rebuildPageSize,
+		SortBy:    note.SortByID,
+		Ascending: true,
+		Archived:  note.ArchivedInclude,
+	}, func(n *note.Note) error {
+		notes[n.ID] = newEntry(n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	i.notes = notes
+	i.mu.Unlock()
+	return nil
+}
+
+// Put parses the links of n note and adds or replaces it in the index.
+func (i *Index) Put(n *note.Note) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.notes[n.ID] = newEntry(n)
+}
+
+// Remove removes the note with id from the index.
+func (i *Index) Remove(id uuid.UUID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.notes, id)
+}
+
+// Links returns the outgoing links of the note with id. It returns
+// note.ErrNotFound when the note is not in the index.
+func (i *Index) Links(id uuid.UUID) ([]Link, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	e, found := i.notes[id]
+	if !found {
+		return nil, note.ErrNotFound
+	}
+
+	titles := i.titles()
+	links := make([]Link, 0, len(e.targets))
+	for _, target := range e.targets {
+		l := Link{Target: target}
+		if targetID, ok := i.resolve(target, titles); ok {
+			l.NoteID = targetID
+			l.Title = i.notes[targetID].title
+		} else {
+			l.Broken = true
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
+
+// Backlinks returns the notes that link to the note with id. It returns
+// note.ErrNotFound when the note is not in the index.
+func (i *Index) Backlinks(id uuid.UUID) ([]Backlink, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if _, found := i.notes[id]; !found {
+		return nil, note.ErrNotFound
+	}
+
+	titles := i.titles()
+	backlinks := make([]Backlink, 0)
+	for sourceID, e := range i.notes {
+		for _, target := range e.targets {
+			if targetID, ok := i.resolve(target, titles); ok && targetID == id {
+				backlinks = append(backlinks, Backlink{NoteID: sourceID, Title: e.title})
+				break
+			}
+		}
+	}
+
+	sort.Slice(backlinks, func(a, b int) bool {
+		return backlinks[a].Title < backlinks[b].Title
+	})
+	return backlinks, nil
+}
+
+// BrokenLinks returns all the links that don't resolve to any note.
+func (i *Index) BrokenLinks() []BrokenLink {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	titles := i.titles()
+	broken := make([]BrokenLink, 0)
+	for sourceID, e := range i.notes {
+		for _, target := range e.targets {
+			if _, ok := i.resolve(target, titles); !ok {
+				broken = append(broken, BrokenLink{Source: sourceID, Target: target})
+			}
+		}
+	}
+
+	sort.Slice(broken, func(a, b int) bool {
+		if broken[a].Source != broken[b].Source {
+			return broken[a].Source.String() < broken[b].Source.String()
+		}
+		return broken[a].Target < broken[b].Target
+	})
+	return broken
+}
+
+// Graph returns all the notes as nodes and the resolved links as edges.
+func (i *Index) Graph() *Graph {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	titles := i.titles()
+	graph := &Graph{
+		Nodes: make([]Node, 0, len(i.notes)),
+		Edges: make([]Edge, 0),
+	}
+
+	for id, e := range i.notes {
+		graph.Nodes = append(graph.Nodes, Node{ID: id, Title: e.title})
+		for _, target := range e.targets {
+			if targetID, ok := i.resolve(target, titles); ok {
+				graph.Edges = append(graph.Edges, Edge{Source: id, Target: targetID})
+			}
+		}
+	}
+
+	sort.Slice(graph.Nodes, func(a, b int) bool {
+		return graph.Nodes[a].ID.String() < graph.Nodes[b].ID.String()
+	})
+	sort.Slice(graph.Edges, func(a, b int) bool {
+		if graph.Edges[a].Source != graph.Edges[b].Source {
+			return graph.Edges[a].Source.String() < graph.Edges[b].Source.String()
+		}
+		return graph.Edges[a].Target.String() < graph.Edges[b].Target.String()
+	})
+	return graph
+}
+
+// titles returns the note IDs by their normalized title. When
+// several notes have the same title the smallest ID wins so the
+// resolution is deterministic. The caller must hold the lock.
+func (i *Index) titles() map[string]uuid.UUID {
+	titles := make(map[string]uuid.UUID, len(i.notes))
+	for id, e := range i.notes {
+		key := normalize(e.title)
+		if key == "" {
+			continue
+		}
+		if existing, found := titles[key]; found && existing.String() < id.String() {
+			continue
+		}
+		titles[key] = id
+	}
+	return titles
+}
+
+// resolve resolves the target to a note ID. The target is first
+// matched as a note ID and then as a title. The caller must hold
+// the lock.
+func (i *Index) resolve(target string, titles map[string]uuid.UUID) (uuid.UUID, bool) {
+	if id, err := uuid.Parse(target); err == nil {
+		_, found := i.notes[id]
+		return id, found
+	}
+
+	id, found := titles[normalize(target)]
+	return id, found
+}
+
+func newEntry(n *note.Note) entry {
+	return entry{
+		title:   n.GetTitle(),
+		targets: Parse(n.GetContent()),
+	}
+}
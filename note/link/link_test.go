@@ -0,0 +1,132 @@
+package link
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noterfy/note"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+func TestParse(t *testing.T) {
+	suite.Run(t, new(ParseTestSuite))
+}
+
+type ParseTestSuite struct {
+	suite.Suite
+}
+
+func (s *ParseTestSuite) TestParse() {
+	table := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "No links", content: "Plain content", want: nil},
+		{name: "Single link", content: "See [[Other Note]].", want: []string{"Other Note"}},
+		{name: "Link with alias", content: "See [[Other Note|the other]].", want: []string{"Other Note"}},
+		{name: "Duplicated links", content: "[[A]] and [[a]] and [[B]]", want: []string{"A", "B"}},
+		{name: "Empty link", content: "[[ ]]", want: nil},
+		{name: "Unclosed link", content: "[[Unclosed", want: nil},
+	}
+
+	for _, row := range table {
+		s.Run(row.name, func() {
+			s.Equal(row.want, Parse(row.content))
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	suite.Run(t, new(IndexTestSuite))
+}
+
+type IndexTestSuite struct {
+	suite.Suite
+	index *Index
+	svc   note.Service
+}
+
+func (s *IndexTestSuite) SetupTest() {
+	s.index = NewIndex()
+	s.svc = Middleware(s.index)(service.New(memory.New()))
+}
+
+func (s *IndexTestSuite) create(title, content string) *note.Note {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle(title).SetContent(content))
+	s.Require().NoError(err)
+	return n
+}
+
+func (s *IndexTestSuite) TestLinks() {
+	target := s.create("Target", "")
+	source := s.create("Source", "[[target]] [["+target.ID.String()+"]] [[Missing]]")
+
+	got, err := s.index.Links(source.ID)
+	s.Require().NoError(err)
+	s.Equal([]Link{
+		{Target: "target", NoteID: target.ID, Title: "Target"},
+		{Target: target.ID.String(), NoteID: target.ID, Title: "Target"},
+		{Target: "Missing", Broken: true},
+	}, got)
+
+	_, err = s.index.Links(uuid.New())
+	s.Equal(note.ErrNotFound, err)
+}
+
+func (s *IndexTestSuite) TestBacklinks() {
+	target := s.create("Target", "")
+	source := s.create("Source", "[[Target]]")
+	s.create("Unrelated", "[[Other]]")
+
+	got, err := s.index.Backlinks(target.ID)
+	s.Require().NoError(err)
+	s.Equal([]Backlink{{NoteID: source.ID, Title: "Source"}}, got)
+
+	s.Run("Updating the content should update the backlinks", func() {
+		_, err := s.svc.Update(dummyCtx, new(note.Note).SetID(source.ID).SetContent("No more links"))
+		s.Require().NoError(err)
+
+		got, err := s.index.Backlinks(target.ID)
+		s.Require().NoError(err)
+		s.Empty(got)
+	})
+}
+
+func (s *IndexTestSuite) TestBrokenLinks() {
+	target := s.create("Target", "")
+	source := s.create("Source", "[[Target]]")
+	s.Empty(s.index.BrokenLinks())
+
+	s.Require().NoError(s.svc.Delete(dummyCtx, target.ID))
+	s.Equal([]BrokenLink{{Source: source.ID, Target: "Target"}}, s.index.BrokenLinks())
+}
+
+func (s *IndexTestSuite) TestGraph() {
+	target := s.create("Target", "")
+	source := s.create("Source", "[[Target]] [[Missing]]")
+
+	got := s.index.Graph()
+	s.ElementsMatch([]Node{{ID: target.ID, Title: "Target"}, {ID: source.ID, Title: "Source"}}, got.Nodes)
+	s.Equal([]Edge{{Source: source.ID, Target: target.ID}}, got.Edges)
+}
+
+func (s *IndexTestSuite) TestRebuild() {
+	store := memory.New()
+	svc := service.New(store)
+	target, err := svc.Create(dummyCtx, new(note.Note).SetTitle("Target"))
+	s.Require().NoError(err)
+	source, err := svc.Create(dummyCtx, new(note.Note).SetTitle("Source").SetContent("[[Target]]"))
+	s.Require().NoError(err)
+
+	index := NewIndex()
+	s.Require().NoError(index.Rebuild(dummyCtx, store))
+
+	got, err := index.Backlinks(target.ID)
+	s.Require().NoError(err)
+	s.Equal([]Backlink{{NoteID: source.ID, Title: "Source"}}, got)
+}
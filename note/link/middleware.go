@@ -0,0 +1,52 @@
+package link
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"noterfy/note"
+)
+
+// Middleware returns a note.Service middleware that keeps the
+// index up to date with the created, updated and deleted notes.
+func Middleware(index *Index) note.Middleware {
+	return func(next note.Service) note.Service {
+		return &indexingService{Service: next, index: index}
+	}
+}
+
+type indexingService struct {
+	note.Service
+	index *Index
+}
+
+func (s *indexingService) Create(ctx context.Context, n *note.Note) (*note.Note, error) {
+	created, err := s.Service.Create(ctx, n)
+	if err == nil {
+		s.index.Put(created)
+	}
+	return created, err
+}
+
+func (s *indexingService) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	updated, err := s.Service.Update(ctx, n)
+	if err == nil {
+		s.index.Put(updated)
+	}
+	return updated, err
+}
+
+func (s *indexingService) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error) {
+	patched, err := s.Service.Patch(ctx, id, p)
+	if err == nil {
+		s.index.Put(patched)
+	}
+	return patched, err
+}
+
+func (s *indexingService) Delete(ctx context.Context, id uuid.UUID) error {
+	err := s.Service.Delete(ctx, id)
+	if err == nil {
+		s.index.Remove(id)
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package link
+
+import (
+	"regexp"
+	"strings"
+)
+
+// wikiLinkPattern matches the wiki-style links such as "[[Other Note]]"
+// and "[[Other Note|alias]]".
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|[^\[\]]*)?\]\]`)
+
+// Parse parses the wiki-style links from the content and returns the
+// unique link targets in order of appearance. A target can either be
+// the ID or the title of the linked note.
+func Parse(content string) []string {
+	matches := wikiLinkPattern.FindAllStringSubmatch(content, -1)
+
+	var (
+		targets []string
+		seen    = make(map[string]bool)
+	)
+
+	for _, match := range matches {
+		target := strings.TrimSpace(match[1])
+		key := normalize(target)
+		if target == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// normalize normalizes the target so the titles are matched
+// case-insensitively.
+func normalize(target string) string {
+	return strings.ToLower(strings.TrimSpace(target))
+}
@@ -99,6 +99,29 @@ func (_m *Service) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 	return r0, r1
 }
 
+// Patch provides a mock function with given fields: ctx, id, p
+func (_m *Service) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error) {
+	ret := _m.Called(ctx, id, p)
+
+	var r0 *note.Note
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, note.Patch) *note.Note); ok {
+		r0 = rf(ctx, id, p)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*note.Note)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, note.Patch) error); ok {
+		r1 = rf(ctx, id, p)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, n
 func (_m *Service) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
 	ret := _m.Called(ctx, n)
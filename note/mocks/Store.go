@@ -16,6 +16,27 @@ type Store struct {
 	mock.Mock
 }
 
+// Count provides a mock function with given fields: ctx
+func (_m *Store) Count(ctx context.Context) (uint64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(context.Context) uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Delete provides a mock function with given fields: ctx, id
 func (_m *Store) Delete(ctx context.Context, id uuid.UUID) error {
 	ret := _m.Called(ctx, id)
@@ -90,6 +111,29 @@ func (_m *Store) Insert(ctx context.Context, n *note.Note) error {
 	return r0
 }
 
+// Modify provides a mock function with given fields: ctx, id, fn
+func (_m *Store) Modify(ctx context.Context, id uuid.UUID, fn func(*note.Note) error) (*note.Note, error) {
+	ret := _m.Called(ctx, id, fn)
+
+	var r0 *note.Note
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, func(*note.Note) error) *note.Note); ok {
+		r0 = rf(ctx, id, fn)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*note.Note)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, func(*note.Note) error) error); ok {
+		r1 = rf(ctx, id, fn)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, n
 func (_m *Store) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
 	ret := _m.Called(ctx, n)
@@ -112,3 +156,24 @@ func (_m *Store) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
 
 	return r0, r1
 }
+
+// Version provides a mock function with given fields: ctx
+func (_m *Store) Version(ctx context.Context) (note.Version, error) {
+	ret := _m.Called(ctx)
+
+	var r0 note.Version
+	if rf, ok := ret.Get(0).(func(context.Context) note.Version); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(note.Version)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"noterfy/pkg/ptrconv"
+	"noterfy/pkg/validation"
 	"text/tabwriter"
 	"time"
 )
@@ -22,6 +23,14 @@ var (
 	ErrNilID = errors.New("note: note id must not empty value")
 )
 
+// The maximum sizes in characters of the note fields.
+const (
+	// MaxTitleLength is the maximum length of the title.
+	MaxTitleLength = 256
+	// MaxContentLength is the maximum length of the content.
+	MaxContentLength = 1 << 20
+)
+
 // Note represents a note.
 type Note struct {
 	// ID is a unique identifier UUID of the note.
@@ -36,6 +45,38 @@ type Note struct {
 	UpdatedTime *time.Time `json:"updated_time,omitempty" example:"2016-02-24 11:12:13"`
 	// IsFavorite is a flag when then the note is marked as favorite
 	IsFavorite *bool `json:"is_favorite,omitempty" example:"true"`
+	// Attachments are the files attached to the note.
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	// RemindAt is the timestamp when the reminder of the note will fire.
+	RemindAt *time.Time `json:"remind_at,omitempty" example:"2016-02-24 11:12:13"`
+	// DueTime is the timestamp when the note is due.
+	DueTime *time.Time `json:"due_time,omitempty" example:"2016-02-24 11:12:13"`
+	// IsPinned is a flag when the note is pinned on top of the
+	// manually ordered notes.
+	IsPinned *bool `json:"is_pinned,omitempty" example:"true"`
+	// Position is the fractional key of the note in the manual order.
+	// A note without a position is placed after the positioned notes.
+	Position *float64 `json:"position,omitempty" example:"1.5"`
+	// IsArchived is a flag when the note is archived. The archived
+	// notes are hidden from the default listing but are not deleted.
+	IsArchived *bool `json:"is_archived,omitempty" example:"true"`
+	// ArchivedTime is the timestamp when the note was archived.
+	ArchivedTime *time.Time `json:"archived_time,omitempty" example:"2016-02-24 11:12:13"`
+}
+
+// Validate checks the fields of the note set by the clients. The
+// title must be a single line while the content can have tabs and
+// line breaks. It returns a *validation.Error listing all the
+// invalid fields.
+func (n *Note) Validate() error {
+	var v validation.Validator
+	if n.Title != nil {
+		v.Text("title", *n.Title, MaxTitleLength, "")
+	}
+	if n.Content != nil {
+		v.Text("content", *n.Content, MaxContentLength, "\t\n\r")
+	}
+	return v.Err()
 }
 
 // SetID sets the id of the note.
@@ -78,6 +119,48 @@ func (n *Note) SetIsFavorite(b bool) *Note {
 	return n
 }
 
+// SetRemindAt sets the reminder time of the note.
+func (n *Note) SetRemindAt(t time.Time) *Note {
+	if !t.IsZero() {
+		n.RemindAt = ptrconv.TimePointer(t)
+	}
+	return n
+}
+
+// SetDueTime sets the due time of the note.
+func (n *Note) SetDueTime(t time.Time) *Note {
+	if !t.IsZero() {
+		n.DueTime = ptrconv.TimePointer(t)
+	}
+	return n
+}
+
+// SetIsPinned sets the is-pinned value for the note.
+func (n *Note) SetIsPinned(b bool) *Note {
+	n.IsPinned = ptrconv.BoolPointer(b)
+	return n
+}
+
+// SetPosition sets the position of the note in the manual order.
+func (n *Note) SetPosition(position float64) *Note {
+	n.Position = ptrconv.Float64Pointer(position)
+	return n
+}
+
+// SetIsArchived sets the is-archived value for the note.
+func (n *Note) SetIsArchived(b bool) *Note {
+	n.IsArchived = ptrconv.BoolPointer(b)
+	return n
+}
+
+// SetArchivedTime sets the archived time of the note.
+func (n *Note) SetArchivedTime(t time.Time) *Note {
+	if !t.IsZero() {
+		n.ArchivedTime = ptrconv.TimePointer(t)
+	}
+	return n
+}
+
 // GetTitle gets the string value title of the note.
 func (n *Note) GetTitle() string {
 	return ptrconv.StringValue(n.Title)
@@ -103,6 +186,36 @@ func (n *Note) GetIsFavorite() bool {
 	return ptrconv.BoolValue(n.IsFavorite)
 }
 
+// GetRemindAt gets the reminder time value of the note.
+func (n *Note) GetRemindAt() time.Time {
+	return ptrconv.TimeValue(n.RemindAt)
+}
+
+// GetDueTime gets the due time value of the note.
+func (n *Note) GetDueTime() time.Time {
+	return ptrconv.TimeValue(n.DueTime)
+}
+
+// GetIsPinned gets the is-pinned boolean value of the note.
+func (n *Note) GetIsPinned() bool {
+	return ptrconv.BoolValue(n.IsPinned)
+}
+
+// GetPosition gets the position value of the note.
+func (n *Note) GetPosition() float64 {
+	return ptrconv.Float64Value(n.Position)
+}
+
+// GetIsArchived gets the is-archived boolean value of the note.
+func (n *Note) GetIsArchived() bool {
+	return ptrconv.BoolValue(n.IsArchived)
+}
+
+// GetArchivedTime gets the archived time value of the note.
+func (n *Note) GetArchivedTime() time.Time {
+	return ptrconv.TimeValue(n.ArchivedTime)
+}
+
 func (n *Note) String() string {
 	var buff bytes.Buffer
 	w := tabwriter.NewWriter(&buff, 0, 8, 4, ' ', tabwriter.TabIndent)
@@ -115,6 +228,12 @@ func (n *Note) String() string {
 	write("📚 Created Time:\t%s\n", n.GetCreatedTime())
 	write("📚 Updated Time:\t%s\n", n.GetUpdatedTime())
 	write("📚 Favorite:\t%v\n", n.GetIsFavorite())
+	write("📚 Attachments:\t%d\n", len(n.Attachments))
+	write("📚 Remind At:\t%s\n", n.GetRemindAt())
+	write("📚 Due Time:\t%s\n", n.GetDueTime())
+	write("📚 Pinned:\t%v\n", n.GetIsPinned())
+	write("📚 Position:\t%v\n", n.GetPosition())
+	write("📚 Archived:\t%v\n", n.GetIsArchived())
 	write("\n")
 	_ = w.Flush()
 	return buff.String()
@@ -8,7 +8,27 @@ import (
 // Copy takes a note and then returns a deeply copied note with
 // a new address.
 func Copy(n *note.Note) *note.Note {
+	from := *n
+	from.Attachments = nil
+
 	cpyNote := new(note.Note)
-	_ = copier.Copy(cpyNote, n)
+	_ = copier.Copy(cpyNote, &from)
+	cpyNote.Attachments = CopyAttachments(n.Attachments)
 	return cpyNote
 }
+
+// CopyAttachments takes attachments and returns a copy of each
+// attachment with a new address. It returns nil when attachments
+// is nil.
+func CopyAttachments(attachments []*note.Attachment) []*note.Attachment {
+	if attachments == nil {
+		return nil
+	}
+
+	cpyAttachments := make([]*note.Attachment, 0, len(attachments))
+	for _, a := range attachments {
+		cpyAttachment := *a
+		cpyAttachments = append(cpyAttachments, &cpyAttachment)
+	}
+	return cpyAttachments
+}
@@ -0,0 +1,19 @@
+package noteutil
+
+import "noterfy/note"
+
+// FilterArchived returns the notes that pass the archived filter. An
+// empty or unknown filter is treated as note.ArchivedExclude.
+func FilterArchived(notes []*note.Note, filter note.ArchivedFilter) []*note.Note {
+	if filter == note.ArchivedInclude {
+		return notes
+	}
+
+	filtered := notes[:0:0]
+	for _, n := range notes {
+		if n.GetIsArchived() == (filter == note.ArchivedOnly) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,52 @@
+package noteutil
+
+import (
+	"context"
+	"noterfy/note"
+)
+
+// Fetcher fetches the notes page by page, e.g. a note.Store
+// or a note.Service.
+type Fetcher interface {
+	Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, error)
+}
+
+// ForEach calls fn with every note fetched from f with the pagination
+// p, one page of p.Size notes at a time starting from the first page.
+// It stops at the first error of the fetch or of fn and returns it.
+func ForEach(ctx context.Context, f Fetcher, p note.Pagination, fn func(n *note.Note) error) error {
+	for p.Page = 1; ; p.Page++ {
+		page := p
+		iter, err := f.Fetch(ctx, &page)
+		if err != nil {
+			return err
+		}
+
+		count, err := forEachInPage(iter, p.Size, fn)
+		if err != nil {
+			return err
+		}
+		if count < p.Size {
+			return nil
+		}
+	}
+}
+
+// forEachInPage calls fn with the notes of the page from iter and
+// returns their count. Only the notes of the page are read because
+// some iterators, e.g. of the client, continue to the next pages.
+func forEachInPage(iter note.Iterator, size uint64, fn func(n *note.Note) error) (count uint64, err error) {
+	defer func() {
+		if cerr := iter.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	for count < size && iter.Next() {
+		count++
+		if err := fn(iter.Note()); err != nil {
+			return count, err
+		}
+	}
+	return count, iter.Error()
+}
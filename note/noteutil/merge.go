@@ -8,13 +8,41 @@ import (
 // Merge merges note from fromNote to toNote. This will
 // ignore empty fields from fromNote.
 func Merge(toNote, fromNote *note.Note) error {
+	// The attachments are replaced instead of merged because
+	// copier merges the slices element by element. The same goes
+	// for the time pointers because copier won't overwrite a time
+	// that is already set.
+	from := *fromNote
+	from.Attachments = nil
+	from.RemindAt = nil
+	from.DueTime = nil
+	from.ArchivedTime = nil
+
 	err := copier.CopyWithOption(
 		toNote,
-		fromNote,
+		&from,
 		copier.Option{IgnoreEmpty: true, DeepCopy: true},
 	)
 	if err != nil {
 		return err
 	}
+
+	if fromNote.Attachments != nil {
+		toNote.Attachments = CopyAttachments(fromNote.Attachments)
+	}
+	if fromNote.RemindAt != nil {
+		toNote.SetRemindAt(fromNote.GetRemindAt())
+	}
+	if fromNote.DueTime != nil {
+		toNote.SetDueTime(fromNote.GetDueTime())
+	}
+	if fromNote.ArchivedTime != nil {
+		toNote.SetArchivedTime(fromNote.GetArchivedTime())
+	}
+
+	// Unarchiving the note clears its archived time.
+	if fromNote.IsArchived != nil && !fromNote.GetIsArchived() {
+		toNote.ArchivedTime = nil
+	}
 	return nil
 }
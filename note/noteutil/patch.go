@@ -0,0 +1,116 @@
+package noteutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	jsonpatch "github.com/evanphx/json-patch"
+	"noterfy/note"
+	"noterfy/pkg/validation"
+)
+
+// readOnlyFields are the fields of the note that the patches can't
+// change. They are managed by the services, e.g. the attachments by
+// the attachment service and the archived flag by the archive service.
+var readOnlyFields = []string{"id", "created_time", "updated_time", "attachments", "is_archived", "archived_time"}
+
+// ApplyPatch applies the patch p to the note n and returns the patched
+// copy of n. The patch is applied on the JSON encoding of the note, so
+// the fields removed by the patch are cleared. It returns a
+// *validation.Error when the patch is malformed, doesn't apply to the
+// note or changes a read-only field, note.ErrPatchTestFailed when a
+// test operation fails and note.ErrUnsupportedPatch when the media type
+// of the patch is not supported.
+func ApplyPatch(n *note.Note, p note.Patch) (*note.Note, error) {
+	doc, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch p.MediaType {
+	case note.MediaTypeMergePatch:
+		// A merge patch that is not an object replaces the whole
+		// note instead of patching it.
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(p.Document, &fields); err != nil || fields == nil {
+			return nil, validation.Malformed("patch", errors.New("must be a JSON object"))
+		}
+		patched, err = jsonpatch.MergePatch(doc, p.Document)
+		if err != nil {
+			return nil, validation.Malformed("patch", err)
+		}
+	case note.MediaTypeJSONPatch:
+		ops, err := jsonpatch.DecodePatch(p.Document)
+		if err != nil {
+			return nil, validation.Malformed("patch", err)
+		}
+		patched, err = ops.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, fmt.Errorf("%w: %v", note.ErrPatchTestFailed, err)
+		}
+		if err != nil {
+			var v validation.Validator
+			v.Addf("patch", validation.CodeInvalid, "%s", err)
+			return nil, v.Err()
+		}
+	default:
+		return nil, note.ErrUnsupportedPatch
+	}
+
+	var v validation.Validator
+	checkReadOnly(&v, doc, patched)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	result := new(note.Note)
+	if err := json.Unmarshal(patched, result); err != nil {
+		v.Addf("patch", validation.CodeInvalid, "the patched note is invalid: %s", err)
+		return nil, v.Err()
+	}
+
+	// The read-only fields are the ones of n rather than
+	// their JSON round trip.
+	result.ID = n.ID
+	result.CreatedTime = n.CreatedTime
+	result.UpdatedTime = n.UpdatedTime
+	result.Attachments = CopyAttachments(n.Attachments)
+	result.IsArchived = n.IsArchived
+	result.ArchivedTime = n.ArchivedTime
+	return Copy(result), nil
+}
+
+// checkReadOnly adds a violation of every read-only field that
+// differs between the JSON documents of the note before and
+// after the patch.
+func checkReadOnly(v *validation.Validator, before, after []byte) {
+	var beforeFields, afterFields map[string]json.RawMessage
+	if err := json.Unmarshal(before, &beforeFields); err != nil {
+		v.Addf("patch", validation.CodeInvalid, "%s", err)
+		return
+	}
+	if err := json.Unmarshal(after, &afterFields); err != nil {
+		v.Addf("patch", validation.CodeInvalid, "the patched note must be a JSON object")
+		return
+	}
+
+	for _, field := range readOnlyFields {
+		if !jsonEqual(beforeFields[field], afterFields[field]) {
+			v.Addf(field, validation.CodeReadOnly, "must not be changed by the patch")
+		}
+	}
+}
+
+// jsonEqual tells whether the JSON values a and b are equal
+// regardless of their formatting. The missing values are equal.
+func jsonEqual(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	if bytes.Equal(a, b) {
+		return true
+	}
+	return jsonpatch.Equal(a, b)
+}
@@ -26,6 +26,12 @@ func Sort(notes []*note.Note, sortBy note.SortBy, ascending bool) {
 		} else {
 			sort.Sort(note.SortByCreatedDateDescendingSorter(notes))
 		}
+	case note.SortByManual:
+		if ascending {
+			sort.Sort(note.SortByManualSorter(notes))
+		} else {
+			sort.Sort(note.SortByManualDescendingSorter(notes))
+		}
 	default:
 		if ascending {
 			sort.Sort(note.SortByIDSorter(notes))
@@ -0,0 +1,158 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"noterfy/note"
+	"noterfy/note/noteutil"
+	"sync"
+)
+
+// rebalancePageSize is the page size use when reading the notes
+// from the service to rebalance their positions.
+const rebalancePageSize = 100
+
+// ErrInvalidMove is an error when a note can't be placed between
+// the given notes such as when the note is moved relative to itself.
+var ErrInvalidMove = errors.New("order: invalid move of the note")
+
+// New takes the note service and returns an order service instance.
+// The positions are changed through svc so its middlewares, e.g. the
+// audit log, see the moves.
+func New(svc note.Service) *Service {
+	return &Service{svc: svc}
+}
+
+// Service manages the manual order of the notes.
+//
+// The order is kept as a fractional position in each note so a
+// note can be moved by updating only the note itself. The position
+// of a moved note is the midpoint of its new neighbours. Once the
+// midpoint can't be represented anymore, or a neighbour has no
+// position yet, all the positions are renumbered.
+type Service struct {
+	svc note.Service
+
+	// mu serializes the moves so two notes moved at the same
+	// time can't get the same position.
+	mu sync.Mutex
+}
+
+// Move places the note with id after the note with after and before the
+// note with before. Either after or before can be uuid.Nil to move the note
+// to the end or to the start of the manual order respectively. It returns
+// the moved note with its new position.
+func (s *Service) Move(ctx context.Context, id, before, after uuid.UUID) (*note.Note, error) {
+	if id == uuid.Nil {
+		return nil, note.ErrNilID
+	}
+
+	if (before == uuid.Nil && after == uuid.Nil) || before == id || after == id || before == after {
+		return nil, ErrInvalidMove
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.svc.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	position, err := s.position(ctx, before, after)
+	if err == errNeedsRebalance {
+		if err := s.rebalance(ctx); err != nil {
+			return nil, err
+		}
+		position, err = s.position(ctx, before, after)
+	}
+	if err == errNeedsRebalance {
+		return nil, ErrInvalidMove
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.svc.Update(ctx, new(note.Note).SetID(id).SetPosition(position))
+}
+
+// errNeedsRebalance is returned by position when the positions of
+// the neighbours don't leave room for the moved note.
+var errNeedsRebalance = errors.New("order: positions need rebalance")
+
+// position returns the position between the note with after and the
+// note with before.
+func (s *Service) position(ctx context.Context, before, after uuid.UUID) (float64, error) {
+	var prev, next *note.Note
+	var err error
+
+	if after != uuid.Nil {
+		if prev, err = s.svc.Get(ctx, after); err != nil {
+			return 0, err
+		}
+		if prev.Position == nil {
+			return 0, errNeedsRebalance
+		}
+	}
+
+	if before != uuid.Nil {
+		if next, err = s.svc.Get(ctx, before); err != nil {
+			return 0, err
+		}
+		if next.Position == nil {
+			return 0, errNeedsRebalance
+		}
+	}
+
+	var position float64
+	switch {
+	case prev == nil:
+		// The positions are kept above 0 so the first
+		// position is halved instead of decremented.
+		position = next.GetPosition() / 2
+		if position <= 0 || position >= next.GetPosition() {
+			return 0, errNeedsRebalance
+		}
+	case next == nil:
+		position = prev.GetPosition() + 1
+	default:
+		position = prev.GetPosition() + (next.GetPosition()-prev.GetPosition())/2
+		if position <= prev.GetPosition() || position >= next.GetPosition() {
+			return 0, errNeedsRebalance
+		}
+	}
+
+	return position, nil
+}
+
+// rebalance renumbers the positions of all the notes to 1, 2, 3...
+// following their current manual order.
+func (s *Service) rebalance(ctx context.Context) error {
+	var notes []*note.Note
+
+	err := noteutil.ForEach(ctx, s.svc, note.Pagination{
+		Size:      rebalancePageSize,
+		SortBy:    note.SortByManual,
+		Ascending: true,
+		Archived:  note.ArchivedInclude,
+	}, func(n *note.Note) error {
+		notes = append(notes, n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, n := range notes {
+		position := float64(i + 1)
+		if n.Position != nil && n.GetPosition() == position {
+			continue
+		}
+
+		if _, err := s.svc.Update(ctx, new(note.Note).SetID(n.ID).SetPosition(position)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
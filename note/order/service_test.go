@@ -0,0 +1,153 @@
+package order
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noterfy/note"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+func TestService(t *testing.T) {
+	suite.Run(t, new(ServiceTestSuite))
+}
+
+type ServiceTestSuite struct {
+	suite.Suite
+	store note.Store
+	svc   *Service
+	notes []*note.Note
+	// updated are the IDs of the notes updated through the
+	// note service.
+	updated []uuid.UUID
+}
+
+// updateRecorder records the IDs of the updated notes.
+type updateRecorder struct {
+	note.Service
+	updated *[]uuid.UUID
+}
+
+func (r *updateRecorder) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	*r.updated = append(*r.updated, n.ID)
+	return r.Service.Update(ctx, n)
+}
+
+func (s *ServiceTestSuite) SetupTest() {
+	s.store = memory.New()
+	s.updated = nil
+	s.svc = New(&updateRecorder{Service: service.New(s.store), updated: &s.updated})
+
+	// The notes have no position so they are ordered by
+	// their created time.
+	now := time.Now()
+	s.notes = nil
+	for i := 0; i < 4; i++ {
+		n := new(note.Note).SetID(uuid.New()).SetCreatedTime(now.Add(time.Duration(i) * time.Second))
+		s.Require().NoError(s.store.Insert(dummyCtx, n))
+		s.notes = append(s.notes, n)
+	}
+}
+
+func (s *ServiceTestSuite) order() []uuid.UUID {
+	iter, err := s.store.Fetch(dummyCtx, &note.Pagination{Size: 10, Page: 1, SortBy: note.SortByManual, Ascending: true})
+	s.Require().NoError(err)
+	defer func() { _ = iter.Close() }()
+
+	var ids []uuid.UUID
+	for iter.Next() {
+		ids = append(ids, iter.Note().ID)
+	}
+	return ids
+}
+
+func (s *ServiceTestSuite) ids(indexes ...int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(indexes))
+	for _, i := range indexes {
+		ids = append(ids, s.notes[i].ID)
+	}
+	return ids
+}
+
+func (s *ServiceTestSuite) TestMove() {
+	s.Run("Move to the start", func() {
+		_, err := s.svc.Move(dummyCtx, s.notes[2].ID, s.notes[0].ID, uuid.Nil)
+		s.Require().NoError(err)
+		s.Equal(s.ids(2, 0, 1, 3), s.order())
+	})
+
+	s.Run("Move between two notes", func() {
+		s.updated = nil
+		moved, err := s.svc.Move(dummyCtx, s.notes[3].ID, s.notes[0].ID, s.notes[2].ID)
+		s.Require().NoError(err)
+		s.Equal(s.ids(2, 3, 0, 1), s.order())
+
+		// Only the moved note is updated once the notes have positions.
+		s.Equal(s.ids(3), s.updated)
+		prev, err := s.store.Get(dummyCtx, s.notes[2].ID)
+		s.Require().NoError(err)
+		next, err := s.store.Get(dummyCtx, s.notes[0].ID)
+		s.Require().NoError(err)
+		s.Greater(moved.GetPosition(), prev.GetPosition())
+		s.Less(moved.GetPosition(), next.GetPosition())
+	})
+
+	s.Run("Move to the end", func() {
+		_, err := s.svc.Move(dummyCtx, s.notes[2].ID, uuid.Nil, s.notes[1].ID)
+		s.Require().NoError(err)
+		s.Equal(s.ids(3, 0, 1, 2), s.order())
+	})
+
+	s.Run("Pinned notes stay first", func() {
+		_, err := s.store.Update(dummyCtx, new(note.Note).SetID(s.notes[2].ID).SetIsPinned(true))
+		s.Require().NoError(err)
+		s.Equal(s.ids(2, 3, 0, 1), s.order())
+	})
+}
+
+func (s *ServiceTestSuite) TestMoveRebalance() {
+	_, err := s.svc.Move(dummyCtx, s.notes[1].ID, uuid.Nil, s.notes[3].ID)
+	s.Require().NoError(err)
+
+	// Keep moving a note right after the first note so the gap
+	// between the neighbours is halved on every move until the
+	// midpoint can't be represented and the positions are renumbered.
+	moving, other := s.notes[2], s.notes[3]
+	for i := 0; i < 100; i++ {
+		_, err := s.svc.Move(dummyCtx, moving.ID, other.ID, s.notes[0].ID)
+		s.Require().NoError(err)
+		moving, other = other, moving
+	}
+
+	s.Equal([]uuid.UUID{s.notes[0].ID, other.ID, moving.ID, s.notes[1].ID}, s.order())
+	// The renumbered positions are updated through the note service too.
+	s.Greater(len(s.updated), 100)
+}
+
+func (s *ServiceTestSuite) TestMoveInvalid() {
+	table := []struct {
+		name          string
+		id            uuid.UUID
+		before, after uuid.UUID
+		want          error
+	}{
+		{name: "Nil ID", id: uuid.Nil, before: s.notes[0].ID, want: note.ErrNilID},
+		{name: "No neighbours", id: s.notes[0].ID, want: ErrInvalidMove},
+		{name: "Relative to itself", id: s.notes[0].ID, before: s.notes[0].ID, want: ErrInvalidMove},
+		{name: "Neighbours in the wrong order", id: s.notes[0].ID, before: s.notes[1].ID, after: s.notes[3].ID, want: ErrInvalidMove},
+		{name: "Note not found", id: uuid.New(), before: s.notes[0].ID, want: note.ErrNotFound},
+		{name: "Neighbour not found", id: s.notes[0].ID, before: uuid.New(), want: note.ErrNotFound},
+	}
+
+	for _, row := range table {
+		s.Run(row.name, func() {
+			_, err := s.svc.Move(dummyCtx, row.id, row.before, row.after)
+			s.ErrorIs(err, row.want)
+		})
+	}
+}
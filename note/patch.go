@@ -0,0 +1,31 @@
+package note
+
+import "errors"
+
+// The media types of the patch documents.
+const (
+	// MediaTypeMergePatch is the media type of a JSON Merge Patch
+	// (RFC 7396). The fields set to null are cleared.
+	MediaTypeMergePatch = "application/merge-patch+json"
+	// MediaTypeJSONPatch is the media type of a JSON Patch (RFC 6902).
+	MediaTypeJSONPatch = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedPatch is an error when the media type of the
+	// patch document is not supported.
+	ErrUnsupportedPatch = errors.New("note: unsupported patch media type")
+	// ErrPatchTestFailed is an error when a test operation of the
+	// JSON Patch doesn't match the note.
+	ErrPatchTestFailed = errors.New("note: patch test failed")
+)
+
+// Patch is a patch document of a note. The fields are the ones of
+// the JSON encoding of the note, e.g. "title" and "is_favorite".
+type Patch struct {
+	// MediaType is the media type of the document. It can be either
+	// MediaTypeMergePatch or MediaTypeJSONPatch.
+	MediaType string
+	// Document is the patch document.
+	Document []byte
+}
@@ -124,19 +124,101 @@ func ProtoToNote(p *pb.Note) (*note.Note, error) {
 		SetContent(p.Content).
 		SetCreatedTime(p.CreatedTime.AsTime()).
 		SetUpdatedTime(p.UpdatedTime.AsTime()).
-		SetIsFavorite(p.IsFavorite)
+		SetIsFavorite(p.IsFavorite).
+		SetIsPinned(p.IsPinned).
+		SetIsArchived(p.IsArchived)
+
+	if p.Position > 0 {
+		n.SetPosition(p.Position)
+	}
+
+	// The optional timestamps are only set when present because a
+	// nil timestamp is converted to the Unix epoch.
+	if p.RemindAt != nil {
+		n.SetRemindAt(p.RemindAt.AsTime())
+	}
+
+	if p.DueTime != nil {
+		n.SetDueTime(p.DueTime.AsTime())
+	}
+
+	if p.ArchivedTime != nil {
+		n.SetArchivedTime(p.ArchivedTime.AsTime())
+	}
+
+	for _, pa := range p.Attachments {
+		a, err := ProtoToAttachment(pa)
+		if err != nil {
+			return nil, err
+		}
+		n.Attachments = append(n.Attachments, a)
+	}
+
 	return n, nil
 }
 
 // NoteToProto converts the note to protocol buffer message.
 func NoteToProto(n *note.Note) *pb.Note {
-	return &pb.Note{
+	var attachments []*pb.Attachment
+	for _, a := range n.Attachments {
+		attachments = append(attachments, AttachmentToProto(a))
+	}
+
+	p := &pb.Note{
 		Id:          []byte(n.ID.String()),
 		Title:       n.GetTitle(),
 		Content:     n.GetContent(),
 		CreatedTime: timestamppb.New(n.GetCreatedTime()),
 		UpdatedTime: timestamppb.New(n.GetUpdatedTime()),
 		IsFavorite:  n.GetIsFavorite(),
+		IsPinned:    n.GetIsPinned(),
+		Position:    n.GetPosition(),
+		IsArchived:  n.GetIsArchived(),
+		Attachments: attachments,
+	}
+
+	if n.RemindAt != nil {
+		p.RemindAt = timestamppb.New(*n.RemindAt)
+	}
+
+	if n.DueTime != nil {
+		p.DueTime = timestamppb.New(*n.DueTime)
+	}
+
+	if n.ArchivedTime != nil {
+		p.ArchivedTime = timestamppb.New(*n.ArchivedTime)
+	}
+
+	return p
+}
+
+// ProtoToAttachment converts the attachment protocol buffer
+// message to note.Attachment. If there's any error, it will be
+// related to UUID byte parsing.
+func ProtoToAttachment(p *pb.Attachment) (*note.Attachment, error) {
+	id, err := uuid.ParseBytes(p.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &note.Attachment{
+		ID:          id,
+		Name:        p.Name,
+		MediaType:   p.MediaType,
+		Size:        p.Size,
+		Digest:      p.Digest,
+		CreatedTime: p.CreatedTime.AsTime(),
+	}, nil
+}
+
+// AttachmentToProto converts the attachment to protocol buffer message.
+func AttachmentToProto(a *note.Attachment) *pb.Attachment {
+	return &pb.Attachment{
+		Id:          []byte(a.ID.String()),
+		Name:        a.Name,
+		MediaType:   a.MediaType,
+		Size:        a.Size,
+		Digest:      a.Digest,
+		CreatedTime: timestamppb.New(a.CreatedTime),
 	}
 }
 
@@ -11,6 +11,7 @@ import (
 	"noterfy/note"
 	pb "noterfy/note/proto"
 	"testing"
+	"time"
 )
 
 // https://stackoverflow.com/questions/59163455/sequentially-write-protobuf-messages-to-a-file-in-go
@@ -50,13 +51,19 @@ func TestWriteProtoMessage(t *testing.T) {
 		note1.SetID(uuid.New()).
 			SetTitle("First Note").
 			SetContent("First note content").
-			SetIsFavorite(true)
+			SetIsFavorite(true).
+			SetIsPinned(true).
+			SetPosition(1.5).
+			SetIsArchived(true).
+			SetArchivedTime(time.Now().UTC().Truncate(time.Second))
 
 		note2 := &note.Note{}
 		note2.SetID(uuid.New()).
 			SetTitle("Second Note").
 			SetContent("Second note content").
-			SetIsFavorite(false)
+			SetIsFavorite(false).
+			SetIsPinned(false).
+			SetIsArchived(false)
 
 		var buff bytes.Buffer
 
@@ -75,6 +82,55 @@ func TestWriteProtoMessage(t *testing.T) {
 		assert.Equal(t, want, got)
 	})
 
+	t.Run("Message with reminder", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Second)
+		n := &note.Note{}
+		n.SetID(uuid.New()).
+			SetTitle("Note with reminder").
+			SetContent("Note with reminder content").
+			SetIsFavorite(false).
+			SetIsPinned(false).
+			SetIsArchived(false).
+			SetRemindAt(now.Add(time.Hour)).
+			SetDueTime(now.Add(24 * time.Hour))
+
+		var buff bytes.Buffer
+		err := WriteProtoMessage(&buff, NoteToProto(n))
+		require.NoError(t, err)
+
+		got, err := ReadProtoMessage(&buff)
+		require.NoError(t, err)
+		assert.Equal(t, n, got)
+	})
+
+	t.Run("Message with attachments", func(t *testing.T) {
+		n := &note.Note{}
+		n.SetID(uuid.New()).
+			SetTitle("Note with attachments").
+			SetContent("Note with attachments content").
+			SetIsFavorite(false).
+			SetIsPinned(false).
+			SetIsArchived(false)
+		n.Attachments = []*note.Attachment{
+			{
+				ID:          uuid.New(),
+				Name:        "diagram.png",
+				MediaType:   "image/png",
+				Size:        1024,
+				Digest:      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				CreatedTime: time.Now().UTC().Truncate(time.Second),
+			},
+		}
+
+		var buff bytes.Buffer
+		err := WriteProtoMessage(&buff, NoteToProto(n))
+		require.NoError(t, err)
+
+		got, err := ReadProtoMessage(&buff)
+		require.NoError(t, err)
+		assert.Equal(t, n, got)
+	})
+
 }
 
 func TestReadProtoMessage(t *testing.T) {
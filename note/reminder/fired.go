@@ -0,0 +1,75 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"os"
+	"time"
+)
+
+// FiredStore persists the fired markers, i.e. the reminder time of
+// the last fired reminder of each note, so the reminders fired before
+// a restart are not fired again when the schedule is rebuilt.
+type FiredStore interface {
+	// Load returns the saved markers. It returns no markers
+	// when none were saved.
+	Load(ctx context.Context) (map[uuid.UUID]time.Time, error)
+	// Save replaces the saved markers with fired.
+	Save(ctx context.Context, fired map[uuid.UUID]time.Time) error
+}
+
+// NewFileFiredStore returns a FiredStore that keeps the markers as
+// JSON in the file with name in fs.
+func NewFileFiredStore(fs afero.Fs, name string) FiredStore {
+	return &fileFiredStore{fs: fs, name: name}
+}
+
+type fileFiredStore struct {
+	fs   afero.Fs
+	name string
+}
+
+func (s *fileFiredStore) Load(ctx context.Context) (map[uuid.UUID]time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fired := make(map[uuid.UUID]time.Time)
+	data, err := afero.ReadFile(s.fs, s.name)
+	if os.IsNotExist(err) {
+		return fired, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fired); err != nil {
+		return nil, err
+	}
+	return fired, nil
+}
+
+func (s *fileFiredStore) Save(ctx context.Context, fired map[uuid.UUID]time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fired)
+	if err != nil {
+		return err
+	}
+
+	// The file is replaced at once so a crash can't leave
+	// it partially written.
+	tmpName := s.name + ".tmp"
+	if err := afero.WriteFile(s.fs, tmpName, data, 0644); err != nil {
+		_ = s.fs.Remove(tmpName)
+		return err
+	}
+	if err := s.fs.Rename(tmpName, s.name); err != nil {
+		_ = s.fs.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package reminder
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"noterfy/note"
+)
+
+// Middleware returns a note.Service middleware that keeps the
+// schedule up to date with the created, updated and deleted notes.
+func Middleware(s *Scheduler) note.Middleware {
+	return func(next note.Service) note.Service {
+		return &schedulingService{Service: next, scheduler: s}
+	}
+}
+
+type schedulingService struct {
+	note.Service
+	scheduler *Scheduler
+}
+
+func (s *schedulingService) Create(ctx context.Context, n *note.Note) (*note.Note, error) {
+	created, err := s.Service.Create(ctx, n)
+	if err == nil {
+		s.scheduler.Schedule(created)
+	}
+	return created, err
+}
+
+func (s *schedulingService) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	updated, err := s.Service.Update(ctx, n)
+	if err == nil {
+		s.scheduler.Schedule(updated)
+	}
+	return updated, err
+}
+
+func (s *schedulingService) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error) {
+	patched, err := s.Service.Patch(ctx, id, p)
+	if err == nil {
+		s.scheduler.Schedule(patched)
+	}
+	return patched, err
+}
+
+func (s *schedulingService) Delete(ctx context.Context, id uuid.UUID) error {
+	err := s.Service.Delete(ctx, id)
+	if err == nil {
+		s.scheduler.Unschedule(id)
+	}
+	return err
+}
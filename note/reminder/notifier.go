@@ -0,0 +1,149 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Notifier delivers the reminder events.
+type Notifier interface {
+	// Notify delivers the event e. It takes ctx context in order
+	// to let the caller stop the execution in any form.
+	Notify(ctx context.Context, e Event) error
+}
+
+// NotifierFunc is an adapter to allow the use of ordinary
+// functions as a Notifier.
+type NotifierFunc func(ctx context.Context, e Event) error
+
+// Notify calls f(ctx, e).
+func (f NotifierFunc) Notify(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// NewLogNotifier returns a notifier that logs the reminder events.
+func NewLogNotifier() Notifier {
+	return NotifierFunc(func(_ context.Context, e Event) error {
+		logrus.WithFields(logrus.Fields{
+			"note_id":   e.NoteID,
+			"title":     e.Title,
+			"remind_at": e.RemindAt,
+		}).Info("reminder fired")
+		return nil
+	})
+}
+
+// DefaultWebhookTimeout is the timeout of the requests of the
+// webhook notifier with the default client.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// NewWebhookNotifier returns a notifier that posts the reminder events
+// as JSON to the url. If client is nil a client with the
+// DefaultWebhookTimeout will be use.
+func NewWebhookNotifier(url string, client *http.Client) Notifier {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+
+	return NotifierFunc(func(ctx context.Context, e Event) error {
+		body, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("reminder: webhook responded with status %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+// subscriberBufferSize is the number of events buffered
+// for each subscriber of the broker.
+const subscriberBufferSize = 16
+
+// NewBroker returns a broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Event]struct{})}
+}
+
+// Broker is a notifier that fans out the reminder events to its
+// subscribers such as the server-sent events clients. A slow
+// subscriber drops the events instead of blocking the others.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+// Subscribe subscribes to the reminder events. The returned function
+// must be called to unsubscribe. The channel is closed when the broker
+// is closed.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subscribers[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Close closes the channels of all the subscribers so they stop
+// listening, e.g. when the server shuts down. The events published
+// after the broker is closed are dropped. It is safe to call more
+// than once.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for ch := range b.subscribers {
+		close(ch)
+		delete(b.subscribers, ch)
+	}
+}
+
+// Notify publishes the event e to all the subscribers.
+func (b *Broker) Notify(_ context.Context, e Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			logrus.Warn("reminder: dropping event for a slow subscriber")
+		}
+	}
+	return nil
+}
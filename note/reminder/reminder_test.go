@@ -0,0 +1,347 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/note"
+	"noterfy/note/service"
+	"noterfy/note/store/memory"
+	"noterfy/pkg/clock"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+var epoch = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestScheduler(t *testing.T) {
+	suite.Run(t, new(SchedulerTestSuite))
+}
+
+type SchedulerTestSuite struct {
+	suite.Suite
+	clock     *clock.Fake
+	events    chan Event
+	store     note.Store
+	scheduler *Scheduler
+	svc       note.Service
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func (s *SchedulerTestSuite) SetupTest() {
+	s.clock = clock.NewFake(epoch)
+	s.events = make(chan Event, 10)
+	s.store = memory.New()
+	s.scheduler = New(&Config{
+		Clock: s.clock,
+		Notifiers: []Notifier{NotifierFunc(func(_ context.Context, e Event) error {
+			s.events <- e
+			return nil
+		})},
+	})
+	s.svc = Middleware(s.scheduler)(service.New(s.store))
+}
+
+func (s *SchedulerTestSuite) TearDownTest() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+		s.cancel = nil
+	}
+}
+
+func (s *SchedulerTestSuite) run() {
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(dummyCtx)
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.NoError(s.scheduler.Run(ctx))
+	}()
+}
+
+func (s *SchedulerTestSuite) create(title string, remindAt time.Time) *note.Note {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle(title).SetRemindAt(remindAt))
+	s.Require().NoError(err)
+	return n
+}
+
+// advance moves the fake clock once the run loop is waiting for its timer.
+func (s *SchedulerTestSuite) advance(d time.Duration) {
+	s.Eventually(func() bool { return s.clock.Timers() > 0 }, time.Second, time.Millisecond)
+	s.clock.Advance(d)
+}
+
+func (s *SchedulerTestSuite) receive() Event {
+	select {
+	case e := <-s.events:
+		return e
+	case <-time.After(time.Second):
+		s.FailNow("timeout waiting for the reminder event")
+		return Event{}
+	}
+}
+
+func (s *SchedulerTestSuite) assertNoEvent() {
+	select {
+	case e := <-s.events:
+		s.Failf("unexpected reminder event", "%v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func (s *SchedulerTestSuite) TestFire() {
+	first := s.create("First", epoch.Add(time.Minute))
+	second := s.create("Second", epoch.Add(2*time.Minute))
+	s.run()
+
+	s.advance(time.Minute)
+	e := s.receive()
+	s.Equal(first.ID, e.NoteID)
+	s.Equal("First", e.Title)
+	s.True(e.RemindAt.Equal(epoch.Add(time.Minute)))
+	s.assertNoEvent()
+
+	s.advance(time.Minute)
+	s.Equal(second.ID, s.receive().NoteID)
+	s.Empty(s.scheduler.Upcoming(0, 0))
+}
+
+func (s *SchedulerTestSuite) TestReschedule() {
+	n := s.create("Note", epoch.Add(time.Minute))
+	s.run()
+
+	_, err := s.svc.Update(dummyCtx, &note.Note{ID: n.ID, RemindAt: ptrTime(epoch.Add(time.Hour))})
+	s.Require().NoError(err)
+
+	s.advance(time.Minute)
+	s.assertNoEvent()
+
+	s.advance(time.Hour)
+	s.Equal(n.ID, s.receive().NoteID)
+
+	s.Run("Fired reminder is not fired again on update", func() {
+		_, err := s.svc.Update(dummyCtx, &note.Note{ID: n.ID, Title: ptrString("Renamed")})
+		s.Require().NoError(err)
+		s.Empty(s.scheduler.Upcoming(0, 0))
+	})
+}
+
+func (s *SchedulerTestSuite) TestDelete() {
+	n := s.create("Note", epoch.Add(time.Minute))
+	s.run()
+
+	s.Require().NoError(s.svc.Delete(dummyCtx, n.ID))
+	s.Empty(s.scheduler.Upcoming(0, 0))
+
+	s.clock.Advance(time.Hour)
+	s.assertNoEvent()
+}
+
+func (s *SchedulerTestSuite) TestUpcoming() {
+	later := s.create("Later", epoch.Add(2*time.Hour))
+	soon := s.create("Soon", epoch.Add(time.Minute))
+	soonest := s.create("Soonest", epoch.Add(time.Second))
+	s.create("Past", epoch.Add(-time.Hour))
+	_, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle("No reminder"))
+	s.Require().NoError(err)
+
+	ids := func(events []Event) (ids []uuid.UUID) {
+		for _, e := range events {
+			ids = append(ids, e.NoteID)
+		}
+		return
+	}
+
+	s.Equal([]uuid.UUID{soonest.ID, soon.ID, later.ID}, ids(s.scheduler.Upcoming(0, 0)))
+	s.Equal([]uuid.UUID{soonest.ID, soon.ID}, ids(s.scheduler.Upcoming(time.Hour, 0)))
+	s.Equal([]uuid.UUID{soonest.ID}, ids(s.scheduler.Upcoming(0, 1)))
+}
+
+func (s *SchedulerTestSuite) TestRebuild() {
+	// Store the notes directly so the scheduler doesn't know them.
+	n := new(note.Note).SetTitle("Stored").SetRemindAt(epoch.Add(time.Minute))
+	n.ID = uuid.New()
+	s.Require().NoError(s.store.Insert(dummyCtx, n))
+
+	missed := new(note.Note).SetTitle("Missed").SetRemindAt(epoch.Add(-time.Minute))
+	missed.ID = uuid.New()
+	s.Require().NoError(s.store.Insert(dummyCtx, missed))
+
+	s.Require().NoError(s.scheduler.Rebuild(dummyCtx, s.store))
+	upcoming := s.scheduler.Upcoming(0, 0)
+	s.Require().Len(upcoming, 1)
+	s.Equal(n.ID, upcoming[0].NoteID)
+
+	s.Run("Missed reminder within the grace period", func() {
+		s.scheduler.missedGrace = time.Hour
+		s.Require().NoError(s.scheduler.Rebuild(dummyCtx, s.store))
+		s.run()
+		s.Equal(missed.ID, s.receive().NoteID)
+	})
+}
+
+func (s *SchedulerTestSuite) TestSlowNotifier() {
+	release := make(chan struct{})
+	timedOut := make(chan error, 1)
+	s.scheduler = New(&Config{
+		Clock:         s.clock,
+		NotifyTimeout: 10 * time.Millisecond,
+		Notifiers: []Notifier{
+			NotifierFunc(func(ctx context.Context, e Event) error {
+				select {
+				case <-release:
+				case <-ctx.Done():
+					timedOut <- ctx.Err()
+				}
+				return ctx.Err()
+			}),
+			NotifierFunc(func(_ context.Context, e Event) error {
+				s.events <- e
+				return nil
+			}),
+		},
+	})
+	s.svc = Middleware(s.scheduler)(service.New(s.store))
+	defer close(release)
+
+	first := s.create("First", epoch.Add(time.Minute))
+	second := s.create("Second", epoch.Add(2*time.Minute))
+	s.run()
+
+	// The other notifier and the next reminders are not delayed.
+	s.advance(time.Minute)
+	s.Equal(first.ID, s.receive().NoteID)
+	s.advance(time.Minute)
+	s.Equal(second.ID, s.receive().NoteID)
+
+	select {
+	case err := <-timedOut:
+		s.Equal(context.DeadlineExceeded, err)
+	case <-time.After(time.Second):
+		s.Fail("the slow notifier was not cancelled")
+	}
+}
+
+func (s *SchedulerTestSuite) TestFiredStore() {
+	fired := NewFileFiredStore(afero.NewMemMapFs(), "fired.json")
+	newScheduler := func() *Scheduler {
+		return New(&Config{
+			Clock:       s.clock,
+			MissedGrace: time.Hour,
+			FiredStore:  fired,
+			Notifiers: []Notifier{NotifierFunc(func(_ context.Context, e Event) error {
+				s.events <- e
+				return nil
+			})},
+		})
+	}
+	s.scheduler = newScheduler()
+	s.svc = Middleware(s.scheduler)(service.New(s.store))
+
+	n := s.create("Note", epoch.Add(time.Minute))
+	rescheduled := s.create("Rescheduled", epoch.Add(time.Minute))
+	s.run()
+	s.advance(time.Minute)
+	s.receive()
+	s.receive()
+
+	// The server restarts after the reminder of one note changed.
+	s.TearDownTest()
+	_, err := s.store.Update(dummyCtx, &note.Note{ID: rescheduled.ID, RemindAt: ptrTime(epoch.Add(30 * time.Second))})
+	s.Require().NoError(err)
+	s.scheduler = newScheduler()
+	s.Require().NoError(s.scheduler.Rebuild(dummyCtx, s.store))
+
+	upcoming := s.scheduler.Upcoming(0, 0)
+	s.Require().Len(upcoming, 1)
+	s.Equal(rescheduled.ID, upcoming[0].NoteID)
+
+	markers, err := fired.Load(dummyCtx)
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{n.ID}, keys(markers))
+}
+
+func keys(m map[uuid.UUID]time.Time) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestNotifier(t *testing.T) {
+	suite.Run(t, new(NotifierTestSuite))
+}
+
+type NotifierTestSuite struct {
+	suite.Suite
+}
+
+func (s *NotifierTestSuite) TestWebhook() {
+	event := Event{NoteID: uuid.New(), Title: "Note", RemindAt: epoch}
+
+	s.Run("Success", func() {
+		var got Event
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.Equal(http.MethodPost, r.Method)
+			s.NoError(json.NewDecoder(r.Body).Decode(&got))
+		}))
+		defer srv.Close()
+
+		s.NoError(NewWebhookNotifier(srv.URL, nil).Notify(dummyCtx, event))
+		s.Equal(event.NoteID, got.NoteID)
+		s.True(event.RemindAt.Equal(got.RemindAt))
+	})
+
+	s.Run("Error status", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		s.Error(NewWebhookNotifier(srv.URL, nil).Notify(dummyCtx, event))
+	})
+}
+
+func (s *NotifierTestSuite) TestBroker() {
+	broker := NewBroker()
+	events, unsubscribe := broker.Subscribe()
+
+	event := Event{NoteID: uuid.New()}
+	s.NoError(broker.Notify(dummyCtx, event))
+	s.Equal(event, <-events)
+
+	unsubscribe()
+	s.NoError(broker.Notify(dummyCtx, event))
+	s.Empty(events)
+}
+
+func (s *NotifierTestSuite) TestBrokerClose() {
+	broker := NewBroker()
+	events, unsubscribe := broker.Subscribe()
+	defer unsubscribe()
+
+	broker.Close()
+	broker.Close()
+
+	_, ok := <-events
+	s.False(ok)
+	s.NoError(broker.Notify(dummyCtx, Event{NoteID: uuid.New()}))
+
+	late, unsubscribeLate := broker.Subscribe()
+	defer unsubscribeLate()
+	_, ok = <-late
+	s.False(ok)
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
+
+func ptrString(s string) *string { return &s }
@@ -0,0 +1,423 @@
+package reminder
+
+import (
+	"container/heap"
+	"context"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"noterfy/note"
+	"noterfy/note/noteutil"
+	"noterfy/pkg/clock"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rebuildPageSize is the page size use when reading the notes
+// from the store to rebuild the schedule.
+const rebuildPageSize = 100
+
+// DefaultNotifyTimeout is how long a notifier can take to deliver
+// an event.
+const DefaultNotifyTimeout = 10 * time.Second
+
+// deliveryBufferSize is the number of events buffered for each
+// notifier before the scheduler waits for the notifier.
+const deliveryBufferSize = 64
+
+// Event is a reminder event that is delivered to the notifiers
+// once the reminder time of a note is reached.
+type Event struct {
+	// NoteID is the ID of the note of the reminder.
+	NoteID uuid.UUID `json:"note_id" example:"ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	// Title is the title of the note of the reminder.
+	Title string `json:"title,omitempty" example:"How to Write a Note"`
+	// RemindAt is the timestamp when the reminder fires.
+	RemindAt time.Time `json:"remind_at" example:"2016-02-24 11:12:13"`
+	// DueTime is the timestamp when the note is due.
+	DueTime *time.Time `json:"due_time,omitempty" example:"2016-02-24 11:12:13"`
+}
+
+// New takes conf for all the arguments that the scheduler needs and
+// returns a scheduler instance.
+func New(conf *Config) *Scheduler {
+	conf.checkDefaults()
+	return &Scheduler{
+		clock:         conf.Clock,
+		notifiers:     conf.Notifiers,
+		missedGrace:   conf.MissedGrace,
+		notifyTimeout: conf.NotifyTimeout,
+		firedStore:    conf.FiredStore,
+		index:         make(map[uuid.UUID]*item),
+		fired:         make(map[uuid.UUID]time.Time),
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Config contains all the arguments that the scheduler needs.
+type Config struct {
+	// Clock is the clock use to tell the time. Default is the
+	// real clock.
+	Clock clock.Clock
+	// Notifiers are the notifiers where the reminder events
+	// are delivered.
+	Notifiers []Notifier
+	// MissedGrace is how old a reminder can be and still be fired.
+	// It allows the reminders missed while the server was down to be
+	// fired when the schedule is rebuilt. Default is 0 where the missed
+	// reminders are skipped.
+	MissedGrace time.Duration
+	// NotifyTimeout is how long a notifier can take to deliver an
+	// event. Default is DefaultNotifyTimeout.
+	NotifyTimeout time.Duration
+	// FiredStore persists the fired reminders so they are not fired
+	// again after a restart. Default is nil where they are only kept
+	// in memory.
+	FiredStore FiredStore
+}
+
+func (c *Config) checkDefaults() {
+	if c.Clock == nil {
+		c.Clock = clock.New()
+	}
+	if c.NotifyTimeout <= 0 {
+		c.NotifyTimeout = DefaultNotifyTimeout
+	}
+}
+
+// Scheduler fires the reminder events of the notes at their
+// reminder time. The pending reminders are kept in a timer
+// heap. This is safe for concurrent use.
+//
+// The events are delivered to each notifier in its own goroutine so
+// a slow notifier doesn't delay the reminders nor the other notifiers.
+type Scheduler struct {
+	clock         clock.Clock
+	notifiers     []Notifier
+	missedGrace   time.Duration
+	notifyTimeout time.Duration
+	firedStore    FiredStore
+	// saveMu serializes the saves of the fired markers
+	// so they are saved in order.
+	saveMu sync.Mutex
+
+	mu    sync.Mutex
+	queue queue
+	index map[uuid.UUID]*item
+	// fired contains the reminder time of the last fired
+	// reminder of each note so an update of the note won't
+	// fire the same reminder again.
+	fired map[uuid.UUID]time.Time
+
+	// wake signals the run loop that the earliest
+	// reminder may have changed.
+	wake chan struct{}
+}
+
+// Schedule schedules the reminder of n note. It replaces the existing
+// reminder of the note or removes it when the note has no reminder time.
+func (s *Scheduler) Schedule(n *note.Note) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduleLocked(n, s.clock.Now())
+	s.notify()
+}
+
+// Unschedule removes the reminder of the note with id.
+func (s *Scheduler) Unschedule(id uuid.UUID) {
+	s.mu.Lock()
+	s.removeLocked(id)
+	_, fired := s.fired[id]
+	delete(s.fired, id)
+	s.notify()
+	s.mu.Unlock()
+
+	if fired {
+		s.saveFired(context.Background())
+	}
+}
+
+// Rebuild replaces the pending reminders with the reminders of the
+// notes from the store. It is use to restore the schedule on startup.
+// The reminders fired before, according to the fired store, are not
+// scheduled again.
+func (s *Scheduler) Rebuild(ctx context.Context, store note.Store) error {
+	var notes []*note.Note
+
+	var fired map[uuid.UUID]time.Time
+	if s.firedStore != nil {
+		var err error
+		if fired, err = s.firedStore.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	err := noteutil.ForEach(ctx, store, note.Pagination{
+		Size:      rebuildPageSize,
+		SortBy:    note.SortByID,
+		Ascending: true,
+		Archived:  note.ArchivedInclude,
+	}, func(n *note.Note) error {
+		if n.RemindAt != nil {
+			notes = append(notes, n)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	// The markers of the deleted notes and of the changed
+	// reminders are dropped.
+	for _, n := range notes {
+		if remindAt, ok := fired[n.ID]; ok && remindAt.Equal(n.GetRemindAt()) {
+			s.fired[n.ID] = remindAt
+		}
+	}
+
+	s.queue = nil
+	s.index = make(map[uuid.UUID]*item)
+	now := s.clock.Now()
+	for _, n := range notes {
+		s.scheduleLocked(n, now)
+	}
+	s.notify()
+	s.mu.Unlock()
+
+	if fired != nil {
+		s.saveFired(ctx)
+	}
+	return nil
+}
+
+// Upcoming returns the pending reminders that fire within the duration
+// from now sorted by their reminder time. If within is 0 all the pending
+// reminders are returned. If limit is greater than 0 only the first limit
+// reminders are returned.
+func (s *Scheduler) Upcoming(within time.Duration, limit int) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until := s.clock.Now().Add(within)
+	events := make([]Event, 0)
+	for _, it := range s.queue {
+		if within > 0 && it.event.RemindAt.After(until) {
+			continue
+		}
+		events = append(events, it.event)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].RemindAt.Before(events[j].RemindAt)
+	})
+
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+// Run fires the reminders at their time until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	deliveries := make([]chan Event, len(s.notifiers))
+	var wg sync.WaitGroup
+	for i, n := range s.notifiers {
+		deliveries[i] = make(chan Event, deliveryBufferSize)
+		wg.Add(1)
+		go func(n Notifier, events <-chan Event) {
+			defer wg.Done()
+			s.deliver(ctx, n, events)
+		}(n, deliveries[i])
+	}
+	defer func() {
+		for _, ch := range deliveries {
+			close(ch)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		s.mu.Lock()
+		var next *item
+		if len(s.queue) > 0 {
+			next = s.queue[0]
+		}
+		s.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-s.wake:
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		timer := s.clock.NewTimer(next.event.RemindAt.Sub(s.clock.Now()))
+		select {
+		case <-timer.C():
+			s.fireDue(ctx, deliveries)
+		case <-s.wake:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+	}
+}
+
+// fireDue sends the events of all the reminders that are due to the
+// deliveries of the notifiers. The reminders are marked as fired
+// before, so a crash can't fire a reminder twice.
+func (s *Scheduler) fireDue(ctx context.Context, deliveries []chan Event) {
+	now := s.clock.Now()
+
+	var due []Event
+	s.mu.Lock()
+	for len(s.queue) > 0 && !s.queue[0].event.RemindAt.After(now) {
+		it := heap.Pop(&s.queue).(*item)
+		delete(s.index, it.event.NoteID)
+		s.fired[it.event.NoteID] = it.event.RemindAt
+		due = append(due, it.event)
+	}
+	s.mu.Unlock()
+
+	if len(due) > 0 {
+		s.saveFired(ctx)
+	}
+
+	for _, e := range due {
+		for _, ch := range deliveries {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// deliver delivers the events to the notifier n until the
+// events are closed.
+func (s *Scheduler) deliver(ctx context.Context, n Notifier, events <-chan Event) {
+	for e := range events {
+		nctx, cancel := context.WithTimeout(ctx, s.notifyTimeout)
+		if err := n.Notify(nctx, e); err != nil {
+			logrus.WithField("note_id", e.NoteID).Error("reminder: unable to notify: ", err)
+		}
+		cancel()
+	}
+}
+
+// saveFired saves the fired markers to the fired store, if any. An
+// error is only logged because the reminders are fired anyway.
+func (s *Scheduler) saveFired(ctx context.Context) {
+	if s.firedStore == nil {
+		return
+	}
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	fired := make(map[uuid.UUID]time.Time, len(s.fired))
+	for id, remindAt := range s.fired {
+		fired[id] = remindAt
+	}
+	s.mu.Unlock()
+
+	if err := s.firedStore.Save(ctx, fired); err != nil {
+		logrus.Error("reminder: unable to save the fired reminders: ", err)
+	}
+}
+
+// scheduleLocked schedules the reminder of n. The caller must
+// hold the lock.
+func (s *Scheduler) scheduleLocked(n *note.Note, now time.Time) {
+	if n.RemindAt == nil {
+		s.removeLocked(n.ID)
+		return
+	}
+
+	remindAt := n.GetRemindAt()
+	if fired, ok := s.fired[n.ID]; ok && fired.Equal(remindAt) {
+		s.removeLocked(n.ID)
+		return
+	}
+
+	if remindAt.Before(now.Add(-s.missedGrace)) {
+		s.removeLocked(n.ID)
+		return
+	}
+
+	event := Event{
+		NoteID:   n.ID,
+		Title:    n.GetTitle(),
+		RemindAt: remindAt,
+		DueTime:  n.DueTime,
+	}
+
+	if it, found := s.index[n.ID]; found {
+		it.event = event
+		heap.Fix(&s.queue, it.index)
+		return
+	}
+
+	it := &item{event: event}
+	heap.Push(&s.queue, it)
+	s.index[n.ID] = it
+}
+
+// removeLocked removes the pending reminder of the note with id.
+// The caller must hold the lock.
+func (s *Scheduler) removeLocked(id uuid.UUID) {
+	if it, found := s.index[id]; found {
+		heap.Remove(&s.queue, it.index)
+		delete(s.index, id)
+	}
+}
+
+// notify wakes up the run loop without blocking.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// item is a pending reminder in the queue.
+type item struct {
+	event Event
+	index int
+}
+
+// queue implements heap.Interface ordered by the reminder time.
+type queue []*item
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	return q[i].event.RemindAt.Before(q[j].event.RemindAt)
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return it
+}
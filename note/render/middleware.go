@@ -0,0 +1,45 @@
+package render
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"noterfy/note"
+)
+
+// Middleware returns a note.Service middleware that invalidates the
+// cached rendered HTML of r whenever a note is updated, patched or
+// deleted.
+func Middleware(r *Renderer) note.Middleware {
+	return func(next note.Service) note.Service {
+		return &invalidatingService{Service: next, renderer: r}
+	}
+}
+
+type invalidatingService struct {
+	note.Service
+	renderer *Renderer
+}
+
+func (s *invalidatingService) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	updated, err := s.Service.Update(ctx, n)
+	if err == nil {
+		s.renderer.Invalidate(updated.ID)
+	}
+	return updated, err
+}
+
+func (s *invalidatingService) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error) {
+	patched, err := s.Service.Patch(ctx, id, p)
+	if err == nil {
+		s.renderer.Invalidate(id)
+	}
+	return patched, err
+}
+
+func (s *invalidatingService) Delete(ctx context.Context, id uuid.UUID) error {
+	err := s.Service.Delete(ctx, id)
+	if err == nil {
+		s.renderer.Invalidate(id)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting"
+	"github.com/yuin/goldmark/extension"
+	"noterfy/note"
+	"regexp"
+	"sync"
+)
+
+// highlightClassPattern matches the CSS classes that the syntax
+// highlighter emits for the code block tokens.
+var highlightClassPattern = regexp.MustCompile(`^[a-z0-9 -]+$`)
+
+// New returns a renderer that converts the note content from
+// CommonMark with GitHub Flavored Markdown extensions to a
+// sanitized HTML.
+func New() *Renderer {
+	return &Renderer{
+		markdown: newMarkdown(),
+		policy:   newPolicy(),
+		cache:    make(map[uuid.UUID]entry),
+	}
+}
+
+// Renderer renders the note content into HTML and caches the result
+// per note. This is safe for concurrent use.
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+
+	mu    sync.RWMutex
+	cache map[uuid.UUID]entry
+}
+
+// entry is a cached rendered HTML together with the checksum
+// of the content it was rendered from.
+type entry struct {
+	checksum [sha256.Size]byte
+	html     []byte
+}
+
+// Render converts the content of n note into a sanitized HTML. The result
+// is cached by the note ID and will be re-rendered once the content
+// of the note changed or the note was invalidated.
+func (r *Renderer) Render(n *note.Note) ([]byte, error) {
+	content := []byte(n.GetContent())
+	checksum := sha256.Sum256(content)
+
+	r.mu.RLock()
+	cached, found := r.cache[n.ID]
+	r.mu.RUnlock()
+
+	if found && cached.checksum == checksum {
+		return cached.html, nil
+	}
+
+	var buff bytes.Buffer
+	if err := r.markdown.Convert(content, &buff); err != nil {
+		return nil, err
+	}
+
+	sanitized := r.policy.SanitizeBytes(buff.Bytes())
+
+	if n.ID != uuid.Nil {
+		r.mu.Lock()
+		r.cache[n.ID] = entry{checksum: checksum, html: sanitized}
+		r.mu.Unlock()
+	}
+
+	return sanitized, nil
+}
+
+// Invalidate removes the cached rendered HTML of the note with id.
+func (r *Renderer) Invalidate(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cache, id)
+}
+
+func newMarkdown() goldmark.Markdown {
+	return goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			highlighting.NewHighlighting(
+				highlighting.WithStyle("github"),
+				highlighting.WithFormatOptions(
+					// Use classes instead of inline styles because the
+					// sanitizer strips the style attributes.
+					html.WithClasses(true),
+				),
+			),
+		),
+	)
+}
+
+func newPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+
+	// Allow the task list checkboxes from GFM.
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+
+	// Allow the classes of the syntax-highlighted code blocks.
+	policy.AllowAttrs("class").Matching(highlightClassPattern).OnElements("pre", "code", "span")
+
+	return policy
+}
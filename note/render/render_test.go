@@ -0,0 +1,93 @@
+package render
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noterfy/note"
+	"strings"
+	"testing"
+)
+
+func TestRenderer(t *testing.T) {
+	suite.Run(t, new(RendererTestSuite))
+}
+
+type RendererTestSuite struct {
+	suite.Suite
+	renderer *Renderer
+}
+
+func (s *RendererTestSuite) SetupTest() {
+	s.renderer = New()
+}
+
+func (s *RendererTestSuite) render(content string) string {
+	n := new(note.Note).SetID(uuid.New()).SetContent(content)
+	got, err := s.renderer.Render(n)
+	s.Require().NoError(err)
+	return string(got)
+}
+
+func (s *RendererTestSuite) TestCommonMark() {
+	got := s.render("# Title\n\nSome **bold** text")
+	s.Contains(got, "<h1>Title</h1>")
+	s.Contains(got, "<strong>bold</strong>")
+}
+
+func (s *RendererTestSuite) TestTable() {
+	got := s.render("| a | b |\n|---|---|\n| 1 | 2 |")
+	s.Contains(got, "<table>")
+	s.Contains(got, "<td>1</td>")
+}
+
+func (s *RendererTestSuite) TestTaskList() {
+	got := s.render("- [x] done\n- [ ] todo")
+	s.Contains(got, `<input checked="" disabled="" type="checkbox"`)
+	s.Contains(got, `<input disabled="" type="checkbox"`)
+}
+
+func (s *RendererTestSuite) TestCodeHighlighting() {
+	got := s.render("```go\nfunc main() {}\n```")
+	s.Contains(got, `<pre class="chroma">`)
+	s.Contains(got, `<span class="kd">func</span>`)
+}
+
+func (s *RendererTestSuite) TestSanitize() {
+	s.Run("Script tag should be removed", func() {
+		got := s.render("<script>alert('x')</script>hello")
+		s.NotContains(got, "<script>")
+	})
+
+	s.Run("Javascript links should be removed", func() {
+		got := s.render("[click](javascript:alert(1))")
+		s.NotContains(got, "javascript:")
+	})
+
+	s.Run("Event handler attributes should be removed", func() {
+		got := s.render(`<img src="x.png" onerror="alert(1)">`)
+		s.False(strings.Contains(got, "onerror"), got)
+	})
+}
+
+func (s *RendererTestSuite) TestCache() {
+	n := new(note.Note).SetID(uuid.New()).SetContent("first")
+
+	first, err := s.renderer.Render(n)
+	s.Require().NoError(err)
+	s.Contains(string(first), "first")
+
+	s.Run("Changing the content should re-render the note", func() {
+		n.SetContent("second")
+		got, err := s.renderer.Render(n)
+		s.Require().NoError(err)
+		s.Contains(string(got), "second")
+	})
+
+	s.Run("Invalidating the note should remove the cached entry", func() {
+		s.renderer.Invalidate(n.ID)
+		s.renderer.mu.RLock()
+		_, found := s.renderer.cache[n.ID]
+		s.renderer.mu.RUnlock()
+		s.False(found)
+	})
+}
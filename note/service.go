@@ -14,6 +14,9 @@ type Service interface {
 	// Update updates an existing note. It takes ctx to let the
 	// caller stop the execution
 	Update(ctx context.Context, n *Note) (*Note, error)
+	// Patch applies the patch p to the existing note with an id
+	// atomically. It takes ctx to let the caller stop the execution.
+	Patch(ctx context.Context, id uuid.UUID, p Patch) (*Note, error)
 	// Delete deletes an existing note with an id.
 	Delete(ctx context.Context, id uuid.UUID) error
 	// Get gets the note with an id.
@@ -22,3 +25,7 @@ type Service interface {
 	// It returns an iterator of the note results.
 	Fetch(ctx context.Context, pagination *Pagination) (Iterator, error)
 }
+
+// Middleware describes a note.Service middleware. It is used to
+// decorate a service with an extra behavior.
+type Middleware func(Service) Service
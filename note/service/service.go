@@ -4,10 +4,11 @@ import (
 	"context"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"noterfy/note"
 	"noterfy/note/noteutil"
+	"noterfy/pkg/logger"
 	"noterfy/pkg/timestamp"
+	"noterfy/pkg/validation"
 )
 
 var _ note.Service = (*Service)(nil)
@@ -20,6 +21,9 @@ type Service struct {
 // Fetch fetches notes from the store using the pagination setting.
 // It returns an iterator of the note results.
 func (s *Service) Fetch(ctx context.Context, pagination *note.Pagination) (note.Iterator, error) {
+	if err := pagination.Validate(); err != nil {
+		return nil, err
+	}
 	pagination.Check()
 	return s.store.Fetch(ctx, pagination)
 }
@@ -32,6 +36,9 @@ func New(store note.Store) *Service {
 // Create creates a new note n with optional value in ID field.
 // It takes ctx to let the caller stop the execution.
 func (s *Service) Create(ctx context.Context, n *note.Note) (*note.Note, error) {
+	if err := validate(n); err != nil {
+		return nil, err
+	}
 
 	if n.ID != uuid.Nil {
 		isExists, err := s.checkNoteIfExists(ctx, n.ID)
@@ -47,12 +54,18 @@ func (s *Service) Create(ctx context.Context, n *note.Note) (*note.Note, error)
 		n.ID = uuid.New()
 	}
 
+	// The attachments are managed by the attachment service and the
+	// archived flag by the archive service so the clients can't set
+	// them, as they can't patch them.
+	n.Attachments = nil
+	n.IsArchived = nil
+	n.ArchivedTime = nil
+	n.UpdatedTime = nil
 	n.CreatedTime = timestamp.GenerateTimestamp()
 
 	err := s.store.Insert(ctx, n)
-
-	logrus.Debug(err)
 	if err != nil {
+		logger.FromContext(ctx).WithField("note_id", n.ID).Debug("service: unable to insert the note: ", err)
 		return nil, err
 	}
 
@@ -62,6 +75,9 @@ func (s *Service) Create(ctx context.Context, n *note.Note) (*note.Note, error)
 // Update updates an existing note. It takes ctx to let the
 // caller stop the execution
 func (s *Service) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	if err := validate(n); err != nil {
+		return nil, err
+	}
 
 	cpyNote := noteutil.Copy(n)
 
@@ -79,6 +95,9 @@ func (s *Service) Update(ctx context.Context, n *note.Note) (*note.Note, error)
 		return nil, fmt.Errorf("service/update: note '%s' not found: %w", cpyNote.ID, note.ErrNotFound)
 	}
 
+	// The attachments are managed by the attachment service
+	// and must not be overwritten by the update.
+	cpyNote.Attachments = nil
 	cpyNote.UpdatedTime = timestamp.GenerateTimestamp()
 
 	updatedNote, err := s.store.Update(ctx, cpyNote)
@@ -89,9 +108,42 @@ func (s *Service) Update(ctx context.Context, n *note.Note) (*note.Note, error)
 	return updatedNote, nil
 }
 
+// Patch applies the patch p to the existing note with an id
+// atomically. The fields removed by the patch are cleared.
+func (s *Service) Patch(ctx context.Context, id uuid.UUID, p note.Patch) (*note.Note, error) {
+	if id == uuid.Nil {
+		return nil, note.ErrNilID
+	}
+
+	return s.store.Modify(ctx, id, func(n *note.Note) error {
+		patched, err := noteutil.ApplyPatch(n, p)
+		if err != nil {
+			return err
+		}
+		if err := patched.Validate(); err != nil {
+			return err
+		}
+
+		patched.UpdatedTime = timestamp.GenerateTimestamp()
+		*n = *patched
+		return nil
+	})
+}
+
+// validate checks the note n from the clients. It returns
+// a *validation.Error when n is nil or has invalid fields.
+func validate(n *note.Note) error {
+	if n == nil {
+		var v validation.Validator
+		v.Addf("note", validation.CodeRequired, "must not be empty")
+		return v.Err()
+	}
+	return n.Validate()
+}
+
 func (s *Service) checkNoteIfExists(ctx context.Context, id uuid.UUID) (bool, error) {
 	existingNote, err := s.store.Get(ctx, id)
-	logrus.Debug("checking note:", existingNote, err)
+	logger.FromContext(ctx).WithField("note_id", id).Debug("service: checking note: ", err)
 	if err == nil && existingNote != nil {
 		return true, nil
 	} else if err == note.ErrNotFound {
@@ -10,10 +10,12 @@ import (
 	"noterfy/note/noteutil"
 	"noterfy/note/store/memory"
 	"noterfy/pkg/ptrconv"
-	"noterfy/pkg/timestamp"
 	"noterfy/pkg/util/errorutil"
+	"noterfy/pkg/validation"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 var dummyCtx = context.TODO()
@@ -84,6 +86,32 @@ func (s *TestSuite) TestCreate() {
 		s.Nil(got)
 	})
 
+	s.Run("Creating a note with the read-only fields", func() {
+		cpyNote := getNote()
+		cpyNote.Attachments = []*note.Attachment{{
+			ID:        uuid.New(),
+			Name:      "made-up.png",
+			MediaType: "image/png",
+			Size:      1024,
+			Digest:    strings.Repeat("a", 64),
+		}}
+		cpyNote.SetIsArchived(true).
+			SetArchivedTime(time.Now().AddDate(0, 0, -1)).
+			SetUpdatedTime(time.Now().AddDate(0, 0, -1))
+
+		got, err := s.svc.Create(dummyCtx, cpyNote)
+		s.Require().NoError(err)
+
+		stored, err := s.store.Get(dummyCtx, got.ID)
+		s.Require().NoError(err)
+		for _, n := range []*note.Note{got, stored} {
+			s.Empty(n.Attachments)
+			s.Nil(n.IsArchived)
+			s.Nil(n.ArchivedTime)
+			s.Nil(n.UpdatedTime)
+		}
+	})
+
 	s.Run("While inserting to  store it returns an error", func() {
 		cpyNote := getNote()
 		store := memory.New()
@@ -98,19 +126,20 @@ func (s *TestSuite) TestCreate() {
 func (s *TestSuite) TestUpdate() {
 	s.Run("Updating an existing note", func() {
 		want := noteutil.Copy(dummyNote)
-		want.UpdatedTime = timestamp.GenerateTimestamp()
 
 		store := memory.New()
 
 		svc := New(store)
 		newNote, err := svc.Create(dummyCtx, want)
 		s.Require().NoError(err)
+		s.Nil(newNote.UpdatedTime)
 
 		got, err := svc.Update(dummyCtx, newNote)
 
 		s.NoError(err)
+		s.Require().NotNil(got.UpdatedTime)
+		newNote.UpdatedTime = got.UpdatedTime
 		s.Equal(newNote, got)
-		s.NotNil(got.UpdatedTime)
 	})
 
 	s.Run("Updating a non-existing note should return an error", func() {
@@ -143,6 +172,109 @@ func (s *TestSuite) TestUpdate() {
 	})
 }
 
+func (s *TestSuite) TestPatch() {
+	mergePatch := func(doc string) note.Patch {
+		return note.Patch{MediaType: note.MediaTypeMergePatch, Document: []byte(doc)}
+	}
+	jsonPatch := func(doc string) note.Patch {
+		return note.Patch{MediaType: note.MediaTypeJSONPatch, Document: []byte(doc)}
+	}
+	create := func() *note.Note {
+		created, err := s.svc.Create(dummyCtx, noteutil.Copy(dummyNote).SetID(uuid.New()))
+		s.Require().NoError(err)
+		return created
+	}
+
+	s.Run("Merge patch clears the null fields", func() {
+		n := create()
+
+		got, err := s.svc.Patch(dummyCtx, n.ID, mergePatch(`{"title": "Patched", "content": null}`))
+		s.Require().NoError(err)
+		s.Equal("Patched", *got.Title)
+		s.Nil(got.Content)
+		s.Equal(n.IsFavorite, got.IsFavorite)
+		s.NotNil(got.UpdatedTime)
+
+		stored, err := s.store.Get(dummyCtx, n.ID)
+		s.NoError(err)
+		s.Equal(got, stored)
+	})
+
+	s.Run("JSON Patch with a passing test operation", func() {
+		n := create()
+
+		got, err := s.svc.Patch(dummyCtx, n.ID, jsonPatch(`[
+			{"op": "test", "path": "/title", "value": "First Test"},
+			{"op": "replace", "path": "/title", "value": "Patched"},
+			{"op": "remove", "path": "/content"}
+		]`))
+		s.Require().NoError(err)
+		s.Equal("Patched", *got.Title)
+		s.Nil(got.Content)
+	})
+
+	s.Run("JSON Patch with a failing test operation leaves the note unchanged", func() {
+		n := create()
+
+		got, err := s.svc.Patch(dummyCtx, n.ID, jsonPatch(`[
+			{"op": "replace", "path": "/title", "value": "Patched"},
+			{"op": "test", "path": "/title", "value": "Other"}
+		]`))
+		s.True(errors.Is(err, note.ErrPatchTestFailed))
+		s.Nil(got)
+
+		stored, err := s.store.Get(dummyCtx, n.ID)
+		s.NoError(err)
+		s.Equal(n, stored)
+	})
+
+	tests := []struct {
+		name  string
+		patch note.Patch
+		field string
+		code  string
+	}{
+		{name: "Merge patch that is not an object", patch: mergePatch(`"title"`), field: "patch", code: validation.CodeMalformed},
+		{name: "Malformed JSON Patch", patch: jsonPatch(`{"op": "remove"}`), field: "patch", code: validation.CodeMalformed},
+		{name: "JSON Patch of a missing path", patch: jsonPatch(`[{"op": "remove", "path": "/remind_at"}]`), field: "patch", code: validation.CodeInvalid},
+		{name: "Patch of a read-only field", patch: mergePatch(`{"created_time": null}`), field: "created_time", code: validation.CodeReadOnly},
+		{name: "Patch of the identifier", patch: jsonPatch(`[{"op": "replace", "path": "/id", "value": "ffffffff-ffff-ffff-ffff-ffffffffffff"}]`), field: "id", code: validation.CodeReadOnly},
+		{name: "Patch with an invalid value", patch: mergePatch(`{"is_favorite": "yes"}`), field: "patch", code: validation.CodeInvalid},
+		{name: "Patched note is invalid", patch: mergePatch(`{"title": "First\nTest"}`), field: "title", code: validation.CodeControlCharacter},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			n := create()
+
+			got, err := s.svc.Patch(dummyCtx, n.ID, tt.patch)
+			s.Nil(got)
+			s.Require().True(errors.Is(err, validation.ErrInvalid), "got %v", err)
+			violations := validation.Violations(err)
+			s.Require().Len(violations, 1)
+			s.Equal(tt.field, violations[0].Field)
+			s.Equal(tt.code, violations[0].Code)
+		})
+	}
+
+	s.Run("Patch with an unsupported media type should return an error", func() {
+		n := create()
+		_, err := s.svc.Patch(dummyCtx, n.ID, note.Patch{MediaType: "application/json", Document: []byte(`{}`)})
+		s.Equal(note.ErrUnsupportedPatch, err)
+	})
+
+	s.Run("Patching a non-existing note should return an error", func() {
+		got, err := s.svc.Patch(dummyCtx, uuid.New(), mergePatch(`{}`))
+		s.Equal(note.ErrNotFound, err)
+		s.Nil(got)
+	})
+
+	s.Run("Patching a note with no ID should return an error", func() {
+		_, err := s.svc.Patch(dummyCtx, uuid.Nil, mergePatch(`{}`))
+		s.Equal(note.ErrNilID, err)
+	})
+}
+
 func (s *TestSuite) TestDelete() {
 	s.Run("Deleting a note", func() {
 		cpyNote := noteutil.Copy(dummyNote)
@@ -251,4 +383,53 @@ func (s *TestSuite) TestFetch() {
 		got := drainIterator(iter)
 		s.Len(got, 25)
 	})
+
+	s.Run("Fetching a page larger than the maximum page size should return an error", func() {
+		_, err := s.svc.Fetch(dummyCtx, &note.Pagination{Size: note.MaxPageSize + 1})
+		s.True(errors.Is(err, validation.ErrInvalid))
+		s.Equal("size", validation.Violations(err)[0].Field)
+	})
+}
+
+func (s *TestSuite) TestValidation() {
+	tooLong := strings.Repeat("a", note.MaxTitleLength+1)
+
+	tests := []struct {
+		name  string
+		note  *note.Note
+		codes []string
+	}{
+		{name: "No note", codes: []string{validation.CodeRequired}},
+		{name: "Title too long", note: noteFactory(0).SetTitle(tooLong), codes: []string{validation.CodeTooLong}},
+		{name: "Title with a line break", note: noteFactory(0).SetTitle("First\nTest"), codes: []string{validation.CodeControlCharacter}},
+		{
+			name:  "Invalid title and content",
+			note:  noteFactory(0).SetTitle("\xff").SetContent("Lorem\x00Ipsum"),
+			codes: []string{validation.CodeInvalidUTF8, validation.CodeControlCharacter},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			for _, do := range []func(n *note.Note) (*note.Note, error){
+				func(n *note.Note) (*note.Note, error) { return s.svc.Create(dummyCtx, n) },
+				func(n *note.Note) (*note.Note, error) { return s.svc.Update(dummyCtx, n) },
+			} {
+				got, err := do(tt.note)
+				s.Nil(got)
+				s.True(errors.Is(err, validation.ErrInvalid))
+
+				var codes []string
+				for _, v := range validation.Violations(err) {
+					codes = append(codes, v.Code)
+				}
+				s.Equal(tt.codes, codes)
+			}
+		})
+	}
+
+	s.Run("Content with line breaks and tabs is valid", func() {
+		_, err := s.svc.Create(dummyCtx, noteFactory(0).SetContent("Lorem\n\tIpsum\r\n"))
+		s.NoError(err)
+	})
 }
@@ -15,6 +15,8 @@ func GetSortBy(s string) SortBy {
 		return SortByTitle
 	case "created_date":
 		return SortByCreatedTime
+	case "manual":
+		return SortByManual
 	default:
 		return SortByTitle
 	}
@@ -121,3 +123,63 @@ func (n SortByCreatedDateDescendingSorter) Less(i, j int) bool {
 func (n SortByCreatedDateDescendingSorter) Swap(i, j int) {
 	n[i], n[j] = n[j], n[i]
 }
+
+// SortByManualSorter implements sort.Interface which sort the
+// note by its position. The pinned notes are placed first and
+// the notes without position are placed last by created date.
+type SortByManualSorter []*Note
+
+// Len returns the length of notes.
+func (n SortByManualSorter) Len() int { return len(n) }
+
+// Less compare the adjacent positions of the note.
+func (n SortByManualSorter) Less(i, j int) bool {
+	if n[i].GetIsPinned() != n[j].GetIsPinned() {
+		return n[i].GetIsPinned()
+	}
+	return manualLess(n[i], n[j])
+}
+
+// Swap swaps the note i, and note j.
+func (n SortByManualSorter) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// SortByManualDescendingSorter implements sort.Interface which
+// sort the note by its position in descending order. The pinned
+// notes are still placed first.
+type SortByManualDescendingSorter []*Note
+
+// Len returns the length of notes.
+func (n SortByManualDescendingSorter) Len() int { return len(n) }
+
+// Less compare the adjacent positions of the note.
+func (n SortByManualDescendingSorter) Less(i, j int) bool {
+	if n[i].GetIsPinned() != n[j].GetIsPinned() {
+		return n[i].GetIsPinned()
+	}
+	return manualLess(n[j], n[i])
+}
+
+// Swap swaps the note i, and note j.
+func (n SortByManualDescendingSorter) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// manualLess reports whether a is placed before b in the manual
+// order regardless of the pinned flag.
+func manualLess(a, b *Note) bool {
+	if (a.Position == nil) != (b.Position == nil) {
+		return a.Position != nil
+	}
+
+	if a.GetPosition() != b.GetPosition() {
+		return a.GetPosition() < b.GetPosition()
+	}
+
+	if !a.GetCreatedTime().Equal(b.GetCreatedTime()) {
+		return a.GetCreatedTime().Before(b.GetCreatedTime())
+	}
+
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
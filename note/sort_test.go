@@ -54,3 +54,27 @@ func (s *SortTestSuite) TestSortByCreatedDateDescending() {
 	sort.Sort(SortByCreatedDateDescendingSorter(notes))
 	s.Equal(want, notes)
 }
+
+func (s *SortTestSuite) TestSortByManual() {
+	now := time.Now()
+	notes := []*Note{
+		{ID: uuid.New(), CreatedTime: ptrconv.TimePointer(now)},
+		{ID: uuid.New(), Position: ptrconv.Float64Pointer(2)},
+		{ID: uuid.New(), Position: ptrconv.Float64Pointer(3), IsPinned: ptrconv.BoolPointer(true)},
+		{ID: uuid.New(), Position: ptrconv.Float64Pointer(1.5)},
+		{ID: uuid.New(), CreatedTime: ptrconv.TimePointer(now.Add(-time.Hour))},
+		{ID: uuid.New(), Position: ptrconv.Float64Pointer(1), IsPinned: ptrconv.BoolPointer(true)},
+	}
+
+	s.Run("Ascending", func() {
+		got := append([]*Note(nil), notes...)
+		sort.Sort(SortByManualSorter(got))
+		s.Equal([]*Note{notes[5], notes[2], notes[3], notes[1], notes[4], notes[0]}, got)
+	})
+
+	s.Run("Descending", func() {
+		got := append([]*Note(nil), notes...)
+		sort.Sort(SortByManualDescendingSorter(got))
+		s.Equal([]*Note{notes[2], notes[5], notes[0], notes[4], notes[1], notes[3]}, got)
+	})
+}
@@ -3,6 +3,9 @@ package note
 import (
 	"context"
 	"github.com/google/uuid"
+	"noterfy/pkg/validation"
+	"strings"
+	"time"
 )
 
 // Store is an interface for the storing the data.
@@ -22,6 +25,15 @@ type Store interface {
 	// if encountered and it will be ErrNotFound or ErrCancelled.
 	Update(ctx context.Context, n *Note) (updated *Note, err error)
 
+	// Modify modifies the existing note with id in the store with fn
+	// atomically. The fn is called with a copy of the note and the note
+	// is replaced by the copy when fn doesn't return an error, so the
+	// fields cleared by fn are cleared in the store. It takes ctx context
+	// in order to let the caller stop the execution in any form. It will
+	// return the modified note or an error if encountered and it will
+	// be ErrNotFound, ErrCancelled or the error of fn.
+	Modify(ctx context.Context, id uuid.UUID, fn func(n *Note) error) (modified *Note, err error)
+
 	// Delete deletes an existing note with id from the store. It takes ctx
 	// context in order to let the caller stop the execution in any form.
 	// An error can also return if encountered and it can be ErrCancelled.
@@ -38,6 +50,28 @@ type Store interface {
 	// I returns the fetch result containing the current pagination settings, the
 	// note data and the number of pages of the current fetch pagination.
 	Fetch(ctx context.Context, p *Pagination) (Iterator, error)
+
+	// Version gets the version of the notes in the store. It takes ctx
+	// context in order to let the caller stop the execution in any form.
+	// It is cheap to get so the callers can tell whether the notes
+	// changed without fetching them.
+	Version(ctx context.Context) (Version, error)
+
+	// Count gets the number of notes in the store including the
+	// archived notes. It takes ctx context in order to let the caller
+	// stop the execution in any form. It is cheap to get so the
+	// callers don't need to fetch the notes to count them.
+	Count(ctx context.Context) (uint64, error)
+}
+
+// Version is the version of the notes in a store.
+type Version struct {
+	// Counter is incremented every time a note is inserted,
+	// updated or deleted.
+	Counter uint64
+	// Modified is the time of the last change of the notes. It is
+	// zero when it is unknown.
+	Modified time.Time
 }
 
 // SortBy describe the type of sorts supported by the pagination.
@@ -50,6 +84,44 @@ const (
 	SortByCreatedTime SortBy = "created_date"
 	// SortByID is a sort type that sort the note according to its ID.
 	SortByID SortBy = "id"
+	// SortByManual is a sort type that sort the note according to its
+	// position set by the user. The pinned notes are always placed first.
+	SortByManual SortBy = "manual"
+)
+
+// ArchivedFilter describe how the archived notes are treated
+// by the pagination.
+type ArchivedFilter string
+
+const (
+	// ArchivedExclude is a filter that leaves out the archived notes.
+	ArchivedExclude ArchivedFilter = "exclude"
+	// ArchivedInclude is a filter that keeps the archived notes
+	// together with the other notes.
+	ArchivedInclude ArchivedFilter = "include"
+	// ArchivedOnly is a filter that keeps only the archived notes.
+	ArchivedOnly ArchivedFilter = "only"
+)
+
+// GetArchivedFilter parses s and get the equivalent value of
+// ArchivedFilter type.
+func GetArchivedFilter(s string) ArchivedFilter {
+	switch ArchivedFilter(strings.ToLower(s)) {
+	case ArchivedInclude:
+		return ArchivedInclude
+	case ArchivedOnly:
+		return ArchivedOnly
+	default:
+		return ArchivedExclude
+	}
+}
+
+// The bounds of the pagination.
+const (
+	// MaxPageSize is the maximum size of a page.
+	MaxPageSize = 100
+	// MaxPage is the maximum page number.
+	MaxPage = 1000000
 )
 
 // Pagination contains all the necessary settings for the pagination.
@@ -66,6 +138,10 @@ type Pagination struct {
 	// Ascending indicates that the pagination is ascend.
 	// Default is true.
 	Ascending bool `json:"ascending,omitempty"`
+	// Archived is how the archived notes are treated during the
+	// pagination. If Archived is empty string the default will
+	// be ArchivedExclude.
+	Archived ArchivedFilter `json:"archived,omitempty"`
 }
 
 // Check checks the value of each pagination field and set default
@@ -82,6 +158,22 @@ func (p *Pagination) Check() {
 	if p.SortBy == "" {
 		p.SortBy = SortByID
 	}
+
+	if p.Archived == "" {
+		p.Archived = ArchivedExclude
+	}
+}
+
+// Validate checks that the size and the page of the pagination
+// are within the bounds. The zero values are valid and are
+// replaced with the defaults by Check.
+func (p *Pagination) Validate() error {
+	var v validation.Validator
+	v.Check(p.Size <= MaxPageSize, "size", validation.CodeOutOfRange,
+		"must be between 1 and %d, got %d", MaxPageSize, p.Size)
+	v.Check(p.Page <= MaxPage, "page", validation.CodeOutOfRange,
+		"must be between 1 and %d, got %d", MaxPage, p.Page)
+	return v.Err()
 }
 
 // FetchResult contains the result of the fetch pagination.
@@ -0,0 +1,41 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"noterfy/pkg/health"
+	"os"
+	"path/filepath"
+)
+
+// healthReadSize is the number of bytes read from the file by
+// the health checker.
+const healthReadSize = 4 << 10
+
+// NewChecker returns a health checker of the file store using the
+// file f. It fails when the path of f can't be stat, e.g. the file
+// was removed, when f can't be read or when the disk where f is
+// located has less than minFree bytes free.
+func NewChecker(f *os.File, minFree uint64) health.Checker {
+	disk := health.DiskSpace(filepath.Dir(f.Name()), minFree)
+
+	return health.CheckerFunc(func(ctx context.Context) error {
+		info, err := os.Stat(f.Name())
+		if err != nil {
+			return fmt.Errorf("file: unable to stat '%s': %w", f.Name(), err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("file: '%s' is not a regular file", f.Name())
+		}
+
+		// ReadAt doesn't move the offset of the file so it is safe
+		// to use while the store is writing.
+		buf := make([]byte, healthReadSize)
+		if _, err := f.ReadAt(buf, 0); err != nil && err != io.EOF {
+			return fmt.Errorf("file: unable to read '%s': %w", f.Name(), err)
+		}
+
+		return disk.Check(ctx)
+	})
+}
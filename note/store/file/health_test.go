@@ -0,0 +1,22 @@
+package file
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewChecker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.pb")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	checker := NewChecker(f, 0)
+	require.NoError(t, checker.Check(context.Background()))
+
+	require.NoError(t, os.Remove(path))
+	require.Error(t, checker.Check(context.Background()))
+}
@@ -0,0 +1,41 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+)
+
+// ErrLocked is an error when the file is already locked by
+// another process, e.g. a running server or another CLI.
+var ErrLocked = errors.New("file: file is locked by another process")
+
+// ErrLockUnsupported is an error when the file can't be locked
+// on the platform.
+var ErrLockUnsupported = errors.New("file: file locking is not supported on this platform")
+
+// Open opens the file in path for reading and writing, creating it
+// if it doesn't exist, and takes an exclusive advisory lock on it.
+// It returns ErrLocked when another process holds the lock. The lock
+// is released when the file is closed. On the platforms without the
+// locking the file is opened unlocked with a warning.
+func Open(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(f)
+	if errors.Is(err, ErrLockUnsupported) {
+		// Nothing stops another process from writing the file
+		// so the user is warned instead of failing.
+		logrus.Warnf("file: '%s' is opened without a lock: %s", path, err)
+		return f, nil
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("file: unable to lock '%s': %w", path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package file
+
+import "os"
+
+// lockFile returns ErrLockUnsupported on the platforms without flock.
+func lockFile(_ *os.File) error {
+	return ErrLockUnsupported
+}
@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package file
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.pb")
+
+	first, err := Open(path)
+	require.NoError(t, err)
+
+	_, err = Open(path)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrLocked), err)
+
+	// The lock is released when the file is closed.
+	require.NoError(t, first.Close())
+
+	second, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, second.Close())
+}
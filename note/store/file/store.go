@@ -3,13 +3,14 @@ package file
 import (
 	"context"
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"io"
 	"noterfy/note"
 	"noterfy/note/noteutil"
 	"noterfy/note/proto/protoutil"
+	"noterfy/pkg/logger"
 	"sort"
 	"sync"
+	"time"
 )
 
 var _ note.Store = (*Store)(nil)
@@ -36,6 +37,9 @@ type Store struct {
 
 	mu    sync.RWMutex
 	notes map[uuid.UUID]*note.Note
+	// version is the version of the notes. Its modification time
+	// starts at the modification time of the file.
+	version note.Version
 
 	// once use to initialize the store only
 	// once.
@@ -48,7 +52,7 @@ type Store struct {
 // note data and the number of pages of the current fetch pagination.
 func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, error) {
 
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return nil, err
 	}
 
@@ -76,17 +80,18 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 		s.mu.RLock()
 		defer s.mu.RUnlock()
 
-		if int(start) > len(s.notes) {
-			iterChan <- nil
-			return
-		}
-
 		// Get all the notes in array.
 		var notes []*note.Note
 		for _, n := range s.notes {
 			notes = append(notes, n)
 		}
 
+		notes = noteutil.FilterArchived(notes, p.Archived)
+		// The pages past the last note are empty.
+		if noteSize := uint64(len(notes)); start > noteSize {
+			start = noteSize
+		}
+
 		// Sort by ID
 		noteutil.Sort(notes, p.SortBy, p.Ascending)
 
@@ -111,7 +116,7 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 	}
 }
 
-func (s *Store) lazyInit() (err error) {
+func (s *Store) lazyInit(ctx context.Context) (err error) {
 	s.once.Do(func() {
 		_, err = s.file.Seek(0, io.SeekStart)
 		if err != nil {
@@ -123,7 +128,8 @@ func (s *Store) lazyInit() (err error) {
 			err = serr
 			return
 		}
-		logrus.Debug("size:", info.Size())
+		log := logger.FromContext(ctx)
+		log.WithField("size", info.Size()).Debug("file: loading the notes")
 
 		// Read all first the messages from the
 		// existing file.
@@ -136,11 +142,12 @@ func (s *Store) lazyInit() (err error) {
 		notesWithKey := make(map[uuid.UUID]*note.Note)
 
 		for _, n := range notes {
-			logrus.Debug("note:", n.ID)
+			log.WithField("note_id", n.ID).Debug("file: loaded the note")
 			notesWithKey[n.ID] = n
 		}
 
 		s.notes = notesWithKey
+		s.version.Modified = info.ModTime().UTC()
 
 	})
 	return
@@ -148,7 +155,7 @@ func (s *Store) lazyInit() (err error) {
 
 // Insert inserts an n note to the store.
 func (s *Store) Insert(ctx context.Context, n *note.Note) error {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return err
 	}
 
@@ -185,6 +192,7 @@ func (s *Store) Insert(ctx context.Context, n *note.Note) error {
 		}
 
 		s.notes[n.ID] = noteutil.Copy(n)
+		s.changed()
 
 		err := s.writeAllNotesToFile()
 		if err != nil {
@@ -205,7 +213,7 @@ func (s *Store) Insert(ctx context.Context, n *note.Note) error {
 
 // Update updates an existing n note to the store.
 func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return nil, err
 	}
 
@@ -246,6 +254,7 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, e
 		existingNote.UpdatedTime = n.UpdatedTime
 
 		s.notes[n.ID] = existingNote
+		s.changed()
 
 		err = s.writeAllNotesToFile()
 		if err != nil {
@@ -265,9 +274,71 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, e
 
 }
 
+// Modify modifies the existing note with id in the store with fn
+// atomically. The note is replaced by the copy modified by fn when
+// fn doesn't return an error.
+func (s *Store) Modify(ctx context.Context, id uuid.UUID, fn func(n *note.Note) error) (modified *note.Note, err error) {
+	if err := s.lazyInit(ctx); err != nil {
+		return nil, err
+	}
+
+	var (
+		errChan  = make(chan error, 1)
+		noteChan = make(chan *note.Note, 1)
+	)
+
+	go func() {
+		defer func() {
+			close(errChan)
+			close(noteChan)
+		}()
+
+		select {
+		case <-ctx.Done():
+			errChan <- ctx.Err()
+			return
+		default:
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		existingNote, found := s.notes[id]
+		if !found {
+			errChan <- note.ErrNotFound
+			return
+		}
+
+		n := noteutil.Copy(existingNote)
+		if err := fn(n); err != nil {
+			errChan <- err
+			return
+		}
+		// The identity of the note can't be modified.
+		n.ID = id
+
+		s.notes[id] = noteutil.Copy(n)
+		s.changed()
+
+		if err := s.writeAllNotesToFile(); err != nil {
+			errChan <- err
+			return
+		}
+
+		noteChan <- n
+	}()
+
+	select {
+	case err = <-errChan:
+		return nil, err
+	case n := <-noteChan:
+		return n, nil
+	}
+}
+
 // Delete deletes an existing note with id from the store.
 func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return err
 	}
 
@@ -292,7 +363,10 @@ func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
-		delete(s.notes, id)
+		if _, found := s.notes[id]; found {
+			delete(s.notes, id)
+			s.changed()
+		}
 
 		err := s.writeAllNotesToFile()
 		if err != nil {
@@ -313,7 +387,7 @@ func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
 
 // Get gets the existing note with id from the store.
 func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return nil, err
 	}
 
@@ -353,6 +427,41 @@ func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 	}
 }
 
+// Version gets the version of the notes in the store.
+func (s *Store) Version(ctx context.Context) (note.Version, error) {
+	if err := s.lazyInit(ctx); err != nil {
+		return note.Version{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return note.Version{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version, nil
+}
+
+// Count gets the number of notes in the store.
+func (s *Store) Count(ctx context.Context) (uint64, error) {
+	if err := s.lazyInit(ctx); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return uint64(len(s.notes)), nil
+}
+
+// changed updates the version of the notes. The caller must
+// hold the write lock.
+func (s *Store) changed() {
+	s.version.Counter++
+	s.version.Modified = time.Now().UTC()
+}
+
 func convertMapValueToSlice(notes map[uuid.UUID]*note.Note) []*note.Note {
 
 	var noteSlice []*note.Note
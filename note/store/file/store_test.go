@@ -31,6 +31,8 @@ func TestMain(m *testing.M) {
 		newNote.SetTitle("Test note")
 		newNote.SetContent("Test note content")
 		newNote.SetIsFavorite(false)
+		newNote.SetIsPinned(false)
+		newNote.SetIsArchived(false)
 		newNote.SetCreatedTime(*timestamp.GenerateTimestamp())
 		return newNote
 	}
@@ -3,10 +3,11 @@ package memory
 import (
 	"context"
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"noterfy/note"
 	"noterfy/note/noteutil"
+	"noterfy/pkg/logger"
 	"sync"
+	"time"
 )
 
 var _ note.Store = (*Store)(nil)
@@ -14,8 +15,9 @@ var _ note.Store = (*Store)(nil)
 // Store is the in-memory implementation for note.Store.
 // This is safe for concurrent use.
 type Store struct {
-	mu   sync.RWMutex
-	data map[uuid.UUID]*note.Note
+	mu      sync.RWMutex
+	data    map[uuid.UUID]*note.Note
+	version note.Version
 }
 
 // Fetch fetches the notes in the store using the pagination setting
@@ -47,10 +49,6 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 
 		s.mu.RLock()
 		defer s.mu.RUnlock()
-		if int(start) > len(s.data) {
-			iterChan <- nil
-			return
-		}
 
 		// Get the all the notes in array.
 		var notes []*note.Note
@@ -58,6 +56,12 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 			notes = append(notes, n)
 		}
 
+		notes = noteutil.FilterArchived(notes, p.Archived)
+		// The pages past the last note are empty.
+		if noteSize := uint64(len(notes)); start > noteSize {
+			start = noteSize
+		}
+
 		noteutil.Sort(notes, p.SortBy, p.Ascending)
 		if noteSize := uint64(len(notes)); stop > noteSize {
 			stop = noteSize
@@ -127,6 +131,7 @@ func (s *Store) Insert(ctx context.Context, n *note.Note) error {
 
 		cpyNote := noteutil.Copy(n)
 		s.data[n.ID] = cpyNote
+		s.changed()
 		doneChan <- struct{}{}
 	}()
 
@@ -182,8 +187,9 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
 
 		// Workaround 💪😅
 		exist.UpdatedTime = n.UpdatedTime
+		s.changed()
 
-		logrus.Debug(exist.UpdatedTime)
+		logger.FromContext(ctx).WithField("note_id", n.ID).Debug("memory: updated the note")
 		noteChan <- noteutil.Copy(exist)
 	}()
 
@@ -195,6 +201,61 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
 	}
 }
 
+// Modify modifies the existing note with id in the store with fn
+// atomically. The note is replaced by the copy modified by fn when
+// fn doesn't return an error. An error can also return if encountered
+// and it will be ErrNotFound, ErrCancelled or the error of fn.
+func (s *Store) Modify(ctx context.Context, id uuid.UUID, fn func(n *note.Note) error) (*note.Note, error) {
+
+	var (
+		errChan  = make(chan error, 1)
+		noteChan = make(chan *note.Note, 1)
+	)
+
+	go func() {
+		defer func() {
+			close(errChan)
+			close(noteChan)
+		}()
+
+		select {
+		case <-ctx.Done():
+			errChan <- ctx.Err()
+			return
+		default:
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		exist, found := s.data[id]
+		if !found {
+			errChan <- note.ErrNotFound
+			return
+		}
+
+		modified := noteutil.Copy(exist)
+		if err := fn(modified); err != nil {
+			errChan <- err
+			return
+		}
+		// The identity of the note can't be modified.
+		modified.ID = id
+
+		s.data[id] = noteutil.Copy(modified)
+		s.changed()
+
+		logger.FromContext(ctx).WithField("note_id", id).Debug("memory: modified the note")
+		noteChan <- modified
+	}()
+
+	select {
+	case err := <-errChan:
+		return nil, err
+	case modified := <-noteChan:
+		return modified, nil
+	}
+}
+
 // Delete deletes an existing note with id from the store. It takes ctx
 // context in order to let the caller stop the execution in any form.
 // An error can also return if encountered and it can be ErrCancelled.
@@ -220,7 +281,10 @@ func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		delete(s.data, id)
+		if _, found := s.data[id]; found {
+			delete(s.data, id)
+			s.changed()
+		}
 
 		doneChan <- struct{}{}
 	}()
@@ -275,3 +339,34 @@ func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 		return _note, nil
 	}
 }
+
+// Version gets the version of the notes in the store. It takes ctx
+// context in order to let the caller stop the execution in any form.
+func (s *Store) Version(ctx context.Context) (note.Version, error) {
+	if err := ctx.Err(); err != nil {
+		return note.Version{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version, nil
+}
+
+// Count gets the number of notes in the store. It takes ctx
+// context in order to let the caller stop the execution in any form.
+func (s *Store) Count(ctx context.Context) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return uint64(len(s.data)), nil
+}
+
+// changed updates the version of the notes. The caller must
+// hold the write lock.
+func (s *Store) changed() {
+	s.version.Counter++
+	s.version.Modified = time.Now().UTC()
+}
@@ -2,6 +2,7 @@ package storetest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/suite"
@@ -151,6 +152,62 @@ func (s *TestSuite) TestUpdate() {
 	})
 }
 
+// TestModify tests the store modify method.
+func (s *TestSuite) TestModify() {
+
+	s.Run("Modifying an existing note replaces it", func() {
+		want := s.setupFunc()
+
+		modified, err := s.store.Modify(dummyCtx, want.ID, func(n *note.Note) error {
+			n.Content = nil
+			n.Title = ptrconv.StringPointer("Modified")
+			return nil
+		})
+		s.Require().NoError(err)
+
+		want.Content = nil
+		want.Title = ptrconv.StringPointer("Modified")
+		s.Equal(want, modified)
+
+		got, err := s.store.Get(dummyCtx, want.ID)
+		s.NoError(err)
+		s.Equal(want, got)
+	})
+
+	s.Run("An error of the function leaves the note unchanged", func() {
+		want := s.setupFunc()
+		fnErr := errors.New("modify failed")
+
+		modified, err := s.store.Modify(dummyCtx, want.ID, func(n *note.Note) error {
+			n.Title = ptrconv.StringPointer("Modified")
+			return fnErr
+		})
+		s.Equal(fnErr, err)
+		s.Nil(modified)
+
+		got, err := s.store.Get(dummyCtx, want.ID)
+		s.NoError(err)
+		s.Equal(want, got)
+	})
+
+	s.Run("Modifying a non-existing note should return an error", func() {
+		modified, err := s.store.Modify(dummyCtx, uuid.New(), func(n *note.Note) error {
+			s.Fail("the function must not be called")
+			return nil
+		})
+		s.Equal(note.ErrNotFound, err)
+		s.Nil(modified)
+	})
+
+	s.Run("Calling context cancel should return an notes.ErrCancelled", func() {
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+
+		_, err := s.store.Modify(ctx, s.setupFunc().ID, func(n *note.Note) error { return nil })
+		s.Equal(note.ErrCancelled, err)
+	})
+}
+
 // TestDelete tests the store delete method.
 func (s *TestSuite) TestDelete() {
 
@@ -179,6 +236,77 @@ func (s *TestSuite) TestDelete() {
 	})
 }
 
+// TestVersion tests the store version method.
+func (s *TestSuite) TestVersion() {
+	require := s.Require()
+
+	before, err := s.store.Version(dummyCtx)
+	require.NoError(err)
+
+	n := s.setupFunc()
+	inserted, err := s.store.Version(dummyCtx)
+	require.NoError(err)
+	s.Equal(before.Counter+1, inserted.Counter)
+	s.False(inserted.Modified.Before(before.Modified))
+
+	_, err = s.store.Update(dummyCtx, noteutil.Copy(n).SetTitle("Updated"))
+	require.NoError(err)
+	updated, err := s.store.Version(dummyCtx)
+	require.NoError(err)
+	s.Equal(inserted.Counter+1, updated.Counter)
+
+	require.NoError(s.store.Delete(dummyCtx, n.ID))
+	deleted, err := s.store.Version(dummyCtx)
+	require.NoError(err)
+	s.Equal(updated.Counter+1, deleted.Counter)
+
+	s.Run("Deleting a missing note doesn't change the version", func() {
+		require.NoError(s.store.Delete(dummyCtx, n.ID))
+		got, err := s.store.Version(dummyCtx)
+		s.NoError(err)
+		s.Equal(deleted, got)
+	})
+
+	s.Run("Calling context cancel should return an notes.ErrCancelled", func() {
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+
+		_, err := s.store.Version(ctx)
+		s.Equal(note.ErrCancelled, err)
+	})
+}
+
+// TestCount tests the store count method.
+func (s *TestSuite) TestCount() {
+	require := s.Require()
+
+	before, err := s.store.Count(dummyCtx)
+	require.NoError(err)
+
+	n := s.setupFunc()
+	archived := s.setupFunc()
+	_, err = s.store.Update(dummyCtx, noteutil.Copy(archived).SetIsArchived(true))
+	require.NoError(err)
+
+	// The archived notes are counted.
+	got, err := s.store.Count(dummyCtx)
+	require.NoError(err)
+	s.Equal(before+2, got)
+
+	require.NoError(s.store.Delete(dummyCtx, n.ID))
+	got, err = s.store.Count(dummyCtx)
+	require.NoError(err)
+	s.Equal(before+1, got)
+
+	s.Run("Calling context cancel should return an notes.ErrCancelled", func() {
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+
+		_, err := s.store.Count(ctx)
+		s.Equal(note.ErrCancelled, err)
+	})
+}
+
 // TestFetch test the fetch store method.
 func (s *TestSuite) TestFetch() {
 
@@ -244,6 +372,16 @@ func (s *TestSuite) TestFetch() {
 		}
 	})
 
+	s.Run("Fetching a page past the last note should return no notes", func() {
+		iter := fetch(&note.Pagination{
+			Size:      20,
+			Page:      100,
+			SortBy:    note.SortByTitle,
+			Ascending: true,
+		})
+		s.Empty(drainIterator(iter))
+	})
+
 	s.Run("Calling context cancel should return an notes.ErrCancelled", func() {
 		ctx, cancel := context.WithCancel(dummyCtx)
 		cancel()
@@ -260,6 +398,56 @@ func (s *TestSuite) TestFetch() {
 	})
 }
 
+// TestFetchArchived test the archived filter of the store fetch method.
+func (s *TestSuite) TestFetchArchived() {
+	var active, archived []*note.Note
+	for i := 0; i < 4; i++ {
+		n := noteFactory(i)
+		if i%2 == 1 {
+			n.SetIsArchived(true).SetArchivedTime(time.Now().UTC())
+			archived = append(archived, n)
+		} else {
+			active = append(active, n)
+		}
+		s.Require().NoError(s.store.Insert(dummyCtx, n))
+	}
+
+	table := []struct {
+		name   string
+		filter note.ArchivedFilter
+		want   []*note.Note
+	}{
+		{name: "Excluding the archived notes by default", filter: "", want: active},
+		{name: "Excluding the archived notes", filter: note.ArchivedExclude, want: active},
+		{name: "Including the archived notes", filter: note.ArchivedInclude, want: append(append([]*note.Note{}, active...), archived...)},
+		{name: "Only the archived notes", filter: note.ArchivedOnly, want: archived},
+	}
+
+	for _, row := range table {
+		s.Run(row.name, func() {
+			iter, err := s.store.Fetch(dummyCtx, &note.Pagination{
+				Size:      10,
+				Page:      1,
+				SortBy:    note.SortByTitle,
+				Ascending: true,
+				Archived:  row.filter,
+			})
+			s.Require().NoError(err)
+			s.Require().NotNil(iter)
+
+			var got []*note.Note
+			for iter.Next() {
+				got = append(got, iter.Note())
+			}
+
+			want := append([]*note.Note{}, row.want...)
+			sort.Sort(note.SortByTitleSorter(want))
+			s.Equal(want, got)
+			s.Equal(uint64(len(want)), iter.TotalCount())
+		})
+	}
+}
+
 func (s *TestSuite) setupFunc() *note.Note {
 	n := noteutil.Copy(dummyNote)
 	n.ID = uuid.New()
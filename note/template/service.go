@@ -0,0 +1,254 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/spf13/afero"
+	"noterfy/note"
+	"noterfy/pkg/clock"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	fileName    = "templates.json"
+	tmpFileName = "templates.json.tmp"
+)
+
+// New takes a fs filesystem where the templates are stored and the clock
+// use for the timestamps and returns a template service instance.
+func New(fs afero.Fs, c clock.Clock) *Service {
+	return &Service{
+		fs:        fs,
+		clock:     c,
+		templates: make(map[string]*Template),
+	}
+}
+
+// Service manages the note templates. The templates are kept in
+// memory and written to a single JSON file on every change. This
+// is safe for concurrent use.
+type Service struct {
+	fs    afero.Fs
+	clock clock.Clock
+
+	mu        sync.RWMutex
+	templates map[string]*Template
+
+	// once use to load the templates only once.
+	once sync.Once
+}
+
+func (s *Service) lazyInit() (err error) {
+	s.once.Do(func() {
+		file, oerr := s.fs.Open(fileName)
+		if os.IsNotExist(oerr) {
+			return
+		}
+		if oerr != nil {
+			err = oerr
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		var templates []*Template
+		if err = json.NewDecoder(file).Decode(&templates); err != nil {
+			return
+		}
+
+		for _, t := range templates {
+			s.templates[t.Name] = t
+		}
+	})
+	return
+}
+
+// Create validates and stores the new template t. It returns ErrExists
+// when a template with the same name already exists.
+func (s *Service) Create(ctx context.Context, t *Template) (*Template, error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cpy := *t
+	if err := cpy.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.templates[cpy.Name]; found {
+		return nil, ErrExists
+	}
+
+	now := s.clock.Now()
+	cpy.CreatedTime = now
+	cpy.UpdatedTime = now
+	s.templates[cpy.Name] = &cpy
+
+	if err := s.write(); err != nil {
+		delete(s.templates, cpy.Name)
+		return nil, err
+	}
+
+	return copyTemplate(&cpy), nil
+}
+
+// Update validates and replaces the existing template with the
+// same name as t.
+func (s *Service) Update(ctx context.Context, t *Template) (*Template, error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cpy := *t
+	if err := cpy.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, found := s.templates[cpy.Name]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	cpy.CreatedTime = existing.CreatedTime
+	cpy.UpdatedTime = s.clock.Now()
+	s.templates[cpy.Name] = &cpy
+
+	if err := s.write(); err != nil {
+		s.templates[cpy.Name] = existing
+		return nil, err
+	}
+
+	return copyTemplate(&cpy), nil
+}
+
+// Delete deletes the template with name.
+func (s *Service) Delete(ctx context.Context, name string) error {
+	if err := s.lazyInit(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, found := s.templates[name]
+	if !found {
+		return ErrNotFound
+	}
+
+	delete(s.templates, name)
+	if err := s.write(); err != nil {
+		s.templates[name] = existing
+		return err
+	}
+	return nil
+}
+
+// Get gets the template with name.
+func (s *Service) Get(ctx context.Context, name string) (*Template, error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, found := s.templates[name]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return copyTemplate(t), nil
+}
+
+// List returns all the templates sorted by name.
+func (s *Service) List(ctx context.Context) ([]*Template, error) {
+	if err := s.lazyInit(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	templates := make([]*Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		templates = append(templates, copyTemplate(t))
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// Render renders the template with name into a new note. The data.Now
+// is set to the current time when it is zero.
+func (s *Service) Render(ctx context.Context, name string, data Data) (*note.Note, error) {
+	t, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Now.IsZero() {
+		data.Now = s.clock.Now()
+	}
+	return t.Render(data)
+}
+
+// write writes the templates to a temporary file and then renames it
+// so the file is never left half-written. The caller must hold the lock.
+func (s *Service) write() error {
+	templates := make([]*Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	file, err := s.fs.Create(tmpFileName)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(file).Encode(templates)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.fs.Rename(tmpFileName, fileName)
+}
+
+func copyTemplate(t *Template) *Template {
+	cpy := *t
+	cpy.Prompts = append([]string(nil), t.Prompts...)
+	return &cpy
+}
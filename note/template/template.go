@@ -0,0 +1,332 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"noterfy/note"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+)
+
+const (
+	// maxSourceSize is the maximum size of the title and the
+	// content of a template in bytes.
+	maxSourceSize = 64 << 10
+	// maxOutputSize is the maximum size of a rendered template
+	// in bytes.
+	maxOutputSize = 1 << 20
+)
+
+var (
+	// ErrNotFound is an error when the template is not found.
+	ErrNotFound = errors.New("template: template not found")
+	// ErrExists is an error when the template already exists.
+	ErrExists = errors.New("template: template already exists")
+	// ErrInvalid is an error when the template is malformed or
+	// uses an action that is not allowed.
+	ErrInvalid = errors.New("template: invalid template")
+	// ErrMissingVariable is an error when a prompt of the template
+	// has no value and no default value.
+	ErrMissingVariable = errors.New("template: missing template variable")
+)
+
+// namePattern matches the valid template names such as "incident"
+// or "daily-standup".
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Template is a named skeleton of a note. The title and the content
+// are text/template sources restricted to the functions below:
+//
+//	{{date}}                    the current date such as 2021-01-31
+//	{{date "Jan 2, 2006"}}      the current date with a layout
+//	{{time}}                    the current time such as 15:04
+//	{{user}}                    the user creating the note
+//	{{prompt "severity"}}       the value of the variable "severity"
+//	{{prompt "severity" "low"}} the same with a default value
+//	{{upper}}, {{lower}}, {{trim}} and the comparison builtins
+//
+// Loops, nested templates, variables and the data fields are
+// not allowed.
+type Template struct {
+	// Name is the unique name of the template.
+	Name string `json:"name" example:"incident"`
+	// Description is the description of the template.
+	Description string `json:"description,omitempty" example:"Incident report"`
+	// Title is the template of the note title.
+	Title string `json:"title,omitempty" example:"Incident {{date}}"`
+	// Content is the template of the note content.
+	Content string `json:"content,omitempty" example:"Reported by {{user}}. Severity: {{prompt \"severity\"}}"`
+	// Prompts are the variables asked by the template. They are
+	// collected from the template when it is saved.
+	Prompts []string `json:"prompts,omitempty" example:"severity"`
+	// CreatedTime is the timestamp when the template was created.
+	CreatedTime time.Time `json:"created_time" example:"2016-02-24 11:12:13"`
+	// UpdatedTime is the timestamp when the template last updated.
+	UpdatedTime time.Time `json:"updated_time" example:"2016-02-24 11:12:13"`
+}
+
+// Data contains the values use to render a template.
+type Data struct {
+	// Now is the time use for the date and time functions.
+	Now time.Time
+	// User is the user creating the note.
+	User string
+	// Variables are the values of the prompts.
+	Variables map[string]string
+}
+
+// Validate checks the name and the sources of t and collects its prompts.
+func (t *Template) Validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("template: name must match %s: %w", namePattern, ErrInvalid)
+	}
+
+	var prompts []string
+	seen := make(map[string]bool)
+	for _, src := range []string{t.Title, t.Content} {
+		tree, err := parseSource(src)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range collectPrompts(tree.Root) {
+			if !seen[p] {
+				seen[p] = true
+				prompts = append(prompts, p)
+			}
+		}
+	}
+
+	t.Prompts = prompts
+	return nil
+}
+
+// Render renders the title and the content of t into a new note.
+func (t *Template) Render(data Data) (*note.Note, error) {
+	title, err := execute(t.Title, data)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := execute(t.Content, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(note.Note).SetTitle(title).SetContent(content), nil
+}
+
+// builtins are the text/template builtin functions that are allowed.
+var builtins = map[string]bool{
+	"and": true, "or": true, "not": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// funcs returns the function map of the templates. The same names
+// are also use to validate the templates so the values of the
+// parse-only map are never called.
+func funcs(data Data) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"date": func(layout ...string) string {
+			if len(layout) > 0 {
+				return data.Now.Format(layout[0])
+			}
+			return data.Now.Format("2006-01-02")
+		},
+		"time": func() string {
+			return data.Now.Format("15:04")
+		},
+		"user": func() string {
+			return data.User
+		},
+		"prompt": func(name string, def ...string) (string, error) {
+			if v, found := data.Variables[name]; found {
+				return v, nil
+			}
+			if len(def) > 0 {
+				return def[0], nil
+			}
+			return "", missingVariableError(name)
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+	}
+}
+
+func parseSource(src string) (*parse.Tree, error) {
+	if len(src) > maxSourceSize {
+		return nil, fmt.Errorf("template: exceeds %d bytes: %w", maxSourceSize, ErrInvalid)
+	}
+
+	tmpl, err := texttemplate.New("").Funcs(funcs(Data{})).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("template: %v: %w", err, ErrInvalid)
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("template: nested templates are not allowed: %w", ErrInvalid)
+	}
+
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return &parse.Tree{Root: &parse.ListNode{}}, nil
+	}
+
+	if err := checkNode(tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+	return tmpl.Tree, nil
+}
+
+// checkNode walks the parse tree and rejects the nodes that can
+// loop, read data or call functions outside the function map.
+func checkNode(node parse.Node) error {
+	switch n := node.(type) {
+	case nil, *parse.TextNode, *parse.StringNode, *parse.NumberNode, *parse.BoolNode, *parse.NilNode:
+		return nil
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkNode(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.ActionNode:
+		return checkNode(n.Pipe)
+	case *parse.IfNode:
+		if err := checkNode(n.Pipe); err != nil {
+			return err
+		}
+		if err := checkNode(n.List); err != nil {
+			return err
+		}
+		return checkNode(n.ElseList)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		if len(n.Decl) > 0 {
+			return fmt.Errorf("template: variables are not allowed: %w", ErrInvalid)
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkNode(cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkNode(arg); err != nil {
+				return err
+			}
+		}
+		if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "prompt" {
+			if len(n.Args) < 2 {
+				return fmt.Errorf("template: prompt requires a name: %w", ErrInvalid)
+			}
+			if _, ok := n.Args[1].(*parse.StringNode); !ok {
+				return fmt.Errorf("template: prompt name must be a string: %w", ErrInvalid)
+			}
+		}
+		return nil
+	case *parse.IdentifierNode:
+		if _, found := funcs(Data{})[n.Ident]; found || builtins[n.Ident] {
+			return nil
+		}
+		return fmt.Errorf("template: function %q is not allowed: %w", n.Ident, ErrInvalid)
+	default:
+		return fmt.Errorf("template: %q is not allowed: %w", node.String(), ErrInvalid)
+	}
+}
+
+// collectPrompts returns the names of the prompts in the order
+// they appear in the parse tree.
+func collectPrompts(node parse.Node) (prompts []string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			prompts = append(prompts, collectPrompts(child)...)
+		}
+	case *parse.ActionNode:
+		prompts = collectPrompts(n.Pipe)
+	case *parse.IfNode:
+		prompts = append(prompts, collectPrompts(n.Pipe)...)
+		prompts = append(prompts, collectPrompts(n.List)...)
+		prompts = append(prompts, collectPrompts(n.ElseList)...)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			prompts = append(prompts, collectPrompts(cmd)...)
+		}
+	case *parse.CommandNode:
+		if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "prompt" {
+			prompts = append(prompts, n.Args[1].(*parse.StringNode).Text)
+		}
+		for _, arg := range n.Args[1:] {
+			prompts = append(prompts, collectPrompts(arg)...)
+		}
+	}
+	return prompts
+}
+
+func execute(src string, data Data) (string, error) {
+	if _, err := parseSource(src); err != nil {
+		return "", err
+	}
+
+	tmpl, err := texttemplate.New("").Funcs(funcs(data)).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("template: %v: %w", err, ErrInvalid)
+	}
+
+	var buff bytes.Buffer
+	if err := tmpl.Execute(&limitedWriter{w: &buff, n: maxOutputSize}, nil); err != nil {
+		var missing missingVariableError
+		if errors.As(err, &missing) {
+			return "", missing
+		}
+		return "", fmt.Errorf("template: %v: %w", err, ErrInvalid)
+	}
+	return buff.String(), nil
+}
+
+// missingVariableError is returned by the prompt function when the
+// variable has no value. It unwraps to ErrMissingVariable.
+type missingVariableError string
+
+func (e missingVariableError) Error() string {
+	return fmt.Sprintf("template: prompt %q has no value", string(e))
+}
+
+func (e missingVariableError) Unwrap() error {
+	return ErrMissingVariable
+}
+
+var errOutputTooLarge = fmt.Errorf("template: output exceeds %d bytes: %w", maxOutputSize, ErrInvalid)
+
+// limitedWriter writes to w but fails once more than n bytes
+// were written.
+type limitedWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		return 0, errOutputTooLarge
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
+}
@@ -0,0 +1,156 @@
+package template
+
+import (
+	"context"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"noterfy/pkg/clock"
+	"strings"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+var epoch = time.Date(2021, 1, 31, 9, 30, 0, 0, time.UTC)
+
+func TestTemplate(t *testing.T) {
+	suite.Run(t, new(TemplateTestSuite))
+}
+
+type TemplateTestSuite struct {
+	suite.Suite
+}
+
+func (s *TemplateTestSuite) TestValidate() {
+	table := []struct {
+		name    string
+		tmpl    Template
+		wantErr bool
+		prompts []string
+	}{
+		{name: "Plain template", tmpl: Template{Name: "plain", Content: "Hello"}},
+		{
+			name:    "Template with prompts",
+			tmpl:    Template{Name: "incident", Title: "{{prompt \"service\"}} down", Content: "{{prompt \"severity\" \"low\"}} {{prompt \"service\" | upper}}"},
+			prompts: []string{"service", "severity"},
+		},
+		{name: "Prompt inside a condition", tmpl: Template{Name: "cond", Content: "{{if eq (prompt \"a\") \"x\"}}{{prompt \"b\"}}{{end}}"}, prompts: []string{"a", "b"}},
+		{name: "Invalid name", tmpl: Template{Name: "Bad Name"}, wantErr: true},
+		{name: "Malformed template", tmpl: Template{Name: "bad", Content: "{{date"}, wantErr: true},
+		{name: "Unknown function", tmpl: Template{Name: "bad", Content: "{{env \"HOME\"}}"}, wantErr: true},
+		{name: "Builtin function not allowed", tmpl: Template{Name: "bad", Content: "{{printf \"%s\" \"x\"}}"}, wantErr: true},
+		{name: "Loop", tmpl: Template{Name: "bad", Content: "{{range 1000000}}x{{end}}"}, wantErr: true},
+		{name: "Nested template", tmpl: Template{Name: "bad", Content: "{{define \"x\"}}x{{end}}"}, wantErr: true},
+		{name: "Variable", tmpl: Template{Name: "bad", Content: "{{$x := date}}{{$x}}"}, wantErr: true},
+		{name: "Data field", tmpl: Template{Name: "bad", Content: "{{.Secret}}"}, wantErr: true},
+		{name: "Prompt without a constant name", tmpl: Template{Name: "bad", Content: "{{prompt date}}"}, wantErr: true},
+		{name: "Too large", tmpl: Template{Name: "bad", Content: strings.Repeat("x", maxSourceSize+1)}, wantErr: true},
+	}
+
+	for _, row := range table {
+		s.Run(row.name, func() {
+			err := row.tmpl.Validate()
+			if row.wantErr {
+				s.ErrorIs(err, ErrInvalid)
+				return
+			}
+			s.Require().NoError(err)
+			s.Equal(row.prompts, row.tmpl.Prompts)
+		})
+	}
+}
+
+func (s *TemplateTestSuite) TestRender() {
+	tmpl := Template{
+		Name:    "standup",
+		Title:   "Standup {{date}}",
+		Content: "{{user}} at {{time}} on {{date \"Jan 2\"}}: {{prompt \"mood\" \"fine\"}}, {{prompt \"blocker\" | trim | upper}}",
+	}
+
+	s.Run("Rendering with all the variables", func() {
+		n, err := tmpl.Render(Data{Now: epoch, User: "jayson", Variables: map[string]string{"blocker": " none "}})
+		s.Require().NoError(err)
+		s.Equal("Standup 2021-01-31", n.GetTitle())
+		s.Equal("jayson at 09:30 on Jan 31: fine, NONE", n.GetContent())
+	})
+
+	s.Run("Rendering with a missing variable", func() {
+		_, err := tmpl.Render(Data{Now: epoch})
+		s.ErrorIs(err, ErrMissingVariable)
+		s.Contains(err.Error(), `"blocker"`)
+	})
+}
+
+func TestService(t *testing.T) {
+	suite.Run(t, new(ServiceTestSuite))
+}
+
+type ServiceTestSuite struct {
+	suite.Suite
+	fs    afero.Fs
+	clock *clock.Fake
+	svc   *Service
+}
+
+func (s *ServiceTestSuite) SetupTest() {
+	s.fs = afero.NewMemMapFs()
+	s.clock = clock.NewFake(epoch)
+	s.svc = New(s.fs, s.clock)
+}
+
+func (s *ServiceTestSuite) TestCRUD() {
+	created, err := s.svc.Create(dummyCtx, &Template{Name: "incident", Content: "{{prompt \"severity\"}}"})
+	s.Require().NoError(err)
+	s.Equal([]string{"severity"}, created.Prompts)
+	s.Equal(epoch, created.CreatedTime)
+
+	s.Run("Creating an existing template", func() {
+		_, err := s.svc.Create(dummyCtx, &Template{Name: "incident"})
+		s.ErrorIs(err, ErrExists)
+	})
+
+	s.Run("Creating an invalid template", func() {
+		_, err := s.svc.Create(dummyCtx, &Template{Name: "bad", Content: "{{range 1}}{{end}}"})
+		s.ErrorIs(err, ErrInvalid)
+	})
+
+	s.Run("Updating a template", func() {
+		s.clock.Advance(time.Hour)
+		updated, err := s.svc.Update(dummyCtx, &Template{Name: "incident", Content: "Updated"})
+		s.Require().NoError(err)
+		s.Equal(epoch, updated.CreatedTime)
+		s.Equal(epoch.Add(time.Hour), updated.UpdatedTime)
+		s.Empty(updated.Prompts)
+	})
+
+	s.Run("Updating a template that not exists", func() {
+		_, err := s.svc.Update(dummyCtx, &Template{Name: "missing"})
+		s.ErrorIs(err, ErrNotFound)
+	})
+
+	s.Run("Templates are loaded from the file", func() {
+		_, err := s.svc.Create(dummyCtx, &Template{Name: "standup", Title: "Standup {{date}}"})
+		s.Require().NoError(err)
+
+		templates, err := New(s.fs, s.clock).List(dummyCtx)
+		s.Require().NoError(err)
+		s.Require().Len(templates, 2)
+		s.Equal("incident", templates[0].Name)
+		s.Equal("Updated", templates[0].Content)
+		s.Equal("standup", templates[1].Name)
+	})
+
+	s.Run("Rendering a template", func() {
+		n, err := s.svc.Render(dummyCtx, "standup", Data{})
+		s.Require().NoError(err)
+		s.Equal("Standup 2021-01-31", n.GetTitle())
+	})
+
+	s.Run("Deleting a template", func() {
+		s.Require().NoError(s.svc.Delete(dummyCtx, "incident"))
+		_, err := s.svc.Get(dummyCtx, "incident")
+		s.ErrorIs(err, ErrNotFound)
+		s.ErrorIs(s.svc.Delete(dummyCtx, "incident"), ErrNotFound)
+	})
+}
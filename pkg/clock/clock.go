@@ -0,0 +1,45 @@
+package clock
+
+import "time"
+
+// Clock tells the current time and creates timers. It is use
+// in place of the time package so the time can be faked in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a new Timer that will send the current
+	// time on its channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer represents a single event. See time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing. It returns false if the
+	// timer has already expired or been stopped.
+	Stop() bool
+}
+
+// New returns a clock backed by the time package.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
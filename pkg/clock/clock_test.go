@@ -0,0 +1,58 @@
+package clock
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	suite.Run(t, new(FakeTestSuite))
+}
+
+type FakeTestSuite struct {
+	suite.Suite
+	start time.Time
+	clock *Fake
+}
+
+func (s *FakeTestSuite) SetupTest() {
+	s.start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.clock = NewFake(s.start)
+}
+
+func (s *FakeTestSuite) fired(t Timer) bool {
+	select {
+	case <-t.C():
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *FakeTestSuite) TestAdvance() {
+	timer := s.clock.NewTimer(time.Minute)
+	s.Equal(1, s.clock.Timers())
+
+	s.clock.Advance(30 * time.Second)
+	s.False(s.fired(timer))
+	s.Equal(s.start.Add(30*time.Second), s.clock.Now())
+
+	s.clock.Advance(30 * time.Second)
+	s.True(s.fired(timer))
+	s.Equal(0, s.clock.Timers())
+}
+
+func (s *FakeTestSuite) TestExpiredTimer() {
+	timer := s.clock.NewTimer(0)
+	s.True(s.fired(timer))
+}
+
+func (s *FakeTestSuite) TestStop() {
+	timer := s.clock.NewTimer(time.Minute)
+	s.True(timer.Stop())
+	s.False(timer.Stop())
+
+	s.clock.Advance(time.Hour)
+	s.False(s.fired(timer))
+}
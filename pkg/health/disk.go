@@ -0,0 +1,30 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errDiskUnsupported is an error when the free space of a disk
+// can't be checked on the current platform.
+var errDiskUnsupported = errors.New("health: disk space check is unsupported")
+
+// DiskSpace returns a checker that fails when the free space of the
+// disk where dir is located is less than minFree bytes. The check
+// always passes on the platforms where the free space is unknown.
+func DiskSpace(dir string, minFree uint64) Checker {
+	return CheckerFunc(func(context.Context) error {
+		free, err := diskFree(dir)
+		if err == errDiskUnsupported {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("health: unable to get the free space of '%s': %w", dir, err)
+		}
+		if free < minFree {
+			return fmt.Errorf("health: only %d bytes free in '%s', want at least %d", free, dir, minFree)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux
+// +build !darwin,!dragonfly,!freebsd,!linux
+
+package health
+
+// diskFree is unsupported on this platform.
+func diskFree(string) (uint64, error) {
+	return 0, errDiskUnsupported
+}
@@ -0,0 +1,202 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout is the default maximum duration of a check.
+const DefaultTimeout = 2 * time.Second
+
+// ErrDraining is an error when the server is shutting down and
+// shouldn't receive new requests.
+var ErrDraining = errors.New("health: server is draining")
+
+// Status is the status of a check or of a report.
+type Status string
+
+const (
+	// StatusUp means the check passed.
+	StatusUp Status = "up"
+	// StatusDown means the check failed or timed out.
+	StatusDown Status = "down"
+)
+
+// Checker checks the health of a component.
+type Checker interface {
+	// Check returns an error when the component is unhealthy.
+	// It must return when ctx is done.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is an adapter to use an ordinary function as a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the result of a single check.
+type Result struct {
+	Status     Status  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report is the result of all the checks of a probe.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Healthy tells whether all the checks of the report passed.
+func (r *Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+type check struct {
+	name    string
+	checker Checker
+}
+
+// Registry contains the checkers registered by the components for
+// the liveness and the readiness probes.
+//
+// The liveness probe tells whether the process is working and should
+// be restarted when it isn't. The readiness probe tells whether the
+// process can serve requests, e.g. its store is available and it
+// is not draining.
+type Registry struct {
+	// Timeout is the maximum duration of a check. A check that
+	// doesn't return in time is reported as down. Default is
+	// DefaultTimeout.
+	Timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  []check
+	readiness []check
+	draining  int32
+}
+
+// New returns a registry with a readiness check that fails
+// once Drain is called.
+func New() *Registry {
+	r := &Registry{Timeout: DefaultTimeout}
+	r.AddReadinessCheck("draining", CheckerFunc(func(context.Context) error {
+		if r.Draining() {
+			return ErrDraining
+		}
+		return nil
+	}))
+	return r
+}
+
+// AddLivenessCheck registers the checker c under name for the
+// liveness probe.
+func (r *Registry) AddLivenessCheck(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness = append(r.liveness, check{name: name, checker: c})
+}
+
+// AddReadinessCheck registers the checker c under name for the
+// readiness probe.
+func (r *Registry) AddReadinessCheck(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness = append(r.readiness, check{name: name, checker: c})
+}
+
+// Drain marks the server as draining so the readiness probe fails
+// and the load balancer stops sending new requests.
+func (r *Registry) Drain() {
+	atomic.StoreInt32(&r.draining, 1)
+}
+
+// Draining tells whether Drain was called.
+func (r *Registry) Draining() bool {
+	return atomic.LoadInt32(&r.draining) == 1
+}
+
+// Live runs the liveness checks concurrently and returns their report.
+func (r *Registry) Live(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.liveness...)
+	r.mu.RUnlock()
+	return r.run(ctx, checks)
+}
+
+// Ready runs the readiness checks concurrently and returns their report.
+func (r *Registry) Ready(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.readiness...)
+	r.mu.RUnlock()
+	return r.run(ctx, checks)
+}
+
+func (r *Registry) run(ctx context.Context, checks []check) *Report {
+	report := &Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := r.runCheck(ctx, c.checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (r *Registry) runCheck(ctx context.Context, c Checker) Result {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errc <- fmt.Errorf("health: check panicked: %v", p)
+			}
+		}()
+		errc <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("health: check timed out after %s", timeout)
+	}
+
+	result := Result{
+		Status:     StatusUp,
+		DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
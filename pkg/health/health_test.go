@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	suite.Run(t, new(RegistryTestSuite))
+}
+
+type RegistryTestSuite struct {
+	suite.Suite
+	reg *Registry
+}
+
+func (s *RegistryTestSuite) SetupTest() {
+	s.reg = New()
+	s.reg.Timeout = 50 * time.Millisecond
+}
+
+func (s *RegistryTestSuite) TestReady() {
+	s.reg.AddReadinessCheck("store", CheckerFunc(func(context.Context) error { return nil }))
+
+	report := s.reg.Ready(context.Background())
+	s.True(report.Healthy())
+	s.Len(report.Checks, 2)
+	s.Equal(StatusUp, report.Checks["store"].Status)
+	s.Equal(StatusUp, report.Checks["draining"].Status)
+}
+
+func (s *RegistryTestSuite) TestReadyFailingCheck() {
+	s.reg.AddReadinessCheck("store", CheckerFunc(func(context.Context) error { return errors.New("unavailable") }))
+	s.reg.AddReadinessCheck("other", CheckerFunc(func(context.Context) error { return nil }))
+
+	report := s.reg.Ready(context.Background())
+	s.False(report.Healthy())
+	s.Equal(StatusDown, report.Checks["store"].Status)
+	s.Equal("unavailable", report.Checks["store"].Error)
+	s.Equal(StatusUp, report.Checks["other"].Status)
+}
+
+func (s *RegistryTestSuite) TestTimeout() {
+	block := make(chan struct{})
+	defer close(block)
+	s.reg.AddLivenessCheck("slow", CheckerFunc(func(context.Context) error {
+		<-block
+		return nil
+	}))
+
+	start := time.Now()
+	report := s.reg.Live(context.Background())
+	s.Less(time.Since(start), time.Second)
+	s.False(report.Healthy())
+	s.Contains(report.Checks["slow"].Error, "timed out")
+}
+
+func (s *RegistryTestSuite) TestPanic() {
+	s.reg.AddLivenessCheck("panic", CheckerFunc(func(context.Context) error { panic("boom") }))
+
+	report := s.reg.Live(context.Background())
+	s.False(report.Healthy())
+	s.Contains(report.Checks["panic"].Error, "boom")
+}
+
+func (s *RegistryTestSuite) TestDrain() {
+	s.True(s.reg.Ready(context.Background()).Healthy())
+
+	s.reg.Drain()
+	s.True(s.reg.Draining())
+
+	report := s.reg.Ready(context.Background())
+	s.False(report.Healthy())
+	s.Equal(ErrDraining.Error(), report.Checks["draining"].Error)
+	s.True(s.reg.Live(context.Background()).Healthy())
+}
+
+func (s *RegistryTestSuite) TestDiskSpace() {
+	s.NoError(DiskSpace(s.T().TempDir(), 0).Check(context.Background()))
+	if _, err := diskFree(s.T().TempDir()); err == errDiskUnsupported {
+		s.T().Skip("disk space is unsupported")
+	}
+	s.Error(DiskSpace(s.T().TempDir(), 1<<62).Check(context.Background()))
+}
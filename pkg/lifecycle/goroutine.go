@@ -0,0 +1,49 @@
+package lifecycle
+
+import (
+	"context"
+)
+
+// Go returns a component that runs fn in a goroutine once started.
+// The context of fn is done when the component is stopped, and the
+// stop waits for fn to return. The component fails when fn returns
+// an error before it is stopped.
+func Go(fn func(ctx context.Context) error) Component {
+	return &goroutine{fn: fn, errc: make(chan error, 1)}
+}
+
+type goroutine struct {
+	fn     func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+	errc   chan error
+}
+
+func (g *goroutine) Start(context.Context) error {
+	// The start context is done once all the components are started.
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+	g.done = make(chan struct{})
+
+	go func() {
+		defer close(g.done)
+		if err := g.fn(ctx); err != nil && ctx.Err() == nil {
+			g.errc <- err
+		}
+	}()
+	return nil
+}
+
+func (g *goroutine) Stop(ctx context.Context) error {
+	g.cancel()
+	select {
+	case <-g.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *goroutine) Err() <-chan error {
+	return g.errc
+}
@@ -0,0 +1,276 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"noterfy/pkg/health"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultStartTimeout = 30 * time.Second
+	defaultStopTimeout  = 5 * time.Second
+)
+
+// ErrCycle is an error when the components depend on each other.
+var ErrCycle = errors.New("lifecycle: dependency cycle")
+
+// Component is a part of the application with a lifecycle, e.g.
+// a listener, a scheduler, a store or an event bus.
+type Component interface {
+	// Start starts the component. It must return once the component
+	// is started and run its long-running work in goroutines. ctx is
+	// done when the start takes too long.
+	Start(ctx context.Context) error
+	// Stop stops the component and releases its resources. ctx is
+	// done when the stop takes too long.
+	Stop(ctx context.Context) error
+}
+
+// Failer is implemented by the components that can fail after they
+// are started, e.g. a listener that stops accepting connections.
+type Failer interface {
+	// Err returns the channel that receives the error that
+	// made the component fail.
+	Err() <-chan error
+}
+
+// Hook is a component made of functions. A nil function does nothing.
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Start calls h.OnStart.
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+// Stop calls h.OnStop.
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+// Config contains the configuration of the manager.
+type Config struct {
+	// StartTimeout is the maximum duration to start all the
+	// components. Default is 30 seconds.
+	StartTimeout time.Duration
+	// StopTimeout is the maximum duration to stop all the
+	// components. Default is 5 seconds.
+	StopTimeout time.Duration
+	// Health is the registry of the health checks. When provided it
+	// is marked as draining before the components are stopped.
+	Health *health.Registry
+	// DrainDelay is the duration to wait after the health registry is
+	// marked as draining before the components are stopped, so the load
+	// balancer has time to notice the failing readiness probe.
+	DrainDelay time.Duration
+	// Signals are the signals that stop the components. Default is
+	// os.Interrupt and syscall.SIGTERM.
+	Signals []os.Signal
+}
+
+type entry struct {
+	name      string
+	component Component
+	dependsOn []string
+}
+
+// Manager starts the registered components in their dependency order
+// and stops them in the reverse order.
+type Manager struct {
+	conf    Config
+	entries []entry
+}
+
+// New takes the optional conf and returns a manager without components.
+// If nil is provided it will use the default configuration.
+func New(conf *Config) *Manager {
+	m := &Manager{}
+	if conf != nil {
+		m.conf = *conf
+	}
+	if m.conf.StartTimeout <= 0 {
+		m.conf.StartTimeout = defaultStartTimeout
+	}
+	if m.conf.StopTimeout <= 0 {
+		m.conf.StopTimeout = defaultStopTimeout
+	}
+	if len(m.conf.Signals) == 0 {
+		m.conf.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	return m
+}
+
+// Add registers the component c under name. The component is started
+// after the components it depends on and stopped before them. The
+// components without dependencies between them start in the order
+// they are added.
+func (m *Manager) Add(name string, c Component, dependsOn ...string) {
+	m.entries = append(m.entries, entry{name: name, component: c, dependsOn: dependsOn})
+}
+
+// Run starts the components and then waits until ctx is done, one of
+// the signals is received or a started component fails. The components
+// are then stopped in the reverse order. When a component fails to
+// start the components already started are stopped and its error is
+// returned.
+func (m *Manager) Run(ctx context.Context) error {
+	order, err := m.order()
+	if err != nil {
+		return err
+	}
+
+	startCtx, cancel := context.WithTimeout(ctx, m.conf.StartTimeout)
+	started, err := m.start(startCtx, order)
+	cancel()
+	if err != nil {
+		if serr := m.stop(started); serr != nil {
+			logrus.Error("lifecycle: unable to stop the components:", serr)
+		}
+		return err
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, m.conf.Signals...)
+	defer signal.Stop(sig)
+
+	failed := make(chan error, 1)
+	for _, e := range started {
+		if f, ok := e.component.(Failer); ok {
+			go func(name string, errc <-chan error) {
+				if err, ok := <-errc; ok && err != nil {
+					select {
+					case failed <- fmt.Errorf("lifecycle: %s failed: %w", name, err):
+					default:
+					}
+				}
+			}(e.name, f.Err())
+		}
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logrus.Info("lifecycle: stopping")
+	case s := <-sig:
+		logrus.Infof("lifecycle: received %s, stopping", s)
+	case runErr = <-failed:
+		logrus.Error(runErr)
+	}
+
+	m.drain()
+
+	if err := m.stop(started); err != nil && runErr == nil {
+		runErr = err
+	}
+	return runErr
+}
+
+func (m *Manager) start(ctx context.Context, order []entry) (started []entry, err error) {
+	for _, e := range order {
+		logrus.Debugf("lifecycle: starting %s", e.name)
+		if err := e.component.Start(ctx); err != nil {
+			return started, fmt.Errorf("lifecycle: unable to start %s: %w", e.name, err)
+		}
+		started = append(started, e)
+	}
+	return started, nil
+}
+
+// drain marks the health registry as draining and waits
+// for the drain delay.
+func (m *Manager) drain() {
+	if m.conf.Health == nil {
+		return
+	}
+
+	m.conf.Health.Drain()
+	if m.conf.DrainDelay > 0 {
+		logrus.Infof("lifecycle: draining for %s", m.conf.DrainDelay)
+		time.Sleep(m.conf.DrainDelay)
+	}
+}
+
+// stop stops the started components in the reverse order. A component
+// that fails to stop doesn't prevent the others from stopping.
+func (m *Manager) stop(started []entry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.conf.StopTimeout)
+	defer cancel()
+
+	var errs []string
+	for i := len(started) - 1; i >= 0; i-- {
+		e := started[i]
+		logrus.Debugf("lifecycle: stopping %s", e.name)
+		if err := e.component.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", e.name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("lifecycle: unable to stop %v", errs)
+	}
+	return nil
+}
+
+// order returns the entries sorted by their dependencies, keeping
+// the order they were added between the independent entries.
+func (m *Manager) order() ([]entry, error) {
+	byName := make(map[string]entry, len(m.entries))
+	for _, e := range m.entries {
+		if _, ok := byName[e.name]; ok {
+			return nil, fmt.Errorf("lifecycle: duplicate component %s", e.name)
+		}
+		byName[e.name] = e
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(m.entries))
+	order := make([]entry, 0, len(m.entries))
+
+	var visit func(e entry) error
+	visit = func(e entry) error {
+		switch state[e.name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrCycle, e.name)
+		}
+
+		state[e.name] = visiting
+		for _, dep := range e.dependsOn {
+			d, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("lifecycle: %s depends on the unknown component %s", e.name, dep)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[e.name] = visited
+		order = append(order, e)
+		return nil
+	}
+
+	for _, e := range m.entries {
+		if err := visit(e); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
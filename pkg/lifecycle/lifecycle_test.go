@@ -0,0 +1,179 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"noterfy/pkg/health"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	suite.Run(t, new(ManagerTestSuite))
+}
+
+type ManagerTestSuite struct {
+	suite.Suite
+	mu     sync.Mutex
+	events []string
+}
+
+func (s *ManagerTestSuite) SetupTest() {
+	s.events = nil
+}
+
+func (s *ManagerTestSuite) record(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func (s *ManagerTestSuite) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.events...)
+}
+
+// hook returns a component that records its start and stop.
+func (s *ManagerTestSuite) hook(name string, startErr error) Component {
+	return Hook{
+		OnStart: func(context.Context) error {
+			s.record("start " + name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			s.record("stop " + name)
+			return nil
+		},
+	}
+}
+
+// cancelled returns a context that is already done so Run stops
+// the components right after they are started.
+func cancelled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func (s *ManagerTestSuite) TestOrder() {
+	m := New(nil)
+	m.Add("server", s.hook("server", nil), "store", "scheduler")
+	m.Add("store", s.hook("store", nil))
+	m.Add("scheduler", s.hook("scheduler", nil), "store")
+	m.Add("logger", s.hook("logger", nil))
+
+	s.Require().NoError(m.Run(cancelled()))
+	s.Equal([]string{
+		"start store",
+		"start scheduler",
+		"start server",
+		"start logger",
+		"stop logger",
+		"stop server",
+		"stop scheduler",
+		"stop store",
+	}, s.recorded())
+}
+
+func (s *ManagerTestSuite) TestStartFailure() {
+	errStart := errors.New("address already in use")
+
+	m := New(nil)
+	m.Add("store", s.hook("store", nil))
+	m.Add("server", s.hook("server", errStart), "store")
+	m.Add("late", s.hook("late", nil), "server")
+
+	err := m.Run(context.Background())
+	s.True(errors.Is(err, errStart))
+	s.Contains(err.Error(), "server")
+	s.Equal([]string{"start store", "start server", "stop store"}, s.recorded())
+}
+
+func (s *ManagerTestSuite) TestComponentFailure() {
+	errServe := errors.New("listener closed")
+
+	m := New(nil)
+	m.Add("store", s.hook("store", nil))
+	m.Add("worker", Go(func(ctx context.Context) error {
+		return errServe
+	}), "store")
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		s.True(errors.Is(err, errServe))
+	case <-time.After(5 * time.Second):
+		s.FailNow("the failure didn't stop the manager")
+	}
+	s.Equal([]string{"start store", "stop store"}, s.recorded())
+}
+
+func (s *ManagerTestSuite) TestGoStop() {
+	stopped := make(chan struct{})
+
+	m := New(nil)
+	m.Add("worker", Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	}))
+
+	s.Require().NoError(m.Run(cancelled()))
+	select {
+	case <-stopped:
+	default:
+		s.Fail("the worker was not stopped")
+	}
+}
+
+func (s *ManagerTestSuite) TestStopTimeout() {
+	m := New(&Config{StopTimeout: 10 * time.Millisecond})
+	m.Add("stuck", Go(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+	m.Add("other", s.hook("other", nil))
+
+	err := m.Run(cancelled())
+	s.Error(err)
+	s.Contains(err.Error(), "stuck")
+	s.Equal([]string{"start other", "stop other"}, s.recorded())
+}
+
+func (s *ManagerTestSuite) TestDrainBeforeStop() {
+	reg := health.New()
+
+	m := New(&Config{Health: reg})
+	m.Add("server", Hook{OnStop: func(ctx context.Context) error {
+		s.True(reg.Draining())
+		s.False(reg.Ready(ctx).Healthy())
+		s.record("stop server")
+		return nil
+	}})
+
+	s.Require().NoError(m.Run(cancelled()))
+	s.Equal([]string{"stop server"}, s.recorded())
+}
+
+func (s *ManagerTestSuite) TestInvalidDependencies() {
+	m := New(nil)
+	m.Add("a", s.hook("a", nil), "b")
+	m.Add("b", s.hook("b", nil), "a")
+	s.True(errors.Is(m.Run(cancelled()), ErrCycle))
+
+	m = New(nil)
+	m.Add("a", s.hook("a", nil), "missing")
+	s.Error(m.Run(cancelled()))
+
+	m = New(nil)
+	m.Add("a", s.hook("a", nil))
+	m.Add("a", s.hook("a", nil))
+	s.Error(m.Run(cancelled()))
+
+	s.Empty(s.recorded())
+}
@@ -0,0 +1,78 @@
+// Package logger contains the helpers of the logrus logging that
+// correlate the logs of a request through the context.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// FormatText is the human readable log format.
+	FormatText = "text"
+	// FormatJSON is the log format with a JSON object per line.
+	FormatJSON = "json"
+)
+
+type entryKey struct{}
+
+// WithContext returns a copy of ctx with the log entry. The entry
+// is use by the code down the call chain through FromContext.
+func WithContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns the log entry in ctx. It returns an entry
+// of the standard logger when there's none.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// Configure sets the level, the format and the output of the logger.
+// The output is "stdout", "stderr" or a path of a file where the logs
+// are appended. The empty values are left unchanged. It returns the
+// opened file, if any, so it can be closed by the caller.
+func Configure(l *logrus.Logger, level, format, output string) (io.Closer, error) {
+	if level != "" {
+		lvl, err := logrus.ParseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		l.SetLevel(lvl)
+	}
+
+	switch strings.ToLower(format) {
+	case "":
+	case FormatText:
+		l.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	case FormatJSON:
+		l.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return nil, fmt.Errorf("logger: unknown format '%s'", format)
+	}
+
+	switch output {
+	case "":
+		return nil, nil
+	case "stdout":
+		l.SetOutput(os.Stdout)
+		return nil, nil
+	case "stderr":
+		l.SetOutput(os.Stderr)
+		return nil, nil
+	default:
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		l.SetOutput(file)
+		return file, nil
+	}
+}
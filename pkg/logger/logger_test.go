@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	// The standard logger is the fallback.
+	assert.Equal(t, logrus.StandardLogger(), FromContext(context.Background()).Logger)
+
+	entry := logrus.NewEntry(logrus.New()).WithField("request_id", "abc")
+	ctx := WithContext(context.Background(), entry)
+	assert.Equal(t, entry, FromContext(ctx))
+}
+
+func TestConfigure(t *testing.T) {
+	l := logrus.New()
+	path := filepath.Join(t.TempDir(), "noterfy.log")
+
+	closer, err := Configure(l, "debug", FormatJSON, path)
+	require.NoError(t, err)
+	require.NotNil(t, closer)
+
+	l.Debug("configured")
+	require.NoError(t, closer.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"msg":"configured"`)
+	assert.Equal(t, logrus.DebugLevel, l.GetLevel())
+
+	_, err = Configure(l, "loud", "", "")
+	assert.Error(t, err)
+	_, err = Configure(l, "", "xml", "")
+	assert.Error(t, err)
+}
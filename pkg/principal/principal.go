@@ -0,0 +1,35 @@
+package principal
+
+import "context"
+
+// MethodClientCert is the authentication method of a principal
+// identified by its verified TLS client certificate.
+const MethodClientCert = "client-cert"
+
+// MethodAPIKey is the authentication method of a principal
+// identified by a configured API key.
+const MethodAPIKey = "api-key"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Name identifies the caller, e.g. the subject of its
+	// client certificate.
+	Name string
+	// Method is how the caller was authenticated, e.g.
+	// MethodClientCert.
+	Method string
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx with the principal p.
+func WithContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal in ctx. It returns false when
+// the caller of the request is anonymous.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,139 @@
+// Package problem implements the problem details of the HTTP APIs
+// (RFC 7807). Every kind of error has a stable machine-readable
+// code so the clients don't depend on the human-readable titles.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"noterfy/pkg/validation"
+)
+
+// MediaType is the media type of the problem details.
+const MediaType = "application/problem+json"
+
+// typePrefix is the prefix of the URI of the problem types.
+const typePrefix = "urn:noterfy:problem:"
+
+// CodeInternal is the code of the unexpected errors.
+const CodeInternal = "internal"
+
+// Internal is the kind of the unexpected errors. Its title
+// doesn't leak the details of the error.
+var Internal = Kind{Code: CodeInternal, Status: http.StatusInternalServerError, Title: "Unexpected error"}
+
+// Kind is a kind of error of the API.
+type Kind struct {
+	// Code is the stable machine-readable code of the kind,
+	// e.g. "note.not_found".
+	Code string
+	// Status is the http status code of the responses.
+	Status int
+	// Title is the short human-readable summary of the kind.
+	// It doesn't change from occurrence to occurrence.
+	Title string
+}
+
+// Type returns the URI identifying the problem type of the kind.
+func (k Kind) Type() string {
+	return typePrefix + k.Code
+}
+
+// Error is an error of a kind. It lets the code returning the
+// error choose its kind rather than rely on a Mapping.
+type Error struct {
+	Kind Kind
+	// Detail is the explanation of this occurrence of the
+	// problem. It is optional.
+	Detail string
+	// Err is the cause of the error.
+	Err error
+}
+
+// New returns an error of the kind caused by err. The err can be nil.
+func New(kind Kind, detail string, err error) *Error {
+	return &Error{Kind: kind, Detail: detail, Err: err}
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Code
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Mapping maps the errors to their kinds. The errors are matched
+// in order with errors.Is so they can be wrapped at any depth.
+type Mapping []struct {
+	Err  error
+	Kind Kind
+}
+
+// Kind returns the kind of err. The kind of an *Error in the chain
+// of err takes precedence over the mapping. It returns Internal
+// when err is of no known kind.
+func (m Mapping) Kind(err error) Kind {
+	var perr *Error
+	if errors.As(err, &perr) {
+		return perr.Kind
+	}
+	for _, entry := range m {
+		if errors.Is(err, entry.Err) {
+			return entry.Kind
+		}
+	}
+	return Internal
+}
+
+// Problem is the problem details of an error response.
+type Problem struct {
+	// Type is the URI identifying the problem type.
+	Type string `json:"type" example:"urn:noterfy:problem:note.not_found"`
+	// Title is the short human-readable summary of the problem type.
+	Title string `json:"title" example:"Note not found"`
+	// Status is the http status code of the response.
+	Status int `json:"status" example:"404"`
+	// Detail is the explanation of this occurrence of the problem.
+	Detail string `json:"detail,omitempty" example:"No note has the ID ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	// Instance is the URI of the request with the problem.
+	Instance string `json:"instance,omitempty" example:"/v1/note/ffffffff-ffff-ffff-ffff-ffffffffffff"`
+	// Code is the stable machine-readable code of the problem type.
+	Code string `json:"code" example:"note.not_found"`
+	// Violations are the invalid fields of the request.
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+// FromError returns the problem of err with the kind. The detail
+// of an *Error and the violations of a *validation.Error in the
+// chain of err are part of the problem.
+func FromError(err error, kind Kind) Problem {
+	p := Problem{
+		Type:       kind.Type(),
+		Title:      kind.Title,
+		Status:     kind.Status,
+		Code:       kind.Code,
+		Violations: validation.Violations(err),
+	}
+	var perr *Error
+	if errors.As(err, &perr) {
+		p.Detail = perr.Detail
+	}
+	return p
+}
+
+// Write writes the problem p to w as the response.
+func Write(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
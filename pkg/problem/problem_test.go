@@ -0,0 +1,83 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"noterfy/pkg/validation"
+	"testing"
+)
+
+var (
+	errNotFound = errors.New("not found")
+	notFound    = Kind{Code: "note.not_found", Status: http.StatusNotFound, Title: "Note not found"}
+	conflict    = Kind{Code: "note.conflict", Status: http.StatusConflict, Title: "Note already exists"}
+)
+
+func TestMapping(t *testing.T) {
+	mapping := Mapping{{Err: errNotFound, Kind: notFound}}
+
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{name: "Sentinel error", err: errNotFound, want: notFound},
+		{
+			name: "Deeply wrapped error",
+			err:  fmt.Errorf("service: %w", fmt.Errorf("store: %w", errNotFound)),
+			want: notFound,
+		},
+		{
+			name: "Typed error takes precedence",
+			err:  fmt.Errorf("service: %w", New(conflict, "", errNotFound)),
+			want: conflict,
+		},
+		{name: "Unknown error", err: errors.New("boom"), want: Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mapping.Kind(tt.err))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	err := New(notFound, "no note has the ID 1", errNotFound)
+	assert.Equal(t, "note.not_found: no note has the ID 1: not found", err.Error())
+	assert.True(t, errors.Is(err, errNotFound))
+	assert.Equal(t, "urn:noterfy:problem:note.not_found", notFound.Type())
+}
+
+func TestWrite(t *testing.T) {
+	var v validation.Validator
+	v.Addf("title", validation.CodeTooLong, "must be at most 256 characters")
+	err := fmt.Errorf("service: %w", New(conflict, "the title is taken", v.Err()))
+
+	p := FromError(err, conflict)
+	p.Instance = "/v1/note"
+	rec := httptest.NewRecorder()
+	Write(rec, p)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, MediaType, rec.Header().Get("Content-Type"))
+
+	var got map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "urn:noterfy:problem:note.conflict",
+		"title":    "Note already exists",
+		"status":   float64(http.StatusConflict),
+		"detail":   "the title is taken",
+		"instance": "/v1/note",
+		"code":     "note.conflict",
+		"violations": []interface{}{
+			map[string]interface{}{"field": "title", "code": "too_long", "message": "must be at most 256 characters"},
+		},
+	}, got)
+}
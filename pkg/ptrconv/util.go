@@ -17,6 +17,11 @@ func BoolPointer(b bool) *bool {
 	return &b
 }
 
+// Float64Pointer converts f float into a pointer float.
+func Float64Pointer(f float64) *float64 {
+	return &f
+}
+
 // BoolValue converts the pointer boolean b into a boolean value.
 func BoolValue(b *bool) bool {
 	if b == nil {
@@ -40,3 +45,11 @@ func TimeValue(t *time.Time) time.Time {
 	}
 	return *t
 }
+
+// Float64Value converts the pointer float f into a float value.
+func Float64Value(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
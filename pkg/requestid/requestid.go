@@ -0,0 +1,18 @@
+package requestid
+
+import "context"
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx with the request ID id.
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx. It returns an empty
+// string when ctx is not the context of a request, e.g. a
+// background job.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"context"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// ServerOptions returns the go-kit server options that trace the
+// requests handled by the server with the tracers of provider.
+func ServerOptions(provider oteltrace.TracerProvider) []httptransport.ServerOption {
+	return []httptransport.ServerOption{
+		httptransport.ServerBefore(ServerBefore(provider)),
+		httptransport.ServerFinalizer(ServerFinalizer()),
+	}
+}
+
+// ServerBefore returns a go-kit request func that starts the server
+// span of the request. The span is a child of the span in the
+// traceparent header of the request when there's one.
+func ServerBefore(provider oteltrace.TracerProvider) httptransport.RequestFunc {
+	tracer := provider.Tracer(InstrumentationName)
+	return func(ctx context.Context, r *http.Request) context.Context {
+		ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+
+		route := routeTemplate(r)
+		ctx, _ = tracer.Start(ctx, r.Method+" "+route,
+			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+			oteltrace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", route, r)...),
+		)
+		return ctx
+	}
+}
+
+// ServerFinalizer returns a go-kit finalizer that ends the server
+// span of the request.
+func ServerFinalizer() httptransport.ServerFinalizerFunc {
+	return func(ctx context.Context, code int, _ *http.Request) {
+		span := oteltrace.SpanFromContext(ctx)
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(code)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(code, oteltrace.SpanKindServer))
+		span.End()
+	}
+}
+
+// DecodeRequestFunc returns a go-kit decoder that traces the
+// decoding of the request by dec in a child span.
+func DecodeRequestFunc(dec httptransport.DecodeRequestFunc) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		_, span := Start(ctx, "decode")
+		defer span.End()
+
+		request, err := dec(ctx, r)
+		SetError(span, err)
+		return request, err
+	}
+}
+
+// ClientOptions returns the go-kit client options that trace the
+// requests sent by the client and propagate the trace to the server.
+func ClientOptions() []httptransport.ClientOption {
+	return []httptransport.ClientOption{
+		httptransport.ClientBefore(ClientBefore()),
+		httptransport.ClientFinalizer(ClientFinalizer()),
+	}
+}
+
+// ClientBefore returns a go-kit request func that starts the client
+// span of the request and sets its traceparent header. It does nothing
+// when the request isn't traced.
+func ClientBefore() httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		ctx, span := Start(ctx, r.Method+" "+r.URL.Path,
+			oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+			oteltrace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(r)...),
+		)
+		if !span.SpanContext().IsValid() {
+			return ctx
+		}
+
+		propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+		return context.WithValue(ctx, clientSpanKey{}, span)
+	}
+}
+
+// ClientFinalizer returns a go-kit finalizer that ends the client
+// span of the request.
+func ClientFinalizer() httptransport.ClientFinalizerFunc {
+	return func(ctx context.Context, err error) {
+		// The span in ctx is the span of the caller when the request
+		// isn't traced so only the span of ClientBefore is ended.
+		span, ok := ctx.Value(clientSpanKey{}).(oteltrace.Span)
+		if !ok {
+			return
+		}
+		SetError(span, err)
+		span.End()
+	}
+}
+
+type clientSpanKey struct{}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
@@ -0,0 +1,90 @@
+// Package trace wires the OpenTelemetry tracing into the service. It
+// creates the tracer provider with the exporter of the spans and adapts
+// the spans to the go-kit transports. The spans are propagated between
+// the services with the W3C traceparent header.
+package trace
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"io"
+	"net/url"
+)
+
+// InstrumentationName is the name of the tracers of the service.
+const InstrumentationName = "noterfy"
+
+// propagator propagates the span context in the traceparent header.
+var propagator = propagation.TraceContext{}
+
+// NewTracerProvider takes the name of the service and the exporter
+// of the spans and returns a tracer provider that sends the ended
+// spans to exporter in batches.
+func NewTracerProvider(serviceName string, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+	)
+}
+
+// NewWriterExporter takes w, e.g. os.Stdout or a file, and returns
+// an exporter that writes the spans to w as JSON.
+func NewWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// NewOTLPExporter takes the URL of the OTLP/HTTP traces endpoint of
+// the collector, e.g. http://localhost:4318/v1/traces, and returns an
+// exporter that sends the spans to the collector.
+func NewOTLPExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(u.Path),
+	}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("trace: unsupported scheme of the OTLP endpoint '%s'", u.Scheme)
+	}
+	return otlptrace.New(ctx, otlptracehttp.NewClient(opts...))
+}
+
+// Start starts a child span of the span in ctx with the tracer provider
+// of that span. The returned span is a no-op when there's no span in
+// ctx so the instrumented code doesn't need to check if the request
+// is traced.
+func Start(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	return oteltrace.SpanFromContext(ctx).
+		TracerProvider().
+		Tracer(InstrumentationName).
+		Start(ctx, name, opts...)
+}
+
+// SetError records err in the span and sets its status to error
+// when err isn't nil.
+func SetError(span oteltrace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
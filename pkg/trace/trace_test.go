@@ -0,0 +1,123 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func TestStart(t *testing.T) {
+	provider, recorder := newTestProvider()
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "root")
+	_, child := Start(ctx, "child")
+	SetError(child, errors.New("failed"))
+	child.End()
+	root.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	gotChild, gotRoot := spans[0], spans[1]
+	assert.Equal(t, "child", gotChild.Name())
+	assert.Equal(t, oteltrace.SpanKindInternal, gotChild.SpanKind())
+	assert.Equal(t, gotRoot.SpanContext().TraceID(), gotChild.SpanContext().TraceID())
+	assert.Equal(t, gotRoot.SpanContext().SpanID(), gotChild.Parent().SpanID())
+	assert.Equal(t, codes.Error, gotChild.Status().Code)
+	assert.Equal(t, "failed", gotChild.Status().Description)
+	assert.Equal(t, InstrumentationName, gotChild.InstrumentationLibrary().Name)
+}
+
+func TestStartWithoutSpan(t *testing.T) {
+	ctx, span := Start(context.Background(), "untraced")
+	assert.False(t, span.IsRecording())
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsValid())
+
+	// The no-op span can be used as any span.
+	SetError(span, errors.New("failed"))
+	span.End()
+}
+
+func TestPropagation(t *testing.T) {
+	provider, recorder := newTestProvider()
+
+	router := mux.NewRouter()
+	router.Path("/notes/{id}").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before := ServerBefore(provider)
+		ctx := before(r.Context(), r)
+		ServerFinalizer()(ctx, http.StatusInternalServerError, r)
+	}))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, caller := provider.Tracer("test").Start(context.Background(), "caller")
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/notes/1", nil)
+	require.NoError(t, err)
+	clientCtx := ClientBefore()(ctx, req)
+	assert.NotEmpty(t, req.Header.Get("traceparent"))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	ClientFinalizer()(clientCtx, nil)
+	caller.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	serverSpan, clientSpan := spans[0], spans[1]
+
+	assert.Equal(t, "GET /notes/{id}", serverSpan.Name())
+	assert.Equal(t, oteltrace.SpanKindServer, serverSpan.SpanKind())
+	assert.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID())
+	assert.True(t, serverSpan.Parent().IsRemote())
+	assert.Equal(t, codes.Error, serverSpan.Status().Code)
+
+	assert.Equal(t, "GET /notes/1", clientSpan.Name())
+	assert.Equal(t, oteltrace.SpanKindClient, clientSpan.SpanKind())
+	assert.Equal(t, caller.SpanContext().SpanID(), clientSpan.Parent().SpanID())
+}
+
+func TestClientBeforeWithoutSpan(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	ctx := ClientBefore()(context.Background(), req)
+	assert.Empty(t, req.Header.Get("traceparent"))
+
+	// The finalizer doesn't end a span that ClientBefore didn't start.
+	ClientFinalizer()(ctx, nil)
+}
+
+func TestNewOTLPExporter(t *testing.T) {
+	paths := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(context.Background(), server.URL+"/v1/traces")
+	require.NoError(t, err)
+	provider := NewTracerProvider("test", exporter)
+
+	_, span := provider.Tracer("test").Start(context.Background(), "span")
+	span.End()
+
+	// The pending spans are sent on shutdown.
+	require.NoError(t, provider.Shutdown(context.Background()))
+	assert.Equal(t, "/v1/traces", <-paths)
+
+	_, err = NewOTLPExporter(context.Background(), "ftp://localhost/v1/traces")
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+// Package validation checks the input of the requests and collects
+// the violations of every invalid field in a single error.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The codes of the violations.
+const (
+	// CodeMalformed is the code of a value that can't be parsed,
+	// e.g. an identifier that is not a UUID.
+	CodeMalformed = "malformed"
+	// CodeRequired is the code of a missing value.
+	CodeRequired = "required"
+	// CodeTooLong is the code of a value exceeding its maximum size.
+	CodeTooLong = "too_long"
+	// CodeInvalidUTF8 is the code of a text that is not valid UTF-8.
+	CodeInvalidUTF8 = "invalid_utf8"
+	// CodeControlCharacter is the code of a text with a disallowed
+	// control character.
+	CodeControlCharacter = "control_character"
+	// CodeOutOfRange is the code of a number outside of its bounds.
+	CodeOutOfRange = "out_of_range"
+	// CodeReadOnly is the code of a value that the clients
+	// can't change.
+	CodeReadOnly = "read_only"
+	// CodeInvalid is the code of a well-formed value that
+	// can't be accepted for another reason.
+	CodeInvalid = "invalid"
+)
+
+// ErrInvalid is the cause of every *Error.
+var ErrInvalid = errors.New("validation: invalid input")
+
+// Violation is an invalid field of the input.
+type Violation struct {
+	// Field is the name of the field, e.g. "title" or "page".
+	Field string `json:"field" example:"title"`
+	// Code identifies the kind of the violation, e.g. CodeTooLong.
+	Code string `json:"code" example:"too_long"`
+	// Message tells why the field is invalid.
+	Message string `json:"message" example:"must be at most 256 characters, got 300"`
+}
+
+func (v Violation) String() string {
+	return v.Field + ": " + v.Message
+}
+
+// Error is an error when one or more fields of the input
+// are invalid.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.String())
+	}
+	return "validation: invalid input: " + strings.Join(parts, "; ")
+}
+
+// Unwrap returns ErrInvalid so the error can be checked with errors.Is.
+func (e *Error) Unwrap() error {
+	return ErrInvalid
+}
+
+// Malformed tells whether one of the violations is a value that
+// can't be parsed. The malformed requests are distinguished from
+// the well-formed requests with invalid values.
+func (e *Error) Malformed() bool {
+	for _, v := range e.Violations {
+		if v.Code == CodeMalformed {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator collects the violations of the fields. The zero
+// value is ready to use.
+type Validator struct {
+	violations []Violation
+}
+
+// Addf adds a violation of the field with the code.
+func (v *Validator) Addf(field, code, format string, a ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Field:   field,
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// Check adds a violation of the field with the code when ok is false.
+func (v *Validator) Check(ok bool, field, code, format string, a ...interface{}) {
+	if !ok {
+		v.Addf(field, code, format, a...)
+	}
+}
+
+// UUID parses the value of the field as a UUID. It returns
+// uuid.Nil when the value is missing or malformed.
+func (v *Validator) UUID(field, value string) uuid.UUID {
+	if value == "" {
+		v.Addf(field, CodeRequired, "must not be empty")
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		v.Addf(field, CodeMalformed, "must be a UUID, got '%s'", truncate(value))
+		return uuid.Nil
+	}
+	return id
+}
+
+// Uint parses the optional value of the field as an unsigned
+// integer between min and max. It returns zero when the value
+// is empty or invalid.
+func (v *Validator) Uint(field, value string, min, max uint64) uint64 {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		v.Addf(field, CodeMalformed, "must be a positive integer, got '%s'", truncate(value))
+		return 0
+	}
+	if n < min || n > max {
+		v.Addf(field, CodeOutOfRange, "must be between %d and %d, got %d", min, max, n)
+		return 0
+	}
+	return n
+}
+
+// Text checks that the text of the field is valid UTF-8 of at most
+// maxLen characters without control characters. The characters in
+// allowed are accepted, e.g. the line breaks of a multiline text.
+func (v *Validator) Text(field, value string, maxLen int, allowed string) {
+	if !utf8.ValidString(value) {
+		v.Addf(field, CodeInvalidUTF8, "must be valid UTF-8")
+		return
+	}
+	if n := utf8.RuneCountInString(value); n > maxLen {
+		v.Addf(field, CodeTooLong, "must be at most %d characters, got %d", maxLen, n)
+	}
+	for i, r := range value {
+		if unicode.IsControl(r) && !strings.ContainsRune(allowed, r) {
+			v.Addf(field, CodeControlCharacter, "must not contain the control character %U at byte %d", r, i)
+			return
+		}
+	}
+}
+
+// Err returns an *Error with the violations or nil when
+// there is none.
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: v.violations}
+}
+
+// Violations returns the violations of err when it is
+// an *Error, even if wrapped.
+func Violations(err error) []Violation {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Violations
+	}
+	return nil
+}
+
+// Malformed returns an *Error with a single violation of the field
+// that can't be parsed, e.g. a request body that is not JSON.
+func Malformed(field string, err error) error {
+	var v Validator
+	v.Addf(field, CodeMalformed, "%s", err)
+	return v.Err()
+}
+
+// truncate shortens the value echoed back in the messages.
+func truncate(value string) string {
+	const max = 64
+	if len(value) <= max {
+		return value
+	}
+	// Keep the truncated value valid UTF-8.
+	for i := max; i > 0; i-- {
+		if utf8.RuneStart(value[i]) {
+			return value[:i] + "..."
+		}
+	}
+	return value[:max] + "..."
+}
@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	t.Run("No violation", func(t *testing.T) {
+		var v Validator
+		id := uuid.New()
+		assert.Equal(t, id, v.UUID("id", id.String()))
+		assert.Equal(t, uint64(3), v.Uint("page", "3", 1, 10))
+		assert.Zero(t, v.Uint("size", "", 1, 10))
+		v.Text("title", "Unit Test ✓", 11, "")
+		v.Text("content", "Line 1\n\tLine 2\r\n", 100, "\t\n\r")
+		assert.NoError(t, v.Err())
+	})
+
+	tests := []struct {
+		name     string
+		validate func(v *Validator)
+		want     Violation
+	}{
+		{
+			name:     "Empty UUID",
+			validate: func(v *Validator) { v.UUID("id", "") },
+			want:     Violation{Field: "id", Code: CodeRequired, Message: "must not be empty"},
+		},
+		{
+			name:     "Malformed UUID",
+			validate: func(v *Validator) { v.UUID("id", "1234") },
+			want:     Violation{Field: "id", Code: CodeMalformed, Message: "must be a UUID, got '1234'"},
+		},
+		{
+			name:     "Malformed number",
+			validate: func(v *Validator) { v.Uint("page", "-1", 1, 10) },
+			want:     Violation{Field: "page", Code: CodeMalformed, Message: "must be a positive integer, got '-1'"},
+		},
+		{
+			name:     "Number out of range",
+			validate: func(v *Validator) { v.Uint("size", "11", 1, 10) },
+			want:     Violation{Field: "size", Code: CodeOutOfRange, Message: "must be between 1 and 10, got 11"},
+		},
+		{
+			name:     "Text too long",
+			validate: func(v *Validator) { v.Text("title", "✓✓✓", 2, "") },
+			want:     Violation{Field: "title", Code: CodeTooLong, Message: "must be at most 2 characters, got 3"},
+		},
+		{
+			name:     "Invalid UTF-8",
+			validate: func(v *Validator) { v.Text("title", "\xff", 10, "") },
+			want:     Violation{Field: "title", Code: CodeInvalidUTF8, Message: "must be valid UTF-8"},
+		},
+		{
+			name:     "Control character",
+			validate: func(v *Validator) { v.Text("title", "Line 1\nLine 2", 100, "\t") },
+			want:     Violation{Field: "title", Code: CodeControlCharacter, Message: "must not contain the control character U+000A at byte 6"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			tt.validate(&v)
+			err := v.Err()
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalid))
+			assert.Equal(t, []Violation{tt.want}, Violations(err))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	var v Validator
+	v.Addf("title", CodeTooLong, "must be at most %d characters", 2)
+	v.Check(false, "size", CodeOutOfRange, "must be at most %d", 100)
+	v.Check(true, "page", CodeOutOfRange, "must be at most %d", 100)
+
+	err := fmt.Errorf("service: %w", v.Err())
+	assert.Equal(t, "service: validation: invalid input: title: must be at most 2 characters; size: must be at most 100", err.Error())
+	assert.Len(t, Violations(err), 2)
+
+	var verr *Error
+	require.True(t, errors.As(err, &verr))
+	assert.False(t, verr.Malformed())
+
+	err = Malformed("body", errors.New("unexpected EOF"))
+	require.True(t, errors.As(err, &verr))
+	assert.True(t, verr.Malformed())
+	assert.Equal(t, []Violation{{Field: "body", Code: CodeMalformed, Message: "unexpected EOF"}}, verr.Violations)
+
+	assert.Nil(t, Violations(errors.New("other")))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short"))
+	got := truncate(strings.Repeat("a", 63) + "✓✓")
+	assert.Equal(t, strings.Repeat("a", 63)+"...", got)
+}